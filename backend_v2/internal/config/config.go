@@ -29,6 +29,14 @@ type Config struct {
 	// Chamber
 	HeartbeatTimeout time.Duration
 	CleanupInterval  time.Duration
+
+	// Chamber local API
+	LocalAPIPort    string
+	LocalAPITimeout time.Duration
+
+	// Logging
+	LogLevel  string // debug, info, warn or error, can be changed at runtime
+	LogFormat string // text or json
 }
 
 // Load loads configuration from environment variables
@@ -43,6 +51,9 @@ func Load() (*Config, error) {
 		GinMode:       getEnv("GIN_MODE", "debug"),
 		JWTSecret:     getEnv("JWT_SECRET", "default-secret-key"),
 		APIKey:        getEnv("API_KEY", ""),
+		LocalAPIPort:  getEnv("LOCAL_API_PORT", "8090"),
+		LogLevel:      getEnv("LOG_LEVEL", "info"),
+		LogFormat:     getEnv("LOG_FORMAT", "text"),
 	}
 
 	// Parse JWT expiration
@@ -61,6 +72,10 @@ func Load() (*Config, error) {
 	cleanupInterval := getEnvInt("CLEANUP_INTERVAL", 300)
 	cfg.CleanupInterval = time.Duration(cleanupInterval) * time.Second
 
+	// Parse local API timeout
+	localAPITimeout := getEnvInt("LOCAL_API_TIMEOUT", 60)
+	cfg.LocalAPITimeout = time.Duration(localAPITimeout) * time.Second
+
 	return cfg, nil
 }
 
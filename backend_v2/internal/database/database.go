@@ -5,20 +5,28 @@ import (
 	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MongoDB holds the database connection
 type MongoDB struct {
-	Client                      *mongo.Client
-	Database                    *mongo.Database
-	ChambersCollection          *mongo.Collection
-	ExperimentsCollection       *mongo.Collection
-	UsersCollection             *mongo.Collection
-	SessionsCollection          *mongo.Collection
-	APITokensCollection         *mongo.Collection
-	UserChamberAccessCollection *mongo.Collection
+	Client                         *mongo.Client
+	Database                       *mongo.Database
+	ChambersCollection             *mongo.Collection
+	ExperimentsCollection          *mongo.Collection
+	UsersCollection                *mongo.Collection
+	SessionsCollection             *mongo.Collection
+	APITokensCollection            *mongo.Collection
+	UserChamberAccessCollection    *mongo.Collection
+	DeviationsCollection           *mongo.Collection
+	SetpointApplicationsCollection *mongo.Collection
+	AlarmRulesCollection           *mongo.Collection
+	AlarmsCollection               *mongo.Collection
+	OverridesCollection            *mongo.Collection
+	DiscoveryRulesCollection       *mongo.Collection
+	DiscoveryReportsCollection     *mongo.Collection
 }
 
 // Connect establishes a connection to MongoDB
@@ -43,16 +51,80 @@ func Connect(uri, databaseName string) (*MongoDB, error) {
 	// Get database and collections
 	db := client.Database(databaseName)
 
-	return &MongoDB{
-		Client:                      client,
-		Database:                    db,
-		ChambersCollection:          db.Collection("chambers"),
-		ExperimentsCollection:       db.Collection("experiments"),
-		UsersCollection:             db.Collection("users"),
-		SessionsCollection:          db.Collection("sessions"),
-		APITokensCollection:         db.Collection("api_tokens"),
-		UserChamberAccessCollection: db.Collection("user_chamber_access"),
-	}, nil
+	m := &MongoDB{
+		Client:                         client,
+		Database:                       db,
+		ChambersCollection:             db.Collection("chambers"),
+		ExperimentsCollection:          db.Collection("experiments"),
+		UsersCollection:                db.Collection("users"),
+		SessionsCollection:             db.Collection("sessions"),
+		APITokensCollection:            db.Collection("api_tokens"),
+		UserChamberAccessCollection:    db.Collection("user_chamber_access"),
+		DeviationsCollection:           db.Collection("deviations"),
+		SetpointApplicationsCollection: db.Collection("setpoint_applications"),
+		AlarmRulesCollection:           db.Collection("alarm_rules"),
+		AlarmsCollection:               db.Collection("alarms"),
+		OverridesCollection:            db.Collection("overrides"),
+		DiscoveryRulesCollection:       db.Collection("discovery_rules"),
+		DiscoveryReportsCollection:     db.Collection("discovery_reports"),
+	}
+
+	if err := m.ensureDeviationIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare deviations collection: %v", err)
+	}
+	if err := m.ensureSetpointApplicationIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare setpoint applications collection: %v", err)
+	}
+	if err := m.ensureAlarmIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare alarms collection: %v", err)
+	}
+	if err := m.ensureDiscoveryReportIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare discovery reports collection: %v", err)
+	}
+
+	return m, nil
+}
+
+// ensureDeviationIndexes creates the unique index that keeps deviations reported by the
+// local API idempotent. Deviations created without a local_id are not covered.
+func (m *MongoDB) ensureDeviationIndexes(ctx context.Context) error {
+	_, err := m.DeviationsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "experiment_id", Value: 1}, {Key: "local_id", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"local_id": bson.M{"$exists": true}}),
+	})
+	return err
+}
+
+// ensureSetpointApplicationIndexes creates the unique index the upserts of uploaded setpoint
+// applications are filtered on, so repeated uploads neither scan nor duplicate records
+func (m *MongoDB) ensureSetpointApplicationIndexes(ctx context.Context) error {
+	_, err := m.SetpointApplicationsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "experiment_id", Value: 1}, {Key: "local_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// ensureAlarmIndexes creates the unique index the upserts of alarm events are filtered on,
+// so concurrent deliveries of the same event cannot create two alarms
+func (m *MongoDB) ensureAlarmIndexes(ctx context.Context) error {
+	_, err := m.AlarmsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "chamber_id", Value: 1}, {Key: "local_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// ensureDiscoveryReportIndexes creates the unique index the upserts of discovery reports are
+// filtered on, so redelivered reports are stored once
+func (m *MongoDB) ensureDiscoveryReportIndexes(ctx context.Context) error {
+	_, err := m.DiscoveryReportsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "chamber_id", Value: 1}, {Key: "local_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
 }
 
 // Disconnect closes the database connection
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_v2/internal/logging"
+	"backend_v2/internal/models"
+)
+
+// AdminHandler handles runtime administration requests
+type AdminHandler struct{}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler() *AdminHandler {
+	return &AdminHandler{}
+}
+
+// SetLogLevelRequest represents a request to change the log level
+type SetLogLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+}
+
+// GetLogLevel handles GET /admin/loglevel
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"level": logging.Level()}))
+}
+
+// SetLogLevel handles PUT /admin/loglevel
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var req SetLogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	if err := logging.SetLevel(req.Level); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Log level changed", "level", logging.Level())
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"level": logging.Level()}))
+}
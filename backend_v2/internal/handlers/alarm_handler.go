@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_v2/internal/models"
+	"backend_v2/internal/services"
+)
+
+// AlarmHandler handles alarm and alarm rule HTTP requests
+type AlarmHandler struct {
+	alarmService *services.AlarmService
+}
+
+// NewAlarmHandler creates a new alarm handler
+func NewAlarmHandler(alarmService *services.AlarmService) *AlarmHandler {
+	return &AlarmHandler{
+		alarmService: alarmService,
+	}
+}
+
+// CreateRule handles POST /alarm-rules
+func (h *AlarmHandler) CreateRule(c *gin.Context) {
+	var req services.CreateAlarmRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	rule, err := h.alarmService.CreateRule(&req)
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(rule))
+}
+
+// GetRules handles GET /alarm-rules
+func (h *AlarmHandler) GetRules(c *gin.Context) {
+	rules, err := h.alarmService.GetRules(c.Query("chamber_id"))
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(rules))
+}
+
+// UpdateRule handles PUT /alarm-rules/:id
+func (h *AlarmHandler) UpdateRule(c *gin.Context) {
+	ruleID := c.Param("id")
+
+	var req services.UpdateAlarmRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	rule, err := h.alarmService.UpdateRule(ruleID, &req)
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(rule))
+}
+
+// DeleteRule handles DELETE /alarm-rules/:id
+func (h *AlarmHandler) DeleteRule(c *gin.Context) {
+	ruleID := c.Param("id")
+
+	if err := h.alarmService.DeleteRule(ruleID); err != nil {
+		c.JSON(alarmErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse("Alarm rule deleted successfully"))
+}
+
+// RecordEvent handles POST /alarms/events
+func (h *AlarmHandler) RecordEvent(c *gin.Context) {
+	var req services.AlarmEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	alarm, err := h.alarmService.RecordEvent(&req)
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(alarm))
+}
+
+// GetAlarms handles GET /alarms
+func (h *AlarmHandler) GetAlarms(c *gin.Context) {
+	filter := services.AlarmFilter{
+		ChamberID: c.Query("chamber_id"),
+		Status:    c.Query("status"),
+		Severity:  c.Query("severity"),
+	}
+	if value := c.Query("acknowledged"); value != "" {
+		acknowledged, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid 'acknowledged' parameter: "+err.Error()))
+			return
+		}
+		filter.Acknowledged = &acknowledged
+	}
+
+	alarms, err := h.alarmService.GetAlarms(filter)
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(alarms))
+}
+
+// AcknowledgeAlarm handles POST /alarms/:id/ack
+func (h *AlarmHandler) AcknowledgeAlarm(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req services.AcknowledgeAlarmRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	alarm, err := h.alarmService.AcknowledgeAlarm(c.Param("id"), user.Username, req.Comment)
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(alarm))
+}
+
+// alarmErrorStatus maps an alarm service error to its HTTP status code
+func alarmErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrChamberNotFound), errors.Is(err, services.ErrAlarmRuleNotFound), errors.Is(err, services.ErrAlarmNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAlarmAlreadyAcknowledged):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
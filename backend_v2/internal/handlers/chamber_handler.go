@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -75,7 +76,15 @@ func (h *ChamberHandler) GetChamberWateringZones(c *gin.Context) {
 func (h *ChamberHandler) Heartbeat(c *gin.Context) {
 	chamberID := c.Param("id")
 
-	err := h.chamberService.UpdateHeartbeat(chamberID)
+	var req services.HeartbeatRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	err := h.chamberService.UpdateHeartbeat(chamberID, &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
 		return
@@ -120,6 +129,10 @@ func (h *ChamberHandler) UpdateChamberConfig(c *gin.Context) {
 
 	config, err := h.chamberService.UpdateChamberConfig(chamberID, &req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidProfile) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
 		return
 	}
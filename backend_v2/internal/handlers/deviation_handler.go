@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_v2/internal/models"
+	"backend_v2/internal/services"
+)
+
+// DeviationHandler handles deviation-related HTTP requests
+type DeviationHandler struct {
+	deviationService *services.DeviationService
+}
+
+// NewDeviationHandler creates a new deviation handler
+func NewDeviationHandler(deviationService *services.DeviationService) *DeviationHandler {
+	return &DeviationHandler{
+		deviationService: deviationService,
+	}
+}
+
+// CreateDeviation handles POST /experiments/:id/deviations
+func (h *DeviationHandler) CreateDeviation(c *gin.Context) {
+	experimentID := c.Param("id")
+
+	var req services.CreateDeviationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	deviation, err := h.deviationService.CreateDeviation(experimentID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidExperimentID):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		case errors.Is(err, services.ErrExperimentNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(deviation))
+}
+
+// GetDeviations handles GET /experiments/:id/deviations
+func (h *DeviationHandler) GetDeviations(c *gin.Context) {
+	experimentID := c.Param("id")
+
+	deviations, err := h.deviationService.GetDeviations(experimentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidExperimentID):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		case errors.Is(err, services.ErrExperimentNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(deviations))
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_v2/internal/models"
+	"backend_v2/internal/services"
+)
+
+// DiscoveryReportHandler handles discovery report HTTP requests
+type DiscoveryReportHandler struct {
+	discoveryReportService *services.DiscoveryReportService
+}
+
+// NewDiscoveryReportHandler creates a new discovery report handler
+func NewDiscoveryReportHandler(discoveryReportService *services.DiscoveryReportService) *DiscoveryReportHandler {
+	return &DiscoveryReportHandler{
+		discoveryReportService: discoveryReportService,
+	}
+}
+
+// RecordReport handles POST /chambers/:id/discovery-reports
+func (h *DiscoveryReportHandler) RecordReport(c *gin.Context) {
+	var req services.RecordDiscoveryReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	report, err := h.discoveryReportService.RecordReport(c.Param("id"), &req)
+	if err != nil {
+		// The local API drops reports rejected with 4xx, so only permanent errors may use them
+		switch {
+		case errors.Is(err, services.ErrInvalidID):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		case errors.Is(err, services.ErrChamberNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(report))
+}
+
+// GetReports handles GET /chambers/:id/discovery-reports
+func (h *DiscoveryReportHandler) GetReports(c *gin.Context) {
+	var limit int64
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid 'limit' parameter"))
+			return
+		}
+		limit = parsed
+	}
+
+	reports, err := h.discoveryReportService.GetReports(c.Param("id"), limit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidID) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(reports))
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_v2/internal/models"
+	"backend_v2/internal/services"
+)
+
+// DiscoveryRuleHandler handles discovery rule HTTP requests
+type DiscoveryRuleHandler struct {
+	discoveryRuleService *services.DiscoveryRuleService
+}
+
+// NewDiscoveryRuleHandler creates a new discovery rule handler
+func NewDiscoveryRuleHandler(discoveryRuleService *services.DiscoveryRuleService) *DiscoveryRuleHandler {
+	return &DiscoveryRuleHandler{
+		discoveryRuleService: discoveryRuleService,
+	}
+}
+
+// GetRules handles GET /discovery-rules
+func (h *DiscoveryRuleHandler) GetRules(c *gin.Context) {
+	rules, err := h.discoveryRuleService.GetRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(rules))
+}
+
+// ReplaceRules handles PUT /discovery-rules
+func (h *DiscoveryRuleHandler) ReplaceRules(c *gin.Context) {
+	var req struct {
+		Rules []models.DiscoveryRule `json:"rules"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	rules, err := h.discoveryRuleService.ReplaceRules(req.Rules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(rules))
+}
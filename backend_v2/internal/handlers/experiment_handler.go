@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,12 +13,14 @@ import (
 // ExperimentHandler handles experiment-related HTTP requests
 type ExperimentHandler struct {
 	experimentService *services.ExperimentService
+	simulationService *services.SimulationService
 }
 
 // NewExperimentHandler creates a new experiment handler
-func NewExperimentHandler(experimentService *services.ExperimentService) *ExperimentHandler {
+func NewExperimentHandler(experimentService *services.ExperimentService, simulationService *services.SimulationService) *ExperimentHandler {
 	return &ExperimentHandler{
 		experimentService: experimentService,
+		simulationService: simulationService,
 	}
 }
 
@@ -101,7 +104,8 @@ func (h *ExperimentHandler) UpdateExperimentStatus(c *gin.Context) {
 	experimentID := c.Param("id")
 
 	var req struct {
-		Status models.ExperimentStatus `json:"status" binding:"required"`
+		Status        models.ExperimentStatus `json:"status" binding:"required"`
+		ShiftSchedule bool                    `json:"shift_schedule"` // on resume, move the remaining schedule by the days the pause spanned
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -109,11 +113,48 @@ func (h *ExperimentHandler) UpdateExperimentStatus(c *gin.Context) {
 		return
 	}
 
-	experiment, err := h.experimentService.UpdateExperimentStatus(experimentID, req.Status)
+	experiment, err := h.experimentService.UpdateExperimentStatus(experimentID, req.Status, req.ShiftSchedule)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		switch {
+		case errors.Is(err, services.ErrInvalidExperimentID), errors.Is(err, services.ErrInvalidStatus):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		case errors.Is(err, services.ErrExperimentNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse(experiment))
 }
+
+// SimulateExperiment handles POST /experiments/:id/simulate
+func (h *ExperimentHandler) SimulateExperiment(c *gin.Context) {
+	experimentID := c.Param("id")
+
+	var req services.SimulateExperimentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	result, err := h.simulationService.SimulateExperiment(experimentID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidExperimentID):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		case errors.Is(err, services.ErrExperimentNotFound), errors.Is(err, services.ErrChamberNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		case errors.Is(err, services.ErrLocalAPI):
+			c.JSON(http.StatusBadGateway, models.ErrorResponse(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
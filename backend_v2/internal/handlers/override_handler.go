@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_v2/internal/models"
+	"backend_v2/internal/services"
+)
+
+// OverrideHandler handles chamber override HTTP requests
+type OverrideHandler struct {
+	overrideService *services.OverrideService
+}
+
+// NewOverrideHandler creates a new override handler
+func NewOverrideHandler(overrideService *services.OverrideService) *OverrideHandler {
+	return &OverrideHandler{
+		overrideService: overrideService,
+	}
+}
+
+// CreateOverride handles POST /chambers/:id/overrides
+func (h *OverrideHandler) CreateOverride(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req services.CreateOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	override, err := h.overrideService.CreateOverride(c.Param("id"), &req, user.Username)
+	if err != nil {
+		c.JSON(overrideErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(override))
+}
+
+// GetOverrides handles GET /chambers/:id/overrides
+func (h *OverrideHandler) GetOverrides(c *gin.Context) {
+	includeInactive := false
+	if value := c.Query("all"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid 'all' parameter: "+err.Error()))
+			return
+		}
+		includeInactive = parsed
+	}
+
+	overrides, err := h.overrideService.GetOverrides(c.Param("id"), includeInactive)
+	if err != nil {
+		c.JSON(overrideErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(overrides))
+}
+
+// CancelOverride handles DELETE /chambers/:id/overrides/:override_id
+func (h *OverrideHandler) CancelOverride(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	override, err := h.overrideService.CancelOverride(c.Param("id"), c.Param("override_id"), user.Username)
+	if err != nil {
+		c.JSON(overrideErrorStatus(err), models.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(override))
+}
+
+// overrideErrorStatus maps an override service error to its HTTP status code
+func overrideErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidID), errors.Is(err, services.ErrInvalidOverride):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrChamberNotFound), errors.Is(err, services.ErrOverrideNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// currentUser returns the authenticated user, writing an error response if there is none
+func currentUser(c *gin.Context) (*models.User, bool) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not found"))
+		return nil, false
+	}
+
+	user, ok := userInterface.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Invalid user data"))
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_v2/internal/models"
+	"backend_v2/internal/services"
+)
+
+// SetpointApplicationHandler handles the setpoint audit log HTTP requests
+type SetpointApplicationHandler struct {
+	setpointApplicationService *services.SetpointApplicationService
+}
+
+// NewSetpointApplicationHandler creates a new setpoint application handler
+func NewSetpointApplicationHandler(setpointApplicationService *services.SetpointApplicationService) *SetpointApplicationHandler {
+	return &SetpointApplicationHandler{
+		setpointApplicationService: setpointApplicationService,
+	}
+}
+
+// RecordApplications handles POST /experiments/:id/setpoint-applications
+func (h *SetpointApplicationHandler) RecordApplications(c *gin.Context) {
+	experimentID := c.Param("id")
+
+	var req services.RecordSetpointApplicationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		return
+	}
+
+	stored, err := h.setpointApplicationService.RecordApplications(experimentID, &req)
+	if err != nil {
+		// Client errors are permanent for the outbox of the local API, it drops the batch
+		switch {
+		case errors.Is(err, services.ErrInvalidExperimentID):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		case errors.Is(err, services.ErrExperimentNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse(gin.H{
+		"received": len(req.Applications),
+		"stored":   stored,
+	}))
+}
+
+// GetApplications handles GET /experiments/:id/setpoint-applications
+func (h *SetpointApplicationHandler) GetApplications(c *gin.Context) {
+	experimentID := c.Param("id")
+
+	from, err := parseOptionalTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid 'from' parameter: "+err.Error()))
+		return
+	}
+	to, err := parseOptionalTime(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse("invalid 'to' parameter: "+err.Error()))
+		return
+	}
+
+	applications, err := h.setpointApplicationService.GetApplications(experimentID, c.Query("entity_id"), from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidExperimentID):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(err.Error()))
+		case errors.Is(err, services.ErrExperimentNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(applications))
+}
+
+// parseOptionalTime parses an RFC 3339 query parameter, returning nil for empty values
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
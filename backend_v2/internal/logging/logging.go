@@ -0,0 +1,90 @@
+// Package logging configures the structured slog logger of the backend and carries the
+// request IDs assigned by the gin request logger
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// RequestIDHeader is read from incoming requests and echoed in every response
+const RequestIDHeader = "X-Request-ID"
+
+// level is changed at runtime through the admin API
+var level = new(slog.LevelVar)
+
+// Setup installs the default logger writing text or JSON records to stdout.
+// Gin's router is created without its own logger, requests are logged by the middleware.
+func Setup(levelName, format string) error {
+	if err := SetLevel(levelName); err != nil {
+		return err
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	default:
+		return fmt.Errorf("unknown log format %q, expected text or json", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// SetLevel changes the minimum level of logged records to debug, info, warn or error
+func SetLevel(name string) error {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		level.Set(slog.LevelDebug)
+	case "", "info":
+		level.Set(slog.LevelInfo)
+	case "warn", "warning":
+		level.Set(slog.LevelWarn)
+	case "error":
+		level.Set(slog.LevelError)
+	default:
+		return fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
+	}
+	return nil
+}
+
+// Level returns the name of the current minimum level
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID, set on the request by the middleware
+// so handlers and services can log with c.Request.Context()
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// FromContext returns the default logger with the request ID of the context attached
+func FromContext(ctx context.Context) *slog.Logger {
+	if requestID, _ := ctx.Value(requestIDKey{}).(string); requestID != "" {
+		return slog.Default().With("request_id", requestID)
+	}
+	return slog.Default()
+}
+
+// NewRequestID generates a random request ID
+func NewRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_v2/internal/logging"
+)
+
+// RequestLogger assigns every request an ID, taken from the X-Request-ID header when present,
+// and logs the request once it is handled
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(logging.RequestIDHeader)
+		if requestID == "" {
+			requestID = logging.NewRequestID()
+		}
+		c.Set("request_id", requestID)
+		c.Header(logging.RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []any{
+			"request_id", requestID,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration", time.Since(start),
+			"client_ip", c.ClientIP(),
+		}
+		switch route := c.FullPath(); {
+		case strings.HasPrefix(route, "/api/chambers/:id"):
+			attrs = append(attrs, "chamber_id", c.Param("id"))
+		case strings.HasPrefix(route, "/api/experiments/:id"):
+			attrs = append(attrs, "experiment_id", c.Param("id"))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "error", c.Errors.String())
+		}
+		slog.Log(c.Request.Context(), level, "HTTP request", attrs...)
+	}
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AlarmMetric constants name the measured values alarm rules can watch
+const (
+	AlarmMetricTemperature = "temperature"
+	AlarmMetricHumidity    = "humidity"
+	AlarmMetricCO2         = "co2"
+	AlarmMetricLight       = "light"
+)
+
+// AlarmComparator constants define how a measured value is compared with the threshold
+const (
+	AlarmAbove        = "gt"  // alarm while value > threshold
+	AlarmAboveOrEqual = "gte" // alarm while value >= threshold
+	AlarmBelow        = "lt"  // alarm while value < threshold
+	AlarmBelowOrEqual = "lte" // alarm while value <= threshold
+)
+
+// AlarmSeverity constants
+const (
+	AlarmSeverityInfo     = "info"
+	AlarmSeverityWarning  = "warning"
+	AlarmSeverityCritical = "critical"
+)
+
+// AlarmStatus constants
+const (
+	AlarmStatusActive  = "active"  // the condition is still met
+	AlarmStatusCleared = "cleared" // the value returned to the allowed range
+)
+
+// AlarmEventType constants describe events reported by local APIs
+const (
+	AlarmEventRaise = "raise"
+	AlarmEventClear = "clear"
+)
+
+// AlarmRule defines a threshold on a measured metric of a chamber, evaluated by its local API
+type AlarmRule struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChamberID  primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	Name       string             `bson:"name" json:"name"`
+	Metric     string             `bson:"metric" json:"metric"`                           // temperature, humidity, co2 or light
+	EntityID   string             `bson:"entity_id,omitempty" json:"entity_id,omitempty"` // optional, limits the rule to one sensor
+	Comparator string             `bson:"comparator" json:"comparator"`                   // gt, gte, lt or lte
+	Threshold  float64            `bson:"threshold" json:"threshold"`                     // compared with the measured value
+	Duration   int                `bson:"duration" json:"duration"`                       // seconds the condition must hold before the alarm is raised
+	Severity   string             `bson:"severity" json:"severity"`                       // info, warning or critical
+	Enabled    bool               `bson:"enabled" json:"enabled"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Alarm represents a raised alarm and its acknowledgement
+type Alarm struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LocalID        string             `bson:"local_id" json:"local_id"` // ID of the raise event on the local API
+	RuleID         primitive.ObjectID `bson:"rule_id" json:"rule_id"`
+	ChamberID      primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	RuleName       string             `bson:"rule_name" json:"rule_name"`
+	Metric         string             `bson:"metric" json:"metric"`
+	EntityID       string             `bson:"entity_id" json:"entity_id"`
+	Comparator     string             `bson:"comparator" json:"comparator"`
+	Threshold      float64            `bson:"threshold" json:"threshold"`
+	Severity       string             `bson:"severity" json:"severity"`
+	Status         string             `bson:"status" json:"status"`
+	RaisedValue    float64            `bson:"raised_value" json:"raised_value"`
+	RaisedAt       time.Time          `bson:"raised_at" json:"raised_at"`
+	ClearedValue   *float64           `bson:"cleared_value,omitempty" json:"cleared_value,omitempty"`
+	ClearedAt      *time.Time         `bson:"cleared_at,omitempty" json:"cleared_at,omitempty"`
+	Acknowledged   bool               `bson:"acknowledged" json:"acknowledged"`
+	AcknowledgedAt *time.Time         `bson:"acknowledged_at,omitempty" json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string             `bson:"acknowledged_by,omitempty" json:"acknowledged_by,omitempty"`
+	AckComment     string             `bson:"ack_comment,omitempty" json:"ack_comment,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
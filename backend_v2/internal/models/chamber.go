@@ -21,7 +21,8 @@ type Chamber struct {
 	Suffix             string             `bson:"suffix" json:"suffix"` // e.g., "galo", "sb4", "room1", "default"
 	HAUrl              string             `bson:"ha_url" json:"ha_url"`
 	LocalAPIversion    int                `bson:"local_api_version" json:"local_api_version"`
-	TimeOffset         int                `bson:"time_offset" json:"time_offset"`
+	TimeZone           string             `bson:"time_zone,omitempty" json:"time_zone,omitempty"` // NTP_LOCATION of the local API, schedule days start at its midnight
+	TimeOffset         int                `bson:"time_offset" json:"time_offset"`                 // current UTC offset of TimeZone in minutes, for display only
 	AccessToken        string             `bson:"access_token" json:"-"`
 	LocalIP            string             `bson:"local_ip" json:"local_ip"`
 	Status             ChamberStatus      `bson:"status" json:"status"`
@@ -37,6 +38,8 @@ type ChamberConfig struct {
 	ID                   primitive.ObjectID                `bson:"_id,omitempty" json:"id"`
 	ChamberID            primitive.ObjectID                `bson:"chamber_id" json:"chamber_id"`
 	Lamps                map[string]InputNumber            `bson:"lamps" json:"lamps"`
+	Switches             map[string]InputNumber            `bson:"switches" json:"switches"`       // switch.* entities, value 1 is on
+	Thermostats          map[string]InputNumber            `bson:"thermostats" json:"thermostats"` // climate.* entities, value is the target temperature
 	WateringZones        []WateringZone                    `bson:"watering_zones" json:"watering_zones"`
 	UnrecognisedEntities map[string]InputNumber            `bson:"unrecognised_entities" json:"unrecognised_entities"`
 	DayDuration          map[string]InputNumber            `bson:"day_duration" json:"day_duration"`
@@ -44,10 +47,24 @@ type ChamberConfig struct {
 	Temperature          map[string]map[string]InputNumber `bson:"temperature" json:"temperature"` // day/night -> entity_id -> value
 	Humidity             map[string]map[string]InputNumber `bson:"humidity" json:"humidity"`       // day/night -> entity_id -> value
 	CO2                  map[string]map[string]InputNumber `bson:"co2" json:"co2"`                 // day/night -> entity_id -> value
+	PauseProfile         *PauseProfile                     `bson:"pause_profile,omitempty" json:"pause_profile,omitempty"`
+	IdleProfile          map[string]float64                `bson:"idle_profile,omitempty" json:"idle_profile,omitempty"` // entity_id -> value applied while no experiment is active
 	UpdatedAt            time.Time                         `bson:"updated_at" json:"updated_at"`
 	SyncedAt             *time.Time                        `bson:"synced_at,omitempty" json:"synced_at,omitempty"`
 }
 
+// PauseProfile defines the setpoints a chamber holds while its experiment is paused
+type PauseProfile struct {
+	Mode   string             `bson:"mode" json:"mode"`                         // hold or safe
+	Values map[string]float64 `bson:"values,omitempty" json:"values,omitempty"` // entity_id -> value written in safe mode
+}
+
+// PauseMode constants
+const (
+	PauseModeHold = "hold" // keep the last applied setpoints
+	PauseModeSafe = "safe" // write the profile values, other entities keep their last setpoints
+)
+
 // InputNumber represents a Home Assistant input_number entity
 type InputNumber struct {
 	EntityID string  `bson:"entity_id" json:"entity_id"`
@@ -110,6 +127,8 @@ func (c *Chamber) InitializeConfig() {
 			ID:                   primitive.NewObjectID(),
 			ChamberID:            c.ID,
 			Lamps:                make(map[string]InputNumber),
+			Switches:             make(map[string]InputNumber),
+			Thermostats:          make(map[string]InputNumber),
 			WateringZones:        []WateringZone{},
 			UnrecognisedEntities: make(map[string]InputNumber),
 			DayDuration:          make(map[string]InputNumber),
@@ -129,3 +148,23 @@ func (c *Chamber) InitializeConfig() {
 		c.Config.CO2["night"] = make(map[string]InputNumber)
 	}
 }
+
+// FindInputNumber returns the configured entity with the given ID from any entity group
+func (c *ChamberConfig) FindInputNumber(entityID string) (InputNumber, bool) {
+	groups := []map[string]InputNumber{c.Lamps, c.Switches, c.Thermostats, c.UnrecognisedEntities, c.DayDuration, c.DayStart}
+	for _, periods := range []map[string]map[string]InputNumber{c.Temperature, c.Humidity, c.CO2} {
+		for _, entities := range periods {
+			groups = append(groups, entities)
+		}
+	}
+	for _, zone := range c.WateringZones {
+		groups = append(groups, zone.StartTimeEntityID, zone.PeriodEntityID, zone.PauseBetweenEntityID, zone.DurationEntityID)
+	}
+
+	for _, entities := range groups {
+		if entity, found := entities[entityID]; found {
+			return entity, true
+		}
+	}
+	return InputNumber{}, false
+}
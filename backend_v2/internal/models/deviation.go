@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeviationType represents the kind of protocol deviation
+type DeviationType string
+
+const (
+	DeviationTypeDrift    DeviationType = "drift"    // entity value changed outside of the executor
+	DeviationTypeOverride DeviationType = "override" // entity forced to a value by a time-bounded override
+	DeviationTypeGap      DeviationType = "gap"      // executor not running, schedule days were skipped
+)
+
+// Deviation represents a recorded departure of a chamber from the experiment protocol
+type Deviation struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ExperimentID  primitive.ObjectID `bson:"experiment_id" json:"experiment_id"`
+	ChamberID     primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	Type          DeviationType      `bson:"type" json:"type"`
+	LocalID       string             `bson:"local_id,omitempty" json:"local_id,omitempty"` // ID assigned by local API, makes deliveries idempotent
+	PhaseIndex    *int               `bson:"phase_index,omitempty" json:"phase_index,omitempty"`
+	Day           *int               `bson:"day,omitempty" json:"day,omitempty"`
+	EntityID      string             `bson:"entity_id,omitempty" json:"entity_id,omitempty"`
+	ExpectedValue *float64           `bson:"expected_value,omitempty" json:"expected_value,omitempty"`
+	ObservedValue *float64           `bson:"observed_value,omitempty" json:"observed_value,omitempty"`
+	Action        string             `bson:"action,omitempty" json:"action,omitempty"`
+	OccurredAt    *time.Time         `bson:"occurred_at,omitempty" json:"occurred_at,omitempty"`
+	EndedAt       *time.Time         `bson:"ended_at,omitempty" json:"ended_at,omitempty"` // end of a gap
+	DetectedAt    time.Time          `bson:"detected_at" json:"detected_at"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+
+	// Gap details
+	DurationSeconds int64            `bson:"duration_seconds,omitempty" json:"duration_seconds,omitempty"`
+	SkippedDays     []SkippedDay     `bson:"skipped_days,omitempty" json:"skipped_days,omitempty"`
+	MissedSetpoints []MissedSetpoint `bson:"missed_setpoints,omitempty" json:"missed_setpoints,omitempty"`
+}
+
+// SkippedDay is a day of an experiment phase the executor never ran
+type SkippedDay struct {
+	PhaseIndex int `bson:"phase_index" json:"phase_index"`
+	Day        int `bson:"day" json:"day"`
+}
+
+// MissedSetpoint is a value scheduled for a skipped day that was never written
+type MissedSetpoint struct {
+	PhaseIndex int     `bson:"phase_index" json:"phase_index"`
+	Day        int     `bson:"day" json:"day"`
+	EntityID   string  `bson:"entity_id" json:"entity_id"`
+	Value      float64 `bson:"value" json:"value"`
+	Group      string  `bson:"group" json:"group"`
+}
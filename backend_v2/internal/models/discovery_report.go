@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DiscoveryReport records how a re-discovery on a local API changed the entities of a chamber
+type DiscoveryReport struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChamberID    primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	LocalID      string             `bson:"local_id" json:"local_id"` // ID of the report on the local API
+	Suffix       string             `bson:"suffix" json:"suffix"`
+	Instance     string             `bson:"instance" json:"instance"`
+	Trigger      string             `bson:"trigger" json:"trigger"` // scheduled or manual
+	Changes      []DiscoveryChange  `bson:"changes" json:"changes"`
+	DiscoveredAt time.Time          `bson:"discovered_at" json:"discovered_at"`
+	ReceivedAt   time.Time          `bson:"received_at" json:"received_at"`
+}
+
+// DiscoveryChange is one entity added, removed or retyped by a re-discovery
+type DiscoveryChange struct {
+	Type         string               `bson:"type" json:"type"` // added, removed or retyped
+	EntityID     string               `bson:"entity_id" json:"entity_id"`
+	Name         string               `bson:"name" json:"name"`
+	PreviousType string               `bson:"previous_type,omitempty" json:"previous_type,omitempty"`
+	NewType      string               `bson:"new_type,omitempty" json:"new_type,omitempty"`
+	ReferencedBy []DiscoveryReference `bson:"referenced_by,omitempty" json:"referenced_by,omitempty"` // active experiments still using a removed entity
+}
+
+// DiscoveryReference is an experiment using an entity in some of its phases
+type DiscoveryReference struct {
+	ExperimentID primitive.ObjectID `bson:"experiment_id" json:"experiment_id"`
+	Title        string             `bson:"title" json:"title"`
+	Phases       []int              `bson:"phases" json:"phases"`
+}
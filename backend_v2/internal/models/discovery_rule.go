@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DiscoveryRule classifies controllable Home Assistant entities during the discovery of every local API.
+// Rules replace the rules file and built-in defaults of the local APIs, an empty list restores them.
+type DiscoveryRule struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Position     int                `bson:"position" json:"-"` // order the rules were submitted in
+	Name         string             `bson:"name" json:"name"`
+	Match        string             `bson:"match,omitempty" json:"match,omitempty"` // regex (default) or glob
+	EntityID     string             `bson:"entity_id,omitempty" json:"entity_id,omitempty"`
+	FriendlyName string             `bson:"friendly_name,omitempty" json:"friendly_name,omitempty"`
+	Exclude      string             `bson:"exclude,omitempty" json:"exclude,omitempty"`
+	Target       string             `bson:"target" json:"target"`                                 // input number type, lamp, switch, thermostat or exclude
+	SuffixGroup  string             `bson:"suffix_group,omitempty" json:"suffix_group,omitempty"` // capture group of entity_id holding the chamber suffix
+	Priority     int                `bson:"priority" json:"priority"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"-"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -27,6 +28,7 @@ type Experiment struct {
 	Phases           []Phase            `bson:"phases" json:"phases"`
 	Schedule         []ScheduleItem     `bson:"schedule" json:"schedule"`
 	ActivePhaseIndex *int               `bson:"active_phase_index,omitempty" json:"active_phase_index,omitempty"`
+	Pauses           []PauseRecord      `bson:"pauses,omitempty" json:"pauses,omitempty"`
 	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -44,8 +46,10 @@ type Phase struct {
 	HumidityNightSchedule    map[string]ScheduleConfig       `bson:"humidity_night_schedule,omitempty" json:"humidity_night_schedule,omitempty"`
 	CO2DaySchedule           map[string]ScheduleConfig       `bson:"co2_day_schedule,omitempty" json:"co2_day_schedule,omitempty"`
 	CO2NightSchedule         map[string]ScheduleConfig       `bson:"co2_night_schedule,omitempty" json:"co2_night_schedule,omitempty"`
-	LightIntensitySchedule   map[string]ScheduleConfig       `bson:"light_intensity_schedule,omitempty" json:"light_intensity_schedule,omitempty"`
+	LightIntensitySchedule   map[string]ScheduleConfig       `bson:"light_intensity_schedule,omitempty" json:"light_intensity_schedule,omitempty"` // light.* entities take the brightness in percent, 0 turns them off
+	SwitchSchedule           map[string]ScheduleConfig       `bson:"switch_schedule,omitempty" json:"switch_schedule,omitempty"`                   // 1 turns the switch on, 0 off, always stepped
 	WateringZones            map[string]WateringZoneSchedule `bson:"watering_zones,omitempty" json:"watering_zones,omitempty"`
+	TimeOfDaySchedule        map[string]TimeOfDaySchedule    `bson:"time_of_day_schedule,omitempty" json:"time_of_day_schedule,omitempty"`
 }
 
 // StartDayConfig represents the configuration for start day values
@@ -69,8 +73,46 @@ type WateringZoneSchedule struct {
 
 // ScheduleConfig represents a schedule configuration for various parameters
 type ScheduleConfig struct {
-	EntityID string          `bson:"entity_id" json:"entity_id"`
-	Schedule map[int]float64 `bson:"schedule" json:"schedule"`
+	EntityID      string          `bson:"entity_id" json:"entity_id"`
+	Schedule      map[int]float64 `bson:"schedule" json:"schedule"`
+	Interpolation string          `bson:"interpolation,omitempty" json:"interpolation,omitempty"` // step (default), linear or sigmoid
+}
+
+// TimeOfDaySchedule represents an intra-day program of an entity that repeats every day of the phase.
+// It takes precedence over daily schedules of the same entity.
+type TimeOfDaySchedule struct {
+	EntityID      string           `bson:"entity_id" json:"entity_id"`
+	Breakpoints   []TimeBreakpoint `bson:"breakpoints" json:"breakpoints"`
+	Interpolation string           `bson:"interpolation,omitempty" json:"interpolation,omitempty"` // step (default), linear or sigmoid
+}
+
+// TimeBreakpoint represents the value of an entity from a time of day on
+type TimeBreakpoint struct {
+	Time  string  `bson:"time" json:"time"` // HH:MM in the chamber's local time
+	Value float64 `bson:"value" json:"value"`
+}
+
+// Minutes returns the breakpoint time as minutes since midnight
+func (b TimeBreakpoint) Minutes() (int, error) {
+	parsed, err := time.Parse("15:04", b.Time)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", b.Time)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Interpolation modes between consecutive schedule values
+const (
+	InterpolationStep    = "step"    // value changes at the day boundary or breakpoint
+	InterpolationLinear  = "linear"  // value ramps linearly towards the next value
+	InterpolationSigmoid = "sigmoid" // value follows an S-curve towards the next value
+)
+
+// PauseRecord represents an interval in which an experiment was paused
+type PauseRecord struct {
+	PausedAt      time.Time  `bson:"paused_at" json:"paused_at"`
+	ResumedAt     *time.Time `bson:"resumed_at,omitempty" json:"resumed_at,omitempty"`
+	ScheduleShift int64      `bson:"schedule_shift,omitempty" json:"schedule_shift,omitempty"` // seconds the remaining schedule was moved on resume
 }
 
 // ScheduleItem represents a schedule item for an experiment
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Override forces an entity of a chamber to a value until it expires,
+// the executor of the local API restores the scheduled value afterwards
+type Override struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ChamberID    primitive.ObjectID  `bson:"chamber_id" json:"chamber_id"`
+	ExperimentID *primitive.ObjectID `bson:"experiment_id,omitempty" json:"experiment_id,omitempty"` // experiment running when the override was created
+	EntityID     string              `bson:"entity_id" json:"entity_id"`
+	Value        float64             `bson:"value" json:"value"`
+	Reason       string              `bson:"reason" json:"reason"`
+	ExpiresAt    time.Time           `bson:"expires_at" json:"expires_at"`
+	CreatedBy    string              `bson:"created_by" json:"created_by"`
+	CancelledAt  *time.Time          `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CancelledBy  string              `bson:"cancelled_by,omitempty" json:"cancelled_by,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// IsActive reports whether the override is in effect at the given time
+func (o *Override) IsActive(now time.Time) bool {
+	return o.CancelledAt == nil && now.Before(o.ExpiresAt)
+}
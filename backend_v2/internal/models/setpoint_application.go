@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetpointApplication is an audit record of a value written to a chamber by its local API
+type SetpointApplication struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ExperimentID   primitive.ObjectID `bson:"experiment_id" json:"experiment_id"`
+	ChamberID      primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	LocalID        string             `bson:"local_id" json:"local_id"` // ID of the record on the local API
+	PhaseIndex     int                `bson:"phase_index" json:"phase_index"`
+	Day            int                `bson:"day" json:"day"`
+	EntityID       string             `bson:"entity_id" json:"entity_id"`
+	Group          string             `bson:"group" json:"group"`
+	RequestedValue float64            `bson:"requested_value" json:"requested_value"`
+	ExpectedValue  float64            `bson:"expected_value" json:"expected_value"`
+	ActualValue    *float64           `bson:"actual_value,omitempty" json:"actual_value,omitempty"`
+	Status         string             `bson:"status" json:"status"`
+	Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+	TimeSource     string             `bson:"time_source" json:"time_source"`
+	AppliedAt      time.Time          `bson:"applied_at" json:"applied_at"`
+	SystemTime     time.Time          `bson:"system_time" json:"system_time"`
+	ReceivedAt     time.Time          `bson:"received_at" json:"received_at"`
+}
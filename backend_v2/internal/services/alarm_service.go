@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend_v2/internal/database"
+	"backend_v2/internal/models"
+)
+
+// maxAlarms limits the number of alarms returned by a single query
+const maxAlarms = 1000
+
+// AlarmService manages alarm rules and the alarms raised by local APIs
+type AlarmService struct {
+	db *database.MongoDB
+}
+
+// NewAlarmService creates a new alarm service
+func NewAlarmService(db *database.MongoDB) *AlarmService {
+	return &AlarmService{
+		db: db,
+	}
+}
+
+// CreateRule creates an alarm rule for a chamber
+func (s *AlarmService) CreateRule(req *CreateAlarmRuleRequest) (*models.AlarmRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	chamberID, err := primitive.ObjectIDFromHex(req.ChamberID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+	}
+
+	count, err := s.db.ChambersCollection.CountDocuments(ctx, bson.M{"_id": chamberID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chamber: %v", err)
+	}
+	if count == 0 {
+		return nil, ErrChamberNotFound
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	now := time.Now()
+	rule := models.AlarmRule{
+		ID:         primitive.NewObjectID(),
+		ChamberID:  chamberID,
+		Name:       req.Name,
+		Metric:     req.Metric,
+		EntityID:   req.EntityID,
+		Comparator: req.Comparator,
+		Threshold:  *req.Threshold,
+		Duration:   req.Duration,
+		Severity:   req.Severity,
+		Enabled:    enabled,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if _, err := s.db.AlarmRulesCollection.InsertOne(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create alarm rule: %v", err)
+	}
+
+	return &rule, nil
+}
+
+// GetRules retrieves alarm rules, optionally limited to one chamber
+func (s *AlarmService) GetRules(chamberID string) ([]models.AlarmRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if chamberID != "" {
+		objectID, err := primitive.ObjectIDFromHex(chamberID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+		}
+		filter["chamber_id"] = objectID
+	}
+
+	opts := options.Find().SetSort(bson.D{primitive.E{Key: "created_at", Value: 1}})
+	cursor, err := s.db.AlarmRulesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alarm rules: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	rules := []models.AlarmRule{}
+	if err = cursor.All(ctx, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode alarm rules: %v", err)
+	}
+
+	return rules, nil
+}
+
+// UpdateRule updates an alarm rule
+func (s *AlarmService) UpdateRule(ruleID string, req *UpdateAlarmRuleRequest) (*models.AlarmRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(ruleID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: alarm rule ID: %v", ErrInvalidID, err)
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		update["name"] = *req.Name
+	}
+	if req.Metric != nil {
+		update["metric"] = *req.Metric
+	}
+	if req.EntityID != nil {
+		update["entity_id"] = *req.EntityID
+	}
+	if req.Comparator != nil {
+		update["comparator"] = *req.Comparator
+	}
+	if req.Threshold != nil {
+		update["threshold"] = *req.Threshold
+	}
+	if req.Duration != nil {
+		update["duration"] = *req.Duration
+	}
+	if req.Severity != nil {
+		update["severity"] = *req.Severity
+	}
+	if req.Enabled != nil {
+		update["enabled"] = *req.Enabled
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var rule models.AlarmRule
+	err = s.db.AlarmRulesCollection.FindOneAndUpdate(ctx, bson.M{"_id": objectID}, bson.M{"$set": update}, opts).Decode(&rule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAlarmRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to update alarm rule: %v", err)
+	}
+
+	return &rule, nil
+}
+
+// DeleteRule deletes an alarm rule. Alarms already raised by the rule are kept.
+func (s *AlarmService) DeleteRule(ruleID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(ruleID)
+	if err != nil {
+		return fmt.Errorf("%w: alarm rule ID: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.db.AlarmRulesCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("failed to delete alarm rule: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrAlarmRuleNotFound
+	}
+
+	return nil
+}
+
+// RecordEvent applies a raise or clear event reported by a local API.
+// Events are keyed by the local ID of the raise event, so redelivered events are ignored.
+func (s *AlarmService) RecordEvent(req *AlarmEventRequest) (*models.Alarm, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ruleID, err := primitive.ObjectIDFromHex(req.RuleID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: alarm rule ID: %v", ErrInvalidID, err)
+	}
+	chamberID, err := primitive.ObjectIDFromHex(req.ChamberID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+	}
+
+	now := time.Now()
+	filter := bson.M{"chamber_id": chamberID, "local_id": req.AlarmID}
+
+	var update bson.M
+	switch req.Type {
+	case models.AlarmEventRaise:
+		alarm := models.Alarm{
+			ID:          primitive.NewObjectID(),
+			LocalID:     req.AlarmID,
+			RuleID:      ruleID,
+			ChamberID:   chamberID,
+			RuleName:    req.RuleName,
+			Metric:      req.Metric,
+			EntityID:    req.EntityID,
+			Comparator:  req.Comparator,
+			Threshold:   req.Threshold,
+			Severity:    req.Severity,
+			Status:      models.AlarmStatusActive,
+			RaisedValue: req.Value,
+			RaisedAt:    req.OccurredAt,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		update = bson.M{"$setOnInsert": alarm}
+	case models.AlarmEventClear:
+		// A clear may arrive for an alarm whose raise was never delivered; keep the
+		// alarm record complete enough to be listed and acknowledged anyway.
+		value := req.Value
+		update = bson.M{
+			"$set": bson.M{
+				"status":        models.AlarmStatusCleared,
+				"cleared_value": value,
+				"cleared_at":    req.OccurredAt,
+				"updated_at":    now,
+			},
+			"$setOnInsert": bson.M{
+				"_id":          primitive.NewObjectID(),
+				"rule_id":      ruleID,
+				"rule_name":    req.RuleName,
+				"metric":       req.Metric,
+				"entity_id":    req.EntityID,
+				"comparator":   req.Comparator,
+				"threshold":    req.Threshold,
+				"severity":     req.Severity,
+				"raised_value": req.Value,
+				"raised_at":    req.OccurredAt,
+				"acknowledged": false,
+				"created_at":   now,
+			},
+		}
+	default:
+		return nil, fmt.Errorf("invalid alarm event type: %s", req.Type)
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var alarm models.Alarm
+	if err := s.db.AlarmsCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&alarm); err != nil {
+		return nil, fmt.Errorf("failed to record alarm event: %v", err)
+	}
+
+	return &alarm, nil
+}
+
+// GetAlarms retrieves alarms matching the filter, newest first
+func (s *AlarmService) GetAlarms(filter AlarmFilter) ([]models.Alarm, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.ChamberID != "" {
+		objectID, err := primitive.ObjectIDFromHex(filter.ChamberID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+		}
+		query["chamber_id"] = objectID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Severity != "" {
+		query["severity"] = filter.Severity
+	}
+	if filter.Acknowledged != nil {
+		query["acknowledged"] = *filter.Acknowledged
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{primitive.E{Key: "raised_at", Value: -1}}).
+		SetLimit(maxAlarms)
+
+	cursor, err := s.db.AlarmsCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alarms: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	alarms := []models.Alarm{}
+	if err = cursor.All(ctx, &alarms); err != nil {
+		return nil, fmt.Errorf("failed to decode alarms: %v", err)
+	}
+
+	return alarms, nil
+}
+
+// AcknowledgeAlarm marks an alarm as acknowledged by a user
+func (s *AlarmService) AcknowledgeAlarm(alarmID, username, comment string) (*models.Alarm, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(alarmID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: alarm ID: %v", ErrInvalidID, err)
+	}
+
+	var alarm models.Alarm
+	err = s.db.AlarmsCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&alarm)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAlarmNotFound
+		}
+		return nil, fmt.Errorf("failed to get alarm: %v", err)
+	}
+	if alarm.Acknowledged {
+		return nil, ErrAlarmAlreadyAcknowledged
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"acknowledged":    true,
+		"acknowledged_at": now,
+		"acknowledged_by": username,
+		"ack_comment":     comment,
+		"updated_at":      now,
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = s.db.AlarmsCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "acknowledged": false},
+		bson.M{"$set": update},
+		opts,
+	).Decode(&alarm)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAlarmAlreadyAcknowledged
+		}
+		return nil, fmt.Errorf("failed to acknowledge alarm: %v", err)
+	}
+
+	return &alarm, nil
+}
+
+// CreateAlarmRuleRequest represents the request to create an alarm rule
+type CreateAlarmRuleRequest struct {
+	ChamberID  string   `json:"chamber_id" binding:"required"`
+	Name       string   `json:"name" binding:"required"`
+	Metric     string   `json:"metric" binding:"required,oneof=temperature humidity co2 light"`
+	EntityID   string   `json:"entity_id"`
+	Comparator string   `json:"comparator" binding:"required,oneof=gt gte lt lte"`
+	Threshold  *float64 `json:"threshold" binding:"required"`
+	Duration   int      `json:"duration" binding:"min=0"`
+	Severity   string   `json:"severity" binding:"required,oneof=info warning critical"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// UpdateAlarmRuleRequest represents the request to update an alarm rule
+type UpdateAlarmRuleRequest struct {
+	Name       *string  `json:"name" binding:"omitempty,min=1"`
+	Metric     *string  `json:"metric" binding:"omitempty,oneof=temperature humidity co2 light"`
+	EntityID   *string  `json:"entity_id"`
+	Comparator *string  `json:"comparator" binding:"omitempty,oneof=gt gte lt lte"`
+	Threshold  *float64 `json:"threshold"`
+	Duration   *int     `json:"duration" binding:"omitempty,min=0"`
+	Severity   *string  `json:"severity" binding:"omitempty,oneof=info warning critical"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// AlarmEventRequest represents a raise or clear event reported by a local API
+type AlarmEventRequest struct {
+	AlarmID    string    `json:"alarm_id" binding:"required"` // local ID of the raise event
+	Type       string    `json:"type" binding:"required,oneof=raise clear"`
+	RuleID     string    `json:"rule_id" binding:"required"`
+	ChamberID  string    `json:"chamber_id" binding:"required"`
+	RuleName   string    `json:"rule_name"`
+	Metric     string    `json:"metric"`
+	EntityID   string    `json:"entity_id"`
+	Comparator string    `json:"comparator"`
+	Threshold  float64   `json:"threshold"`
+	Severity   string    `json:"severity"`
+	Value      float64   `json:"value"`
+	OccurredAt time.Time `json:"occurred_at" binding:"required"`
+}
+
+// AcknowledgeAlarmRequest represents the request to acknowledge an alarm
+type AcknowledgeAlarmRequest struct {
+	Comment string `json:"comment"`
+}
+
+// AlarmFilter limits the alarms returned by GetAlarms
+type AlarmFilter struct {
+	ChamberID    string
+	Status       string
+	Severity     string
+	Acknowledged *bool
+}
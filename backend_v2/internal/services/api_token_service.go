@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"time"
 
@@ -132,7 +133,7 @@ func (s *APITokenService) ValidateAPIToken(token string) (*models.User, *models.
 	})
 	if err != nil {
 		// Log but don't fail validation
-		fmt.Printf("Failed to update API token last used: %v\n", err)
+		slog.Error("Failed to update API token last used", "error", err)
 	}
 
 	// Get associated user
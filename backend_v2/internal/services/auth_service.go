@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -134,7 +135,7 @@ func (s *AuthService) Login(req *models.LoginRequest, userAgent, ip string) (*mo
 	})
 	if err != nil {
 		// Log error but don't fail login
-		fmt.Printf("Failed to update last login: %v\n", err)
+		slog.Error("Failed to update last login", "error", err)
 	}
 
 	// Generate tokens and create session
@@ -199,7 +200,7 @@ func (s *AuthService) RefreshToken(refreshToken string) (*models.AuthResponse, e
 	_, err = s.db.SessionsCollection.DeleteOne(ctx, bson.M{"_id": session.ID})
 	if err != nil {
 		// Log error but continue
-		fmt.Printf("Failed to delete old session: %v\n", err)
+		slog.Error("Failed to delete old session", "error", err)
 	}
 
 	// Generate new tokens
@@ -467,7 +468,7 @@ func (s *AuthService) UpdatePassword(userID string, newPassword string) error {
 	_, err = s.db.SessionsCollection.DeleteMany(ctx, bson.M{"user_id": objectID})
 	if err != nil {
 		// Log error but don't fail
-		fmt.Printf("Failed to invalidate sessions: %v\n", err)
+		slog.Error("Failed to invalidate sessions", "error", err)
 	}
 
 	return nil
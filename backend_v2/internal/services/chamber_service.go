@@ -3,7 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -49,6 +49,7 @@ func (s *ChamberService) RegisterChamber(req *RegisterChamberRequest) (*models.C
 			ID:                 primitive.NewObjectID(),
 			Name:               req.Name,
 			Suffix:             req.Suffix,
+			TimeZone:           req.TimeZone,
 			TimeOffset:         req.TimeOffset,
 			HAUrl:              req.HAUrl,
 			AccessToken:        req.AccessToken,
@@ -63,6 +64,8 @@ func (s *ChamberService) RegisterChamber(req *RegisterChamberRequest) (*models.C
 		// Initialize config
 		chamber.InitializeConfig()
 		chamber.Config.Lamps = req.Lamps
+		chamber.Config.Switches = req.Switches
+		chamber.Config.Thermostats = req.Thermostats
 		chamber.Config.WateringZones = req.WateringZones
 		chamber.Config.UnrecognisedEntities = req.UnrecognisedEntities
 		chamber.Config.DayDuration = req.DayDuration
@@ -77,7 +80,7 @@ func (s *ChamberService) RegisterChamber(req *RegisterChamberRequest) (*models.C
 			return nil, fmt.Errorf("failed to create chamber: %v", err)
 		}
 
-		log.Printf("New chamber registered: %s (%s)", chamber.Name, chamber.ID.Hex())
+		slog.Info("New chamber registered", "chamber_id", chamber.ID.Hex(), "chamber", chamber.Name)
 		s.logChamberEntities(&chamber)
 
 		return &chamber, nil
@@ -94,6 +97,8 @@ func (s *ChamberService) RegisterChamber(req *RegisterChamberRequest) (*models.C
 
 	// Update config with new entities
 	existingChamber.Config.Lamps = req.Lamps
+	existingChamber.Config.Switches = req.Switches
+	existingChamber.Config.Thermostats = req.Thermostats
 	existingChamber.Config.WateringZones = req.WateringZones
 	existingChamber.Config.UnrecognisedEntities = req.UnrecognisedEntities
 	existingChamber.Config.DayDuration = req.DayDuration
@@ -106,6 +111,7 @@ func (s *ChamberService) RegisterChamber(req *RegisterChamberRequest) (*models.C
 	update := bson.M{
 		"$set": bson.M{
 			"suffix":              req.Suffix,
+			"time_zone":           req.TimeZone,
 			"time_offset":         req.TimeOffset,
 			"ha_url":              req.HAUrl,
 			"access_token":        req.AccessToken,
@@ -126,7 +132,7 @@ func (s *ChamberService) RegisterChamber(req *RegisterChamberRequest) (*models.C
 	existingChamber.LastHeartbeat = now
 	existingChamber.UpdatedAt = now
 
-	log.Printf("Chamber updated: %s (%s)", existingChamber.Name, existingChamber.ID.Hex())
+	slog.Info("Chamber updated", "chamber_id", existingChamber.ID.Hex(), "chamber", existingChamber.Name)
 	s.logChamberEntities(&existingChamber)
 
 	return &existingChamber, nil
@@ -138,53 +144,46 @@ func (s *ChamberService) logChamberEntities(chamber *models.Chamber) {
 		return
 	}
 
-	log.Printf("Chamber %s entities:", chamber.Name)
-	log.Printf("  - %d lamps", len(chamber.Config.Lamps))
-	log.Printf("  - %d watering zones", len(chamber.Config.WateringZones))
-	log.Printf("  - %d unrecognised entities", len(chamber.Config.UnrecognisedEntities))
-
-	// Log climate control mappings
-	log.Printf("  Climate control mappings:")
-	log.Printf("    - Day duration: %d entities", len(chamber.Config.DayDuration))
-	log.Printf("    - Day start: %d entities", len(chamber.Config.DayStart))
-	log.Printf("    - Temperature: %d day, %d night", len(chamber.Config.Temperature["day"]), len(chamber.Config.Temperature["night"]))
-	log.Printf("    - Humidity: %d day, %d night", len(chamber.Config.Humidity["day"]), len(chamber.Config.Humidity["night"]))
-	log.Printf("    - CO2: %d day, %d night", len(chamber.Config.CO2["day"]), len(chamber.Config.CO2["night"]))
+	logger := slog.Default().With("chamber_id", chamber.ID.Hex())
+	logger.Info("Chamber entities",
+		"lamps", len(chamber.Config.Lamps),
+		"switches", len(chamber.Config.Switches),
+		"thermostats", len(chamber.Config.Thermostats),
+		"watering_zones", len(chamber.Config.WateringZones),
+		"unrecognised_entities", len(chamber.Config.UnrecognisedEntities),
+		"day_duration", len(chamber.Config.DayDuration),
+		"day_start", len(chamber.Config.DayStart),
+		"temperature_day", len(chamber.Config.Temperature["day"]),
+		"temperature_night", len(chamber.Config.Temperature["night"]),
+		"humidity_day", len(chamber.Config.Humidity["day"]),
+		"humidity_night", len(chamber.Config.Humidity["night"]),
+		"co2_day", len(chamber.Config.CO2["day"]),
+		"co2_night", len(chamber.Config.CO2["night"]))
 
 	// Log watering zones details
-	if len(chamber.Config.WateringZones) > 0 {
-		log.Println("  Watering zones:")
-		for _, zone := range chamber.Config.WateringZones {
-			for _, entity := range zone.StartTimeEntityID {
-				log.Printf("    - %s:", zone.Name)
-				log.Printf("      Start Time: %s", entity.EntityID)
-				log.Printf("      Period: %s", entity.Name)
-				log.Printf("      Pause: %s", entity.Name)
-				log.Printf("      Duration: %s", entity.Name)
-			}
-			for _, entity := range zone.PeriodEntityID {
-				log.Printf("      Period: %s", entity.EntityID)
-			}
-			for _, entity := range zone.PauseBetweenEntityID {
-				log.Printf("      Pause: %s", entity.EntityID)
-			}
-			for _, entity := range zone.DurationEntityID {
-				log.Printf("      Duration: %s", entity.EntityID)
-			}
+	for _, zone := range chamber.Config.WateringZones {
+		for _, entity := range zone.StartTimeEntityID {
+			logger.Debug("Watering zone start time", "zone", zone.Name, "entity_id", entity.EntityID)
+		}
+		for _, entity := range zone.PeriodEntityID {
+			logger.Debug("Watering zone period", "zone", zone.Name, "entity_id", entity.EntityID)
+		}
+		for _, entity := range zone.PauseBetweenEntityID {
+			logger.Debug("Watering zone pause", "zone", zone.Name, "entity_id", entity.EntityID)
+		}
+		for _, entity := range zone.DurationEntityID {
+			logger.Debug("Watering zone duration", "zone", zone.Name, "entity_id", entity.EntityID)
 		}
 	}
 
 	// Log unrecognised entities
-	if len(chamber.Config.UnrecognisedEntities) > 0 {
-		log.Printf("  Unrecognised entities:")
-		for _, entity := range chamber.Config.UnrecognisedEntities {
-			log.Printf("    - %s (%s)", entity.EntityID, entity.Name)
-		}
+	for _, entity := range chamber.Config.UnrecognisedEntities {
+		logger.Debug("Unrecognised entity", "entity_id", entity.EntityID, "name", entity.Name)
 	}
 }
 
-// UpdateHeartbeat updates the chamber heartbeat
-func (s *ChamberService) UpdateHeartbeat(chamberID string) error {
+// UpdateHeartbeat updates the chamber heartbeat and the time zone reported with it
+func (s *ChamberService) UpdateHeartbeat(chamberID string, req *HeartbeatRequest) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -193,12 +192,18 @@ func (s *ChamberService) UpdateHeartbeat(chamberID string) error {
 		return fmt.Errorf("invalid chamber ID: %v", err)
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"last_heartbeat": time.Now(),
-			"status":         models.StatusOnline,
-		},
+	set := bson.M{
+		"last_heartbeat": time.Now(),
+		"status":         models.StatusOnline,
+	}
+	// Local APIs registered before they reported their time zone refresh it here
+	if req.TimeZone != "" {
+		set["time_zone"] = req.TimeZone
+	}
+	if req.TimeOffset != nil {
+		set["time_offset"] = *req.TimeOffset
 	}
+	update := bson.M{"$set": set}
 
 	result, err := s.db.ChambersCollection.UpdateByID(ctx, objectID, update)
 	if err != nil {
@@ -279,7 +284,7 @@ func (s *ChamberService) UpdateChamberStatus() error {
 	}
 
 	if result.ModifiedCount > 0 {
-		log.Printf("Marked %d chambers as offline", result.ModifiedCount)
+		slog.Info("Marked chambers as offline", "count", result.ModifiedCount)
 	}
 
 	return nil
@@ -292,31 +297,40 @@ func (s *ChamberService) StartStatusMonitor(ctx context.Context) {
 
 	// Initial cleanup
 	if err := s.UpdateChamberStatus(); err != nil {
-		log.Printf("Error updating chamber status: %v", err)
+		slog.Error("Error updating chamber status", "error", err)
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Chamber status monitor stopped")
+			slog.Info("Chamber status monitor stopped")
 			return
 		case <-ticker.C:
 			if err := s.UpdateChamberStatus(); err != nil {
-				log.Printf("Error updating chamber status: %v", err)
+				slog.Error("Error updating chamber status", "error", err)
 			}
 		}
 	}
 }
 
+// HeartbeatRequest represents the optional body of a chamber heartbeat
+type HeartbeatRequest struct {
+	TimeZone   string `json:"time_zone"`
+	TimeOffset *int   `json:"time_offset"`
+}
+
 // RegisterChamberRequest represents the request to register a chamber
 type RegisterChamberRequest struct {
 	Name                 string                                   `json:"name" binding:"required"`
 	Suffix               string                                   `json:"suffix"`
-	TimeOffset           int                                      `json:"time_offset"`
+	TimeZone             string                                   `json:"time_zone"`
+	TimeOffset           int                                      `json:"time_offset"` // UTC offset in minutes, see models.Chamber
 	HAUrl                string                                   `json:"ha_url" binding:"required"`
 	AccessToken          string                                   `json:"access_token" binding:"required"`
 	LocalIP              string                                   `json:"local_ip" binding:"required"`
 	Lamps                map[string]models.InputNumber            `json:"lamps"`
+	Switches             map[string]models.InputNumber            `json:"switches"`
+	Thermostats          map[string]models.InputNumber            `json:"thermostats"`
 	WateringZones        []models.WateringZone                    `json:"watering_zones"`
 	UnrecognisedEntities map[string]models.InputNumber            `json:"unrecognised_entities"`
 	DayDuration          map[string]models.InputNumber            `json:"day_duration"`
@@ -326,9 +340,54 @@ type RegisterChamberRequest struct {
 	CO2                  map[string]map[string]models.InputNumber `json:"co2"`
 }
 
+// validatePauseProfile checks the mode and values of a pause profile
+func validatePauseProfile(config *models.ChamberConfig, profile *models.PauseProfile) error {
+	switch profile.Mode {
+	case models.PauseModeHold:
+		if len(profile.Values) > 0 {
+			return fmt.Errorf("pause profile: values are only used in %q mode", models.PauseModeSafe)
+		}
+	case models.PauseModeSafe:
+		if len(profile.Values) == 0 {
+			return fmt.Errorf("pause profile: %q mode requires at least one value", models.PauseModeSafe)
+		}
+	default:
+		return fmt.Errorf("pause profile: invalid mode %q", profile.Mode)
+	}
+	return validateProfileValues("pause profile", config, profile.Values)
+}
+
+// validateProfileValues checks the entities and values of a chamber profile
+func validateProfileValues(profile string, config *models.ChamberConfig, values map[string]float64) error {
+	for entityID, value := range values {
+		if err := validateEntityValue(config, entityID, value); err != nil {
+			return fmt.Errorf("%s: %v", profile, err)
+		}
+	}
+	return nil
+}
+
+// validateEntityValue checks that an entity is configured for the chamber and the value lies
+// within its min/max. Entities without known limits accept any value.
+func validateEntityValue(config *models.ChamberConfig, entityID string, value float64) error {
+	if config == nil {
+		return fmt.Errorf("entity %s is not configured for the chamber", entityID)
+	}
+	entity, found := config.FindInputNumber(entityID)
+	if !found {
+		return fmt.Errorf("entity %s is not configured for the chamber", entityID)
+	}
+	if entity.Min < entity.Max && (value < entity.Min || value > entity.Max) {
+		return fmt.Errorf("value %v of %s is outside %v-%v", value, entityID, entity.Min, entity.Max)
+	}
+	return nil
+}
+
 // UpdateChamberConfigRequest represents the request to update chamber configuration
 type UpdateChamberConfigRequest struct {
 	Lamps                map[string]models.InputNumber            `json:"lamps"`
+	Switches             map[string]models.InputNumber            `json:"switches"`
+	Thermostats          map[string]models.InputNumber            `json:"thermostats"`
 	WateringZones        []models.WateringZone                    `json:"watering_zones"`
 	UnrecognisedEntities map[string]models.InputNumber            `json:"unrecognised_entities"`
 	DayDuration          map[string]models.InputNumber            `json:"day_duration"`
@@ -336,6 +395,8 @@ type UpdateChamberConfigRequest struct {
 	Temperature          map[string]map[string]models.InputNumber `json:"temperature"`
 	Humidity             map[string]map[string]models.InputNumber `json:"humidity"`
 	CO2                  map[string]map[string]models.InputNumber `json:"co2"`
+	PauseProfile         *models.PauseProfile                     `json:"pause_profile"`
+	IdleProfile          map[string]float64                       `json:"idle_profile"`
 }
 
 func (s *ChamberService) UpdateChamberConfig(chamberID string, req *UpdateChamberConfigRequest) (*models.ChamberConfig, error) {
@@ -365,6 +426,12 @@ func (s *ChamberService) UpdateChamberConfig(chamberID string, req *UpdateChambe
 	if req.Lamps != nil {
 		chamber.Config.Lamps = req.Lamps
 	}
+	if req.Switches != nil {
+		chamber.Config.Switches = req.Switches
+	}
+	if req.Thermostats != nil {
+		chamber.Config.Thermostats = req.Thermostats
+	}
 	if req.WateringZones != nil {
 		chamber.Config.WateringZones = req.WateringZones
 	}
@@ -386,6 +453,18 @@ func (s *ChamberService) UpdateChamberConfig(chamberID string, req *UpdateChambe
 	if req.CO2 != nil {
 		chamber.Config.CO2 = req.CO2
 	}
+	if req.PauseProfile != nil {
+		if err := validatePauseProfile(chamber.Config, req.PauseProfile); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+		}
+		chamber.Config.PauseProfile = req.PauseProfile
+	}
+	if req.IdleProfile != nil {
+		if err := validateProfileValues("idle profile", chamber.Config, req.IdleProfile); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+		}
+		chamber.Config.IdleProfile = req.IdleProfile
+	}
 	chamber.Config.UpdatedAt = now
 
 	// Update the chamber in database
@@ -401,7 +480,7 @@ func (s *ChamberService) UpdateChamberConfig(chamberID string, req *UpdateChambe
 		return nil, fmt.Errorf("failed to update chamber config: %v", err)
 	}
 
-	log.Printf("Chamber config updated: %s (%s)", chamber.Name, chamber.ID.Hex())
+	slog.Info("Chamber config updated", "chamber_id", chamber.ID.Hex(), "chamber", chamber.Name)
 
 	return chamber.Config, nil
 }
@@ -438,7 +517,7 @@ func (s *ChamberService) GetChamberConfig(chamberID string) (*models.ChamberConf
 		}
 		_, err = s.db.ChambersCollection.UpdateByID(ctx, objectID, update)
 		if err != nil {
-			log.Printf("Failed to save initialized config: %v", err)
+			slog.Error("Failed to save initialized config", "chamber_id", chamberID, "error", err)
 		}
 	}
 
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend_v2/internal/database"
+	"backend_v2/internal/models"
+)
+
+// DeviationService handles protocol deviations reported for experiments
+type DeviationService struct {
+	db *database.MongoDB
+}
+
+// NewDeviationService creates a new deviation service
+func NewDeviationService(db *database.MongoDB) *DeviationService {
+	return &DeviationService{
+		db: db,
+	}
+}
+
+// CreateDeviation records a deviation for an experiment. Deviations carrying a local_id
+// that was already recorded are ignored, so local APIs can safely retry deliveries.
+func (s *DeviationService) CreateDeviation(experimentID string, req *CreateDeviationRequest) (*models.Deviation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExperimentID, err)
+	}
+
+	var experiment models.Experiment
+	err = s.db.ExperimentsCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&experiment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrExperimentNotFound
+		}
+		return nil, fmt.Errorf("failed to get experiment: %v", err)
+	}
+
+	now := time.Now()
+	detectedAt := now
+	if req.DetectedAt != nil {
+		detectedAt = *req.DetectedAt
+	}
+
+	deviation := models.Deviation{
+		ID:            primitive.NewObjectID(),
+		ExperimentID:  experiment.ID,
+		ChamberID:     experiment.ChamberID,
+		Type:          req.Type,
+		LocalID:       req.LocalID,
+		PhaseIndex:    req.PhaseIndex,
+		Day:           req.Day,
+		EntityID:      req.EntityID,
+		ExpectedValue: req.ExpectedValue,
+		ObservedValue: req.ObservedValue,
+		Action:        req.Action,
+		OccurredAt:    req.OccurredAt,
+		EndedAt:       req.EndedAt,
+		DetectedAt:    detectedAt,
+		CreatedAt:     now,
+
+		DurationSeconds: req.DurationSeconds,
+		SkippedDays:     req.SkippedDays,
+		MissedSetpoints: req.MissedSetpoints,
+	}
+
+	if deviation.LocalID == "" {
+		if _, err := s.db.DeviationsCollection.InsertOne(ctx, deviation); err != nil {
+			return nil, fmt.Errorf("failed to create deviation: %v", err)
+		}
+		return &deviation, nil
+	}
+
+	// Insert only if this local_id has not been recorded yet
+	filter := bson.M{"experiment_id": experiment.ID, "local_id": deviation.LocalID}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var stored models.Deviation
+	err = s.db.DeviationsCollection.FindOneAndUpdate(ctx, filter, bson.M{"$setOnInsert": deviation}, opts).Decode(&stored)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent delivery of the same deviation inserted it first
+		err = s.db.DeviationsCollection.FindOne(ctx, filter).Decode(&stored)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deviation: %v", err)
+	}
+
+	return &stored, nil
+}
+
+// GetDeviations retrieves all deviations of an experiment, newest first
+func (s *DeviationService) GetDeviations(experimentID string) ([]models.Deviation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExperimentID, err)
+	}
+
+	count, err := s.db.ExperimentsCollection.CountDocuments(ctx, bson.M{"_id": objectID}, options.Count().SetLimit(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment: %v", err)
+	}
+	if count == 0 {
+		return nil, ErrExperimentNotFound
+	}
+
+	opts := options.Find().SetSort(bson.D{primitive.E{Key: "detected_at", Value: -1}})
+	cursor, err := s.db.DeviationsCollection.Find(ctx, bson.M{"experiment_id": objectID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deviations: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	deviations := []models.Deviation{}
+	if err = cursor.All(ctx, &deviations); err != nil {
+		return nil, fmt.Errorf("failed to decode deviations: %v", err)
+	}
+
+	return deviations, nil
+}
+
+// CreateDeviationRequest represents the request to record a deviation
+type CreateDeviationRequest struct {
+	Type          models.DeviationType `json:"type" binding:"required,oneof=drift override gap"`
+	LocalID       string               `json:"local_id"`
+	PhaseIndex    *int                 `json:"phase_index"`
+	Day           *int                 `json:"day"`
+	EntityID      string               `json:"entity_id"`
+	ExpectedValue *float64             `json:"expected_value"`
+	ObservedValue *float64             `json:"observed_value"`
+	Action        string               `json:"action"`
+	OccurredAt    *time.Time           `json:"occurred_at"`
+	EndedAt       *time.Time           `json:"ended_at"`
+	DetectedAt    *time.Time           `json:"detected_at"`
+
+	// Gap details
+	DurationSeconds int64                   `json:"duration_seconds"`
+	SkippedDays     []models.SkippedDay     `json:"skipped_days"`
+	MissedSetpoints []models.MissedSetpoint `json:"missed_setpoints"`
+}
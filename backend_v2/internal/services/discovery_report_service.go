@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend_v2/internal/database"
+	"backend_v2/internal/models"
+)
+
+// maxDiscoveryReports limits the number of reports returned by a single query
+const maxDiscoveryReports = 500
+
+// DiscoveryReportService stores the discovery reports uploaded by local APIs
+type DiscoveryReportService struct {
+	db *database.MongoDB
+}
+
+// NewDiscoveryReportService creates a new discovery report service
+func NewDiscoveryReportService(db *database.MongoDB) *DiscoveryReportService {
+	return &DiscoveryReportService{
+		db: db,
+	}
+}
+
+// RecordReport stores a discovery report of a chamber. Reports are keyed by their local_id,
+// so repeated uploads do not create duplicates.
+func (s *DiscoveryReportService) RecordReport(chamberID string, req *RecordDiscoveryReportRequest) (*models.DiscoveryReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(chamberID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+	}
+
+	count, err := s.db.ChambersCollection.CountDocuments(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chamber: %v", err)
+	}
+	if count == 0 {
+		return nil, ErrChamberNotFound
+	}
+
+	report := models.DiscoveryReport{
+		ID:           primitive.NewObjectID(),
+		ChamberID:    objectID,
+		LocalID:      req.LocalID,
+		Suffix:       req.Suffix,
+		Instance:     req.Instance,
+		Trigger:      req.Trigger,
+		Changes:      req.Changes,
+		DiscoveredAt: req.DiscoveredAt,
+		ReceivedAt:   time.Now(),
+	}
+
+	filter := bson.M{"chamber_id": objectID, "local_id": req.LocalID}
+	opts := options.Update().SetUpsert(true)
+	if _, err := s.db.DiscoveryReportsCollection.UpdateOne(ctx, filter, bson.M{"$setOnInsert": report}, opts); err != nil {
+		return nil, fmt.Errorf("failed to store discovery report: %v", err)
+	}
+
+	for _, change := range report.Changes {
+		for _, reference := range change.ReferencedBy {
+			slog.Warn("Removed entity is used by an active experiment",
+				"chamber_id", chamberID,
+				"entity_id", change.EntityID,
+				"experiment_id", reference.ExperimentID.Hex(),
+				"experiment", reference.Title)
+		}
+	}
+
+	return &report, nil
+}
+
+// GetReports retrieves the discovery reports of a chamber, newest first
+func (s *DiscoveryReportService) GetReports(chamberID string, limit int64) ([]models.DiscoveryReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(chamberID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+	}
+
+	if limit <= 0 || limit > maxDiscoveryReports {
+		limit = maxDiscoveryReports
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{primitive.E{Key: "discovered_at", Value: -1}}).
+		SetLimit(limit)
+	cursor, err := s.db.DiscoveryReportsCollection.Find(ctx, bson.M{"chamber_id": objectID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get discovery reports: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	reports := []models.DiscoveryReport{}
+	if err = cursor.All(ctx, &reports); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery reports: %v", err)
+	}
+
+	return reports, nil
+}
+
+// RecordDiscoveryReportRequest represents a discovery report uploaded by a local API
+type RecordDiscoveryReportRequest struct {
+	LocalID      string                   `json:"local_id" binding:"required"`
+	Suffix       string                   `json:"suffix"`
+	Instance     string                   `json:"instance"`
+	Trigger      string                   `json:"trigger" binding:"required,oneof=scheduled manual"`
+	Changes      []models.DiscoveryChange `json:"changes"`
+	DiscoveredAt time.Time                `json:"discovered_at" binding:"required"`
+}
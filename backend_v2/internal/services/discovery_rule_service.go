@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend_v2/internal/database"
+	"backend_v2/internal/models"
+)
+
+// DiscoveryRuleService manages the entity discovery rules pulled by local APIs
+type DiscoveryRuleService struct {
+	db *database.MongoDB
+}
+
+// NewDiscoveryRuleService creates a new discovery rule service
+func NewDiscoveryRuleService(db *database.MongoDB) *DiscoveryRuleService {
+	return &DiscoveryRuleService{
+		db: db,
+	}
+}
+
+// GetRules retrieves the discovery rules in the order they were submitted
+func (s *DiscoveryRuleService) GetRules() ([]models.DiscoveryRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{primitive.E{Key: "position", Value: 1}})
+	cursor, err := s.db.DiscoveryRulesCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get discovery rules: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	rules := []models.DiscoveryRule{}
+	if err = cursor.All(ctx, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery rules: %v", err)
+	}
+
+	return rules, nil
+}
+
+// ReplaceRules validates rules and replaces the stored list with them
+func (s *DiscoveryRuleService) ReplaceRules(rules []models.DiscoveryRule) ([]models.DiscoveryRule, error) {
+	for i := range rules {
+		if err := validateDiscoveryRule(&rules[i]); err != nil {
+			return nil, fmt.Errorf("rules[%d]: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := s.db.DiscoveryRulesCollection.DeleteMany(ctx, bson.M{}); err != nil {
+		return nil, fmt.Errorf("failed to clear discovery rules: %v", err)
+	}
+	if len(rules) == 0 {
+		return rules, nil
+	}
+
+	now := time.Now()
+	documents := make([]interface{}, len(rules))
+	for i := range rules {
+		rules[i].ID = primitive.NewObjectID()
+		rules[i].Position = i
+		rules[i].UpdatedAt = now
+		documents[i] = rules[i]
+	}
+	if _, err := s.db.DiscoveryRulesCollection.InsertMany(ctx, documents); err != nil {
+		return nil, fmt.Errorf("failed to store discovery rules: %v", err)
+	}
+
+	return rules, nil
+}
+
+// validateDiscoveryRule checks what the backend can check without knowing the entities,
+// local APIs reject the whole list when a rule is still invalid for them
+func validateDiscoveryRule(rule *models.DiscoveryRule) error {
+	if rule.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+	if rule.EntityID == "" && rule.FriendlyName == "" {
+		return fmt.Errorf("entity_id or friendly_name pattern is required")
+	}
+
+	for _, pattern := range []string{rule.EntityID, rule.FriendlyName, rule.Exclude} {
+		if pattern == "" {
+			continue
+		}
+		switch rule.Match {
+		case "", "regex":
+			if _, err := regexp.Compile("(?i)" + pattern); err != nil {
+				return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+		case "glob":
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+		default:
+			return fmt.Errorf("invalid match %q, expected regex or glob", rule.Match)
+		}
+	}
+
+	if rule.SuffixGroup != "" && (rule.Match == "glob" || rule.EntityID == "") {
+		return fmt.Errorf("suffix_group needs an entity_id regex")
+	}
+	return nil
+}
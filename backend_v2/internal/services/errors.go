@@ -0,0 +1,22 @@
+package services
+
+import "errors"
+
+// Errors returned by the services that handlers map to HTTP status codes
+var (
+	ErrInvalidExperimentID = errors.New("invalid experiment ID")
+	ErrExperimentNotFound  = errors.New("experiment not found")
+	ErrChamberNotFound     = errors.New("chamber not found")
+	ErrInvalidStatus       = errors.New("invalid status change")
+	ErrInvalidProfile      = errors.New("invalid chamber profile")
+	// ErrInvalidID is returned for malformed object IDs other than experiment IDs
+	ErrInvalidID                = errors.New("invalid ID")
+	ErrAlarmRuleNotFound        = errors.New("alarm rule not found")
+	ErrAlarmNotFound            = errors.New("alarm not found")
+	ErrAlarmAlreadyAcknowledged = errors.New("alarm already acknowledged")
+	ErrInvalidOverride          = errors.New("invalid override")
+	// ErrOverrideNotFound is returned for overrides that do not exist or are no longer active
+	ErrOverrideNotFound = errors.New("override not found or no longer active")
+	// ErrLocalAPI wraps failures of calls to the local API of a chamber
+	ErrLocalAPI = errors.New("local API call failed")
+)
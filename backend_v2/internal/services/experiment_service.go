@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"time"
+	_ "time/tzdata" // chamber time zones are loaded on hosts without a zone database
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -71,6 +72,12 @@ func (s *ExperimentService) CreateExperiment(req *CreateExperimentRequest) (*mod
 		if len(phase.CO2NightSchedule) == 0 {
 			return nil, fmt.Errorf("phase %d: co2_night_schedule configuration is required", i+1)
 		}
+		if err := validatePhaseInterpolation(phase); err != nil {
+			return nil, fmt.Errorf("phase %d: %v", i+1, err)
+		}
+		if err := validateTimeOfDaySchedule(phase); err != nil {
+			return nil, fmt.Errorf("phase %d: %v", i+1, err)
+		}
 	}
 
 	// Validate chamber exists
@@ -115,14 +122,14 @@ func (s *ExperimentService) GetExperiment(experimentID string) (*models.Experime
 
 	objectID, err := primitive.ObjectIDFromHex(experimentID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid experiment ID: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExperimentID, err)
 	}
 
 	var experiment models.Experiment
 	err = s.db.ExperimentsCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&experiment)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("experiment not found")
+			return nil, ErrExperimentNotFound
 		}
 		return nil, fmt.Errorf("failed to get experiment: %v", err)
 	}
@@ -186,6 +193,14 @@ func (s *ExperimentService) UpdateExperiment(experimentID string, req *UpdateExp
 		update["$set"].(bson.M)["status"] = req.Status
 	}
 	if req.Phases != nil {
+		for i, phase := range req.Phases {
+			if err := validatePhaseInterpolation(phase); err != nil {
+				return nil, fmt.Errorf("phase %d: %v", i+1, err)
+			}
+			if err := validateTimeOfDaySchedule(phase); err != nil {
+				return nil, fmt.Errorf("phase %d: %v", i+1, err)
+			}
+		}
 		update["$set"].(bson.M)["phases"] = req.Phases
 	}
 	if req.Schedule != nil {
@@ -236,14 +251,15 @@ func (s *ExperimentService) DeleteExperiment(experimentID string) error {
 	return nil
 }
 
-// UpdateExperimentStatus updates only the status of an experiment
-func (s *ExperimentService) UpdateExperimentStatus(experimentID string, status models.ExperimentStatus) (*models.Experiment, error) {
+// UpdateExperimentStatus updates only the status of an experiment. Pausing and resuming are recorded
+// in the pause history; with shiftSchedule a resumed experiment continues from the day it was paused on.
+func (s *ExperimentService) UpdateExperimentStatus(experimentID string, status models.ExperimentStatus, shiftSchedule bool) (*models.Experiment, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(experimentID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid experiment ID: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExperimentID, err)
 	}
 
 	// Validate status
@@ -264,29 +280,173 @@ func (s *ExperimentService) UpdateExperimentStatus(experimentID string, status m
 	}
 
 	if !isValidStatus {
-		return nil, fmt.Errorf("invalid status: %s", status)
+		return nil, fmt.Errorf("%w: unknown status %s", ErrInvalidStatus, status)
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"status":     status,
-			"updated_at": time.Now(),
-		},
+	var experiment models.Experiment
+	err = s.db.ExperimentsCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&experiment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrExperimentNotFound
+		}
+		return nil, fmt.Errorf("failed to get experiment: %v", err)
 	}
 
-	result, err := s.db.ExperimentsCollection.UpdateByID(ctx, objectID, update)
+	now := time.Now()
+	set := bson.M{
+		"status":     status,
+		"updated_at": now,
+	}
+
+	resuming := experiment.Status == models.ExperimentStatusPaused && status != models.ExperimentStatusPaused
+	if shiftSchedule && (!resuming || status != models.ExperimentStatusActive) {
+		return nil, fmt.Errorf("%w: the schedule can only be shifted when resuming a paused experiment", ErrInvalidStatus)
+	}
+
+	switch {
+	case status == models.ExperimentStatusPaused && experiment.Status != models.ExperimentStatusPaused:
+		set["pauses"] = append(experiment.Pauses, models.PauseRecord{PausedAt: now})
+	case resuming:
+		pauses := experiment.Pauses
+		if len(pauses) > 0 && pauses[len(pauses)-1].ResumedAt == nil {
+			pause := &pauses[len(pauses)-1]
+			pause.ResumedAt = &now
+
+			if shiftSchedule {
+				location := s.chamberLocation(ctx, experiment.ChamberID)
+				days := pausedDays(pause.PausedAt, now, location)
+				pause.ScheduleShift = addDays(pause.PausedAt.Unix(), days, location) - pause.PausedAt.Unix()
+				set["schedule"] = shiftRemainingSchedule(experiment.Schedule, pause.PausedAt.Unix(), days, location)
+			}
+			set["pauses"] = pauses
+		}
+	}
+
+	result, err := s.db.ExperimentsCollection.UpdateByID(ctx, objectID, bson.M{"$set": set})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update experiment status: %v", err)
 	}
 
 	if result.MatchedCount == 0 {
-		return nil, fmt.Errorf("experiment not found")
+		return nil, ErrExperimentNotFound
 	}
 
 	// Get updated experiment
 	return s.GetExperiment(experimentID)
 }
 
+// chamberLocation returns the time zone schedule days of a chamber start in, UTC when the
+// chamber did not report one
+func (s *ExperimentService) chamberLocation(ctx context.Context, chamberID primitive.ObjectID) *time.Location {
+	var chamber models.Chamber
+	if err := s.db.ChambersCollection.FindOne(ctx, bson.M{"_id": chamberID}).Decode(&chamber); err != nil || chamber.TimeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(chamber.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// pausedDays returns the number of local midnights between pausedAt and resumedAt. Schedule
+// days start at local midnight, so shifting by these days resumes on the day the pause began;
+// a pause within one day does not shift the schedule.
+func pausedDays(pausedAt, resumedAt time.Time, location *time.Location) int {
+	pausedAt, resumedAt = pausedAt.In(location), resumedAt.In(location)
+	// Compare dates as UTC days, they are 24 hours long whatever the DST changes of the location
+	from := time.Date(pausedAt.Year(), pausedAt.Month(), pausedAt.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(resumedAt.Year(), resumedAt.Month(), resumedAt.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from) / (24 * time.Hour))
+}
+
+// addDays moves a timestamp by whole calendar days in location, keeping its local time of day
+func addDays(timestamp int64, days int, location *time.Location) int64 {
+	t := time.Unix(timestamp, 0).In(location)
+	return time.Date(t.Year(), t.Month(), t.Day()+days, t.Hour(), t.Minute(), t.Second(), 0, location).Unix()
+}
+
+// shiftRemainingSchedule moves the part of the schedule after pausedAt by whole days in location.
+// The item running at pausedAt is moved as a whole, like later items, so the executor counts its
+// days from the moved start and continues on the day the pause began.
+func shiftRemainingSchedule(schedule []models.ScheduleItem, pausedAt int64, days int, location *time.Location) []models.ScheduleItem {
+	shifted := make([]models.ScheduleItem, len(schedule))
+	for i, item := range schedule {
+		if item.EndTimestamp > pausedAt {
+			item.StartTimestamp = addDays(item.StartTimestamp, days, location)
+			item.EndTimestamp = addDays(item.EndTimestamp, days, location)
+		}
+		shifted[i] = item
+	}
+	return shifted
+}
+
+// validatePhaseInterpolation checks that all schedules of a phase use a known interpolation mode
+func validatePhaseInterpolation(phase models.Phase) error {
+	schedules := map[string]map[string]models.ScheduleConfig{
+		"work_day_schedule":          phase.WorkDaySchedule,
+		"temperature_day_schedule":   phase.TemperatureDaySchedule,
+		"temperature_night_schedule": phase.TemperatureNightSchedule,
+		"humidity_day_schedule":      phase.HumidityDaySchedule,
+		"humidity_night_schedule":    phase.HumidityNightSchedule,
+		"co2_day_schedule":           phase.CO2DaySchedule,
+		"co2_night_schedule":         phase.CO2NightSchedule,
+		"light_intensity_schedule":   phase.LightIntensitySchedule,
+		"switch_schedule":            phase.SwitchSchedule,
+	}
+
+	for field, configs := range schedules {
+		for key, config := range configs {
+			if !isValidInterpolation(config.Interpolation) {
+				return fmt.Errorf("%s.%s: invalid interpolation %q", field, key, config.Interpolation)
+			}
+			if field == "switch_schedule" && config.Interpolation != "" && config.Interpolation != models.InterpolationStep {
+				return fmt.Errorf("%s.%s: switches only support %q interpolation", field, key, models.InterpolationStep)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateTimeOfDaySchedule checks the intra-day programs of a phase
+func validateTimeOfDaySchedule(phase models.Phase) error {
+	for key, program := range phase.TimeOfDaySchedule {
+		if program.EntityID == "" {
+			return fmt.Errorf("time_of_day_schedule.%s: entity_id is required", key)
+		}
+		if len(program.Breakpoints) == 0 {
+			return fmt.Errorf("time_of_day_schedule.%s: at least one breakpoint is required", key)
+		}
+		if !isValidInterpolation(program.Interpolation) {
+			return fmt.Errorf("time_of_day_schedule.%s: invalid interpolation %q", key, program.Interpolation)
+		}
+
+		seen := make(map[int]bool, len(program.Breakpoints))
+		for _, breakpoint := range program.Breakpoints {
+			minutes, err := breakpoint.Minutes()
+			if err != nil {
+				return fmt.Errorf("time_of_day_schedule.%s: %v", key, err)
+			}
+			if seen[minutes] {
+				return fmt.Errorf("time_of_day_schedule.%s: duplicate breakpoint at %s", key, breakpoint.Time)
+			}
+			seen[minutes] = true
+		}
+	}
+
+	return nil
+}
+
+// isValidInterpolation reports whether mode is a known interpolation mode, empty meaning step
+func isValidInterpolation(mode string) bool {
+	switch mode {
+	case "", models.InterpolationStep, models.InterpolationLinear, models.InterpolationSigmoid:
+		return true
+	}
+	return false
+}
+
 // CreateExperimentRequest represents the request to create an experiment
 type CreateExperimentRequest struct {
 	Title       string                `json:"title" binding:"required"`
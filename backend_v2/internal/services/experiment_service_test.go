@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend_v2/internal/models"
+)
+
+func TestShiftRemainingSchedule(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	// Two phases of three days from local midnight of March 28
+	schedule := func(location *time.Location) []models.ScheduleItem {
+		at := func(day int) int64 { return time.Date(2025, 3, 28+day, 0, 0, 0, 0, location).Unix() }
+		return []models.ScheduleItem{
+			{PhaseIndex: 0, StartTimestamp: at(0), EndTimestamp: at(3)},
+			{PhaseIndex: 1, StartTimestamp: at(3), EndTimestamp: at(6)},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		location   *time.Location
+		pausedAt   time.Time
+		resumedAt  time.Time
+		wantDays   int
+		wantStarts []time.Time // local start of the phases after the shift
+		wantEnds   []time.Time // local end of the phases after the shift
+	}{
+		{
+			name:       "within one day",
+			location:   moscow,
+			pausedAt:   time.Date(2025, 3, 29, 9, 0, 0, 0, moscow),
+			resumedAt:  time.Date(2025, 3, 29, 17, 0, 0, 0, moscow),
+			wantDays:   0,
+			wantStarts: []time.Time{time.Date(2025, 3, 28, 0, 0, 0, 0, moscow), time.Date(2025, 3, 31, 0, 0, 0, 0, moscow)},
+			wantEnds:   []time.Time{time.Date(2025, 3, 31, 0, 0, 0, 0, moscow), time.Date(2025, 4, 3, 0, 0, 0, 0, moscow)},
+		},
+		{
+			name:       "across midnight",
+			location:   moscow,
+			pausedAt:   time.Date(2025, 3, 29, 22, 0, 0, 0, moscow),
+			resumedAt:  time.Date(2025, 3, 30, 9, 0, 0, 0, moscow),
+			wantDays:   1,
+			wantStarts: []time.Time{time.Date(2025, 3, 29, 0, 0, 0, 0, moscow), time.Date(2025, 4, 1, 0, 0, 0, 0, moscow)},
+			wantEnds:   []time.Time{time.Date(2025, 4, 1, 0, 0, 0, 0, moscow), time.Date(2025, 4, 4, 0, 0, 0, 0, moscow)},
+		},
+		{
+			name:       "midnight in the zone, not in UTC",
+			location:   moscow,
+			pausedAt:   time.Date(2025, 3, 29, 23, 0, 0, 0, moscow),
+			resumedAt:  time.Date(2025, 3, 30, 2, 0, 0, 0, moscow), // same UTC date
+			wantDays:   1,
+			wantStarts: []time.Time{time.Date(2025, 3, 29, 0, 0, 0, 0, moscow), time.Date(2025, 4, 1, 0, 0, 0, 0, moscow)},
+			wantEnds:   []time.Time{time.Date(2025, 4, 1, 0, 0, 0, 0, moscow), time.Date(2025, 4, 4, 0, 0, 0, 0, moscow)},
+		},
+		{
+			name:       "several days in the second phase",
+			location:   moscow,
+			pausedAt:   time.Date(2025, 3, 31, 12, 0, 0, 0, moscow),
+			resumedAt:  time.Date(2025, 4, 3, 8, 0, 0, 0, moscow),
+			wantDays:   3,
+			wantStarts: []time.Time{time.Date(2025, 3, 28, 0, 0, 0, 0, moscow), time.Date(2025, 4, 3, 0, 0, 0, 0, moscow)},
+			wantEnds:   []time.Time{time.Date(2025, 3, 31, 0, 0, 0, 0, moscow), time.Date(2025, 4, 6, 0, 0, 0, 0, moscow)},
+		},
+		{
+			name:       "across a DST change",
+			location:   berlin,
+			pausedAt:   time.Date(2025, 3, 29, 20, 0, 0, 0, berlin),
+			resumedAt:  time.Date(2025, 3, 30, 10, 0, 0, 0, berlin), // 23 hour day
+			wantDays:   1,
+			wantStarts: []time.Time{time.Date(2025, 3, 29, 0, 0, 0, 0, berlin), time.Date(2025, 4, 1, 0, 0, 0, 0, berlin)},
+			wantEnds:   []time.Time{time.Date(2025, 4, 1, 0, 0, 0, 0, berlin), time.Date(2025, 4, 4, 0, 0, 0, 0, berlin)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := pausedDays(tt.pausedAt, tt.resumedAt, tt.location)
+			if days != tt.wantDays {
+				t.Fatalf("pausedDays = %d, want %d", days, tt.wantDays)
+			}
+
+			original := schedule(tt.location)
+			shifted := shiftRemainingSchedule(original, tt.pausedAt.Unix(), days, tt.location)
+			for i, item := range shifted {
+				if end := time.Unix(item.EndTimestamp, 0); !end.Equal(tt.wantEnds[i]) {
+					t.Errorf("phase %d ends %v, want %v", i, end.In(tt.location), tt.wantEnds[i])
+				}
+				if start := time.Unix(item.StartTimestamp, 0); !start.Equal(tt.wantStarts[i]) {
+					t.Errorf("phase %d starts %v, want %v", i, start.In(tt.location), tt.wantStarts[i])
+				}
+				// The phase running at the pause continues on the schedule day it was paused on
+				if original[i].StartTimestamp <= tt.pausedAt.Unix() && tt.pausedAt.Unix() < original[i].EndTimestamp {
+					pausedDay := pausedDays(time.Unix(original[i].StartTimestamp, 0), tt.pausedAt, tt.location)
+					resumedDay := pausedDays(time.Unix(item.StartTimestamp, 0), tt.resumedAt, tt.location)
+					if resumedDay != pausedDay {
+						t.Errorf("phase %d resumes on day %d, paused on day %d", i, resumedDay, pausedDay)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend_v2/internal/database"
+	"backend_v2/internal/models"
+)
+
+// OverrideService manages time-bounded overrides of chamber entities
+type OverrideService struct {
+	db               *database.MongoDB
+	deviationService *DeviationService
+}
+
+// NewOverrideService creates a new override service
+func NewOverrideService(db *database.MongoDB, deviationService *DeviationService) *OverrideService {
+	return &OverrideService{
+		db:               db,
+		deviationService: deviationService,
+	}
+}
+
+// CreateOverride forces an entity of a chamber to a value until the override expires.
+// The override is recorded as a deviation of the experiment running in the chamber.
+func (s *OverrideService) CreateOverride(chamberID string, req *CreateOverrideRequest, createdBy string) (*models.Override, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(chamberID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+	}
+
+	var chamber models.Chamber
+	err = s.db.ChambersCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&chamber)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrChamberNotFound
+		}
+		return nil, fmt.Errorf("failed to get chamber: %v", err)
+	}
+	if err := validateEntityValue(chamber.Config, req.EntityID, *req.Value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOverride, err)
+	}
+
+	now := time.Now()
+	var expiresAt time.Time
+	switch {
+	case req.ExpiresAt != nil:
+		expiresAt = *req.ExpiresAt
+	case req.DurationMinutes > 0:
+		expiresAt = now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	default:
+		return nil, fmt.Errorf("%w: expires_at or duration_minutes is required", ErrInvalidOverride)
+	}
+	if !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidOverride)
+	}
+
+	override := models.Override{
+		ID:        primitive.NewObjectID(),
+		ChamberID: objectID,
+		EntityID:  req.EntityID,
+		Value:     *req.Value,
+		Reason:    req.Reason,
+		ExpiresAt: expiresAt,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	var experiment models.Experiment
+	err = s.db.ExperimentsCollection.FindOne(ctx, bson.M{
+		"chamber_id": objectID,
+		"status":     bson.M{"$in": []models.ExperimentStatus{models.ExperimentStatusActive, models.ExperimentStatusPaused}},
+	}).Decode(&experiment)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to get running experiment: %v", err)
+	}
+	if err == nil {
+		override.ExperimentID = &experiment.ID
+	}
+
+	if _, err := s.db.OverridesCollection.InsertOne(ctx, override); err != nil {
+		return nil, fmt.Errorf("failed to create override: %v", err)
+	}
+
+	if override.ExperimentID != nil {
+		value := override.Value
+		_, err := s.deviationService.CreateDeviation(override.ExperimentID.Hex(), &CreateDeviationRequest{
+			Type:          models.DeviationTypeOverride,
+			LocalID:       "override:" + override.ID.Hex(),
+			EntityID:      override.EntityID,
+			ObservedValue: &value,
+			Action:        fmt.Sprintf("forced by %s until %s: %s", createdBy, expiresAt.Format(time.RFC3339), override.Reason),
+			OccurredAt:    &now,
+		})
+		if err != nil {
+			slog.Error("Failed to record override as deviation", "chamber_id", override.ChamberID.Hex(), "override_id", override.ID.Hex(), "error", err)
+		}
+	}
+
+	return &override, nil
+}
+
+// GetOverrides retrieves the overrides of a chamber, newest first.
+// Unless includeInactive is set, only overrides in effect are returned.
+func (s *OverrideService) GetOverrides(chamberID string, includeInactive bool) ([]models.Override, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(chamberID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+	}
+
+	filter := bson.M{"chamber_id": objectID}
+	if !includeInactive {
+		filter["cancelled_at"] = bson.M{"$exists": false}
+		filter["expires_at"] = bson.M{"$gt": time.Now()}
+	}
+
+	opts := options.Find().SetSort(bson.D{primitive.E{Key: "created_at", Value: -1}})
+	cursor, err := s.db.OverridesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overrides: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	overrides := []models.Override{}
+	if err = cursor.All(ctx, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to decode overrides: %v", err)
+	}
+
+	return overrides, nil
+}
+
+// CancelOverride ends an override before it expires
+func (s *OverrideService) CancelOverride(chamberID, overrideID, cancelledBy string) (*models.Override, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	chamberObjectID, err := primitive.ObjectIDFromHex(chamberID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chamber ID: %v", ErrInvalidID, err)
+	}
+	objectID, err := primitive.ObjectIDFromHex(overrideID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: override ID: %v", ErrInvalidID, err)
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"_id":          objectID,
+		"chamber_id":   chamberObjectID,
+		"cancelled_at": bson.M{"$exists": false},
+		"expires_at":   bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{
+		"cancelled_at": now,
+		"cancelled_by": cancelledBy,
+		"updated_at":   now,
+	}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var override models.Override
+	if err := s.db.OverridesCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&override); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrOverrideNotFound
+		}
+		return nil, fmt.Errorf("failed to cancel override: %v", err)
+	}
+
+	return &override, nil
+}
+
+// CreateOverrideRequest represents the request to create an override
+type CreateOverrideRequest struct {
+	EntityID        string     `json:"entity_id" binding:"required"`
+	Value           *float64   `json:"value" binding:"required"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+	DurationMinutes int        `json:"duration_minutes" binding:"omitempty,min=1"`
+	Reason          string     `json:"reason" binding:"required"`
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"backend_v2/internal/database"
+	"backend_v2/internal/models"
+)
+
+// maxSetpointApplications limits the number of records returned by a single query
+const maxSetpointApplications = 5000
+
+// SetpointApplicationService stores the setpoint audit log uploaded by local APIs
+type SetpointApplicationService struct {
+	db *database.MongoDB
+}
+
+// NewSetpointApplicationService creates a new setpoint application service
+func NewSetpointApplicationService(db *database.MongoDB) *SetpointApplicationService {
+	return &SetpointApplicationService{
+		db: db,
+	}
+}
+
+// RecordApplications stores a batch of setpoint applications of an experiment.
+// Records are keyed by their local_id, so repeated uploads do not create duplicates.
+func (s *SetpointApplicationService) RecordApplications(experimentID string, req *RecordSetpointApplicationsRequest) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(experimentID)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidExperimentID, err)
+	}
+
+	var experiment models.Experiment
+	err = s.db.ExperimentsCollection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&experiment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, ErrExperimentNotFound
+		}
+		return 0, fmt.Errorf("failed to get experiment: %v", err)
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(req.Applications))
+	for _, record := range req.Applications {
+		application := models.SetpointApplication{
+			ID:             primitive.NewObjectID(),
+			ExperimentID:   experiment.ID,
+			ChamberID:      experiment.ChamberID,
+			LocalID:        record.LocalID,
+			PhaseIndex:     record.PhaseIndex,
+			Day:            record.Day,
+			EntityID:       record.EntityID,
+			Group:          record.Group,
+			RequestedValue: record.RequestedValue,
+			ExpectedValue:  record.ExpectedValue,
+			ActualValue:    record.ActualValue,
+			Status:         record.Status,
+			Error:          record.Error,
+			TimeSource:     record.TimeSource,
+			AppliedAt:      record.AppliedAt,
+			SystemTime:     record.SystemTime,
+			ReceivedAt:     now,
+		}
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"experiment_id": experiment.ID, "local_id": record.LocalID}).
+			SetUpdate(bson.M{"$setOnInsert": application}).
+			SetUpsert(true))
+	}
+
+	if len(writes) == 0 {
+		return 0, nil
+	}
+
+	result, err := s.db.SetpointApplicationsCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, fmt.Errorf("failed to store setpoint applications: %v", err)
+	}
+
+	return int(result.UpsertedCount), nil
+}
+
+// GetApplications retrieves the setpoint applications of an experiment, newest first
+func (s *SetpointApplicationService) GetApplications(experimentID, entityID string, from, to *time.Time) ([]models.SetpointApplication, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExperimentID, err)
+	}
+
+	count, err := s.db.ExperimentsCollection.CountDocuments(ctx, bson.M{"_id": objectID}, options.Count().SetLimit(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment: %v", err)
+	}
+	if count == 0 {
+		return nil, ErrExperimentNotFound
+	}
+
+	filter := bson.M{"experiment_id": objectID}
+	if entityID != "" {
+		filter["entity_id"] = entityID
+	}
+	appliedAt := bson.M{}
+	if from != nil {
+		appliedAt["$gte"] = *from
+	}
+	if to != nil {
+		appliedAt["$lte"] = *to
+	}
+	if len(appliedAt) > 0 {
+		filter["applied_at"] = appliedAt
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{primitive.E{Key: "applied_at", Value: -1}}).
+		SetLimit(maxSetpointApplications)
+
+	cursor, err := s.db.SetpointApplicationsCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get setpoint applications: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	applications := []models.SetpointApplication{}
+	if err = cursor.All(ctx, &applications); err != nil {
+		return nil, fmt.Errorf("failed to decode setpoint applications: %v", err)
+	}
+
+	return applications, nil
+}
+
+// SetpointApplicationRecord represents a single uploaded setpoint application
+type SetpointApplicationRecord struct {
+	LocalID        string    `json:"local_id" binding:"required"`
+	PhaseIndex     int       `json:"phase_index"`
+	Day            int       `json:"day"`
+	EntityID       string    `json:"entity_id" binding:"required"`
+	Group          string    `json:"group"`
+	RequestedValue float64   `json:"requested_value"`
+	ExpectedValue  float64   `json:"expected_value"`
+	ActualValue    *float64  `json:"actual_value"`
+	Status         string    `json:"status" binding:"required"`
+	Error          string    `json:"error"`
+	TimeSource     string    `json:"time_source"`
+	AppliedAt      time.Time `json:"applied_at" binding:"required"`
+	SystemTime     time.Time `json:"system_time"`
+}
+
+// RecordSetpointApplicationsRequest represents a batch upload of setpoint applications
+type RecordSetpointApplicationsRequest struct {
+	Applications []SetpointApplicationRecord `json:"applications" binding:"required,dive"`
+}
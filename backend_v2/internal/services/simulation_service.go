@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"backend_v2/internal/config"
+	"backend_v2/internal/database"
+	"backend_v2/internal/models"
+)
+
+// SimulationService runs dry-run simulations of experiments on the local API of their chamber
+type SimulationService struct {
+	db                *database.MongoDB
+	config            *config.Config
+	experimentService *ExperimentService
+	httpClient        *http.Client
+}
+
+// NewSimulationService creates a new simulation service
+func NewSimulationService(db *database.MongoDB, cfg *config.Config, experimentService *ExperimentService) *SimulationService {
+	return &SimulationService{
+		db:                db,
+		config:            cfg,
+		experimentService: experimentService,
+		httpClient: &http.Client{
+			Timeout: cfg.LocalAPITimeout,
+		},
+	}
+}
+
+// SimulateExperimentRequest represents the options of a simulation run
+type SimulateExperimentRequest struct {
+	StepMinutes int `json:"step_minutes" binding:"omitempty,min=1,max=1440"`
+}
+
+// SimulateExperiment sends an experiment to the local API of its chamber, which replays the
+// schedule against a simulated clock and returns the timeline of writes and found issues
+func (s *SimulationService) SimulateExperiment(experimentID string, req *SimulateExperimentRequest) (json.RawMessage, error) {
+	experiment, err := s.experimentService.GetExperiment(experimentID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var chamber models.Chamber
+	err = s.db.ChambersCollection.FindOne(ctx, bson.M{"_id": experiment.ChamberID}).Decode(&chamber)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrChamberNotFound
+		}
+		return nil, fmt.Errorf("failed to get chamber: %v", err)
+	}
+	if chamber.LocalIP == "" {
+		return nil, fmt.Errorf("%w: chamber has no local API address", ErrLocalAPI)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"experiment":   experiment,
+		"step_minutes": req.StepMinutes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal simulation request: %v", err)
+	}
+
+	url := fmt.Sprintf("http://%s:%s/api/v1/simulate", chamber.LocalIP, s.config.LocalAPIPort)
+	resp, err := s.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to reach chamber local API: %v", ErrLocalAPI, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read simulation response: %v", ErrLocalAPI, err)
+	}
+
+	var result struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   string          `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("%w: invalid simulation response (status %d): %v", ErrLocalAPI, resp.StatusCode, err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("%w: simulation failed: %s", ErrLocalAPI, result.Error)
+	}
+
+	return result.Data, nil
+}
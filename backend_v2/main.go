@@ -4,7 +4,7 @@ import (
 	"context"
 	"embed"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,6 +17,7 @@ import (
 	"backend_v2/internal/config"
 	"backend_v2/internal/database"
 	"backend_v2/internal/handlers"
+	"backend_v2/internal/logging"
 	"backend_v2/internal/middleware"
 	"backend_v2/internal/models"
 	"backend_v2/internal/services"
@@ -29,58 +30,79 @@ func main() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
 	}
 
 	// Connect to MongoDB
 	db, err := database.Connect(cfg.MongoURI, cfg.MongoDatabase)
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		slog.Error("Failed to connect to MongoDB", "error", err)
+		os.Exit(1)
 	}
 	defer func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := db.Disconnect(ctx); err != nil {
-			log.Printf("Failed to disconnect from MongoDB: %v", err)
+			slog.Error("Failed to disconnect from MongoDB", "error", err)
 		}
 	}()
 
-	log.Println("✅ Connected to MongoDB")
+	slog.Info("Connected to MongoDB", "database", cfg.MongoDatabase)
 
 	chamberService := services.NewChamberService(db, cfg)
 	experimentService := services.NewExperimentService(db)
 	authService := services.NewAuthService(db, cfg)
 	apiTokenService := services.NewAPITokenService(db)
 	userChamberAccessService := services.NewUserChamberAccessService(db)
+	deviationService := services.NewDeviationService(db)
+	setpointApplicationService := services.NewSetpointApplicationService(db)
+	simulationService := services.NewSimulationService(db, cfg, experimentService)
+	alarmService := services.NewAlarmService(db)
+	discoveryRuleService := services.NewDiscoveryRuleService(db)
+	discoveryReportService := services.NewDiscoveryReportService(db)
+	overrideService := services.NewOverrideService(db, deviationService)
 
 	// Initialize handlers
 	chamberHandler := handlers.NewChamberHandler(chamberService)
-	experimentHandler := handlers.NewExperimentHandler(experimentService)
+	experimentHandler := handlers.NewExperimentHandler(experimentService, simulationService)
 	authHandler := handlers.NewAuthHandler(authService)
 	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenService)
 	userChamberAccessHandler := handlers.NewUserChamberAccessHandler(userChamberAccessService)
 	userHandler := handlers.NewUserManagementHandler(authService)
+	deviationHandler := handlers.NewDeviationHandler(deviationService)
+	setpointApplicationHandler := handlers.NewSetpointApplicationHandler(setpointApplicationService)
+	alarmHandler := handlers.NewAlarmHandler(alarmService)
+	discoveryRuleHandler := handlers.NewDiscoveryRuleHandler(discoveryRuleService)
+	discoveryReportHandler := handlers.NewDiscoveryReportHandler(discoveryReportService)
+	overrideHandler := handlers.NewOverrideHandler(overrideService)
+	adminHandler := handlers.NewAdminHandler()
 
 	// Set Gin mode
 	gin.SetMode(cfg.GinMode)
 
 	// Create router
 	router := gin.New()
-	router.Use(gin.Logger())
+	router.Use(middleware.RequestLogger())
 	router.Use(gin.Recovery())
 
 	// Configure CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", logging.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", logging.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	// Setup API routes
-	setupAPIRoutes(router, chamberHandler, experimentHandler, authHandler, apiTokenHandler, userChamberAccessHandler, userHandler, apiTokenService, authService)
+	setupAPIRoutes(router, chamberHandler, experimentHandler, authHandler, apiTokenHandler, userChamberAccessHandler, userHandler, deviationHandler, setpointApplicationHandler, alarmHandler, discoveryRuleHandler, discoveryReportHandler, overrideHandler, adminHandler, apiTokenService, authService)
 
 	// Setup frontend routes
 	setupFrontendRoutes(router)
@@ -90,7 +112,7 @@ func main() {
 	defer cancel()
 
 	go chamberService.StartStatusMonitor(ctx)
-	log.Println("✅ Started chamber status monitor")
+	slog.Info("Started chamber status monitor")
 
 	// Start server
 	srv := &http.Server{
@@ -100,11 +122,13 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("🚀 Server starting on port %s", cfg.Port)
-		log.Printf("🌐 Frontend available at: http://localhost:%s", cfg.Port)
-		log.Printf("🔧 API available at: http://localhost:%s/api", cfg.Port)
+		slog.Info("Server starting",
+			"port", cfg.Port,
+			"frontend", "http://localhost:"+cfg.Port,
+			"api", "http://localhost:"+cfg.Port+"/api")
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			slog.Error("Failed to start server", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -113,7 +137,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("⏸️  Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Cancel background services
 	cancel()
@@ -123,10 +147,10 @@ func main() {
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
 	}
 
-	log.Println("✅ Server shutdown complete")
+	slog.Info("Server shutdown complete")
 }
 
 func setupAPIRoutes(
@@ -137,6 +161,13 @@ func setupAPIRoutes(
 	apiTokenHandler *handlers.APITokenHandler,
 	userChamberAccessHandler *handlers.UserChamberAccessHandler,
 	userHandler *handlers.UserManagementHandler,
+	deviationHandler *handlers.DeviationHandler,
+	setpointApplicationHandler *handlers.SetpointApplicationHandler,
+	alarmHandler *handlers.AlarmHandler,
+	discoveryRuleHandler *handlers.DiscoveryRuleHandler,
+	discoveryReportHandler *handlers.DiscoveryReportHandler,
+	overrideHandler *handlers.OverrideHandler,
+	adminHandler *handlers.AdminHandler,
 	apiTokenService *services.APITokenService,
 	authService *services.AuthService,
 ) {
@@ -178,6 +209,11 @@ func setupAPIRoutes(
 		api.PUT("/chambers/:id/config", chamberHandler.UpdateChamberConfig)
 		api.GET("/chambers/:id/config", chamberHandler.GetChamberConfig)
 		api.GET("/chambers/:id/config/check", chamberHandler.CheckChamberConfigUpdate)
+		api.POST("/chambers/:id/overrides", overrideHandler.CreateOverride)
+		api.GET("/chambers/:id/overrides", overrideHandler.GetOverrides)
+		api.DELETE("/chambers/:id/overrides/:override_id", overrideHandler.CancelOverride)
+		api.POST("/chambers/:id/discovery-reports", discoveryReportHandler.RecordReport)
+		api.GET("/chambers/:id/discovery-reports", discoveryReportHandler.GetReports)
 
 		// Experiment routes
 		api.GET("/experiments/:id", experimentHandler.GetExperiment)
@@ -186,6 +222,23 @@ func setupAPIRoutes(
 		api.PUT("/experiments/:id", experimentHandler.UpdateExperiment)
 		api.PATCH("/experiments/:id/status", experimentHandler.UpdateExperimentStatus)
 		api.DELETE("/experiments/:id", experimentHandler.DeleteExperiment)
+		api.POST("/experiments/:id/simulate", experimentHandler.SimulateExperiment)
+		api.POST("/experiments/:id/deviations", deviationHandler.CreateDeviation)
+		api.GET("/experiments/:id/deviations", deviationHandler.GetDeviations)
+		api.POST("/experiments/:id/setpoint-applications", setpointApplicationHandler.RecordApplications)
+		api.GET("/experiments/:id/setpoint-applications", setpointApplicationHandler.GetApplications)
+
+		// Alarm routes
+		api.GET("/alarm-rules", alarmHandler.GetRules)
+		api.POST("/alarm-rules", alarmHandler.CreateRule)
+		api.PUT("/alarm-rules/:id", alarmHandler.UpdateRule)
+		api.DELETE("/alarm-rules/:id", alarmHandler.DeleteRule)
+		api.GET("/alarms", alarmHandler.GetAlarms)
+		api.POST("/alarms/events", alarmHandler.RecordEvent)
+		api.POST("/alarms/:id/ack", alarmHandler.AcknowledgeAlarm)
+
+		// Discovery rule routes, pulled by local APIs
+		api.GET("/discovery-rules", discoveryRuleHandler.GetRules)
 
 		// User Chamber Access routes (Admin only)
 		adminRoutes := api.Group("/")
@@ -207,6 +260,13 @@ func setupAPIRoutes(
 			adminRoutes.DELETE("/users/:id/chambers/:chamber_id", userChamberAccessHandler.RevokeChamberAccess)
 			adminRoutes.GET("/users/:id/chambers/:chamber_id/check", userChamberAccessHandler.HasChamberAccess)
 
+			// Runtime administration
+			adminRoutes.GET("/admin/loglevel", adminHandler.GetLogLevel)
+			adminRoutes.PUT("/admin/loglevel", adminHandler.SetLogLevel)
+
+			// Discovery rules apply to every local API
+			adminRoutes.PUT("/discovery-rules", discoveryRuleHandler.ReplaceRules)
+
 		}
 
 		// User's own chamber access (non-admin users can check their own access)
@@ -255,15 +315,14 @@ func setupFrontendRoutes(router *gin.Engine) {
 	// Get the frontend dist subdirectory from embedded FS
 	frontendDistFS, err := fs.Sub(frontendFS, "frontend/dist")
 	if err != nil {
-		log.Printf("Warning: Could not load embedded frontend files: %v", err)
-		log.Println("Frontend will not be served. Make sure to build frontend and place dist files in backend/frontend/dist/")
+		slog.Warn("Could not load embedded frontend files, frontend will not be served. Make sure to build frontend and place dist files in backend/frontend/dist/", "error", err)
 		return
 	}
 
 	// Get the assets subdirectory from the dist directory
 	assetsFS, err := fs.Sub(frontendDistFS, "assets")
 	if err != nil {
-		log.Printf("Warning: Could not load frontend assets: %v", err)
+		slog.Warn("Could not load frontend assets", "error", err)
 		// Fallback to serving the entire dist directory
 		router.StaticFS("/assets", http.FS(frontendDistFS))
 	} else {
@@ -311,7 +370,7 @@ func setupFrontendRoutes(router *gin.Engine) {
 	router.NoRoute(func(c *gin.Context) {
 		// Skip API routes
 		if gin.IsDebugging() {
-			log.Printf("Serving frontend for route: %s", c.Request.URL.Path)
+			slog.Debug("Serving frontend", "path", c.Request.URL.Path)
 		}
 
 		if c.Request.URL.Path != "/" &&
@@ -325,7 +384,7 @@ func setupFrontendRoutes(router *gin.Engine) {
 
 		indexHTML, err := frontendDistFS.Open("index.html")
 		if err != nil {
-			log.Printf("Error serving index.html: %v", err)
+			logging.FromContext(c.Request.Context()).Error("Error serving index.html", "error", err)
 			c.String(500, "Frontend not available")
 			return
 		}
@@ -339,5 +398,5 @@ func setupFrontendRoutes(router *gin.Engine) {
 
 		c.DataFromReader(200, stat.Size(), "text/html; charset=utf-8", indexHTML, nil)
 	})
-	log.Println("✅ Frontend routes configured")
+	slog.Info("Frontend routes configured")
 }
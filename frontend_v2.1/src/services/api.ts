@@ -1,6 +1,6 @@
 import axios from 'axios'
 import type { AxiosInstance } from 'axios'
-import type { ApiResponse, Chamber, Experiment } from '@/types'
+import type { ApiResponse, Chamber, Experiment, Alarm, AlarmRule, Override, CreateOverrideRequest } from '@/types'
 import type { LoginCredentials, RegisterData, AuthResponse, User, ApiToken } from '@/types/auth'
 import { useAuthStore } from '@/stores/auth'
 
@@ -101,8 +101,8 @@ class ApiService {
     return response.data
   }
 
-  async updateExperimentStatus(id: string, status: string): Promise<ApiResponse<Experiment>> {
-    const response = await this.api.patch(`/experiments/${id}/status`, { status })
+  async updateExperimentStatus(id: string, status: string, shiftSchedule = false): Promise<ApiResponse<Experiment>> {
+    const response = await this.api.patch(`/experiments/${id}/status`, { status, shift_schedule: shiftSchedule })
     return response.data
   }
 
@@ -272,6 +272,53 @@ class ApiService {
     return response.data
   }
 
+  // Override endpoints
+  async getOverrides(chamberId: string, all = false): Promise<ApiResponse<Override[]>> {
+    const response = await this.api.get(`/chambers/${chamberId}/overrides`, { params: { all } })
+    return response.data
+  }
+
+  async createOverride(chamberId: string, override: CreateOverrideRequest): Promise<ApiResponse<Override>> {
+    const response = await this.api.post(`/chambers/${chamberId}/overrides`, override)
+    return response.data
+  }
+
+  async cancelOverride(chamberId: string, overrideId: string): Promise<ApiResponse<Override>> {
+    const response = await this.api.delete(`/chambers/${chamberId}/overrides/${overrideId}`)
+    return response.data
+  }
+
+  // Alarm endpoints
+  async getAlarmRules(chamberId?: string): Promise<ApiResponse<AlarmRule[]>> {
+    const response = await this.api.get('/alarm-rules', { params: { chamber_id: chamberId } })
+    return response.data
+  }
+
+  async createAlarmRule(rule: Partial<AlarmRule>): Promise<ApiResponse<AlarmRule>> {
+    const response = await this.api.post('/alarm-rules', rule)
+    return response.data
+  }
+
+  async updateAlarmRule(id: string, rule: Partial<AlarmRule>): Promise<ApiResponse<AlarmRule>> {
+    const response = await this.api.put(`/alarm-rules/${id}`, rule)
+    return response.data
+  }
+
+  async deleteAlarmRule(id: string): Promise<ApiResponse<void>> {
+    const response = await this.api.delete(`/alarm-rules/${id}`)
+    return response.data
+  }
+
+  async getAlarms(params?: { chamber_id?: string; status?: string; severity?: string; acknowledged?: boolean }): Promise<ApiResponse<Alarm[]>> {
+    const response = await this.api.get('/alarms', { params })
+    return response.data
+  }
+
+  async acknowledgeAlarm(id: string, comment?: string): Promise<ApiResponse<Alarm>> {
+    const response = await this.api.post(`/alarms/${id}/ack`, { comment })
+    return response.data
+  }
+
   // Helper methods
   formatError(error: any): string {
     if (error.response?.data?.error) {
@@ -66,10 +66,18 @@ export interface ChamberConfig {
     day: Record<string, InputNumber>;
     night: Record<string, InputNumber>;
   };
+  pause_profile?: PauseProfile;
+  idle_profile?: Record<string, number>; // entity_id -> value applied while no experiment is active
   updated_at?: string;
   synced_at?: string;
 }
 
+// Setpoints a chamber holds while its experiment is paused
+export interface PauseProfile {
+  mode: 'hold' | 'safe';
+  values?: Record<string, number>; // entity_id -> value written in safe mode
+}
+
 export interface InputNumber {
   entity_id: string;
   name: string;
@@ -103,10 +111,17 @@ export interface Experiment {
   phases: Phase[];
   schedule: ScheduleItem[];
   active_phase_index?: number;
+  pauses?: PauseRecord[];
   created_at: string;
   updated_at: string;
 }
 
+export interface PauseRecord {
+  paused_at: string;
+  resumed_at?: string;
+  schedule_shift?: number; // seconds the remaining schedule was moved on resume
+}
+
 export interface Phase {
   title: string;
   description: string;
@@ -121,6 +136,7 @@ export interface Phase {
   co2_night_schedule?: Record<string, ScheduleConfig>;
   light_intensity_schedule?: Record<string, ScheduleConfig>;
   watering_zones?: Record<string, WateringZoneSchedule>;
+  time_of_day_schedule?: Record<string, TimeOfDaySchedule>;
   last_executed?: string;
 }
 
@@ -129,9 +145,23 @@ export interface StartDayConfig {
   value: number;
 }
 
+export type ScheduleInterpolation = 'step' | 'linear' | 'sigmoid';
+
 export interface ScheduleConfig {
   entity_id: string;
   schedule: Record<number, number>;
+  interpolation?: ScheduleInterpolation;
+}
+
+export interface TimeBreakpoint {
+  time: string; // HH:MM, chamber local time
+  value: number;
+}
+
+export interface TimeOfDaySchedule {
+  entity_id: string;
+  breakpoints: TimeBreakpoint[];
+  interpolation?: ScheduleInterpolation;
 }
 
 export interface WateringZoneSchedule {
@@ -181,6 +211,74 @@ export interface UserChamberAccess {
   chambers: Chamber[];
 }
 
+// Time-bounded override of a chamber entity
+export interface Override {
+  id: string;
+  chamber_id: string;
+  experiment_id?: string;
+  entity_id: string;
+  value: number;
+  reason: string;
+  expires_at: string;
+  created_by: string;
+  cancelled_at?: string;
+  cancelled_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateOverrideRequest {
+  entity_id: string;
+  value: number;
+  reason: string;
+  expires_at?: string;
+  duration_minutes?: number;
+}
+
+// Alarm types
+export type AlarmMetric = 'temperature' | 'humidity' | 'co2' | 'light';
+export type AlarmComparator = 'gt' | 'gte' | 'lt' | 'lte';
+export type AlarmSeverity = 'info' | 'warning' | 'critical';
+
+export interface AlarmRule {
+  id: string;
+  chamber_id: string;
+  name: string;
+  metric: AlarmMetric;
+  entity_id?: string; // limits the rule to one sensor
+  comparator: AlarmComparator;
+  threshold: number;
+  duration: number; // seconds the condition must hold before raising
+  severity: AlarmSeverity;
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Alarm {
+  id: string;
+  local_id: string;
+  rule_id: string;
+  chamber_id: string;
+  rule_name: string;
+  metric: AlarmMetric;
+  entity_id: string;
+  comparator: AlarmComparator;
+  threshold: number;
+  severity: AlarmSeverity;
+  status: 'active' | 'cleared';
+  raised_value: number;
+  raised_at: string;
+  cleared_value?: number;
+  cleared_at?: string;
+  acknowledged: boolean;
+  acknowledged_at?: string;
+  acknowledged_by?: string;
+  ack_comment?: string;
+  created_at: string;
+  updated_at: string;
+}
+
 // Form Data types
 export interface ExperimentFormData {
   title: string;
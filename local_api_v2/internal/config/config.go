@@ -1,107 +1,504 @@
 package config
 
 import (
-	"log"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
-// Config holds all configuration for the service
+// Config holds all configuration for the service.
+// Values are read from an optional YAML file and overridden by environment variables.
 type Config struct {
+	// ConfigFile is the YAML file the configuration was loaded from, empty when env only
+	ConfigFile string `yaml:"-"`
+
 	// Server configuration
-	Port    string
-	GinMode string
+	Port    string `yaml:"port"`
+	GinMode string `yaml:"gin_mode"`
 
 	// Home Assistant configuration
-	HomeAssistantURL   string
-	HomeAssistantToken string
+	HomeAssistantURL   string `yaml:"ha_url"`
+	HomeAssistantToken string `yaml:"ha_token"`
+	HAWebSocketEnabled bool   `yaml:"ha_websocket_enabled"` // mirror entity states over the WebSocket API instead of polling
+
+	// HomeAssistant lists the Home Assistant instances and the chamber suffixes each one owns.
+	// When empty a single instance is built from HA_URL, HA_TOKEN and CHAMBER_SUFFIXES.
+	HomeAssistant []HomeAssistantInstance `yaml:"home_assistant"`
+
+	// MQTT lists the brokers whose devices expose chambers directly, without Home Assistant
+	MQTT []MQTTInstance `yaml:"mqtt"`
+
+	// Modbus lists climate controllers driven over Modbus TCP, one chamber per controller
+	Modbus []ModbusInstance `yaml:"modbus"`
 
 	// MongoDB configuration
-	MongoDBURI      string
-	MongoDBDatabase string
+	MongoDBURI      string `yaml:"mongodb_uri"`
+	MongoDBDatabase string `yaml:"mongodb_database"`
 
 	// Backend API configuration
-	BackendURL    string
-	BackendAPIKey string
+	BackendURL    string `yaml:"backend_url"`
+	BackendAPIKey string `yaml:"backend_api_key"`
 
 	// Chamber configuration
-	ChamberName     string
-	LocalIP         string
-	ChamberSuffixes []string // Поддерживаемые суффиксы камер
+	ChamberName     string   `yaml:"chamber_name"`
+	LocalIP         string   `yaml:"local_ip"`
+	ChamberSuffixes []string `yaml:"chamber_suffixes"` // Поддерживаемые суффиксы камер
+
+	// Discovery configuration
+	DiscoveryRulesFile  string        `yaml:"discovery_rules_file"` // YAML rules replacing the built-in classification
+	RediscoveryInterval time.Duration `yaml:"rediscovery_interval"` // how often the entities of known chambers are discovered again
 
 	// Heartbeat configuration
-	HeartbeatInterval int
+	HeartbeatInterval int `yaml:"heartbeat_interval"` // seconds
 
 	// Local API version
-	LocalAPIversion int
+	LocalAPIversion int `yaml:"local_api_version"`
 
 	// NTP configuration
-	NTPLocation     string
-	NTPEnabled      bool
-	NTPServers      []string
-	NTPSyncInterval time.Duration
-	NTPTimeout      time.Duration
+	NTPLocation     string        `yaml:"ntp_location"`
+	NTPEnabled      bool          `yaml:"ntp_enabled"`
+	NTPServers      []string      `yaml:"ntp_servers"`
+	NTPSyncInterval time.Duration `yaml:"ntp_sync_interval"`
+	NTPTimeout      time.Duration `yaml:"ntp_timeout"`
+
+	// Telemetry configuration
+	TelemetryEnabled  bool          `yaml:"telemetry_enabled"`
+	TelemetryInterval time.Duration `yaml:"telemetry_interval"`
+
+	// Executor configuration
+	DriftReconciliation  bool          `yaml:"drift_reconciliation"`
+	ExecutorGapThreshold time.Duration `yaml:"executor_gap_threshold"` // downtime after which a restart reports an executor gap
+
+	// Outbox configuration
+	OutboxInterval time.Duration `yaml:"outbox_interval"`
+
+	// Setpoint audit configuration
+	AuditUploadInterval time.Duration `yaml:"audit_upload_interval"`
+
+	// Alarm configuration
+	AlarmInterval time.Duration `yaml:"alarm_interval"`
 
 	// Logging
-	LogLevel string
+	LogLevel  string `yaml:"log_level"`  // debug, info, warn or error, can be changed at runtime
+	LogFormat string `yaml:"log_format"` // text or json
+
+	// Config file watching
+	ConfigWatchInterval time.Duration `yaml:"config_watch_interval"`
+}
+
+// HomeAssistantInstance configures one Home Assistant connection
+type HomeAssistantInstance struct {
+	Name            string   `yaml:"name"`
+	URL             string   `yaml:"url"`
+	Token           string   `yaml:"token"`
+	ChamberSuffixes []string `yaml:"chamber_suffixes"`
+}
+
+// MQTTInstance configures one MQTT broker, see pkg/mqtt for the topic layout
+type MQTTInstance struct {
+	Name            string        `yaml:"name"`
+	Broker          string        `yaml:"broker"` // e.g. tcp://localhost:1883
+	ClientID        string        `yaml:"client_id"`
+	Username        string        `yaml:"username"`
+	Password        string        `yaml:"password"`
+	TopicPrefix     string        `yaml:"topic_prefix"`
+	ConfirmTimeout  time.Duration `yaml:"confirm_timeout"` // wait for the device to confirm a setpoint
+	ConfirmBudget   time.Duration `yaml:"confirm_budget"`  // total confirmation wait per minute
+	ChamberSuffixes []string      `yaml:"chamber_suffixes"`
+}
+
+// ModbusInstance configures one controller reached over Modbus TCP
+type ModbusInstance struct {
+	Name          string        `yaml:"name"`
+	Address       string        `yaml:"address"` // host:port, e.g. 192.168.1.50:502
+	UnitID        uint8         `yaml:"unit_id"`
+	RegisterMap   string        `yaml:"register_map"` // YAML register map of the controller model
+	Timeout       time.Duration `yaml:"timeout"`
+	ChamberSuffix string        `yaml:"chamber_suffix"`
 }
 
-// Load loads configuration from environment variables
+// DefaultHomeAssistantInstance names the instance built from HA_URL and HA_TOKEN
+const DefaultHomeAssistantInstance = "default"
+
+// Load loads configuration from the file named by CONFIG_FILE, if any, and environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
-	}
-
-	cfg := &Config{
-		Port:               getEnv("PORT", "8090"),
-		GinMode:            getEnv("GIN_MODE", "release"),
-		HomeAssistantURL:   getEnv("HA_URL", ""),
-		HomeAssistantToken: getEnv("HA_TOKEN", ""),
-		MongoDBURI:         getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-		MongoDBDatabase:    getEnv("MONGODB_DATABASE", "local_api_v2"),
-		BackendURL:         getEnv("BACKEND_URL", "http://localhost:8080/api"),
-		BackendAPIKey:      getEnv("BACKEND_API_KEY", ""),
-		ChamberName:        getEnv("CHAMBER_NAME", "Climate Chamber"),
-		LocalIP:            getEnv("LOCAL_IP", ""),
-		LocalAPIversion:    getEnvAsInt("LOCAL_API_VERSION", 1),
-		ChamberSuffixes:    parseChamberSuffixes(getEnv("CHAMBER_SUFFIXES", "room1,room2,room3,galo,sb4,oreol,sb1")),
-		HeartbeatInterval:  getEnvAsInt("HEARTBEAT_INTERVAL", 30),
+		slog.Debug("No .env file found, using environment variables")
+	}
 
-		// NTP configuration
-		NTPEnabled:      getEnvAsBool("NTP_ENABLED", true),
-		NTPServers:      parseNTPServers(getEnv("NTP_SERVERS", "ru.pool.ntp.org,europe.pool.ntp.org,0.ru.pool.ntp.org,1.ru.pool.ntp.org,pool.ntp.org")),
-		NTPSyncInterval: getEnvAsDuration("NTP_SYNC_INTERVAL", "5m"),
-		NTPTimeout:      getEnvAsDuration("NTP_TIMEOUT", "5s"),
-		NTPLocation:     getEnv("NTP_LOCATION", "Europe/Moscow"),
+	return LoadFile(getEnv("CONFIG_FILE", ""))
+}
 
-		LogLevel: getEnv("LOG_LEVEL", "info"),
-	}
+// LoadFile loads configuration from a YAML file with environment variable overrides.
+// An empty path uses defaults and environment variables only.
+func LoadFile(path string) (*Config, error) {
+	cfg := defaults()
+	cfg.ConfigFile = path
 
-	// Validate required configuration
-	if cfg.HomeAssistantURL == "" {
-		log.Fatal("HA_URL is required")
-	}
-	if cfg.HomeAssistantToken == "" {
-		log.Fatal("HA_TOKEN is required")
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
 	}
 
+	cfg.applyEnv()
+	cfg.resolveInstances()
+
 	// Try to get local IP if not set
 	if cfg.LocalIP == "" {
 		cfg.LocalIP = getLocalIP()
 	}
 
-	log.Printf("Configured chamber suffixes: %v", cfg.ChamberSuffixes)
-	log.Printf("NTP configuration: enabled=%v, servers=%v, sync_interval=%v",
-		cfg.NTPEnabled, cfg.NTPServers, cfg.NTPSyncInterval)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
 
+// defaults returns the configuration used when neither the file nor the environment set a value
+func defaults() *Config {
+	return &Config{
+		Port:               "8090",
+		GinMode:            "release",
+		HAWebSocketEnabled: true,
+		MongoDBURI:         "mongodb://localhost:27017",
+		MongoDBDatabase:    "local_api_v2",
+		BackendURL:         "http://localhost:8080/api",
+		ChamberName:        "Climate Chamber",
+		LocalAPIversion:    1,
+		ChamberSuffixes:    parseChamberSuffixes("room1,room2,room3,galo,sb4,oreol,sb1"),
+		HeartbeatInterval:  30,
+
+		// NTP configuration
+		NTPEnabled:      true,
+		NTPServers:      parseNTPServers("ru.pool.ntp.org,europe.pool.ntp.org,0.ru.pool.ntp.org,1.ru.pool.ntp.org,pool.ntp.org"),
+		NTPSyncInterval: 5 * time.Minute,
+		NTPTimeout:      5 * time.Second,
+		NTPLocation:     "Europe/Moscow",
+
+		// Telemetry configuration
+		TelemetryEnabled:  true,
+		TelemetryInterval: time.Minute,
+
+		// Discovery configuration
+		RediscoveryInterval: time.Hour,
+
+		// Executor configuration
+		DriftReconciliation:  true,
+		ExecutorGapThreshold: 5 * time.Minute,
+
+		// Outbox configuration
+		OutboxInterval: 15 * time.Second,
+
+		// Setpoint audit configuration
+		AuditUploadInterval: 5 * time.Minute,
+
+		// Alarm configuration
+		AlarmInterval: 30 * time.Second,
+
+		LogLevel:  "info",
+		LogFormat: "text",
+
+		ConfigWatchInterval: 10 * time.Second,
+	}
+}
+
+// applyEnv overrides the loaded values with the environment variables that are set
+func (cfg *Config) applyEnv() {
+	cfg.Port = getEnv("PORT", cfg.Port)
+	cfg.GinMode = getEnv("GIN_MODE", cfg.GinMode)
+	cfg.HomeAssistantURL = getEnv("HA_URL", cfg.HomeAssistantURL)
+	cfg.HomeAssistantToken = getEnv("HA_TOKEN", cfg.HomeAssistantToken)
+	cfg.HAWebSocketEnabled = getEnvAsBool("HA_WEBSOCKET_ENABLED", cfg.HAWebSocketEnabled)
+	cfg.MongoDBURI = getEnv("MONGODB_URI", cfg.MongoDBURI)
+	cfg.MongoDBDatabase = getEnv("MONGODB_DATABASE", cfg.MongoDBDatabase)
+	cfg.BackendURL = getEnv("BACKEND_URL", cfg.BackendURL)
+	cfg.BackendAPIKey = getEnv("BACKEND_API_KEY", cfg.BackendAPIKey)
+	cfg.ChamberName = getEnv("CHAMBER_NAME", cfg.ChamberName)
+	cfg.LocalIP = getEnv("LOCAL_IP", cfg.LocalIP)
+	cfg.LocalAPIversion = getEnvAsInt("LOCAL_API_VERSION", cfg.LocalAPIversion)
+	if value := os.Getenv("CHAMBER_SUFFIXES"); value != "" {
+		cfg.ChamberSuffixes = parseChamberSuffixes(value)
+	}
+	cfg.HeartbeatInterval = getEnvAsInt("HEARTBEAT_INTERVAL", cfg.HeartbeatInterval)
+
+	// NTP configuration
+	cfg.NTPEnabled = getEnvAsBool("NTP_ENABLED", cfg.NTPEnabled)
+	if value := os.Getenv("NTP_SERVERS"); value != "" {
+		cfg.NTPServers = parseNTPServers(value)
+	}
+	cfg.NTPSyncInterval = getEnvAsDuration("NTP_SYNC_INTERVAL", cfg.NTPSyncInterval)
+	cfg.NTPTimeout = getEnvAsDuration("NTP_TIMEOUT", cfg.NTPTimeout)
+	cfg.NTPLocation = getEnv("NTP_LOCATION", cfg.NTPLocation)
+
+	// Telemetry configuration
+	cfg.TelemetryEnabled = getEnvAsBool("TELEMETRY_ENABLED", cfg.TelemetryEnabled)
+	cfg.TelemetryInterval = getEnvAsDuration("TELEMETRY_INTERVAL", cfg.TelemetryInterval)
+
+	// Discovery configuration
+	cfg.DiscoveryRulesFile = getEnv("DISCOVERY_RULES_FILE", cfg.DiscoveryRulesFile)
+	cfg.RediscoveryInterval = getEnvAsDuration("REDISCOVERY_INTERVAL", cfg.RediscoveryInterval)
+
+	// Executor configuration
+	cfg.DriftReconciliation = getEnvAsBool("DRIFT_RECONCILIATION", cfg.DriftReconciliation)
+	cfg.ExecutorGapThreshold = getEnvAsDuration("EXECUTOR_GAP_THRESHOLD", cfg.ExecutorGapThreshold)
+
+	// Outbox configuration
+	cfg.OutboxInterval = getEnvAsDuration("OUTBOX_INTERVAL", cfg.OutboxInterval)
+
+	// Setpoint audit configuration
+	cfg.AuditUploadInterval = getEnvAsDuration("AUDIT_UPLOAD_INTERVAL", cfg.AuditUploadInterval)
+
+	// Alarm configuration
+	cfg.AlarmInterval = getEnvAsDuration("ALARM_INTERVAL", cfg.AlarmInterval)
+
+	cfg.LogLevel = getEnv("LOG_LEVEL", cfg.LogLevel)
+	cfg.LogFormat = getEnv("LOG_FORMAT", cfg.LogFormat)
+
+	cfg.ConfigWatchInterval = getEnvAsDuration("CONFIG_WATCH_INTERVAL", cfg.ConfigWatchInterval)
+}
+
+// resolveInstances builds the default Home Assistant instance from HA_URL and HA_TOKEN when no
+// instances are listed, owning the CHAMBER_SUFFIXES no MQTT broker claims.
+// When instances are configured ChamberSuffixes is derived from them.
+func (cfg *Config) resolveInstances() {
+	claimed := make(map[string]bool)
+	for i := range cfg.MQTT {
+		instance := &cfg.MQTT[i]
+		if instance.Name == "" {
+			instance.Name = fmt.Sprintf("mqtt%d", i+1)
+		}
+		for _, suffix := range instance.ChamberSuffixes {
+			claimed[strings.ToLower(suffix)] = true
+		}
+	}
+	for i := range cfg.Modbus {
+		instance := &cfg.Modbus[i]
+		if instance.Name == "" {
+			instance.Name = fmt.Sprintf("modbus%d", i+1)
+		}
+		claimed[strings.ToLower(instance.ChamberSuffix)] = true
+	}
+
+	if len(cfg.HomeAssistant) == 0 && (cfg.HomeAssistantURL != "" || cfg.HomeAssistantToken != "") {
+		var suffixes []string
+		for _, suffix := range cfg.ChamberSuffixes {
+			if !claimed[strings.ToLower(suffix)] {
+				suffixes = append(suffixes, suffix)
+			}
+		}
+		if len(suffixes) > 0 || len(claimed) == 0 {
+			cfg.HomeAssistant = []HomeAssistantInstance{{
+				Name:            DefaultHomeAssistantInstance,
+				URL:             cfg.HomeAssistantURL,
+				Token:           cfg.HomeAssistantToken,
+				ChamberSuffixes: suffixes,
+			}}
+		}
+	}
+	if len(cfg.HomeAssistant) == 0 && len(cfg.MQTT) == 0 && len(cfg.Modbus) == 0 {
+		return
+	}
+
+	var suffixes []string
+	for i := range cfg.HomeAssistant {
+		instance := &cfg.HomeAssistant[i]
+		if instance.Name == "" {
+			instance.Name = fmt.Sprintf("ha%d", i+1)
+		}
+		suffixes = append(suffixes, instance.ChamberSuffixes...)
+	}
+	for _, instance := range cfg.MQTT {
+		suffixes = append(suffixes, instance.ChamberSuffixes...)
+	}
+	for _, instance := range cfg.Modbus {
+		suffixes = append(suffixes, instance.ChamberSuffix)
+	}
+	cfg.ChamberSuffixes = suffixes
+}
+
+// HomeAssistantInstance returns the instance with the given name, or nil
+func (cfg *Config) HomeAssistantInstance(name string) *HomeAssistantInstance {
+	for i := range cfg.HomeAssistant {
+		if cfg.HomeAssistant[i].Name == name {
+			return &cfg.HomeAssistant[i]
+		}
+	}
+	return nil
+}
+
+// MQTTInstance returns the MQTT broker with the given name, or nil
+func (cfg *Config) MQTTInstance(name string) *MQTTInstance {
+	for i := range cfg.MQTT {
+		if cfg.MQTT[i].Name == name {
+			return &cfg.MQTT[i]
+		}
+	}
+	return nil
+}
+
+// ModbusInstance returns the Modbus controller with the given name, or nil
+func (cfg *Config) ModbusInstance(name string) *ModbusInstance {
+	for i := range cfg.Modbus {
+		if cfg.Modbus[i].Name == name {
+			return &cfg.Modbus[i]
+		}
+	}
+	return nil
+}
+
+// InstanceSuffixes returns the chamber suffixes of the Home Assistant and MQTT instances by name.
+// Comparing them between two configurations finds suffixes that moved between instances, which
+// the flattened ChamberSuffixes does not show.
+func (cfg *Config) InstanceSuffixes() map[string][]string {
+	suffixes := make(map[string][]string, len(cfg.HomeAssistant)+len(cfg.MQTT))
+	for _, instance := range cfg.HomeAssistant {
+		suffixes[instance.Name] = instance.ChamberSuffixes
+	}
+	for _, instance := range cfg.MQTT {
+		suffixes[instance.Name] = instance.ChamberSuffixes
+	}
+	return suffixes
+}
+
+// Validate checks that the configuration is complete and consistent
+func (cfg *Config) Validate() error {
+	if len(cfg.HomeAssistant) == 0 && len(cfg.MQTT) == 0 && len(cfg.Modbus) == 0 {
+		if cfg.HomeAssistantURL == "" {
+			return fmt.Errorf("HA_URL is required")
+		}
+		if cfg.HomeAssistantToken == "" {
+			return fmt.Errorf("HA_TOKEN is required")
+		}
+	}
+	names := make(map[string]bool)
+	for i, instance := range cfg.HomeAssistant {
+		if instance.URL == "" {
+			return fmt.Errorf("home_assistant[%d]: url is required", i)
+		}
+		if instance.Token == "" {
+			return fmt.Errorf("home_assistant[%d]: token is required", i)
+		}
+		if len(instance.ChamberSuffixes) == 0 {
+			return fmt.Errorf("home_assistant[%d]: at least one chamber suffix is required", i)
+		}
+		if names[instance.Name] {
+			return fmt.Errorf("duplicate Home Assistant instance name %q", instance.Name)
+		}
+		names[instance.Name] = true
+	}
+	for i, instance := range cfg.MQTT {
+		if instance.Broker == "" {
+			return fmt.Errorf("mqtt[%d]: broker is required", i)
+		}
+		if len(instance.ChamberSuffixes) == 0 {
+			return fmt.Errorf("mqtt[%d]: at least one chamber suffix is required", i)
+		}
+		if instance.ConfirmTimeout < 0 {
+			return fmt.Errorf("mqtt[%d]: confirm_timeout must not be negative", i)
+		}
+		if instance.ConfirmBudget < 0 {
+			return fmt.Errorf("mqtt[%d]: confirm_budget must not be negative", i)
+		}
+		if names[instance.Name] {
+			return fmt.Errorf("duplicate instance name %q", instance.Name)
+		}
+		names[instance.Name] = true
+	}
+	for i, instance := range cfg.Modbus {
+		if instance.Address == "" {
+			return fmt.Errorf("modbus[%d]: address is required", i)
+		}
+		if instance.RegisterMap == "" {
+			return fmt.Errorf("modbus[%d]: register_map is required", i)
+		}
+		if instance.ChamberSuffix == "" {
+			return fmt.Errorf("modbus[%d]: chamber_suffix is required", i)
+		}
+		if instance.Timeout < 0 {
+			return fmt.Errorf("modbus[%d]: timeout must not be negative", i)
+		}
+		if names[instance.Name] {
+			return fmt.Errorf("duplicate instance name %q", instance.Name)
+		}
+		names[instance.Name] = true
+	}
+	if _, err := strconv.Atoi(cfg.Port); err != nil {
+		return fmt.Errorf("invalid port %q", cfg.Port)
+	}
+
+	if len(cfg.ChamberSuffixes) == 0 {
+		return fmt.Errorf("at least one chamber suffix is required")
+	}
+	seen := make(map[string]bool)
+	for _, suffix := range cfg.ChamberSuffixes {
+		if suffix == "" {
+			return fmt.Errorf("chamber suffixes must not be empty")
+		}
+		if seen[strings.ToLower(suffix)] {
+			return fmt.Errorf("duplicate chamber suffix %q", suffix)
+		}
+		seen[strings.ToLower(suffix)] = true
+	}
+
+	if cfg.HeartbeatInterval <= 0 {
+		return fmt.Errorf("heartbeat interval must be positive")
+	}
+	if _, err := time.LoadLocation(cfg.NTPLocation); err != nil {
+		return fmt.Errorf("invalid NTP location %q: %v", cfg.NTPLocation, err)
+	}
+	if cfg.NTPEnabled && len(cfg.NTPServers) == 0 {
+		return fmt.Errorf("at least one NTP server is required when NTP is enabled")
+	}
+
+	intervals := map[string]time.Duration{
+		"ntp_sync_interval":      cfg.NTPSyncInterval,
+		"ntp_timeout":            cfg.NTPTimeout,
+		"telemetry_interval":     cfg.TelemetryInterval,
+		"outbox_interval":        cfg.OutboxInterval,
+		"audit_upload_interval":  cfg.AuditUploadInterval,
+		"alarm_interval":         cfg.AlarmInterval,
+		"rediscovery_interval":   cfg.RediscoveryInterval,
+		"executor_gap_threshold": cfg.ExecutorGapThreshold,
+		"config_watch_interval":  cfg.ConfigWatchInterval,
+	}
+	for name, interval := range intervals {
+		if interval <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+
+	switch strings.ToLower(cfg.LogLevel) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return fmt.Errorf("invalid log level %q", cfg.LogLevel)
+	}
+	switch strings.ToLower(cfg.LogFormat) {
+	case "text", "json":
+	default:
+		return fmt.Errorf("invalid log format %q", cfg.LogFormat)
+	}
+
+	return nil
+}
+
 // parseChamberSuffixes parses comma-separated chamber suffixes
 func parseChamberSuffixes(suffixesStr string) []string {
 	if suffixesStr == "" {
@@ -159,18 +556,13 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 }
 
 // getEnvAsDuration gets an environment variable as duration with a default value
-func getEnvAsDuration(key string, defaultValue string) time.Duration {
-	value := getEnv(key, defaultValue)
-	if duration, err := time.ParseDuration(value); err == nil {
-		return duration
-	}
-
-	// Fallback parsing for defaultValue
-	if duration, err := time.ParseDuration(defaultValue); err == nil {
-		return duration
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
 	}
-
-	return 5 * time.Minute // Ultimate fallback
+	return defaultValue
 }
 
 // getLocalIP attempts to get the local IP address
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets the environment variables that override the config file for the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"PORT", "GIN_MODE", "HA_URL", "HA_TOKEN", "HA_WEBSOCKET_ENABLED", "MONGODB_URI", "MONGODB_DATABASE",
+		"BACKEND_URL", "BACKEND_API_KEY", "CHAMBER_NAME", "LOCAL_IP", "LOCAL_API_VERSION", "CHAMBER_SUFFIXES",
+		"HEARTBEAT_INTERVAL", "NTP_ENABLED", "NTP_SERVERS", "NTP_SYNC_INTERVAL", "NTP_TIMEOUT", "NTP_LOCATION",
+		"TELEMETRY_ENABLED", "TELEMETRY_INTERVAL", "DISCOVERY_RULES_FILE", "REDISCOVERY_INTERVAL",
+		"DRIFT_RECONCILIATION", "EXECUTOR_GAP_THRESHOLD", "OUTBOX_INTERVAL", "AUDIT_UPLOAD_INTERVAL",
+		"ALARM_INTERVAL", "LOG_LEVEL", "LOG_FORMAT", "CONFIG_WATCH_INTERVAL",
+	} {
+		t.Setenv(key, "") // empty values are treated as unset
+	}
+}
+
+// writeConfig writes a YAML config file and returns its path
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, t.TempDir(), `
+home_assistant:
+  - name: main
+    url: http://ha.local:8123
+    token: secret
+    chamber_suffixes: [sb1, sb4]
+mqtt:
+  - broker: tcp://broker.local:1883
+    chamber_suffixes: [sb2]
+telemetry_interval: 2m
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.TelemetryInterval != 2*time.Minute {
+		t.Errorf("telemetry interval %v, want 2m", cfg.TelemetryInterval)
+	}
+	if cfg.AlarmInterval != 30*time.Second {
+		t.Errorf("alarm interval %v, want the default 30s", cfg.AlarmInterval)
+	}
+	if cfg.MQTT[0].Name != "mqtt1" {
+		t.Errorf("unnamed broker named %q, want mqtt1", cfg.MQTT[0].Name)
+	}
+	if want := []string{"sb1", "sb4", "sb2"}; !slices.Equal(cfg.ChamberSuffixes, want) {
+		t.Errorf("chamber suffixes %v, want %v", cfg.ChamberSuffixes, want)
+	}
+
+	// Environment variables override the file
+	t.Setenv("TELEMETRY_INTERVAL", "5m")
+	if cfg, err = LoadFile(path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.TelemetryInterval != 5*time.Minute {
+		t.Errorf("telemetry interval %v with TELEMETRY_INTERVAL set, want 5m", cfg.TelemetryInterval)
+	}
+}
+
+func TestLoadFileEnvOnly(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("HA_URL", "http://ha.local:8123")
+	t.Setenv("HA_TOKEN", "secret")
+	t.Setenv("CHAMBER_SUFFIXES", "sb1, sb4")
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(cfg.HomeAssistant) != 1 || cfg.HomeAssistant[0].Name != DefaultHomeAssistantInstance {
+		t.Fatalf("instances %+v, want the default instance", cfg.HomeAssistant)
+	}
+	if want := []string{"sb1", "sb4"}; !slices.Equal(cfg.HomeAssistant[0].ChamberSuffixes, want) {
+		t.Errorf("default instance owns %v, want %v", cfg.HomeAssistant[0].ChamberSuffixes, want)
+	}
+}
+
+func TestLoadFileValidation(t *testing.T) {
+	instance := `
+home_assistant:
+  - name: main
+    url: http://ha.local:8123
+    token: secret
+    chamber_suffixes: [sb1]
+`
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown field", instance + "telemetry_intervall: 1m\n", "field telemetry_intervall not found"},
+		{"no instances", "port: \"8090\"\n", "HA_URL is required"},
+		{"instance without url", "home_assistant:\n  - token: secret\n    chamber_suffixes: [sb1]\n", "home_assistant[0]: url is required"},
+		{"instance without suffixes", "home_assistant:\n  - url: http://ha\n    token: secret\n", "home_assistant[0]: at least one chamber suffix is required"},
+		{"duplicate instance names", instance + "mqtt:\n  - name: main\n    broker: tcp://b:1883\n    chamber_suffixes: [sb2]\n", `duplicate instance name "main"`},
+		{"suffix owned twice", instance + "mqtt:\n  - broker: tcp://b:1883\n    chamber_suffixes: [SB1]\n", `duplicate chamber suffix "SB1"`},
+		{"modbus without register map", instance + "modbus:\n  - address: 10.0.0.5:502\n    chamber_suffix: sb3\n", "modbus[0]: register_map is required"},
+		{"negative confirm timeout", instance + "mqtt:\n  - broker: tcp://b:1883\n    confirm_timeout: -1s\n    chamber_suffixes: [sb2]\n", "mqtt[0]: confirm_timeout must not be negative"},
+		{"invalid port", instance + "port: http\n", `invalid port "http"`},
+		{"invalid location", instance + "ntp_location: Mars/Olympus\n", "invalid NTP location"},
+		{"zero interval", instance + "outbox_interval: 0s\n", "outbox_interval must be positive"},
+		{"invalid log level", instance + "log_level: verbose\n", `invalid log level "verbose"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			_, err := LoadFile(writeConfig(t, t.TempDir(), tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadFile error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstanceSuffixesOnReload(t *testing.T) {
+	config := func(haSuffixes, mqttSuffixes string) string {
+		return `
+home_assistant:
+  - name: main
+    url: http://ha.local:8123
+    token: secret
+    chamber_suffixes: [` + haSuffixes + `]
+mqtt:
+  - name: broker
+    broker: tcp://broker.local:1883
+    chamber_suffixes: [` + mqttSuffixes + `]
+`
+	}
+
+	tests := []struct {
+		name        string
+		ha, mqtt    string
+		wantChanged bool
+		wantSameAll bool // all chamber suffixes together stay the same
+	}{
+		{name: "unchanged", ha: "sb1, sb4", mqtt: "sb2", wantSameAll: true},
+		{name: "suffix added", ha: "sb1, sb4, sb5", mqtt: "sb2", wantChanged: true},
+		{name: "suffix removed", ha: "sb1", mqtt: "sb2", wantChanged: true},
+		{name: "suffix moved between instances", ha: "sb1", mqtt: "sb4, sb2", wantChanged: true, wantSameAll: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			dir := t.TempDir()
+			current, err := LoadFile(writeConfig(t, dir, config("sb1, sb4", "sb2")))
+			if err != nil {
+				t.Fatalf("LoadFile: %v", err)
+			}
+			next, err := LoadFile(writeConfig(t, dir, config(tt.ha, tt.mqtt)))
+			if err != nil {
+				t.Fatalf("LoadFile: %v", err)
+			}
+
+			changed := !maps.EqualFunc(current.InstanceSuffixes(), next.InstanceSuffixes(), slices.Equal)
+			if changed != tt.wantChanged {
+				t.Errorf("instance suffixes changed = %v, want %v", changed, tt.wantChanged)
+			}
+			sameAll := slices.Equal(slices.Sorted(slices.Values(current.ChamberSuffixes)), slices.Sorted(slices.Values(next.ChamberSuffixes)))
+			if sameAll != tt.wantSameAll {
+				t.Errorf("chamber suffixes %v and %v, want same %v", current.ChamberSuffixes, next.ChamberSuffixes, tt.wantSameAll)
+			}
+		})
+	}
+}
+
+func TestWatchReloadsOnFileChange(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	valid := func(interval string) string {
+		return `
+home_assistant:
+  - url: http://ha.local:8123
+    token: secret
+    chamber_suffixes: [sb1]
+config_watch_interval: 10ms
+telemetry_interval: ` + interval + "\n"
+	}
+	path := writeConfig(t, dir, valid("1m"))
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloads := make(chan *Config, 10)
+	go Watch(ctx, cfg, func(next *Config) { reloads <- next })
+
+	// Modification times are moved explicitly, file systems may not resolve quick writes
+	touch := func(content string, offset time.Duration) {
+		writeConfig(t, dir, content)
+		modTime := time.Now().Add(offset)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+
+	// An invalid file keeps the previous configuration
+	touch(valid("1m")+"log_level: verbose\n", time.Hour)
+	select {
+	case next := <-reloads:
+		t.Fatalf("invalid file reloaded with telemetry interval %v", next.TelemetryInterval)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	touch(valid("3m"), 2*time.Hour)
+	select {
+	case next := <-reloads:
+		if next.TelemetryInterval != 3*time.Minute {
+			t.Errorf("reloaded telemetry interval %v, want 3m", next.TelemetryInterval)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("changed file not reloaded")
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Watch reloads the configuration on SIGHUP and whenever the config file changes,
+// calling onReload with each configuration that loads and validates.
+// An invalid file is logged and the previous configuration stays in effect.
+func Watch(ctx context.Context, current *Config, onReload func(*Config)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	modTime := fileModTime(current.ConfigFile)
+
+	// Without a file there is nothing to poll, but SIGHUP still re-reads the environment
+	var poll <-chan time.Time
+	if current.ConfigFile != "" {
+		ticker := time.NewTicker(current.ConfigWatchInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	reload := func(reason string) {
+		next, err := LoadFile(current.ConfigFile)
+		if err != nil {
+			slog.Error("Configuration reload failed, keeping previous configuration", "reason", reason, "error", err)
+			return
+		}
+		slog.Info("Configuration reloaded", "reason", reason, "file", current.ConfigFile)
+		current = next
+		onReload(next)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			modTime = fileModTime(current.ConfigFile)
+			reload("SIGHUP")
+		case <-poll:
+			if changed := fileModTime(current.ConfigFile); !changed.Equal(modTime) {
+				modTime = changed
+				reload("file changed")
+			}
+		}
+	}
+}
+
+// fileModTime returns the modification time of path, or the zero time when it cannot be read
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -3,19 +3,32 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MongoDB holds the MongoDB client and database references
 type MongoDB struct {
-	Client                *mongo.Client
-	Database              *mongo.Database
-	ChambersCollection    *mongo.Collection
-	ExperimentsCollection *mongo.Collection
+	Client                         *mongo.Client
+	Database                       *mongo.Database
+	ChambersCollection             *mongo.Collection
+	ExperimentsCollection          *mongo.Collection
+	TelemetryCollection            *mongo.Collection
+	DriftEventsCollection          *mongo.Collection
+	OverridesCollection            *mongo.Collection
+	OutboxCollection               *mongo.Collection
+	SetpointApplicationsCollection *mongo.Collection
+	AlarmRulesCollection           *mongo.Collection
+	AlarmEventsCollection          *mongo.Collection
+	DiscoveryRulesCollection       *mongo.Collection
+	DiscoveryReportsCollection     *mongo.Collection
+	ExecutorTicksCollection        *mongo.Collection
+	ExecutorGapsCollection         *mongo.Collection
 }
 
 // NewMongoDB creates a new MongoDB connection
@@ -31,14 +44,14 @@ func NewMongoDB(uri, dbName string) (*MongoDB, error) {
 		SetMaxConnIdleTime(30 * time.Second)
 
 	// Connect to MongoDB
-	log.Println("Connecting to MongoDB...")
+	slog.Info("Connecting to MongoDB")
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %v", err)
 	}
 
 	// Test the connection
-	log.Println("Testing MongoDB connection...")
+	slog.Debug("Testing MongoDB connection")
 	pingCtx, pingCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer pingCancel()
 
@@ -51,24 +64,119 @@ func NewMongoDB(uri, dbName string) (*MongoDB, error) {
 	database := client.Database(dbName)
 
 	db := &MongoDB{
-		Client:                client,
-		Database:              database,
-		ChambersCollection:    database.Collection("chambers"),
-		ExperimentsCollection: database.Collection("experiments"),
+		Client:                         client,
+		Database:                       database,
+		ChambersCollection:             database.Collection("chambers"),
+		ExperimentsCollection:          database.Collection("experiments"),
+		TelemetryCollection:            database.Collection("telemetry"),
+		DriftEventsCollection:          database.Collection("drift_events"),
+		OverridesCollection:            database.Collection("manual_overrides"),
+		OutboxCollection:               database.Collection("outbox"),
+		SetpointApplicationsCollection: database.Collection("setpoint_applications"),
+		AlarmRulesCollection:           database.Collection("alarm_rules"),
+		AlarmEventsCollection:          database.Collection("alarm_events"),
+		DiscoveryRulesCollection:       database.Collection("discovery_rules"),
+		DiscoveryReportsCollection:     database.Collection("discovery_reports"),
+		ExecutorTicksCollection:        database.Collection("executor_ticks"),
+		ExecutorGapsCollection:         database.Collection("executor_gaps"),
 	}
 
-	log.Printf("✅ Successfully connected to MongoDB database: %s", dbName)
+	if err := db.ensureTelemetryCollection(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare telemetry collection: %v", err)
+	}
+
+	if err := db.ensureOutboxIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare outbox collection: %v", err)
+	}
+
+	if err := db.ensureSetpointApplicationIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare setpoint applications collection: %v", err)
+	}
+
+	if err := db.ensureAlarmEventIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare alarm events collection: %v", err)
+	}
+
+	if err := db.ensureDiscoveryReportIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare discovery reports collection: %v", err)
+	}
+
+	if err := db.ensureExecutorGapIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to prepare executor gaps collection: %v", err)
+	}
+
+	slog.Info("Connected to MongoDB", "database", dbName)
 	return db, nil
 }
 
+// ensureTelemetryCollection creates the telemetry time-series collection if it does not exist yet
+func (db *MongoDB) ensureTelemetryCollection(ctx context.Context) error {
+	names, err := db.Database.ListCollectionNames(ctx, bson.M{"name": "telemetry"})
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return nil
+	}
+
+	opts := options.CreateCollection().SetTimeSeriesOptions(
+		options.TimeSeries().
+			SetTimeField("timestamp").
+			SetMetaField("meta").
+			SetGranularity("minutes"),
+	)
+
+	if err := db.Database.CreateCollection(ctx, "telemetry", opts); err != nil {
+		return err
+	}
+
+	slog.Info("Created telemetry time-series collection")
+	return nil
+}
+
+// ensureOutboxIndexes creates the indexes used for deduplication and delivery of outbox messages
+func (db *MongoDB) ensureOutboxIndexes(ctx context.Context) error {
+	_, err := db.OutboxCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "idempotency_key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
+	})
+	return err
+}
+
+// ensureSetpointApplicationIndexes creates the indexes used for audit queries and uploads
+func (db *MongoDB) ensureSetpointApplicationIndexes(ctx context.Context) error {
+	// Earlier versions recorded writes outside experiments, which are never uploaded
+	if _, err := db.SetpointApplicationsCollection.DeleteMany(ctx, bson.M{"experiment_id": primitive.NilObjectID}); err != nil {
+		return err
+	}
+
+	_, err := db.SetpointApplicationsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "chamber_id", Value: 1}, {Key: "applied_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "experiment_id", Value: 1}, {Key: "applied_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "synced", Value: 1}, {Key: "applied_at", Value: 1}},
+		},
+	})
+	return err
+}
+
 // Disconnect gracefully disconnects from MongoDB
 func (db *MongoDB) Disconnect(ctx context.Context) error {
-	log.Println("Disconnecting from MongoDB...")
+	slog.Info("Disconnecting from MongoDB")
 	err := db.Client.Disconnect(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to disconnect from MongoDB: %v", err)
 	}
-	log.Println("✅ Successfully disconnected from MongoDB")
+	slog.Info("Disconnected from MongoDB")
 	return nil
 }
 
@@ -76,3 +184,32 @@ func (db *MongoDB) Disconnect(ctx context.Context) error {
 func (db *MongoDB) HealthCheck(ctx context.Context) error {
 	return db.Client.Ping(ctx, nil)
 }
+
+// ensureAlarmEventIndexes creates the indexes used for alarm queries and restoring raised alarms
+func (db *MongoDB) ensureAlarmEventIndexes(ctx context.Context) error {
+	_, err := db.AlarmEventsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "chamber_id", Value: 1}, {Key: "occurred_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "cleared", Value: 1}},
+		},
+	})
+	return err
+}
+
+// ensureDiscoveryReportIndexes creates the index used to list the discovery reports of a chamber
+func (db *MongoDB) ensureDiscoveryReportIndexes(ctx context.Context) error {
+	_, err := db.DiscoveryReportsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "chamber_id", Value: 1}, {Key: "discovered_at", Value: -1}},
+	})
+	return err
+}
+
+// ensureExecutorGapIndexes creates the index used to list the gaps of an experiment
+func (db *MongoDB) ensureExecutorGapIndexes(ctx context.Context) error {
+	_, err := db.ExecutorGapsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "experiment_id", Value: 1}, {Key: "from", Value: -1}},
+	})
+	return err
+}
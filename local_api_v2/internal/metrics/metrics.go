@@ -0,0 +1,143 @@
+// Package metrics defines the Prometheus metrics exposed by the local API on /metrics
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"local_api_v2/pkg/ntp"
+)
+
+const namespace = "local_api"
+
+var (
+	// HARequestDuration observes the latency of Home Assistant REST calls
+	HARequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ha",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of Home Assistant REST API calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// HARequestErrors counts failed Home Assistant REST calls
+	HARequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ha",
+		Name:      "request_errors_total",
+		Help:      "Number of failed Home Assistant REST API calls.",
+	}, []string{"operation"})
+
+	// ExecutorTickDuration observes how long an executor run takes per chamber
+	ExecutorTickDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "executor",
+		Name:      "tick_duration_seconds",
+		Help:      "Duration of executor runs.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"chamber_id"})
+
+	// ExecutorTickErrors counts executor runs that returned an error
+	ExecutorTickErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "executor",
+		Name:      "tick_errors_total",
+		Help:      "Number of executor runs that failed.",
+	}, []string{"chamber_id"})
+
+	// SetpointLastApplied holds the value each entity held after the executor last wrote it
+	SetpointLastApplied = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "setpoint",
+		Name:      "last_applied_value",
+		Help:      "Value an entity held after the last executor write.",
+	}, []string{"chamber_id", "entity_id"})
+
+	// SetpointWrites counts executor writes by verification status
+	SetpointWrites = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "setpoint",
+		Name:      "writes_total",
+		Help:      "Number of executor writes by verification status.",
+	}, []string{"chamber_id", "status"})
+
+	// SyncRuns counts backend synchronizations per chamber, kind and result
+	SyncRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "runs_total",
+		Help:      "Number of backend synchronizations by chamber, kind and result.",
+	}, []string{"chamber_id", "kind", "result"})
+
+	// SyncLastSuccess holds the time of the last successful synchronization per chamber and kind
+	SyncLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful backend synchronization.",
+	}, []string{"chamber_id", "kind"})
+
+	// OutboxMessages holds the number of outbox messages per status
+	OutboxMessages = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "messages",
+		Help:      "Number of outbox messages by status.",
+	}, []string{"status"})
+)
+
+// ObserveHARequest records a Home Assistant REST call, it matches homeassistant.RequestObserver
+func ObserveHARequest(operation string, duration time.Duration, err error) {
+	HARequestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		HARequestErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveSync records the result of a backend synchronization of a chamber
+func ObserveSync(chamberID, kind string, err error, now time.Time) {
+	if err != nil {
+		SyncRuns.WithLabelValues(chamberID, kind, "failure").Inc()
+		return
+	}
+	SyncRuns.WithLabelValues(chamberID, kind, "success").Inc()
+	SyncLastSuccess.WithLabelValues(chamberID, kind).Set(float64(now.Unix()))
+}
+
+// RegisterTimeService exposes the NTP state, read at scrape time
+func RegisterTimeService(ts *ntp.TimeService) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ntp",
+		Name:      "offset_seconds",
+		Help:      "Clock offset measured at the last NTP synchronization.",
+	}, func() float64 {
+		return ts.GetOffset().Seconds()
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ntp",
+		Name:      "synced",
+		Help:      "Whether the time service is synchronized with an NTP server (1) or uses system time (0).",
+	}, func() float64 {
+		if ts.IsConnected() {
+			return 1
+		}
+		return 0
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ntp",
+		Name:      "enabled",
+		Help:      "Whether NTP synchronization is enabled.",
+	}, func() float64 {
+		if ts.IsEnabled() {
+			return 1
+		}
+		return 0
+	})
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AlarmComparator constants
+const (
+	AlarmAbove        = "gt"
+	AlarmAboveOrEqual = "gte"
+	AlarmBelow        = "lt"
+	AlarmBelowOrEqual = "lte"
+)
+
+// AlarmEventType constants
+const (
+	AlarmEventRaise = "raise"
+	AlarmEventClear = "clear"
+)
+
+// AlarmRule is the local copy of an alarm rule defined in the backend
+type AlarmRule struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BackendID        primitive.ObjectID `bson:"backend_id" json:"backend_id"`
+	ChamberID        primitive.ObjectID `bson:"chamber_id" json:"chamber_id"` // local chamber ID
+	BackendChamberID primitive.ObjectID `bson:"backend_chamber_id" json:"backend_chamber_id"`
+	Name             string             `bson:"name" json:"name"`
+	Metric           string             `bson:"metric" json:"metric"`
+	EntityID         string             `bson:"entity_id,omitempty" json:"entity_id,omitempty"`
+	Comparator       string             `bson:"comparator" json:"comparator"`
+	Threshold        float64            `bson:"threshold" json:"threshold"`
+	Duration         int                `bson:"duration" json:"duration"` // seconds
+	Severity         string             `bson:"severity" json:"severity"`
+	Enabled          bool               `bson:"enabled" json:"enabled"`
+	SyncedAt         time.Time          `bson:"synced_at" json:"synced_at"`
+}
+
+// Violated reports whether a measured value breaks the rule
+func (r *AlarmRule) Violated(value float64) bool {
+	switch r.Comparator {
+	case AlarmAbove:
+		return value > r.Threshold
+	case AlarmAboveOrEqual:
+		return value >= r.Threshold
+	case AlarmBelow:
+		return value < r.Threshold
+	case AlarmBelowOrEqual:
+		return value <= r.Threshold
+	}
+	return false
+}
+
+// AlarmEvent records an alarm being raised or cleared for a sensor
+type AlarmEvent struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AlarmID          primitive.ObjectID `bson:"alarm_id" json:"alarm_id"` // ID of the raise event, shared by its clear event
+	Type             string             `bson:"type" json:"type"`
+	RuleID           primitive.ObjectID `bson:"rule_id" json:"rule_id"` // backend rule ID
+	ChamberID        primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	BackendChamberID primitive.ObjectID `bson:"backend_chamber_id" json:"backend_chamber_id"`
+	RuleName         string             `bson:"rule_name" json:"rule_name"`
+	Metric           string             `bson:"metric" json:"metric"`
+	EntityID         string             `bson:"entity_id" json:"entity_id"`
+	Comparator       string             `bson:"comparator" json:"comparator"`
+	Threshold        float64            `bson:"threshold" json:"threshold"`
+	Severity         string             `bson:"severity" json:"severity"`
+	Value            float64            `bson:"value" json:"value"`
+	OccurredAt       time.Time          `bson:"occurred_at" json:"occurred_at"`
+	Cleared          bool               `bson:"cleared,omitempty" json:"cleared,omitempty"` // set on raise events once the alarm is cleared
+}
@@ -20,10 +20,11 @@ type Chamber struct {
 	Name               string             `bson:"name" json:"name"`
 	Suffix             string             `bson:"suffix" json:"suffix"` // e.g., "galo", "sb4", "default"
 	LocalAPIversion    int                `bson:"local_api_version" json:"local_api_version"`
-	TimeOffset         int                `bson:"time_offset" json:"time_offset"`
+	TimeOffset         int                `bson:"time_offset" json:"time_offset"` // current UTC offset in minutes of NTP_LOCATION, informational: schedule days follow the location itself
 	BackendID          primitive.ObjectID `bson:"backend_id,omitempty" json:"backend_id,omitempty"`
 	LocalIP            string             `bson:"local_ip" json:"local_ip"`
 	HomeAssistantURL   string             `bson:"ha_url" json:"ha_url"`
+	HomeAssistantName  string             `bson:"ha_instance,omitempty" json:"ha_instance,omitempty"` // config name of the owning Home Assistant instance
 	Status             ChamberStatus      `bson:"status" json:"status"`
 	LastHeartbeat      time.Time          `bson:"last_heartbeat" json:"last_heartbeat"`
 	DiscoveryCompleted bool               `bson:"discovery_completed" json:"discovery_completed"`
@@ -37,6 +38,8 @@ type ChamberConfig struct {
 	ID                   primitive.ObjectID                `bson:"_id,omitempty" json:"id"`
 	ChamberID            primitive.ObjectID                `bson:"chamber_id" json:"chamber_id"`
 	Lamps                map[string]InputNumber            `bson:"lamps" json:"lamps"`
+	Switches             map[string]InputNumber            `bson:"switches" json:"switches"`       // switch.* entities, value 1 is on
+	Thermostats          map[string]InputNumber            `bson:"thermostats" json:"thermostats"` // climate.* entities, value is the target temperature
 	WateringZones        []WateringZone                    `bson:"watering_zones" json:"watering_zones"`
 	UnrecognisedEntities map[string]InputNumber            `bson:"unrecognised_entities" json:"unrecognised_entities"`
 	DayDuration          map[string]InputNumber            `bson:"day_duration" json:"day_duration"`
@@ -44,10 +47,24 @@ type ChamberConfig struct {
 	Temperature          map[string]map[string]InputNumber `bson:"temperature" json:"temperature"` // day/night -> entity_id -> value
 	Humidity             map[string]map[string]InputNumber `bson:"humidity" json:"humidity"`       // day/night -> entity_id -> value
 	CO2                  map[string]map[string]InputNumber `bson:"co2" json:"co2"`                 // day/night -> entity_id -> value
+	PauseProfile         *PauseProfile                     `bson:"pause_profile,omitempty" json:"pause_profile,omitempty"`
+	IdleProfile          map[string]float64                `bson:"idle_profile,omitempty" json:"idle_profile,omitempty"` // entity_id -> value applied while no experiment is active
 	UpdatedAt            time.Time                         `bson:"updated_at" json:"updated_at"`
 	SyncedAt             *time.Time                        `bson:"synced_at,omitempty" json:"synced_at,omitempty"`
 }
 
+// PauseProfile defines the setpoints a chamber holds while its experiment is paused
+type PauseProfile struct {
+	Mode   string             `bson:"mode" json:"mode"`                         // hold or safe
+	Values map[string]float64 `bson:"values,omitempty" json:"values,omitempty"` // entity_id -> value written in safe mode
+}
+
+// PauseMode constants
+const (
+	PauseModeHold = "hold" // keep the last applied setpoints
+	PauseModeSafe = "safe" // write the profile values, other entities keep their last setpoints
+)
+
 // InputNumber represents a Home Assistant input_number entity
 type InputNumber struct {
 	EntityID string  `bson:"entity_id" json:"entity_id"`
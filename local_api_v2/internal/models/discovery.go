@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Discovery rule targets besides the input number types
+const (
+	DiscoveryTargetLamp       = "lamp"
+	DiscoveryTargetSwitch     = "switch"
+	DiscoveryTargetThermostat = "thermostat"
+	DiscoveryTargetExclude    = "exclude" // drop the entity, it is not even reported as unrecognised
+)
+
+// Discovery rule match kinds
+const (
+	DiscoveryMatchRegex = "regex"
+	DiscoveryMatchGlob  = "glob"
+)
+
+// Discovery rule sources
+const (
+	DiscoveryRulesDefault = "default"
+	DiscoveryRulesFile    = "file"
+	DiscoveryRulesBackend = "backend"
+)
+
+// DiscoveryRule classifies controllable entities during discovery. Patterns match case-insensitively.
+// An entity matches when every pattern of the rule matches and Exclude matches neither its
+// entity_id nor its friendly name. Rules are tried by descending priority, the first match wins.
+type DiscoveryRule struct {
+	Name         string `bson:"name" json:"name" yaml:"name"`
+	Match        string `bson:"match,omitempty" json:"match,omitempty" yaml:"match"` // regex (default) or glob
+	EntityID     string `bson:"entity_id,omitempty" json:"entity_id,omitempty" yaml:"entity_id"`
+	FriendlyName string `bson:"friendly_name,omitempty" json:"friendly_name,omitempty" yaml:"friendly_name"`
+	Exclude      string `bson:"exclude,omitempty" json:"exclude,omitempty" yaml:"exclude"`
+	Target       string `bson:"target" json:"target" yaml:"target"` // input number type, lamp, switch, thermostat or exclude
+	// SuffixGroup names or numbers the capture group of the entity_id regex holding the chamber suffix.
+	// Without it the suffix is found by the configured chamber suffixes.
+	SuffixGroup string `bson:"suffix_group,omitempty" json:"suffix_group,omitempty" yaml:"suffix_group"`
+	Priority    int    `bson:"priority" json:"priority" yaml:"priority"`
+}
+
+// DiscoveryPreview shows how discovery classifies one entity
+type DiscoveryPreview struct {
+	Instance     string `json:"instance"`
+	EntityID     string `json:"entity_id"`
+	FriendlyName string `json:"friendly_name"`
+	Suffix       string `json:"suffix,omitempty"` // chamber the entity belongs to, empty when it is skipped
+	Target       string `json:"target"`
+	Rule         string `json:"rule,omitempty"` // empty when no rule matched
+	Zone         string `json:"zone,omitempty"` // watering zone of watering entities
+}
+
+// Discovery report triggers
+const (
+	DiscoveryTriggerScheduled = "scheduled"
+	DiscoveryTriggerManual    = "manual"
+)
+
+// Discovery change types
+const (
+	DiscoveryChangeAdded   = "added"
+	DiscoveryChangeRemoved = "removed"
+	DiscoveryChangeRetyped = "retyped" // the entity is now classified as another type
+)
+
+// DiscoveryReport records how a re-discovery changed the entities of a chamber
+type DiscoveryReport struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChamberID        primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	BackendChamberID primitive.ObjectID `bson:"backend_chamber_id,omitempty" json:"backend_chamber_id,omitempty"`
+	Suffix           string             `bson:"suffix" json:"suffix"`
+	Instance         string             `bson:"instance" json:"instance"`
+	Trigger          string             `bson:"trigger" json:"trigger"` // scheduled or manual
+	Changes          []DiscoveryChange  `bson:"changes" json:"changes"`
+	DiscoveredAt     time.Time          `bson:"discovered_at" json:"discovered_at"`
+}
+
+// DiscoveryChange is one entity added, removed or retyped by a re-discovery
+type DiscoveryChange struct {
+	Type         string `bson:"type" json:"type"`
+	EntityID     string `bson:"entity_id" json:"entity_id"`
+	Name         string `bson:"name" json:"name"`
+	PreviousType string `bson:"previous_type,omitempty" json:"previous_type,omitempty"` // input number type, lamp, switch, thermostat or watering type
+	NewType      string `bson:"new_type,omitempty" json:"new_type,omitempty"`
+	// ReferencedBy lists the active experiments whose phases still use a removed entity
+	ReferencedBy []DiscoveryReference `bson:"referenced_by,omitempty" json:"referenced_by,omitempty"`
+}
+
+// DiscoveryReference is an experiment using an entity in some of its phases
+type DiscoveryReference struct {
+	ExperimentID primitive.ObjectID `bson:"experiment_id" json:"experiment_id"` // backend experiment ID
+	Title        string             `bson:"title" json:"title"`
+	Phases       []int              `bson:"phases" json:"phases"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DriftAction constants describe how the executor handled a detected drift
+const (
+	DriftActionRestored   = "restored"   // the scheduled value was written back
+	DriftActionOverridden = "overridden" // a manual override is registered, the value was left alone
+)
+
+// DriftEvent represents a change of an entity value made outside of the executor
+// while an experiment phase was active
+type DriftEvent struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChamberID           primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	ExperimentID        primitive.ObjectID `bson:"experiment_id" json:"experiment_id"`
+	BackendExperimentID primitive.ObjectID `bson:"backend_experiment_id" json:"backend_experiment_id"`
+	PhaseIndex          int                `bson:"phase_index" json:"phase_index"`
+	Day                 int                `bson:"day" json:"day"`
+	EntityID            string             `bson:"entity_id" json:"entity_id"`
+	ExpectedValue       float64            `bson:"expected_value" json:"expected_value"`
+	ObservedValue       float64            `bson:"observed_value" json:"observed_value"`
+	ChangedAt           *time.Time         `bson:"changed_at,omitempty" json:"changed_at,omitempty"` // last_changed reported by Home Assistant
+	DetectedAt          time.Time          `bson:"detected_at" json:"detected_at"`
+	Action              string             `bson:"action" json:"action"`
+	Synced              bool               `bson:"synced" json:"synced"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"local_api_v2/pkg/ntp"
@@ -23,6 +24,7 @@ type Experiment struct {
 	TotalDuration    int                `bson:"total_duration" json:"total_duration"`
 	Schedule         []ScheduleItem     `bson:"schedule" json:"schedule"`
 	ActivePhaseIndex *int               `bson:"active_phase_index,omitempty" json:"active_phase_index,omitempty"`
+	Pauses           []PauseRecord      `bson:"pauses,omitempty" json:"pauses,omitempty"`
 	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
 	SyncedAt         time.Time          `bson:"synced_at" json:"synced_at"`
@@ -41,8 +43,10 @@ type Phase struct {
 	HumidityNightSchedule    map[string]ScheduleConfig       `bson:"humidity_night_schedule,omitempty" json:"humidity_night_schedule,omitempty"`
 	CO2DaySchedule           map[string]ScheduleConfig       `bson:"co2_day_schedule,omitempty" json:"co2_day_schedule,omitempty"`
 	CO2NightSchedule         map[string]ScheduleConfig       `bson:"co2_night_schedule,omitempty" json:"co2_night_schedule,omitempty"`
-	LightIntensitySchedule   map[string]ScheduleConfig       `bson:"light_intensity_schedule,omitempty" json:"light_intensity_schedule,omitempty"`
+	LightIntensitySchedule   map[string]ScheduleConfig       `bson:"light_intensity_schedule,omitempty" json:"light_intensity_schedule,omitempty"` // light.* entities take the brightness in percent, 0 turns them off
+	SwitchSchedule           map[string]ScheduleConfig       `bson:"switch_schedule,omitempty" json:"switch_schedule,omitempty"`                   // 1 turns the switch on, 0 off, always stepped
 	WateringZones            map[string]WateringZoneSchedule `bson:"watering_zones,omitempty" json:"watering_zones,omitempty"`
+	TimeOfDaySchedule        map[string]TimeOfDaySchedule    `bson:"time_of_day_schedule,omitempty" json:"time_of_day_schedule,omitempty"`
 }
 
 // StartDayConfig represents the configuration for start day values
@@ -66,10 +70,41 @@ type WateringZoneSchedule struct {
 
 // ScheduleConfig represents a schedule configuration for various parameters
 type ScheduleConfig struct {
-	EntityID string          `bson:"entity_id" json:"entity_id"`
-	Schedule map[int]float64 `bson:"schedule" json:"schedule"`
+	EntityID      string          `bson:"entity_id" json:"entity_id"`
+	Schedule      map[int]float64 `bson:"schedule" json:"schedule"`
+	Interpolation string          `bson:"interpolation,omitempty" json:"interpolation,omitempty"` // step (default), linear or sigmoid
 }
 
+// TimeOfDaySchedule represents an intra-day program of an entity that repeats every day of the phase.
+// It takes precedence over daily schedules of the same entity.
+type TimeOfDaySchedule struct {
+	EntityID      string           `bson:"entity_id" json:"entity_id"`
+	Breakpoints   []TimeBreakpoint `bson:"breakpoints" json:"breakpoints"`
+	Interpolation string           `bson:"interpolation,omitempty" json:"interpolation,omitempty"` // step (default), linear or sigmoid
+}
+
+// TimeBreakpoint represents the value of an entity from a time of day on
+type TimeBreakpoint struct {
+	Time  string  `bson:"time" json:"time"` // HH:MM in the chamber's local time
+	Value float64 `bson:"value" json:"value"`
+}
+
+// Minutes returns the breakpoint time as minutes since midnight
+func (b TimeBreakpoint) Minutes() (int, error) {
+	parsed, err := time.Parse("15:04", b.Time)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", b.Time)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Interpolation modes between consecutive schedule values
+const (
+	InterpolationStep    = "step"    // value changes at the day boundary or breakpoint
+	InterpolationLinear  = "linear"  // value ramps linearly towards the next value
+	InterpolationSigmoid = "sigmoid" // value follows an S-curve towards the next value
+)
+
 // PhaseInputNumber represents input number configuration for a phase
 type PhaseInputNumber struct {
 	ID        string    `bson:"id" json:"id"`
@@ -93,6 +128,13 @@ type LightIntensity struct {
 	Enabled   bool    `bson:"enabled" json:"enabled"`
 }
 
+// PauseRecord represents an interval in which an experiment was paused
+type PauseRecord struct {
+	PausedAt      time.Time  `bson:"paused_at" json:"paused_at"`
+	ResumedAt     *time.Time `bson:"resumed_at,omitempty" json:"resumed_at,omitempty"`
+	ScheduleShift int64      `bson:"schedule_shift,omitempty" json:"schedule_shift,omitempty"` // seconds the remaining schedule was moved on resume
+}
+
 // ScheduleItem represents a scheduled phase with dates
 type ScheduleItem struct {
 	PhaseIndex     int   `bson:"phase_index" json:"phase_index"`
@@ -137,3 +179,48 @@ func (e *Experiment) GetCurrentPhase(ntpService *ntp.TimeService) (*Phase, int,
 
 	return nil, -1, nil
 }
+
+// EntityIDs returns the entities the phase writes, in no particular order
+func (p *Phase) EntityIDs() []string {
+	seen := make(map[string]bool)
+	add := func(entityID string) {
+		if entityID != "" {
+			seen[entityID] = true
+		}
+	}
+
+	for _, config := range p.StartDay {
+		add(config.EntityID)
+	}
+	schedules := []map[string]ScheduleConfig{
+		p.WorkDaySchedule,
+		p.TemperatureDaySchedule,
+		p.TemperatureNightSchedule,
+		p.HumidityDaySchedule,
+		p.HumidityNightSchedule,
+		p.CO2DaySchedule,
+		p.CO2NightSchedule,
+		p.LightIntensitySchedule,
+		p.SwitchSchedule,
+	}
+	for _, configs := range schedules {
+		for _, config := range configs {
+			add(config.EntityID)
+		}
+	}
+	for _, zone := range p.WateringZones {
+		add(zone.StartTimeEntityID)
+		add(zone.PeriodEntityID)
+		add(zone.PauseBetweenEntityID)
+		add(zone.DurationEntityID)
+	}
+	for _, program := range p.TimeOfDaySchedule {
+		add(program.EntityID)
+	}
+
+	entityIDs := make([]string, 0, len(seen))
+	for entityID := range seen {
+		entityIDs = append(entityIDs, entityID)
+	}
+	return entityIDs
+}
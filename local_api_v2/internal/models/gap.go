@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExecutorTick records the last time the executor ran an active or paused experiment
+type ExecutorTick struct {
+	ExperimentID primitive.ObjectID `bson:"_id" json:"experiment_id"`
+	ChamberID    primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	TickedAt     time.Time          `bson:"ticked_at" json:"ticked_at"`
+}
+
+// ExecutorGap is a period in which the executor did not run an active experiment,
+// e.g. while the local API was down
+type ExecutorGap struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChamberID           primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	ExperimentID        primitive.ObjectID `bson:"experiment_id" json:"experiment_id"`
+	BackendExperimentID primitive.ObjectID `bson:"backend_experiment_id,omitempty" json:"backend_experiment_id,omitempty"`
+	From                time.Time          `bson:"from" json:"from"` // last tick before the gap
+	To                  time.Time          `bson:"to" json:"to"`     // start of the executor that detected the gap
+	DurationSeconds     int64              `bson:"duration_seconds" json:"duration_seconds"`
+	// SkippedDays lists the schedule days that started and ended within the gap
+	SkippedDays []SkippedDay `bson:"skipped_days" json:"skipped_days"`
+	// MissedSetpoints lists the values the skipped days scheduled at their start
+	MissedSetpoints []MissedSetpoint `bson:"missed_setpoints" json:"missed_setpoints"`
+}
+
+// SkippedDay is a day of an experiment phase the executor never ran
+type SkippedDay struct {
+	PhaseIndex int `bson:"phase_index" json:"phase_index"`
+	Day        int `bson:"day" json:"day"`
+}
+
+// MissedSetpoint is a value scheduled for a skipped day that was never written
+type MissedSetpoint struct {
+	PhaseIndex int     `bson:"phase_index" json:"phase_index"`
+	Day        int     `bson:"day" json:"day"`
+	EntityID   string  `bson:"entity_id" json:"entity_id"`
+	Value      float64 `bson:"value" json:"value"`
+	Group      string  `bson:"group" json:"group"`
+}
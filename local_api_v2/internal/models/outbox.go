@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxStatus constants
+const (
+	OutboxPending   = "pending"   // waiting for (re)delivery
+	OutboxDelivered = "delivered" // accepted by the backend
+	OutboxFailed    = "failed"    // rejected by the backend, will not be retried automatically
+)
+
+// Outbox message kinds
+const (
+	OutboxKindExperimentStatus     = "experiment_status"
+	OutboxKindDriftEvent           = "drift_event"
+	OutboxKindHeartbeat            = "heartbeat"
+	OutboxKindSetpointApplications = "setpoint_applications"
+	OutboxKindAlarmEvent           = "alarm_event"
+	OutboxKindOverride             = "override"
+	OutboxKindDiscoveryReport      = "discovery_report"
+	OutboxKindExecutorGap          = "executor_gap"
+)
+
+// OutboxMessage represents an outbound backend call that is persisted until it is delivered
+type OutboxMessage struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	IdempotencyKey string             `bson:"idempotency_key" json:"idempotency_key"`               // sent as Idempotency-Key header, unique per message
+	CoalesceKey    string             `bson:"coalesce_key,omitempty" json:"coalesce_key,omitempty"` // pending messages with the same key are replaced by newer ones
+	Kind           string             `bson:"kind" json:"kind"`
+	Method         string             `bson:"method" json:"method"`
+	Path           string             `bson:"path" json:"path"` // relative to the backend URL
+	Payload        json.RawMessage    `bson:"payload" json:"payload"`
+	Headers        map[string]string  `bson:"headers,omitempty" json:"headers,omitempty"`
+	Status         string             `bson:"status" json:"status"`
+	Attempts       int                `bson:"attempts" json:"attempts"`
+	LastError      string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	LastStatusCode int                `bson:"last_status_code,omitempty" json:"last_status_code,omitempty"`
+	NextAttemptAt  time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	DeliveredAt    *time.Time         `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ManualOverride marks an entity as manually controlled, so the executor leaves its
+// value alone, or holds it at Value, until the override is removed or expires
+type ManualOverride struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BackendID primitive.ObjectID `bson:"backend_id,omitempty" json:"backend_id,omitempty"` // set for overrides synced from the backend
+	ChamberID primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	EntityID  string             `bson:"entity_id" json:"entity_id"`
+	Value     *float64           `bson:"value,omitempty" json:"value,omitempty"` // forced value, nil leaves the entity as it is
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// IsActive reports whether the override is in effect at the given time
+func (o *ManualOverride) IsActive(now time.Time) bool {
+	return o.ExpiresAt == nil || now.Before(*o.ExpiresAt)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// SetpointStatus constants describe the verified outcome of a setpoint write
+const (
+	SetpointApplied = "applied" // read-back value matches the requested value
+	SetpointClamped = "clamped" // HA stored the value adjusted to the entity step/min/max
+	SetpointFailed  = "failed"  // write failed, read-back failed or the value does not match
+)
+
+// SetpointResult represents the verified outcome of writing a value to an entity
+type SetpointResult struct {
+	EntityID       string    `bson:"entity_id" json:"entity_id"`
+	RequestedValue float64   `bson:"requested_value" json:"requested_value"`
+	ExpectedValue  float64   `bson:"expected_value" json:"expected_value"` // requested value normalized to step/min/max
+	ActualValue    *float64  `bson:"actual_value,omitempty" json:"actual_value,omitempty"`
+	Min            float64   `bson:"min" json:"min"`
+	Max            float64   `bson:"max" json:"max"`
+	Step           float64   `bson:"step" json:"step"`
+	Status         string    `bson:"status" json:"status"`
+	Error          string    `bson:"error,omitempty" json:"error,omitempty"`
+	Timestamp      time.Time `bson:"timestamp" json:"timestamp"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Time sources recorded with setpoint applications
+const (
+	TimeSourceNTP    = "ntp"
+	TimeSourceSystem = "system"
+)
+
+// SetpointApplication is an append-only audit record of a single value written
+// to Home Assistant by the executor, together with the verified result
+type SetpointApplication struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChamberID           primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	ExperimentID        primitive.ObjectID `bson:"experiment_id" json:"experiment_id"`
+	BackendExperimentID primitive.ObjectID `bson:"backend_experiment_id" json:"backend_experiment_id"`
+	PhaseIndex          int                `bson:"phase_index" json:"phase_index"`
+	Day                 int                `bson:"day" json:"day"`
+	EntityID            string             `bson:"entity_id" json:"entity_id"`
+	Group               string             `bson:"group" json:"group"` // schedule the value comes from, e.g. "temperature"
+	RequestedValue      float64            `bson:"requested_value" json:"requested_value"`
+	ExpectedValue       float64            `bson:"expected_value" json:"expected_value"`
+	ActualValue         *float64           `bson:"actual_value,omitempty" json:"actual_value,omitempty"`
+	Status              string             `bson:"status" json:"status"` // SetpointApplied, SetpointClamped or SetpointFailed
+	Error               string             `bson:"error,omitempty" json:"error,omitempty"`
+	TimeSource          string             `bson:"time_source" json:"time_source"`
+	AppliedAt           time.Time          `bson:"applied_at" json:"applied_at"`   // timestamp from the executor clock
+	SystemTime          time.Time          `bson:"system_time" json:"system_time"` // local system clock at the same moment
+	Synced              bool               `bson:"synced" json:"synced"`
+}
+
+// SetpointApplicationFilter selects setpoint applications for queries
+type SetpointApplicationFilter struct {
+	ChamberID    primitive.ObjectID
+	ExperimentID primitive.ObjectID // matches the local or the backend experiment ID
+	EntityID     string
+	Status       string
+	From         time.Time
+	To           time.Time
+	Limit        int64
+}
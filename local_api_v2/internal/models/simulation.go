@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// SimulationIssue types
+const (
+	SimulationMissingEntity   = "missing_entity"    // entity is written but does not exist in the chamber
+	SimulationValueClamped    = "value_clamped"     // value is outside the entity min/max or not on its step
+	SimulationNoActivePhase   = "no_active_phase"   // no phase is active between the schedule start and end
+	SimulationMissingDayValue = "missing_day_value" // a schedule has no value for a day the executor reaches
+	SimulationUnusedDayValue  = "unused_day_value"  // a schedule has a value for a day the executor never reaches
+)
+
+// SimulatedWrite represents a setpoint write of the executor during a simulation
+type SimulatedWrite struct {
+	Timestamp   time.Time `json:"timestamp"`
+	PhaseIndex  int       `json:"phase_index"`
+	Day         int       `json:"day"`
+	EntityID    string    `json:"entity_id"`
+	Value       float64   `json:"value"`
+	StoredValue *float64  `json:"stored_value,omitempty"` // value the simulated entity holds after the write
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// SimulationIssue represents a problem found while simulating an experiment
+type SimulationIssue struct {
+	Type       string     `json:"type"`
+	PhaseIndex *int       `json:"phase_index,omitempty"`
+	Day        *int       `json:"day,omitempty"`
+	EntityID   string     `json:"entity_id,omitempty"`
+	Timestamp  *time.Time `json:"timestamp,omitempty"`
+	Message    string     `json:"message"`
+}
+
+// SimulationResult represents the outcome of running an experiment against a simulated clock
+type SimulationResult struct {
+	From         time.Time         `json:"from"`
+	To           time.Time         `json:"to"`
+	Step         string            `json:"step"`
+	Ticks        int               `json:"ticks"`
+	TotalWrites  int               `json:"total_writes"`
+	FailedWrites int               `json:"failed_writes"`
+	Timeline     []SimulatedWrite  `json:"timeline"` // writes that change the value of an entity
+	Issues       []SimulationIssue `json:"issues"`
+}
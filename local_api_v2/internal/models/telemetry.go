@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TelemetrySample represents a single measured sensor value of a chamber.
+// Samples are stored in a time-series collection with Meta as the meta field.
+type TelemetrySample struct {
+	Timestamp time.Time     `bson:"timestamp" json:"timestamp"`
+	Meta      TelemetryMeta `bson:"meta" json:"meta"`
+	Value     float64       `bson:"value" json:"value"`
+}
+
+// TelemetryMeta identifies the series a telemetry sample belongs to
+type TelemetryMeta struct {
+	ChamberID primitive.ObjectID `bson:"chamber_id" json:"chamber_id"`
+	Metric    string             `bson:"metric" json:"metric"`
+	EntityID  string             `bson:"entity_id" json:"entity_id"`
+	Unit      string             `bson:"unit" json:"unit"`
+}
+
+// TelemetryMetric constants
+const (
+	MetricTemperature = "temperature"
+	MetricHumidity    = "humidity"
+	MetricCO2         = "co2"
+	MetricLight       = "light"
+)
+
+// TelemetryDeviceClasses maps Home Assistant sensor device classes to telemetry metrics
+var TelemetryDeviceClasses = map[string]string{
+	"temperature":    MetricTemperature,
+	"humidity":       MetricHumidity,
+	"carbon_dioxide": MetricCO2,
+	"illuminance":    MetricLight,
+}
+
+// TelemetrySensorSubstrings defines the substrings to search for each metric
+// when a sensor does not declare a known device class
+var TelemetrySensorSubstrings = map[string][]string{
+	MetricTemperature: {"temperature", "temp"},
+	MetricHumidity:    {"humidity", "hum"},
+	MetricCO2:         {"co2", "carbon_dioxide"},
+	MetricLight:       {"illuminance", "ppfd", "lux"},
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/device"
+	"local_api_v2/pkg/ntp"
+)
+
+// maxAlarmEventQuery limits the number of alarm events returned by a single query
+const maxAlarmEventQuery = 1000
+
+// alarmState tracks a single rule on a single sensor between evaluations
+type alarmState struct {
+	ruleID       primitive.ObjectID // backend rule ID
+	pendingSince time.Time          // when the condition started to hold, zero if it does not
+	raised       *models.AlarmEvent // recorded raise event of the active alarm, nil if not raised
+	unsent       *models.AlarmEvent // event that failed to be recorded, retried before evaluating again
+}
+
+// AlarmService evaluates the alarm rules synced from the backend against live sensor values
+type AlarmService struct {
+	config         *config.Config
+	db             *database.MongoDB
+	chamberManager *ChamberManager
+	ntpService     *ntp.TimeService
+	outbox         *OutboxService
+	interval       intervalUpdates
+
+	mu     sync.Mutex
+	states map[string]*alarmState // keyed by backend rule ID and entity ID
+}
+
+// NewAlarmService creates a new alarm service
+func NewAlarmService(cfg *config.Config, db *database.MongoDB, chamberManager *ChamberManager, ntpService *ntp.TimeService) *AlarmService {
+	return &AlarmService{
+		config:         cfg,
+		db:             db,
+		chamberManager: chamberManager,
+		ntpService:     ntpService,
+		states:         make(map[string]*alarmState),
+		interval:       newIntervalUpdates(),
+	}
+}
+
+// SetOutboxService sets the outbox used to report alarm events to the backend
+func (s *AlarmService) SetOutboxService(outbox *OutboxService) {
+	s.outbox = outbox
+}
+
+// StartEvaluation periodically evaluates the alarm rules until ctx is cancelled
+func (s *AlarmService) StartEvaluation(ctx context.Context) {
+	if err := s.restoreRaised(ctx); err != nil {
+		slog.Error("Failed to restore raised alarms", "error", err)
+	}
+
+	ticker := time.NewTicker(s.config.AlarmInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Alarm service stopped")
+			return
+		case interval := <-s.interval:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			if err := s.evaluate(ctx); err != nil {
+				slog.Error("Alarm evaluation failed", "error", err)
+			}
+		}
+	}
+}
+
+// SetInterval changes the evaluation period of a running alarm service
+func (s *AlarmService) SetInterval(interval time.Duration) {
+	s.interval.set(interval)
+}
+
+// restoreRaised loads alarms that were raised and not cleared before a restart
+func (s *AlarmService) restoreRaised(ctx context.Context) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.db.AlarmEventsCollection.Find(queryCtx, bson.M{
+		"type":    models.AlarmEventRaise,
+		"cleared": bson.M{"$ne": true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query raised alarms: %v", err)
+	}
+
+	var events []models.AlarmEvent
+	if err := cursor.All(queryCtx, &events); err != nil {
+		return fmt.Errorf("failed to decode raised alarms: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range events {
+		s.states[alarmStateKey(events[i].RuleID, events[i].EntityID)] = &alarmState{
+			ruleID: events[i].RuleID,
+			raised: &events[i],
+		}
+	}
+
+	if len(events) > 0 {
+		slog.Info("Restored raised alarms", "count", len(events))
+	}
+	return nil
+}
+
+// evaluate compares the current sensor values with all enabled rules
+func (s *AlarmService) evaluate(ctx context.Context) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.db.AlarmRulesCollection.Find(queryCtx, bson.M{"enabled": true})
+	if err != nil {
+		return fmt.Errorf("failed to query alarm rules: %v", err)
+	}
+
+	var rules []models.AlarmRule
+	if err := cursor.All(queryCtx, &rules); err != nil {
+		return fmt.Errorf("failed to decode alarm rules: %v", err)
+	}
+
+	sensors, err := s.chamberManager.GetSensors()
+	if err != nil {
+		return fmt.Errorf("failed to get sensors: %v", err)
+	}
+
+	now := s.ntpService.Now()
+	ruleIDs := make(map[primitive.ObjectID]bool, len(rules))
+	for _, rule := range rules {
+		ruleIDs[rule.BackendID] = true
+	}
+
+	var events []models.AlarmEvent
+	seen := make(map[string]bool)
+
+	s.mu.Lock()
+	for _, sensor := range sensors {
+		metric := classifySensor(sensor.Sensor)
+		if metric == "" {
+			continue
+		}
+
+		chamber := sensor.Chamber
+
+		for i := range rules {
+			rule := &rules[i]
+			if rule.ChamberID != chamber.ID || rule.Metric != metric {
+				continue
+			}
+			if rule.EntityID != "" && rule.EntityID != sensor.EntityID {
+				continue
+			}
+
+			seen[alarmStateKey(rule.BackendID, sensor.EntityID)] = true
+			if event := s.evaluateRule(rule, sensor.Sensor, now); event != nil {
+				events = append(events, *event)
+			}
+		}
+	}
+
+	events = append(events, s.clearRemoved(ruleIDs, seen, now)...)
+	s.mu.Unlock()
+
+	// The state of an alarm only changes once its event is recorded, failed events are
+	// retried on the next evaluation
+	for _, event := range events {
+		if err := s.record(ctx, event); err != nil {
+			slog.Error("Failed to record alarm event", "chamber_id", event.ChamberID.Hex(), "error", err)
+			continue
+		}
+		s.mu.Lock()
+		s.commit(event)
+		s.mu.Unlock()
+	}
+
+	return nil
+}
+
+// clearRemoved returns the clear events of alarms whose rule was deleted or disabled in the
+// backend. Sensors that did not report a value start their pending duration over.
+// s.mu must be held.
+func (s *AlarmService) clearRemoved(ruleIDs map[primitive.ObjectID]bool, seen map[string]bool, now time.Time) []models.AlarmEvent {
+	var events []models.AlarmEvent
+	for key, state := range s.states {
+		if ruleIDs[state.ruleID] {
+			if !seen[key] {
+				state.pendingSince = time.Time{}
+			}
+			continue
+		}
+		switch {
+		case state.unsent != nil:
+			events = append(events, *state.unsent)
+		case state.raised != nil:
+			event := newClearEvent(state.raised, state.raised.Value, now)
+			state.unsent = &event
+			events = append(events, event)
+		default:
+			delete(s.states, key)
+		}
+	}
+	return events
+}
+
+// commit applies a recorded event to the state of its alarm. s.mu must be held.
+func (s *AlarmService) commit(event models.AlarmEvent) {
+	key := alarmStateKey(event.RuleID, event.EntityID)
+	state, ok := s.states[key]
+	if !ok {
+		return
+	}
+
+	state.unsent = nil
+	if event.Type == models.AlarmEventClear {
+		delete(s.states, key)
+		return
+	}
+	state.raised = &event
+}
+
+// evaluateRule updates the state of a rule on a sensor and returns the event it produced, if any.
+// The event takes effect once it is recorded and passed to commit, until then it is returned again.
+// s.mu must be held.
+func (s *AlarmService) evaluateRule(rule *models.AlarmRule, sensor device.Sensor, now time.Time) *models.AlarmEvent {
+	key := alarmStateKey(rule.BackendID, sensor.EntityID)
+	state, ok := s.states[key]
+	if !ok {
+		state = &alarmState{ruleID: rule.BackendID}
+		s.states[key] = state
+	}
+
+	if state.unsent != nil {
+		return state.unsent
+	}
+
+	if !rule.Violated(sensor.Value) {
+		state.pendingSince = time.Time{}
+		if state.raised == nil {
+			delete(s.states, key)
+			return nil
+		}
+		event := newClearEvent(state.raised, sensor.Value, now)
+		state.unsent = &event
+		return state.unsent
+	}
+
+	if state.raised != nil {
+		return nil
+	}
+	if state.pendingSince.IsZero() {
+		state.pendingSince = now
+	}
+	if now.Sub(state.pendingSince) < time.Duration(rule.Duration)*time.Second {
+		return nil
+	}
+
+	id := primitive.NewObjectID()
+	state.unsent = &models.AlarmEvent{
+		ID:               id,
+		AlarmID:          id,
+		Type:             models.AlarmEventRaise,
+		RuleID:           rule.BackendID,
+		ChamberID:        rule.ChamberID,
+		BackendChamberID: rule.BackendChamberID,
+		RuleName:         rule.Name,
+		Metric:           rule.Metric,
+		EntityID:         sensor.EntityID,
+		Comparator:       rule.Comparator,
+		Threshold:        rule.Threshold,
+		Severity:         rule.Severity,
+		Value:            sensor.Value,
+		OccurredAt:       now,
+	}
+	return state.unsent
+}
+
+// newClearEvent creates the clear event of a raised alarm
+func newClearEvent(raised *models.AlarmEvent, value float64, now time.Time) models.AlarmEvent {
+	event := *raised
+	event.ID = primitive.NewObjectID()
+	event.Type = models.AlarmEventClear
+	event.Value = value
+	event.OccurredAt = now
+	event.Cleared = false
+	return event
+}
+
+// record stores an alarm event and queues it for the backend. Recording the same event again,
+// after a failure, neither stores nor queues it twice.
+func (s *AlarmService) record(ctx context.Context, event models.AlarmEvent) error {
+	storeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := s.db.AlarmEventsCollection.InsertOne(storeCtx, event); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to store alarm event: %v", err)
+	}
+
+	if event.Type == models.AlarmEventClear {
+		_, err := s.db.AlarmEventsCollection.UpdateByID(storeCtx, event.AlarmID, bson.M{"$set": bson.M{"cleared": true}})
+		if err != nil {
+			return fmt.Errorf("failed to mark alarm as cleared: %v", err)
+		}
+		slog.Info("Alarm cleared",
+			"chamber_id", event.ChamberID.Hex(),
+			"rule", event.RuleName,
+			"entity_id", event.EntityID,
+			"value", event.Value)
+	} else {
+		slog.Warn("Alarm raised",
+			"chamber_id", event.ChamberID.Hex(),
+			"rule", event.RuleName,
+			"entity_id", event.EntityID,
+			"value", event.Value,
+			"comparator", event.Comparator,
+			"threshold", event.Threshold)
+	}
+
+	if s.outbox == nil {
+		return fmt.Errorf("outbox not set")
+	}
+
+	return s.outbox.Enqueue(storeCtx, OutboxRequest{
+		Kind:   models.OutboxKindAlarmEvent,
+		Method: http.MethodPost,
+		Path:   "/alarms/events",
+		Payload: map[string]interface{}{
+			"alarm_id":    event.AlarmID.Hex(),
+			"type":        event.Type,
+			"rule_id":     event.RuleID.Hex(),
+			"chamber_id":  event.BackendChamberID.Hex(),
+			"rule_name":   event.RuleName,
+			"metric":      event.Metric,
+			"entity_id":   event.EntityID,
+			"comparator":  event.Comparator,
+			"threshold":   event.Threshold,
+			"severity":    event.Severity,
+			"value":       event.Value,
+			"occurred_at": event.OccurredAt,
+		},
+		IdempotencyKey: fmt.Sprintf("alarm_event:%s", event.ID.Hex()),
+	})
+}
+
+// GetEvents returns the alarm events of a chamber, most recent first.
+// With activeOnly set, only raise events of alarms that are not cleared yet are returned.
+func (s *AlarmService) GetEvents(ctx context.Context, chamberID primitive.ObjectID, activeOnly bool, limit int64) ([]models.AlarmEvent, error) {
+	query := bson.M{}
+	if !chamberID.IsZero() {
+		query["chamber_id"] = chamberID
+	}
+	if activeOnly {
+		query["type"] = models.AlarmEventRaise
+		query["cleared"] = bson.M{"$ne": true}
+	}
+
+	if limit <= 0 || limit > maxAlarmEventQuery {
+		limit = maxAlarmEventQuery
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "occurred_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.db.AlarmEventsCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alarm events: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.AlarmEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode alarm events: %v", err)
+	}
+
+	return events, nil
+}
+
+func alarmStateKey(ruleID primitive.ObjectID, entityID string) string {
+	return ruleID.Hex() + "/" + entityID
+}
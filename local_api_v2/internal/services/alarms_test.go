@@ -0,0 +1,122 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/device"
+)
+
+func TestEvaluateRule(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	rule := &models.AlarmRule{
+		BackendID:  primitive.NewObjectID(),
+		ChamberID:  primitive.NewObjectID(),
+		Name:       "Too hot",
+		Metric:     models.MetricTemperature,
+		Comparator: models.AlarmAbove,
+		Threshold:  30,
+		Duration:   60,
+	}
+
+	// Each step evaluates a value, the event it produces is recorded unless the step fails it
+	steps := []struct {
+		name      string
+		after     time.Duration
+		value     float64
+		fail      bool   // recording the event fails
+		wantEvent string // type of the returned event, empty for none
+		wantRaise bool   // an alarm is raised after the step
+	}{
+		{name: "below threshold", after: 0, value: 25},
+		{name: "violation starts pending", after: 10 * time.Second, value: 31},
+		{name: "within duration", after: 69 * time.Second, value: 32},
+		{name: "back to normal resets pending", after: 70 * time.Second, value: 29},
+		{name: "violation pending again", after: 80 * time.Second, value: 31},
+		{name: "duration reached but recording fails", after: 140 * time.Second, value: 33, fail: true, wantEvent: models.AlarmEventRaise},
+		{name: "raise retried", after: 150 * time.Second, value: 33, wantEvent: models.AlarmEventRaise, wantRaise: true},
+		{name: "still violated", after: 160 * time.Second, value: 35, wantRaise: true},
+		{name: "clear fails", after: 170 * time.Second, value: 20, fail: true, wantEvent: models.AlarmEventClear, wantRaise: true},
+		{name: "clear retried", after: 180 * time.Second, value: 35, wantEvent: models.AlarmEventClear},
+		{name: "violation after clear starts pending", after: 190 * time.Second, value: 35},
+	}
+
+	s := &AlarmService{states: make(map[string]*alarmState)}
+	var raised, failed *models.AlarmEvent
+	for _, step := range steps {
+		now := start.Add(step.after)
+		event := s.evaluateRule(rule, device.Sensor{EntityID: "sensor.temperature_1", Value: step.value}, now)
+
+		eventType := ""
+		if event != nil {
+			eventType = event.Type
+		}
+		if eventType != step.wantEvent {
+			t.Fatalf("%s: event %q, want %q", step.name, eventType, step.wantEvent)
+		}
+
+		if event != nil {
+			if failed != nil && event.ID != failed.ID {
+				t.Errorf("%s: retried event has ID %s, want %s", step.name, event.ID.Hex(), failed.ID.Hex())
+			}
+			failed = nil
+			if step.fail {
+				failed = event
+			} else {
+				s.commit(*event)
+			}
+			if event.Type == models.AlarmEventRaise {
+				raised = event
+			} else if event.AlarmID != raised.AlarmID {
+				t.Errorf("%s: clear event of alarm %s, want %s", step.name, event.AlarmID.Hex(), raised.AlarmID.Hex())
+			}
+		}
+
+		state := s.states[alarmStateKey(rule.BackendID, "sensor.temperature_1")]
+		if isRaised := state != nil && state.raised != nil; isRaised != step.wantRaise {
+			t.Fatalf("%s: raised = %v, want %v", step.name, isRaised, step.wantRaise)
+		}
+	}
+}
+
+func TestClearRemovedRules(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	kept := primitive.NewObjectID()
+	removed := primitive.NewObjectID()
+	raise := &models.AlarmEvent{AlarmID: primitive.NewObjectID(), Type: models.AlarmEventRaise, RuleID: removed, EntityID: "sensor.co2_1", Value: 1500}
+
+	s := &AlarmService{states: map[string]*alarmState{
+		alarmStateKey(kept, "sensor.temperature_1"): {ruleID: kept, pendingSince: now.Add(-time.Minute)},
+		alarmStateKey(kept, "sensor.temperature_2"): {ruleID: kept, pendingSince: now.Add(-time.Minute)},
+		alarmStateKey(removed, "sensor.co2_1"):      {ruleID: removed, raised: raise},
+		alarmStateKey(removed, "sensor.humidity_1"): {ruleID: removed, pendingSince: now.Add(-time.Minute)},
+	}}
+	ruleIDs := map[primitive.ObjectID]bool{kept: true}
+	seen := map[string]bool{alarmStateKey(kept, "sensor.temperature_1"): true}
+
+	events := s.clearRemoved(ruleIDs, seen, now)
+	if len(events) != 1 || events[0].Type != models.AlarmEventClear || events[0].AlarmID != raise.AlarmID {
+		t.Fatalf("clearRemoved returned %+v, want the clear event of the raised alarm", events)
+	}
+	if _, ok := s.states[alarmStateKey(removed, "sensor.humidity_1")]; ok {
+		t.Error("pending state of a removed rule is kept")
+	}
+	if state := s.states[alarmStateKey(kept, "sensor.temperature_1")]; state.pendingSince.IsZero() {
+		t.Error("pending duration of a reporting sensor was reset")
+	}
+	if state := s.states[alarmStateKey(kept, "sensor.temperature_2")]; !state.pendingSince.IsZero() {
+		t.Error("pending duration of a silent sensor was kept")
+	}
+
+	// The raised alarm stays until its clear event is recorded, a failed clear is sent again
+	if retried := s.clearRemoved(ruleIDs, seen, now.Add(time.Minute)); len(retried) != 1 || retried[0].ID != events[0].ID {
+		t.Fatalf("clearRemoved after a failed clear returned %+v, want %s again", retried, events[0].ID.Hex())
+	}
+	s.commit(events[0])
+	if _, ok := s.states[alarmStateKey(removed, "sensor.co2_1")]; ok {
+		t.Error("alarm of a removed rule is kept after its clear event was recorded")
+	}
+}
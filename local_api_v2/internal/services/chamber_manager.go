@@ -3,8 +3,9 @@ package services
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
+	"sync"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,59 +14,307 @@ import (
 	"local_api_v2/internal/config"
 	"local_api_v2/internal/database"
 	"local_api_v2/internal/models"
+	"local_api_v2/pkg/device"
 	"local_api_v2/pkg/ntp"
 )
 
+// DeviceInstance is one Home Assistant instance or MQTT broker with the discovery of the chambers it owns
+type DeviceInstance struct {
+	Name      string
+	Driver    device.Driver
+	Discovery *DiscoveryService // holds the chamber suffixes of this instance
+}
+
+// NewDeviceInstance creates an instance owning the chambers with the given suffixes
+func NewDeviceInstance(name string, driver device.Driver, suffixes []string) *DeviceInstance {
+	discovery := NewDiscoveryService(driver)
+	discovery.SetChamberSuffixes(suffixes)
+	return &DeviceInstance{
+		Name:      name,
+		Driver:    driver,
+		Discovery: discovery,
+	}
+}
+
 // ChamberManager manages multiple chambers based on room suffixes
 type ChamberManager struct {
 	config     *config.Config
 	db         *database.MongoDB
-	discovery  *DiscoveryService
+	instances  []*DeviceInstance
 	ntpService *ntp.TimeService
-	chambers   map[string]*models.Chamber // key is suffix
-}
 
-// NewChamberManager creates a new chamber manager
-func NewChamberManager(cfg *config.Config, db *database.MongoDB, discovery *DiscoveryService, ntpService *ntp.TimeService) *ChamberManager {
-	// Set chamber suffixes in discovery service
-	discovery.SetChamberSuffixes(cfg.ChamberSuffixes)
+	mu       sync.RWMutex
+	chambers map[string]*models.Chamber // key is suffix
+	owners   map[string]*DeviceInstance // key is suffix
+}
 
+// NewChamberManager creates a new chamber manager for the chambers of the given device instances
+func NewChamberManager(cfg *config.Config, db *database.MongoDB, instances []*DeviceInstance, ntpService *ntp.TimeService) *ChamberManager {
 	return &ChamberManager{
 		config:     cfg,
 		db:         db,
-		discovery:  discovery,
+		instances:  instances,
 		ntpService: ntpService,
 		chambers:   make(map[string]*models.Chamber),
+		owners:     make(map[string]*DeviceInstance),
+	}
+}
+
+// Instances returns the device instances the chambers are discovered from
+func (cm *ChamberManager) Instances() []*DeviceInstance {
+	return cm.instances
+}
+
+// DriverFor returns the driver of the instance that owns the chamber, or nil
+func (cm *ChamberManager) DriverFor(chamberID primitive.ObjectID) device.Driver {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for suffix, chamber := range cm.chambers {
+		if chamber.ID == chamberID {
+			return cm.owners[suffix].Driver
+		}
+	}
+	return nil
+}
+
+// SetDiscoveryRules makes the discovery of every instance use rules
+func (cm *ChamberManager) SetDiscoveryRules(rules *DiscoveryRuleSet) {
+	for _, instance := range cm.instances {
+		instance.Discovery.SetRules(rules)
+	}
+}
+
+// PreviewDiscovery classifies the current entities of every reachable instance with the given
+// rules, or the rules in use when nil. It fails only when no instance could be read.
+func (cm *ChamberManager) PreviewDiscovery(rules *DiscoveryRuleSet) ([]models.DiscoveryPreview, error) {
+	var (
+		previews []models.DiscoveryPreview
+		lastErr  error
+		read     int
+	)
+
+	for _, instance := range cm.instances {
+		instancePreviews, err := instance.Discovery.Preview(rules)
+		if err != nil {
+			slog.Warn("Failed to preview discovery", "instance", instance.Name, "error", err)
+			lastErr = err
+			continue
+		}
+		read++
+
+		for i := range instancePreviews {
+			instancePreviews[i].Instance = instance.Name
+		}
+		previews = append(previews, instancePreviews...)
+	}
+
+	if read == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return previews, nil
+}
+
+// IsConnected reports whether at least one instance is reachable
+func (cm *ChamberManager) IsConnected() bool {
+	for _, instance := range cm.instances {
+		if instance.Driver.IsConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+// ChamberSensor is a numeric sensor attributed to the chamber whose suffix it carries
+type ChamberSensor struct {
+	device.Sensor
+	Chamber *models.Chamber
+}
+
+// GetSensors reads the sensors of every instance and returns the ones that belong to a known chamber.
+// It fails only when no instance could be read.
+func (cm *ChamberManager) GetSensors() ([]ChamberSensor, error) {
+	var (
+		sensors []ChamberSensor
+		lastErr error
+		read    int
+	)
+
+	for _, instance := range cm.instances {
+		instanceSensors, err := instance.Driver.GetSensors()
+		if err != nil {
+			slog.Warn("Failed to read sensors", "instance", instance.Name, "error", err)
+			lastErr = err
+			continue
+		}
+		read++
+
+		for _, sensor := range instanceSensors {
+			chamber := cm.GetChamber(instance.Discovery.extractRoomSuffix(sensor.EntityID))
+			if chamber == nil {
+				continue
+			}
+			sensors = append(sensors, ChamberSensor{Sensor: sensor, Chamber: chamber})
+		}
+	}
+
+	if read == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return sensors, nil
+}
+
+// SetChamberSuffixes changes the chamber suffixes of each instance, keyed by instance name.
+// Instances missing from suffixes keep theirs. Chambers whose suffix is no longer configured,
+// or moved to another instance, are dropped and returned; new and moved suffixes are picked up
+// by the next InitializeChambers call.
+func (cm *ChamberManager) SetChamberSuffixes(suffixes map[string][]string) []*models.Chamber {
+	configured := make(map[string]*DeviceInstance)
+	for _, instance := range cm.instances {
+		instanceSuffixes, exists := suffixes[instance.Name]
+		if exists {
+			instance.Discovery.SetChamberSuffixes(instanceSuffixes)
+		} else {
+			instanceSuffixes = instance.Discovery.suffixes()
+		}
+		for _, suffix := range instanceSuffixes {
+			configured[strings.ToLower(suffix)] = instance
+		}
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var removed []*models.Chamber
+	for suffix, chamber := range cm.chambers {
+		if owner := configured[suffix]; owner != cm.owners[suffix] {
+			if owner != nil {
+				slog.Info("Chamber moved to another instance", "suffix", suffix, "from", cm.owners[suffix].Name, "to", owner.Name)
+			}
+			removed = append(removed, chamber)
+			delete(cm.chambers, suffix)
+			delete(cm.owners, suffix)
+		}
 	}
+	return removed
 }
 
-// InitializeChambers discovers and initializes all chambers
+// InitializeChambers discovers and initializes the chambers of all reachable instances.
+// It fails only when no instance could be discovered.
 func (cm *ChamberManager) InitializeChambers(ctx context.Context) error {
-	log.Printf("Initializing chambers with suffixes: %v", cm.config.ChamberSuffixes)
+	var lastErr error
+	discovered := 0
+
+	for _, instance := range cm.instances {
+		if err := cm.initializeInstance(ctx, instance); err != nil {
+			slog.Warn("Failed to discover instance", "instance", instance.Name, "error", err)
+			lastErr = err
+			continue
+		}
+		discovered++
+	}
+
+	if discovered == 0 && lastErr != nil {
+		return lastErr
+	}
+
+	slog.Info("Chambers initialized", "count", len(cm.GetChambers()))
+	return nil
+}
+
+// initializeInstance discovers and initializes the chambers of one instance
+func (cm *ChamberManager) initializeInstance(ctx context.Context, instance *DeviceInstance) error {
+	slog.Info("Initializing chambers", "instance", instance.Name, "suffixes", instance.Discovery.suffixes())
 
 	// Discover entities grouped by rooms
-	chamberEntities, err := cm.discovery.DiscoverChamberEntities()
+	chamberEntities, err := instance.Discovery.DiscoverChamberEntities()
 	if err != nil {
 		return fmt.Errorf("failed to discover chamber entities: %w", err)
 	}
 
 	// Create or update chambers
 	for suffix, entities := range chamberEntities {
-		chamber, err := cm.createOrUpdateChamber(ctx, suffix, entities)
+		chamber, err := cm.createOrUpdateChamber(ctx, instance, suffix, entities)
 		if err != nil {
-			log.Printf("Warning: Failed to create/update chamber for %s: %v", suffix, err)
+			slog.Warn("Failed to create or update chamber", "suffix", suffix, "error", err)
 			continue
 		}
+		cm.mu.Lock()
 		cm.chambers[suffix] = chamber
-		log.Printf("Chamber initialized: %s (suffix: %s)", chamber.Name, suffix)
+		cm.owners[suffix] = instance
+		cm.mu.Unlock()
+		slog.Info("Chamber initialized", "chamber_id", chamber.ID.Hex(), "chamber", chamber.Name, "suffix", suffix, "instance", instance.Name)
 	}
 
-	log.Printf("Initialized %d chambers", len(cm.chambers))
 	return nil
 }
 
+// RediscoveredChamber is a known chamber whose entities changed on re-discovery
+type RediscoveredChamber struct {
+	Chamber  *models.Chamber
+	Instance string
+	Changes  []models.DiscoveryChange
+}
+
+// Rediscover discovers the entities of every reachable instance again and updates the chambers
+// whose entities changed. Suffixes without a chamber yet are left to InitializeChambers.
+// It fails only when no instance could be discovered.
+func (cm *ChamberManager) Rediscover(ctx context.Context) ([]RediscoveredChamber, error) {
+	var (
+		changed    []RediscoveredChamber
+		lastErr    error
+		discovered int
+	)
+
+	for _, instance := range cm.instances {
+		chamberEntities, err := instance.Discovery.DiscoverChamberEntities()
+		if err != nil {
+			slog.Warn("Failed to rediscover instance", "instance", instance.Name, "error", err)
+			lastErr = err
+			continue
+		}
+		discovered++
+
+		for suffix, chamber := range cm.GetChambers() {
+			cm.mu.RLock()
+			owner := cm.owners[suffix]
+			cm.mu.RUnlock()
+			if owner != instance {
+				continue
+			}
+
+			entities, found := chamberEntities[suffix]
+			if !found {
+				// Every entity of the chamber is gone
+				entities = &ChamberEntities{RoomSuffix: suffix, Config: newChamberConfig()}
+			}
+
+			changes := diffChamberConfigs(chamber.Config, entities.Config)
+			if len(changes) == 0 {
+				continue
+			}
+
+			updated, err := cm.createOrUpdateChamber(ctx, instance, suffix, entities)
+			if err != nil {
+				slog.Warn("Failed to update rediscovered chamber", "suffix", suffix, "error", err)
+				continue
+			}
+			cm.mu.Lock()
+			cm.chambers[suffix] = updated
+			cm.mu.Unlock()
+
+			changed = append(changed, RediscoveredChamber{Chamber: updated, Instance: instance.Name, Changes: changes})
+		}
+	}
+
+	if discovered == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return changed, nil
+}
+
 // Updated createOrUpdateChamber method in chamber_manager.go
-func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, suffix string, entities *ChamberEntities) (*models.Chamber, error) {
+func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, instance *DeviceInstance, suffix string, entities *ChamberEntities) (*models.Chamber, error) {
 	// Generate chamber name
 	chamberName := cm.generateChamberName(suffix)
 
@@ -80,17 +329,20 @@ func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, suffix stri
 	if err == mongo.ErrNoDocuments {
 		// Create new chamber
 		chamber = models.Chamber{
-			ID:               primitive.NewObjectID(),
-			Name:             chamberName,
-			Suffix:           suffix,
-			LocalIP:          cm.config.LocalIP,
-			LocalAPIversion:  cm.config.LocalAPIversion,
-			TimeOffset:       cm.ntpService.TimeOffset(),
-			HomeAssistantURL: cm.config.HomeAssistantURL,
-			Status:           "online",
-			LastHeartbeat:    now,
+			ID:                primitive.NewObjectID(),
+			Name:              chamberName,
+			Suffix:            suffix,
+			LocalIP:           cm.config.LocalIP,
+			LocalAPIversion:   cm.config.LocalAPIversion,
+			TimeOffset:        cm.ntpService.TimeOffset(),
+			HomeAssistantURL:  instance.Driver.URL(),
+			HomeAssistantName: instance.Name,
+			Status:            "online",
+			LastHeartbeat:     now,
 			Config: models.ChamberConfig{
 				Lamps:                entities.Config.Lamps,
+				Switches:             entities.Config.Switches,
+				Thermostats:          entities.Config.Thermostats,
 				WateringZones:        entities.Config.WateringZones,
 				UnrecognisedEntities: entities.Config.UnrecognisedEntities,
 				DayDuration:          entities.Config.DayDuration,
@@ -110,7 +362,7 @@ func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, suffix stri
 			return nil, fmt.Errorf("failed to create chamber: %w", err)
 		}
 
-		log.Printf("Created new chamber: %s", chamber.Name)
+		slog.Info("Created new chamber", "chamber", chamber.Name)
 		cm.logChamberSummary(&chamber)
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to query chamber: %w", err)
@@ -120,12 +372,16 @@ func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, suffix stri
 			"$set": bson.M{
 				"name":                chamberName,
 				"local_ip":            cm.config.LocalIP,
-				"ha_url":              cm.config.HomeAssistantURL,
+				"ha_url":              instance.Driver.URL(),
+				"ha_instance":         instance.Name,
+				"time_offset":         cm.ntpService.TimeOffset(),
 				"status":              "online",
 				"last_heartbeat":      now,
 				"discovery_completed": true,
 				"config": bson.M{
 					"lamps":                 entities.Config.Lamps,
+					"switches":              entities.Config.Switches,
+					"thermostats":           entities.Config.Thermostats,
 					"watering_zones":        entities.Config.WateringZones,
 					"unrecognised_entities": entities.Config.UnrecognisedEntities,
 					"day_duration":          entities.Config.DayDuration,
@@ -133,6 +389,8 @@ func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, suffix stri
 					"temperature":           entities.Config.Temperature,
 					"humidity":              entities.Config.Humidity,
 					"co2":                   entities.Config.CO2,
+					"pause_profile":         chamber.Config.PauseProfile, // profiles are configured on the backend, not discovered
+					"idle_profile":          chamber.Config.IdleProfile,
 					"updated_at":            now,
 				},
 				"updated_at": now,
@@ -147,11 +405,15 @@ func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, suffix stri
 		// Update local data
 		chamber.Name = chamberName
 		chamber.LocalIP = cm.config.LocalIP
-		chamber.HomeAssistantURL = cm.config.HomeAssistantURL
+		chamber.HomeAssistantURL = instance.Driver.URL()
+		chamber.HomeAssistantName = instance.Name
+		chamber.TimeOffset = cm.ntpService.TimeOffset()
 		chamber.Status = "online"
 		chamber.LastHeartbeat = now
 		chamber.Config = models.ChamberConfig{
 			Lamps:                entities.Config.Lamps,
+			Switches:             entities.Config.Switches,
+			Thermostats:          entities.Config.Thermostats,
 			WateringZones:        entities.Config.WateringZones,
 			UnrecognisedEntities: entities.Config.UnrecognisedEntities,
 			DayDuration:          entities.Config.DayDuration,
@@ -159,12 +421,14 @@ func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, suffix stri
 			Temperature:          entities.Config.Temperature,
 			Humidity:             entities.Config.Humidity,
 			CO2:                  entities.Config.CO2,
+			PauseProfile:         chamber.Config.PauseProfile,
+			IdleProfile:          chamber.Config.IdleProfile,
 			UpdatedAt:            now,
 		}
 		chamber.DiscoveryCompleted = true
 		chamber.UpdatedAt = now
 
-		log.Printf("Updated chamber: %s", chamber.Name)
+		slog.Info("Updated chamber", "chamber", chamber.Name)
 		cm.logChamberSummary(&chamber)
 	}
 
@@ -173,19 +437,22 @@ func (cm *ChamberManager) createOrUpdateChamber(ctx context.Context, suffix stri
 
 // Helper method to log chamber summary
 func (cm *ChamberManager) logChamberSummary(chamber *models.Chamber) {
-	log.Printf("Chamber %s (suffix: %s) summary:", chamber.Name, chamber.Suffix)
-	log.Printf("  - %d lamps", len(chamber.Config.Lamps))
-	log.Printf("  - %d watering zones", len(chamber.Config.WateringZones))
-	log.Printf("  - %d unrecognised entities", len(chamber.Config.UnrecognisedEntities))
-	log.Printf("  - Climate mappings: %d day_start, %d day_duration",
-		len(chamber.Config.DayStart), len(chamber.Config.DayDuration))
-	log.Printf("  - Temperature: %d day, %d night",
-		len(chamber.Config.Temperature["day"]), len(chamber.Config.Temperature["night"]))
-	log.Printf("  - Humidity: %d day, %d night",
-		len(chamber.Config.Humidity["day"]), len(chamber.Config.Humidity["night"]))
-	log.Printf("  - CO2: %d day, %d night",
-		len(chamber.Config.CO2["day"]), len(chamber.Config.CO2["night"]))
-
+	slog.Info("Chamber summary",
+		"chamber", chamber.Name,
+		"suffix", chamber.Suffix,
+		"lamps", len(chamber.Config.Lamps),
+		"switches", len(chamber.Config.Switches),
+		"thermostats", len(chamber.Config.Thermostats),
+		"watering_zones", len(chamber.Config.WateringZones),
+		"unrecognised_entities", len(chamber.Config.UnrecognisedEntities),
+		"day_start", len(chamber.Config.DayStart),
+		"day_duration", len(chamber.Config.DayDuration),
+		"temperature_day", len(chamber.Config.Temperature["day"]),
+		"temperature_night", len(chamber.Config.Temperature["night"]),
+		"humidity_day", len(chamber.Config.Humidity["day"]),
+		"humidity_night", len(chamber.Config.Humidity["night"]),
+		"co2_day", len(chamber.Config.CO2["day"]),
+		"co2_night", len(chamber.Config.CO2["night"]))
 }
 
 // generateChamberName generates a descriptive name for the chamber
@@ -200,18 +467,30 @@ func (cm *ChamberManager) generateChamberName(suffix string) string {
 
 }
 
-// GetChambers returns all chambers
+// GetChambers returns all chambers keyed by suffix
 func (cm *ChamberManager) GetChambers() map[string]*models.Chamber {
-	return cm.chambers
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	chambers := make(map[string]*models.Chamber, len(cm.chambers))
+	for suffix, chamber := range cm.chambers {
+		chambers[suffix] = chamber
+	}
+	return chambers
 }
 
 // GetChamber returns a chamber by suffix
 func (cm *ChamberManager) GetChamber(suffix string) *models.Chamber {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
 	return cm.chambers[suffix]
 }
 
 // GetChamberByID returns a chamber by its MongoDB ID
 func (cm *ChamberManager) GetChamberByID(id primitive.ObjectID) *models.Chamber {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
 	for _, chamber := range cm.chambers {
 		if chamber.ID == id {
 			return chamber
@@ -220,6 +499,19 @@ func (cm *ChamberManager) GetChamberByID(id primitive.ObjectID) *models.Chamber
 	return nil
 }
 
+// GetChamberByBackendID returns a chamber by the ID assigned by the backend
+func (cm *ChamberManager) GetChamberByBackendID(backendID primitive.ObjectID) *models.Chamber {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for _, chamber := range cm.chambers {
+		if !chamber.BackendID.IsZero() && chamber.BackendID == backendID {
+			return chamber
+		}
+	}
+	return nil
+}
+
 // UpdateChamberConfig updates chamber configuration
 func (cm *ChamberManager) UpdateChamberConfig(ctx context.Context, chamberID primitive.ObjectID, config *models.ChamberConfig) error {
 	now := cm.ntpService.Now()
@@ -239,11 +531,11 @@ func (cm *ChamberManager) UpdateChamberConfig(ctx context.Context, chamberID pri
 	}
 
 	// Update local copy
-	for _, chamber := range cm.chambers {
+	for _, chamber := range cm.GetChambers() {
 		if chamber.ID == chamberID {
 			chamber.Config = *config
 			chamber.UpdatedAt = now
-			log.Printf("Updated configuration for chamber: %s", chamber.Name)
+			slog.Info("Updated chamber configuration", "chamber", chamber.Name)
 			break
 		}
 	}
@@ -255,7 +547,7 @@ func (cm *ChamberManager) UpdateChamberConfig(ctx context.Context, chamberID pri
 func (cm *ChamberManager) UpdateHeartbeat(ctx context.Context) error {
 	now := cm.ntpService.Now()
 
-	for suffix, chamber := range cm.chambers {
+	for suffix, chamber := range cm.GetChambers() {
 		update := bson.M{
 			"$set": bson.M{
 				"last_heartbeat": now,
@@ -266,7 +558,7 @@ func (cm *ChamberManager) UpdateHeartbeat(ctx context.Context) error {
 
 		_, err := cm.db.ChambersCollection.UpdateByID(ctx, chamber.ID, update)
 		if err != nil {
-			log.Printf("Failed to update heartbeat for chamber %s: %v", suffix, err)
+			slog.Error("Failed to update chamber heartbeat", "suffix", suffix, "error", err)
 		} else {
 			chamber.LastHeartbeat = now
 			chamber.Status = "online"
@@ -280,11 +572,12 @@ func (cm *ChamberManager) UpdateHeartbeat(ctx context.Context) error {
 // RegisterChambersWithBackend registers all chambers with the backend
 func (cm *ChamberManager) RegisterChambersWithBackend(registrationService *RegistrationService) error {
 	successCount := 0
+	chambers := cm.GetChambers()
 
-	for suffix, chamber := range cm.chambers {
-		log.Printf("Registering chamber '%s' with backend...", suffix)
+	for suffix, chamber := range chambers {
+		slog.Info("Registering chamber with backend", "suffix", suffix)
 		if err := registrationService.RegisterChamberWithBackend(chamber); err != nil {
-			log.Printf("Warning: Failed to register chamber '%s': %v", suffix, err)
+			slog.Warn("Failed to register chamber", "suffix", suffix, "error", err)
 			continue
 		}
 		successCount++
@@ -294,7 +587,7 @@ func (cm *ChamberManager) RegisterChambersWithBackend(registrationService *Regis
 		return fmt.Errorf("failed to register any chambers")
 	}
 
-	log.Printf("Successfully registered %d/%d chambers", successCount, len(cm.chambers))
+	slog.Info("Chambers registered with backend", "registered", successCount, "total", len(chambers))
 	return nil
 }
 
@@ -302,7 +595,7 @@ func (cm *ChamberManager) RegisterChambersWithBackend(registrationService *Regis
 func (cm *ChamberManager) GetRegisteredChambers() []*models.Chamber {
 	var registered []*models.Chamber
 
-	for _, chamber := range cm.chambers {
+	for _, chamber := range cm.GetChambers() {
 		if !chamber.BackendID.IsZero() {
 			registered = append(registered, chamber)
 		}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"local_api_v2/internal/models"
+)
+
+func TestSetChamberSuffixes(t *testing.T) {
+	tests := []struct {
+		name        string
+		suffixes    map[string][]string
+		wantRemoved []string
+		wantOwners  map[string]string
+	}{
+		{
+			name:       "unchanged",
+			suffixes:   map[string][]string{"ha": {"sb1", "sb4"}, "broker": {"sb2"}},
+			wantOwners: map[string]string{"sb1": "ha", "sb4": "ha", "sb2": "broker"},
+		},
+		{
+			name:        "suffix removed",
+			suffixes:    map[string][]string{"ha": {"sb1"}, "broker": {"sb2"}},
+			wantRemoved: []string{"sb4"},
+			wantOwners:  map[string]string{"sb1": "ha", "sb2": "broker"},
+		},
+		{
+			name:        "suffix moved to another instance",
+			suffixes:    map[string][]string{"ha": {"sb1"}, "broker": {"sb2", "sb4"}},
+			wantRemoved: []string{"sb4"},
+			wantOwners:  map[string]string{"sb1": "ha", "sb2": "broker"},
+		},
+		{
+			name:       "instance missing keeps its suffixes",
+			suffixes:   map[string][]string{"ha": {"sb1", "sb4"}},
+			wantOwners: map[string]string{"sb1": "ha", "sb4": "ha", "sb2": "broker"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ha := NewDeviceInstance("ha", nil, []string{"sb1", "sb4"})
+			broker := NewDeviceInstance("broker", nil, []string{"sb2"})
+			cm := NewChamberManager(nil, nil, []*DeviceInstance{ha, broker}, nil)
+			for suffix, owner := range map[string]*DeviceInstance{"sb1": ha, "sb4": ha, "sb2": broker} {
+				cm.chambers[suffix] = &models.Chamber{ID: primitive.NewObjectID(), Suffix: suffix}
+				cm.owners[suffix] = owner
+			}
+
+			removed := cm.SetChamberSuffixes(tt.suffixes)
+
+			if len(removed) != len(tt.wantRemoved) {
+				t.Fatalf("removed %d chambers, want %v", len(removed), tt.wantRemoved)
+			}
+			for i, chamber := range removed {
+				if chamber.Suffix != tt.wantRemoved[i] {
+					t.Errorf("removed %s, want %s", chamber.Suffix, tt.wantRemoved[i])
+				}
+			}
+			if len(cm.owners) != len(tt.wantOwners) {
+				t.Errorf("%d chambers left, want %d", len(cm.owners), len(tt.wantOwners))
+			}
+			for suffix, name := range tt.wantOwners {
+				if owner := cm.owners[suffix]; owner == nil || owner.Name != name {
+					t.Errorf("chamber %s owned by %v, want %s", suffix, owner, name)
+				}
+			}
+		})
+	}
+}
@@ -2,32 +2,102 @@ package services
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"local_api_v2/internal/models"
-	"local_api_v2/pkg/homeassistant"
+	"local_api_v2/pkg/device"
 )
 
-// DiscoveryService handles discovery of Home Assistant entities
+// DiscoveryService handles discovery of the entities exposed by a device driver
 type DiscoveryService struct {
-	haClient        *homeassistant.Client
+	driver          device.Driver
+	mu              sync.RWMutex
 	chamberSuffixes []string // Настраиваемые суффиксы камер
+	rules           *DiscoveryRuleSet
 }
 
 // NewDiscoveryService creates a new discovery service
-func NewDiscoveryService(haClient *homeassistant.Client) *DiscoveryService {
+func NewDiscoveryService(driver device.Driver) *DiscoveryService {
 	return &DiscoveryService{
-		haClient:        haClient,
+		driver:          driver,
 		chamberSuffixes: []string{}, // Будет установлено позже через SetChamberSuffixes
+		rules:           NewDiscoveryRuleSet(),
 	}
 }
 
+// SetRules sets the discovery rules, usually shared by the discovery of all instances
+func (s *DiscoveryService) SetRules(rules *DiscoveryRuleSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = rules
+}
+
+// currentRules returns the discovery rules in use
+func (s *DiscoveryService) currentRules() *DiscoveryRuleSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules
+}
+
+// classify applies discovery rules to an entity and finds the chamber it belongs to.
+// The suffix is empty for excluded entities and for chambers this instance does not own.
+func (s *DiscoveryService) classify(rules *DiscoveryRuleSet, entityID, friendlyName string) discoveryMatch {
+	result := rules.match(entityID, friendlyName)
+	switch {
+	case result.Target == models.DiscoveryTargetExclude:
+		result.Suffix = ""
+	case result.Suffix == "":
+		result.Suffix = s.extractRoomSuffix(entityID)
+	case !slices.ContainsFunc(s.suffixes(), func(suffix string) bool { return strings.EqualFold(suffix, result.Suffix) }):
+		result.Suffix = ""
+	}
+	return result
+}
+
+// Preview classifies the current entities with the given rules, or the rules in use when nil
+func (s *DiscoveryService) Preview(rules *DiscoveryRuleSet) ([]models.DiscoveryPreview, error) {
+	if rules == nil {
+		rules = s.currentRules()
+	}
+
+	entities, err := s.driver.GetControls()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get controls: %v", err)
+	}
+
+	previews := make([]models.DiscoveryPreview, 0, len(entities))
+	for _, entity := range entities {
+		result := s.classify(rules, entity.EntityID, entity.FriendlyName)
+		preview := models.DiscoveryPreview{
+			EntityID:     entity.EntityID,
+			FriendlyName: entity.FriendlyName,
+			Suffix:       result.Suffix,
+			Target:       result.Target,
+			Rule:         result.Rule,
+		}
+		_, preview.Zone = wateringZone(result.Target, entity.EntityID)
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
 // SetChamberSuffixes устанавливает список поддерживаемых суффиксов камер
 func (s *DiscoveryService) SetChamberSuffixes(suffixes []string) {
+	s.mu.Lock()
 	s.chamberSuffixes = suffixes
-	log.Printf("Discovery service configured with chamber suffixes: %v", suffixes)
+	s.mu.Unlock()
+	slog.Info("Discovery service configured", "suffixes", suffixes)
+}
+
+// suffixes returns the configured chamber suffixes
+func (s *DiscoveryService) suffixes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chamberSuffixes
 }
 
 // ChamberEntities represents entities grouped by room suffix
@@ -36,12 +106,28 @@ type ChamberEntities struct {
 	Config     models.ChamberConfig
 }
 
+// newChamberConfig returns a chamber configuration without entities
+func newChamberConfig() models.ChamberConfig {
+	return models.ChamberConfig{
+		Lamps:                make(map[string]models.InputNumber),
+		Switches:             make(map[string]models.InputNumber),
+		Thermostats:          make(map[string]models.InputNumber),
+		WateringZones:        []models.WateringZone{},
+		UnrecognisedEntities: make(map[string]models.InputNumber),
+		DayDuration:          make(map[string]models.InputNumber),
+		DayStart:             make(map[string]models.InputNumber),
+		Temperature:          map[string]map[string]models.InputNumber{"day": {}, "night": {}},
+		Humidity:             map[string]map[string]models.InputNumber{"day": {}, "night": {}},
+		CO2:                  map[string]map[string]models.InputNumber{"day": {}, "night": {}},
+	}
+}
+
 // DiscoverInputNumbers discovers and categorizes input_number entities
 func (s *DiscoveryService) DiscoverInputNumbers() ([]models.InputNumber, []models.WateringZone, error) {
 	// Get all input numbers from Home Assistant
-	haEntities, err := s.haClient.GetInputNumbers()
+	haEntities, err := s.driver.GetControls()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get input numbers: %v", err)
+		return nil, nil, fmt.Errorf("failed to get controls: %v", err)
 	}
 
 	var (
@@ -53,19 +139,20 @@ func (s *DiscoveryService) DiscoverInputNumbers() ([]models.InputNumber, []model
 	)
 
 	// Process each entity
+	rules := s.currentRules()
 	for _, entity := range haEntities {
 		entityID := entity.EntityID
-		lowerEntityID := strings.ToLower(entityID)
 		friendlyName := entity.FriendlyName
 
-		if strings.Contains(entityID, "prog") || strings.Contains(entityID, "test") {
+		match := rules.match(entityID, friendlyName)
+		if match.Target == models.DiscoveryTargetExclude {
 			continue
 		}
 
-		log.Printf("Processing entity: %s (%s)", entityID, friendlyName)
+		slog.Debug("Processing entity", "entity_id", entityID, "name", friendlyName, "target", match.Target, "rule", match.Rule)
 
 		// Check if it's a lamp control
-		if isLampEntity(lowerEntityID, friendlyName) {
+		if match.Target == models.DiscoveryTargetLamp {
 			lampName := extractLampName(entityID, friendlyName)
 			if lamp, exists := lampMap[lampName]; exists {
 				// Update existing lamp
@@ -87,7 +174,7 @@ func (s *DiscoveryService) DiscoverInputNumbers() ([]models.InputNumber, []model
 		}
 
 		// Check if it's a watering control
-		wateringType, zoneName := getWateringType(lowerEntityID, friendlyName)
+		wateringType, zoneName := wateringZone(match.Target, entityID)
 		if wateringType != "" {
 			if zone, exists := wateringMap[zoneName]; exists {
 				// Update existing zone
@@ -152,7 +239,7 @@ func (s *DiscoveryService) DiscoverInputNumbers() ([]models.InputNumber, []model
 		}
 
 		// Check if it's a regular input number (climate control)
-		inputType := getInputNumberType(lowerEntityID, friendlyName)
+		inputType := climateType(match.Target)
 		if inputType != "" {
 			inputNumber := models.InputNumber{
 				EntityID: entityID,
@@ -176,57 +263,22 @@ func (s *DiscoveryService) DiscoverInputNumbers() ([]models.InputNumber, []model
 		wateringZones = append(wateringZones, *zone)
 	}
 
-	log.Printf("Discovered: %d input numbers, %d lamps, %d watering zones",
-		len(inputNumbers), len(lamps), len(wateringZones))
+	slog.Info("Discovered entities",
+		"input_numbers", len(inputNumbers),
+		"lamps", len(lamps),
+		"watering_zones", len(wateringZones))
 
 	return inputNumbers, wateringZones, nil
 }
 
-// getInputNumberType determines the type of input number based on entity ID and friendly name
-func getInputNumberType(entityID, friendlyName string) string {
-	lowerID := strings.ToLower(entityID)
-	lowerName := strings.ToLower(friendlyName)
-
-	for inputType, substrings := range models.InputNumberSubstrings {
-		// Skip watering types
-		if strings.HasPrefix(inputType, "watering_") {
-			continue
-		}
-
-		for _, substr := range substrings {
-			if strings.Contains(lowerID, substr) || strings.Contains(lowerName, substr) {
-				return inputType
-			}
-		}
+// climateType returns target when it is a climate control type, otherwise an empty string
+func climateType(target string) string {
+	if slices.Contains(climateTargets, target) {
+		return target
 	}
-
 	return ""
 }
 
-// isLampEntity checks if the entity is a lamp control
-func isLampEntity(entityID, friendlyName string) bool {
-	lampKeywords := []string{"lamp", "light", "led", "лампа", "свет", "света", "лампы", "ppfd", "lamps", "lights"}
-
-	lampIgnoreList := []string{"day", "night", "work", "sun_rise", "sun_set", "ras_zak", "ras", "rise_sun", "zak_ras"}
-
-	lowerID := strings.ToLower(entityID)
-	lowerName := strings.ToLower(friendlyName)
-
-	for _, ignore := range lampIgnoreList {
-		if strings.Contains(lowerID, ignore) || strings.Contains(lowerName, ignore) {
-			return false
-		}
-	}
-
-	for _, keyword := range lampKeywords {
-		if strings.Contains(lowerID, keyword) || strings.Contains(lowerName, keyword) {
-			return true
-		}
-	}
-
-	return false
-}
-
 // extractLampName extracts the lamp name from entity ID or friendly name
 func extractLampName(entityID, friendlyName string) string {
 	// Try to extract from friendly name first
@@ -254,32 +306,13 @@ func extractLampName(entityID, friendlyName string) string {
 	return "Lamp"
 }
 
-// getWateringType determines if entity is a watering control and returns its type and zone name
-func getWateringType(entityID, friendlyName string) (string, string) {
-	lowerID := strings.ToLower(entityID)
-	lowerName := strings.ToLower(friendlyName)
-
-	// Check each watering type
-	wateringTypes := []string{
-		models.InputNumberWateringStart,
-		models.InputNumberWateringPeriod,
-		models.InputNumberWateringPause,
-		models.InputNumberWateringDuration,
+// wateringZone returns the watering type and zone name of an entity classified as target,
+// or empty strings when it is not a watering control
+func wateringZone(target, entityID string) (string, string) {
+	if !slices.Contains(wateringTargets, target) {
+		return "", ""
 	}
-
-	for _, wateringType := range wateringTypes {
-		if substrings, ok := models.InputNumberSubstrings[wateringType]; ok {
-			for _, substr := range substrings {
-				if strings.Contains(lowerID, substr) || strings.Contains(lowerName, substr) {
-					// Extract zone name
-					zoneName := extractWateringNumber(entityID)
-					return wateringType, zoneName
-				}
-			}
-		}
-	}
-
-	return "", ""
+	return target, extractWateringNumber(entityID)
 }
 
 // extractZoneName extracts the watering zone name
@@ -299,13 +332,13 @@ func (s *DiscoveryService) extractRoomSuffix(entityID string) string {
 	lowerEntityID := strings.ToLower(entityID)
 
 	// Сначала проверяем настраиваемые суффиксы (galo, sb4, oreol, sb1, etc.)
-	for _, suffix := range s.chamberSuffixes {
+	for _, suffix := range s.suffixes() {
 		lowerSuffix := strings.ToLower(suffix)
 
 		// Проверяем различные варианты окончаний:
 		// 1. _suffix в конце (например, input_number.temp_galo)
 		if strings.HasSuffix(lowerEntityID, "_"+lowerSuffix) {
-			log.Printf("Found chamber suffix '%s' in entity '%s' (pattern: _suffix)", suffix, entityID)
+			slog.Debug("Found chamber suffix", "suffix", suffix, "entity_id", entityID, "pattern", "_suffix")
 			return lowerSuffix
 		}
 
@@ -317,25 +350,25 @@ func (s *DiscoveryService) extractRoomSuffix(entityID string) string {
 				prevChar := lowerEntityID[suffixStart-1]
 				// Суффикс должен быть отделен символом, не буквой
 				if prevChar == '_' || prevChar == '.' || prevChar == '-' {
-					log.Printf("Found chamber suffix '%s' in entity '%s' (pattern: suffix)", suffix, entityID)
+					slog.Debug("Found chamber suffix", "suffix", suffix, "entity_id", entityID, "pattern", "suffix")
 					return lowerSuffix
 				}
 			} else {
 				// Суффикс в самом начале
-				log.Printf("Found chamber suffix '%s' in entity '%s' (pattern: suffix)", suffix, entityID)
+				slog.Debug("Found chamber suffix", "suffix", suffix, "entity_id", entityID, "pattern", "suffix")
 				return lowerSuffix
 			}
 		}
 
 		// 3. suffix_ в любом месте (например, input_number.galo_temp)
 		if strings.Contains(lowerEntityID, lowerSuffix+"_") {
-			log.Printf("Found chamber suffix '%s' in entity '%s' (pattern: suffix_)", suffix, entityID)
+			slog.Debug("Found chamber suffix", "suffix", suffix, "entity_id", entityID, "pattern", "suffix_")
 			return lowerSuffix
 		}
 
 		// 4. _suffix_ в любом месте (например, input_number.temp_galo_day)
 		if strings.Contains(lowerEntityID, "_"+lowerSuffix+"_") {
-			log.Printf("Found chamber suffix '%s' in entity '%s' (pattern: _suffix_)", suffix, entityID)
+			slog.Debug("Found chamber suffix", "suffix", suffix, "entity_id", entityID, "pattern", "_suffix_")
 			return lowerSuffix
 		}
 	}
@@ -361,10 +394,10 @@ func (s *DiscoveryService) extractRoomSuffix(entityID string) string {
 
 // DiscoverChamberEntities discovers entities grouped by room suffixes
 func (s *DiscoveryService) DiscoverChamberEntities() (map[string]*ChamberEntities, error) {
-	// Get all input numbers from Home Assistant
-	haEntities, err := s.haClient.GetInputNumbers()
+	// Get all controls from Home Assistant
+	haEntities, err := s.driver.GetControls()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get input numbers: %v", err)
+		return nil, fmt.Errorf("failed to get controls: %v", err)
 	}
 
 	roomMap, err := s.AutomaticalyDiscoverChamberEntities(haEntities)
@@ -373,33 +406,35 @@ func (s *DiscoveryService) DiscoverChamberEntities() (map[string]*ChamberEntitie
 		return nil, fmt.Errorf("failed to discover chamber entities: %v", err)
 	}
 
-	log.Printf("Discovered entities grouped by rooms:")
 	for roomSuffix, room := range roomMap {
-		log.Printf("  Room '%s': %d lamps, %d watering zones",
-			roomSuffix, len(room.Config.Lamps), len(room.Config.WateringZones))
+		slog.Info("Discovered room entities",
+			"suffix", roomSuffix,
+			"lamps", len(room.Config.Lamps),
+			"watering_zones", len(room.Config.WateringZones))
 	}
 
 	return roomMap, nil
 }
 
-func (s *DiscoveryService) AutomaticalyDiscoverChamberEntities(haEntities []homeassistant.InputNumberEntity) (map[string]*ChamberEntities, error) {
+func (s *DiscoveryService) AutomaticalyDiscoverChamberEntities(haEntities []device.Control) (map[string]*ChamberEntities, error) {
 	roomMap := make(map[string]*ChamberEntities)
 
-	log.Printf("Discovering room entities with configured suffixes: %v", s.chamberSuffixes)
+	slog.Info("Discovering room entities", "suffixes", s.suffixes())
 
 	// First collect all entities by rooms
+	rules := s.currentRules()
 	for _, entity := range haEntities {
 		entityID := entity.EntityID
-		lowerEntityID := strings.ToLower(entityID)
 		friendlyName := entity.FriendlyName
 
-		if strings.Contains(entityID, "prog") || strings.Contains(entityID, "test") {
+		match := s.classify(rules, entityID, friendlyName)
+		if match.Target == models.DiscoveryTargetExclude {
 			continue
 		}
 
-		roomSuffix := s.extractRoomSuffix(entityID)
+		roomSuffix := match.Suffix
 		if roomSuffix == "" {
-			log.Printf("Skipping entity without room suffix: %s (%s)", entityID, friendlyName)
+			slog.Debug("Skipping entity without room suffix", "entity_id", entityID, "name", friendlyName)
 			continue // Skip entities without room suffix
 		}
 
@@ -407,26 +442,17 @@ func (s *DiscoveryService) AutomaticalyDiscoverChamberEntities(haEntities []home
 		if _, exists := roomMap[roomSuffix]; !exists {
 			roomMap[roomSuffix] = &ChamberEntities{
 				RoomSuffix: roomSuffix,
-				Config: models.ChamberConfig{
-					Lamps:                make(map[string]models.InputNumber),
-					WateringZones:        []models.WateringZone{},
-					UnrecognisedEntities: make(map[string]models.InputNumber),
-					DayDuration:          make(map[string]models.InputNumber),
-					DayStart:             make(map[string]models.InputNumber),
-					Temperature:          map[string]map[string]models.InputNumber{"day": {}, "night": {}},
-					Humidity:             map[string]map[string]models.InputNumber{"day": {}, "night": {}},
-					CO2:                  map[string]map[string]models.InputNumber{"day": {}, "night": {}},
-				},
+				Config:     newChamberConfig(),
 			}
 		}
 
 		room := roomMap[roomSuffix]
 		entityProcessed := false
 
-		log.Printf("Processing entity for room '%s': %s (%s)", roomSuffix, entityID, friendlyName)
+		slog.Debug("Processing entity", "suffix", roomSuffix, "entity_id", entityID, "name", friendlyName, "target", match.Target, "rule", match.Rule)
 
 		// Process lamps
-		if isLampEntity(lowerEntityID, friendlyName) {
+		if match.Target == models.DiscoveryTargetLamp {
 			room.Config.Lamps[entityID] = models.InputNumber{
 				EntityID: entityID,
 				Name:     friendlyName,
@@ -439,9 +465,29 @@ func (s *DiscoveryService) AutomaticalyDiscoverChamberEntities(haEntities []home
 			entityProcessed = true
 		}
 
+		// Process switches and thermostats
+		if match.Target == models.DiscoveryTargetSwitch || match.Target == models.DiscoveryTargetThermostat {
+			control := models.InputNumber{
+				EntityID: entityID,
+				Name:     friendlyName,
+				Type:     match.Target,
+				Min:      entity.Min,
+				Max:      entity.Max,
+				Step:     entity.Step,
+				Value:    entity.Value,
+				Unit:     entity.Unit,
+			}
+			if match.Target == models.DiscoveryTargetSwitch {
+				room.Config.Switches[entityID] = control
+			} else {
+				room.Config.Thermostats[entityID] = control
+			}
+			entityProcessed = true
+		}
+
 		// Process watering
 		if !entityProcessed {
-			wateringType, zoneName := getWateringType(lowerEntityID, friendlyName)
+			wateringType, zoneName := wateringZone(match.Target, entityID)
 			if wateringType != "" {
 				// Find existing watering zone for this room
 				var targetZone *models.WateringZone
@@ -515,7 +561,7 @@ func (s *DiscoveryService) AutomaticalyDiscoverChamberEntities(haEntities []home
 
 		// Process regular input numbers (climate control)
 		if !entityProcessed {
-			inputType := getInputNumberType(lowerEntityID, friendlyName)
+			inputType := climateType(match.Target)
 			if inputType != "" {
 				switch inputType {
 				case models.InputNumberDayStart:
@@ -615,24 +661,27 @@ func (s *DiscoveryService) AutomaticalyDiscoverChamberEntities(haEntities []home
 				Unit:     entity.Unit,
 			}
 			room.Config.UnrecognisedEntities[inputNumber.EntityID] = inputNumber
-			log.Printf("Unrecognized entity added to room '%s': %s (%s)", roomSuffix, entityID, friendlyName)
+			slog.Debug("Unrecognized entity added", "suffix", roomSuffix, "entity_id", entityID, "name", friendlyName)
 		}
 	}
 
 	// Log summary for each room
 	for roomSuffix, room := range roomMap {
-		log.Printf("Room '%s' summary:", roomSuffix)
-		log.Printf("  - %d lamps", len(room.Config.Lamps))
-		log.Printf("  - %d watering zones", len(room.Config.WateringZones))
-		log.Printf("  - %d unrecognised entities", len(room.Config.UnrecognisedEntities))
-		log.Printf("  - Climate mappings: %d day_start, %d day_duration",
-			len(room.Config.DayStart), len(room.Config.DayDuration))
-		log.Printf("  - Temperature: %d day, %d night",
-			len(room.Config.Temperature["day"]), len(room.Config.Temperature["night"]))
-		log.Printf("  - Humidity: %d day, %d night",
-			len(room.Config.Humidity["day"]), len(room.Config.Humidity["night"]))
-		log.Printf("  - CO2: %d day, %d night",
-			len(room.Config.CO2["day"]), len(room.Config.CO2["night"]))
+		slog.Info("Room summary",
+			"suffix", roomSuffix,
+			"lamps", len(room.Config.Lamps),
+			"switches", len(room.Config.Switches),
+			"thermostats", len(room.Config.Thermostats),
+			"watering_zones", len(room.Config.WateringZones),
+			"unrecognised_entities", len(room.Config.UnrecognisedEntities),
+			"day_start", len(room.Config.DayStart),
+			"day_duration", len(room.Config.DayDuration),
+			"temperature_day", len(room.Config.Temperature["day"]),
+			"temperature_night", len(room.Config.Temperature["night"]),
+			"humidity_day", len(room.Config.Humidity["day"]),
+			"humidity_night", len(room.Config.Humidity["night"]),
+			"co2_day", len(room.Config.CO2["day"]),
+			"co2_night", len(room.Config.CO2["night"]))
 	}
 
 	return roomMap, nil
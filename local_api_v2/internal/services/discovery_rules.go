@@ -0,0 +1,330 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"local_api_v2/internal/models"
+)
+
+// climateTargets lists the input number types of climate controls in evaluation order
+var climateTargets = []string{
+	models.InputNumberDayStart,
+	models.InputNumberDayDuration,
+	models.InputNumberTempDay,
+	models.InputNumberTempNight,
+	models.InputNumberHumidityDay,
+	models.InputNumberHumidityNight,
+	models.InputNumberCO2Day,
+	models.InputNumberCO2Night,
+}
+
+// wateringTargets lists the input number types of watering controls in evaluation order
+var wateringTargets = []string{
+	models.InputNumberWateringStart,
+	models.InputNumberWateringPeriod,
+	models.InputNumberWateringPause,
+	models.InputNumberWateringDuration,
+}
+
+// DefaultDiscoveryRules reproduces the built-in classification: test and programming entities
+// are dropped, switch, light and climate entities are classified by their domain, then lamps,
+// watering and climate controls are matched by models.InputNumberSubstrings
+func DefaultDiscoveryRules() []models.DiscoveryRule {
+	rules := []models.DiscoveryRule{
+		{Name: "exclude_prog_test", EntityID: "prog|test", Target: models.DiscoveryTargetExclude, Priority: 100},
+		{Name: "switch_domain", EntityID: `^switch\.`, Target: models.DiscoveryTargetSwitch, Priority: 60},
+		{Name: "light_domain", EntityID: `^light\.`, Target: models.DiscoveryTargetLamp, Priority: 60},
+		{Name: "climate_domain", EntityID: `^climate\.`, Target: models.DiscoveryTargetThermostat, Priority: 60},
+	}
+
+	lampKeywords := "lamp|light|led|лампа|свет|ppfd"
+	lampIgnore := "day|night|work|sun_rise|sun_set|ras_zak|ras|rise_sun|zak_ras"
+	rules = append(rules,
+		models.DiscoveryRule{Name: "lamp_entity_id", EntityID: lampKeywords, Exclude: lampIgnore, Target: models.DiscoveryTargetLamp, Priority: 50},
+		models.DiscoveryRule{Name: "lamp_friendly_name", FriendlyName: lampKeywords, Exclude: lampIgnore, Target: models.DiscoveryTargetLamp, Priority: 50},
+	)
+
+	substringRules := func(targets []string, priority int) {
+		for _, target := range targets {
+			quoted := make([]string, len(models.InputNumberSubstrings[target]))
+			for i, substr := range models.InputNumberSubstrings[target] {
+				quoted[i] = regexp.QuoteMeta(substr)
+			}
+			pattern := strings.Join(quoted, "|")
+			rules = append(rules,
+				models.DiscoveryRule{Name: target + "_entity_id", EntityID: pattern, Target: target, Priority: priority},
+				models.DiscoveryRule{Name: target + "_friendly_name", FriendlyName: pattern, Target: target, Priority: priority},
+			)
+		}
+	}
+	substringRules(wateringTargets, 40)
+	substringRules(climateTargets, 30)
+
+	return rules
+}
+
+// LoadDiscoveryRulesFile reads discovery rules from a YAML file with a top-level rules list
+func LoadDiscoveryRulesFile(filePath string) ([]models.DiscoveryRule, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discovery rules: %v", err)
+	}
+
+	var file struct {
+		Rules []models.DiscoveryRule `yaml:"rules"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery rules %s: %v", filePath, err)
+	}
+	if _, err := compileDiscoveryRules(file.Rules); err != nil {
+		return nil, fmt.Errorf("invalid discovery rules %s: %v", filePath, err)
+	}
+	return file.Rules, nil
+}
+
+// discoveryMatch is the outcome of classifying an entity
+type discoveryMatch struct {
+	Target string // input number type, lamp, switch, thermostat, exclude or unrecognised
+	Rule   string // name of the matching rule, empty when none matched
+	Suffix string // suffix captured by the rule, empty when the rule has no suffix group
+}
+
+// compiledRule is a validated rule with its patterns compiled
+type compiledRule struct {
+	models.DiscoveryRule
+	entityID     func(string) (bool, []string)
+	friendlyName func(string) (bool, []string)
+	exclude      func(string) (bool, []string)
+	suffixIndex  int // index of the suffix capture group, -1 when the rule has none
+}
+
+// compileDiscoveryRules validates rules and orders them by descending priority, keeping the
+// given order for equal priorities
+func compileDiscoveryRules(rules []models.DiscoveryRule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rules[%d]", i)
+			rule.Name = name
+		}
+
+		if rule.EntityID == "" && rule.FriendlyName == "" {
+			return nil, fmt.Errorf("rule %s: entity_id or friendly_name pattern is required", name)
+		}
+		if !isDiscoveryTarget(rule.Target) {
+			return nil, fmt.Errorf("rule %s: invalid target %q", name, rule.Target)
+		}
+
+		c := compiledRule{DiscoveryRule: rule, suffixIndex: -1}
+		var err error
+		if c.entityID, err = compilePattern(rule.Match, rule.EntityID); err != nil {
+			return nil, fmt.Errorf("rule %s: entity_id: %v", name, err)
+		}
+		if c.friendlyName, err = compilePattern(rule.Match, rule.FriendlyName); err != nil {
+			return nil, fmt.Errorf("rule %s: friendly_name: %v", name, err)
+		}
+		if c.exclude, err = compilePattern(rule.Match, rule.Exclude); err != nil {
+			return nil, fmt.Errorf("rule %s: exclude: %v", name, err)
+		}
+
+		if rule.SuffixGroup != "" {
+			if rule.Match == models.DiscoveryMatchGlob || rule.EntityID == "" {
+				return nil, fmt.Errorf("rule %s: suffix_group needs an entity_id regex", name)
+			}
+			re := regexp.MustCompile("(?i)" + rule.EntityID) // compiled above
+			if index, err := strconv.Atoi(rule.SuffixGroup); err == nil {
+				c.suffixIndex = index
+			} else {
+				c.suffixIndex = re.SubexpIndex(rule.SuffixGroup)
+			}
+			if c.suffixIndex <= 0 || c.suffixIndex > re.NumSubexp() {
+				return nil, fmt.Errorf("rule %s: entity_id has no capture group %q", name, rule.SuffixGroup)
+			}
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	sort.SliceStable(compiled, func(i, j int) bool {
+		return compiled[i].Priority > compiled[j].Priority
+	})
+	return compiled, nil
+}
+
+// compilePattern returns a case-insensitive matcher for a regex or glob pattern, or nil for an empty pattern.
+// Regex matchers also return the submatches.
+func compilePattern(match, pattern string) (func(string) (bool, []string), error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	switch match {
+	case "", models.DiscoveryMatchRegex:
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(value string) (bool, []string) {
+			submatches := re.FindStringSubmatch(value)
+			return submatches != nil, submatches
+		}, nil
+	case models.DiscoveryMatchGlob:
+		pattern = strings.ToLower(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		return func(value string) (bool, []string) {
+			matched, _ := path.Match(pattern, strings.ToLower(value))
+			return matched, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid match %q, expected regex or glob", match)
+	}
+}
+
+// isDiscoveryTarget reports whether target is a valid rule target
+func isDiscoveryTarget(target string) bool {
+	switch target {
+	case models.DiscoveryTargetLamp, models.DiscoveryTargetSwitch, models.DiscoveryTargetThermostat,
+		models.DiscoveryTargetExclude, models.InputNumberUnrecognised:
+		return true
+	}
+	return slices.Contains(climateTargets, target) || slices.Contains(wateringTargets, target)
+}
+
+// match applies the rule to an entity
+func (r *compiledRule) match(entityID, friendlyName string) (discoveryMatch, bool) {
+	var submatches []string
+	if r.entityID != nil {
+		matched, groups := r.entityID(entityID)
+		if !matched {
+			return discoveryMatch{}, false
+		}
+		submatches = groups
+	}
+	if r.friendlyName != nil {
+		if matched, _ := r.friendlyName(friendlyName); !matched {
+			return discoveryMatch{}, false
+		}
+	}
+	if r.exclude != nil {
+		if excluded, _ := r.exclude(entityID); excluded {
+			return discoveryMatch{}, false
+		}
+		if excluded, _ := r.exclude(friendlyName); excluded {
+			return discoveryMatch{}, false
+		}
+	}
+
+	result := discoveryMatch{Target: r.Target, Rule: r.Name}
+	if r.suffixIndex > 0 && r.suffixIndex < len(submatches) {
+		result.Suffix = strings.ToLower(submatches[r.suffixIndex])
+	}
+	return result, true
+}
+
+// DiscoveryRuleSet holds the rules shared by the discovery of all instances. Rules synced from
+// the backend take precedence over the base rules from the rules file or the defaults.
+type DiscoveryRuleSet struct {
+	mu         sync.RWMutex
+	base       []models.DiscoveryRule
+	baseSource string
+	backend    []models.DiscoveryRule
+	compiled   []compiledRule
+}
+
+// NewDiscoveryRuleSet creates a rule set holding the default rules
+func NewDiscoveryRuleSet() *DiscoveryRuleSet {
+	rs := &DiscoveryRuleSet{}
+	if err := rs.SetBase(DefaultDiscoveryRules(), models.DiscoveryRulesDefault); err != nil {
+		panic(fmt.Sprintf("invalid default discovery rules: %v", err))
+	}
+	return rs
+}
+
+// SetBase replaces the rules used while the backend defines none
+func (rs *DiscoveryRuleSet) SetBase(rules []models.DiscoveryRule, source string) error {
+	compiled, err := compileDiscoveryRules(rules)
+	if err != nil {
+		return err
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.base = rules
+	rs.baseSource = source
+	if len(rs.backend) == 0 {
+		rs.compiled = compiled
+	}
+	return nil
+}
+
+// SetBackend replaces the rules defined in the backend. An empty list falls back to the base rules.
+func (rs *DiscoveryRuleSet) SetBackend(rules []models.DiscoveryRule) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	active := rules
+	if len(active) == 0 {
+		active = rs.base
+	}
+	compiled, err := compileDiscoveryRules(active)
+	if err != nil {
+		return err
+	}
+	rs.backend = rules
+	rs.compiled = compiled
+	return nil
+}
+
+// Rules returns the active rules in evaluation order and where they come from
+func (rs *DiscoveryRuleSet) Rules() ([]models.DiscoveryRule, string) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	rules := make([]models.DiscoveryRule, len(rs.compiled))
+	for i, rule := range rs.compiled {
+		rules[i] = rule.DiscoveryRule
+	}
+	source := rs.baseSource
+	if len(rs.backend) > 0 {
+		source = models.DiscoveryRulesBackend
+	}
+	return rules, source
+}
+
+// match classifies an entity with the first matching rule, entities no rule matches are unrecognised
+func (rs *DiscoveryRuleSet) match(entityID, friendlyName string) discoveryMatch {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	for i := range rs.compiled {
+		if result, ok := rs.compiled[i].match(entityID, friendlyName); ok {
+			return result
+		}
+	}
+	return discoveryMatch{Target: models.InputNumberUnrecognised}
+}
+
+// NewPreviewRuleSet compiles rules for a discovery preview without installing them anywhere
+func NewPreviewRuleSet(rules []models.DiscoveryRule) (*DiscoveryRuleSet, error) {
+	compiled, err := compileDiscoveryRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &DiscoveryRuleSet{base: rules, baseSource: "preview", compiled: compiled}, nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"testing"
+
+	"local_api_v2/internal/models"
+)
+
+// customDiscoveryRules exercises the rule features besides the defaults
+func customDiscoveryRules() []models.DiscoveryRule {
+	return []models.DiscoveryRule{
+		{Name: "generic_temp", EntityID: "temp", Target: models.InputNumberTempNight, Priority: 5},
+		{Name: "temp_day", EntityID: "temp_day", Target: models.InputNumberTempDay, Priority: 15},
+		{Name: "room_temp", EntityID: `^input_number\.t_day_(?P<room>[a-z0-9]+)$`, SuffixGroup: "room", Target: models.InputNumberTempDay, Priority: 50},
+		{Name: "numbered_humidity", EntityID: `^input_number\.h_night_([a-z0-9]+)$`, SuffixGroup: "1", Target: models.InputNumberHumidityNight, Priority: 50},
+		{Name: "co2_glob", Match: models.DiscoveryMatchGlob, EntityID: "number.co2_*", Target: models.InputNumberCO2Day, Priority: 20},
+		{Name: "drop_spare", EntityID: "spare", Target: models.DiscoveryTargetExclude, Priority: 90},
+		{Name: "lamp_name", FriendlyName: "lamp", Exclude: "night", Target: models.DiscoveryTargetLamp, Priority: 10},
+	}
+}
+
+func TestClassify(t *testing.T) {
+	defaults := NewDiscoveryRuleSet()
+	custom, err := NewPreviewRuleSet(customDiscoveryRules())
+	if err != nil {
+		t.Fatalf("NewPreviewRuleSet: %v", err)
+	}
+
+	discovery := NewDiscoveryService(nil)
+	discovery.SetChamberSuffixes([]string{"sb1", "sb4"})
+
+	tests := []struct {
+		name         string
+		rules        *DiscoveryRuleSet
+		entityID     string
+		friendlyName string
+		wantTarget   string
+		wantRule     string
+		wantSuffix   string
+	}{
+		{name: "default substring", rules: defaults, entityID: "input_number.temp_day_sb1", friendlyName: "Temp day",
+			wantTarget: models.InputNumberTempDay, wantRule: models.InputNumberTempDay + "_entity_id", wantSuffix: "sb1"},
+		{name: "default friendly name", rules: defaults, entityID: "input_number.setpoint_1_sb4", friendlyName: "CO2_day",
+			wantTarget: models.InputNumberCO2Day, wantRule: models.InputNumberCO2Day + "_friendly_name", wantSuffix: "sb4"},
+		{name: "default exclusion", rules: defaults, entityID: "input_number.test_temp_day_sb1", friendlyName: "Test temp day",
+			wantTarget: models.DiscoveryTargetExclude, wantRule: "exclude_prog_test"},
+		{name: "default domain", rules: defaults, entityID: "light.lamp_1_sb1", friendlyName: "Lamp 1",
+			wantTarget: models.DiscoveryTargetLamp, wantRule: "light_domain", wantSuffix: "sb1"},
+		{name: "default lamp keyword ignored", rules: defaults, entityID: "input_number.lamp_day_sb1", friendlyName: "Lamp day",
+			wantTarget: models.InputNumberUnrecognised, wantSuffix: "sb1"},
+		{name: "chamber of another instance", rules: defaults, entityID: "input_number.temp_day_galo", friendlyName: "Temp day",
+			wantTarget: models.InputNumberTempDay, wantRule: models.InputNumberTempDay + "_entity_id"},
+		{name: "no rule matches", rules: defaults, entityID: "input_number.pressure_sb1", friendlyName: "Pressure",
+			wantTarget: models.InputNumberUnrecognised, wantSuffix: "sb1"},
+
+		{name: "higher priority listed later", rules: custom, entityID: "input_number.temp_day_sb1",
+			wantTarget: models.InputNumberTempDay, wantRule: "temp_day", wantSuffix: "sb1"},
+		{name: "lower priority fallback", rules: custom, entityID: "input_number.temp_sb1",
+			wantTarget: models.InputNumberTempNight, wantRule: "generic_temp", wantSuffix: "sb1"},
+		{name: "named suffix group", rules: custom, entityID: "input_number.t_day_sb4",
+			wantTarget: models.InputNumberTempDay, wantRule: "room_temp", wantSuffix: "sb4"},
+		{name: "captured suffix of another instance", rules: custom, entityID: "input_number.t_day_galo",
+			wantTarget: models.InputNumberTempDay, wantRule: "room_temp"},
+		{name: "numbered suffix group, case insensitive", rules: custom, entityID: "input_number.h_night_SB1",
+			wantTarget: models.InputNumberHumidityNight, wantRule: "numbered_humidity", wantSuffix: "sb1"},
+		{name: "glob", rules: custom, entityID: "number.co2_day_sb1",
+			wantTarget: models.InputNumberCO2Day, wantRule: "co2_glob", wantSuffix: "sb1"},
+		{name: "glob case insensitive", rules: custom, entityID: "NUMBER.CO2_DAY_SB4",
+			wantTarget: models.InputNumberCO2Day, wantRule: "co2_glob", wantSuffix: "sb4"},
+		{name: "glob matches the whole entity ID", rules: custom, entityID: "input_number.co2_day_sb1",
+			wantTarget: models.InputNumberUnrecognised, wantSuffix: "sb1"},
+		{name: "exclusion rule wins by priority", rules: custom, entityID: "input_number.spare_temp_day_sb1",
+			wantTarget: models.DiscoveryTargetExclude, wantRule: "drop_spare"},
+		{name: "friendly name", rules: custom, entityID: "input_number.ch_2_sb4", friendlyName: "Lamp 2",
+			wantTarget: models.DiscoveryTargetLamp, wantRule: "lamp_name", wantSuffix: "sb4"},
+		{name: "exclude pattern", rules: custom, entityID: "input_number.ch_3_sb4", friendlyName: "Lamp night",
+			wantTarget: models.InputNumberUnrecognised, wantSuffix: "sb4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := discovery.classify(tt.rules, tt.entityID, tt.friendlyName)
+			if got.Target != tt.wantTarget || got.Rule != tt.wantRule || got.Suffix != tt.wantSuffix {
+				t.Errorf("classify(%q, %q) = %+v, want target %q, rule %q, suffix %q",
+					tt.entityID, tt.friendlyName, got, tt.wantTarget, tt.wantRule, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestDiscoveryRuleSetPrecedence(t *testing.T) {
+	fileRules := []models.DiscoveryRule{{Name: "file_temp", EntityID: "temp", Target: models.InputNumberTempDay}}
+	backendRules := []models.DiscoveryRule{{Name: "backend_temp", EntityID: "temp", Target: models.InputNumberTempNight}}
+
+	discovery := NewDiscoveryService(nil)
+	discovery.SetChamberSuffixes([]string{"sb1"})
+	rules := NewDiscoveryRuleSet()
+
+	steps := []struct {
+		name       string
+		apply      func() error
+		wantRule   string
+		wantSource string
+	}{
+		{name: "defaults", apply: func() error { return nil },
+			wantRule: models.InputNumberTempDay + "_entity_id", wantSource: models.DiscoveryRulesDefault},
+		{name: "file replaces defaults", apply: func() error { return rules.SetBase(fileRules, models.DiscoveryRulesFile) },
+			wantRule: "file_temp", wantSource: models.DiscoveryRulesFile},
+		{name: "backend overrides file", apply: func() error { return rules.SetBackend(backendRules) },
+			wantRule: "backend_temp", wantSource: models.DiscoveryRulesBackend},
+		{name: "file reload keeps backend rules", apply: func() error { return rules.SetBase(fileRules, models.DiscoveryRulesFile) },
+			wantRule: "backend_temp", wantSource: models.DiscoveryRulesBackend},
+		{name: "no backend rules fall back to file", apply: func() error { return rules.SetBackend(nil) },
+			wantRule: "file_temp", wantSource: models.DiscoveryRulesFile},
+		{name: "invalid backend rules are ignored", apply: func() error {
+			if err := rules.SetBackend([]models.DiscoveryRule{{Name: "broken", EntityID: "(", Target: models.InputNumberTempDay}}); err == nil {
+				t.Error("SetBackend accepted an invalid regex")
+			}
+			return nil
+		}, wantRule: "file_temp", wantSource: models.DiscoveryRulesFile},
+	}
+
+	for _, step := range steps {
+		if err := step.apply(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := discovery.classify(rules, "input_number.temp_day_sb1", "Temp day"); got.Rule != step.wantRule {
+			t.Errorf("%s: classified by rule %q, want %q", step.name, got.Rule, step.wantRule)
+		}
+		if _, source := rules.Rules(); source != step.wantSource {
+			t.Errorf("%s: rules from %q, want %q", step.name, source, step.wantSource)
+		}
+	}
+}
+
+func TestCompileDiscoveryRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule models.DiscoveryRule
+	}{
+		{name: "no pattern", rule: models.DiscoveryRule{Target: models.InputNumberTempDay}},
+		{name: "unknown target", rule: models.DiscoveryRule{EntityID: "temp", Target: "heater"}},
+		{name: "invalid regex", rule: models.DiscoveryRule{EntityID: "temp(", Target: models.InputNumberTempDay}},
+		{name: "invalid glob", rule: models.DiscoveryRule{Match: models.DiscoveryMatchGlob, EntityID: "temp[", Target: models.InputNumberTempDay}},
+		{name: "unknown match", rule: models.DiscoveryRule{Match: "prefix", EntityID: "temp", Target: models.InputNumberTempDay}},
+		{name: "suffix group on glob", rule: models.DiscoveryRule{Match: models.DiscoveryMatchGlob, EntityID: "temp_*", SuffixGroup: "1", Target: models.InputNumberTempDay}},
+		{name: "suffix group without entity_id", rule: models.DiscoveryRule{FriendlyName: "temp", SuffixGroup: "1", Target: models.InputNumberTempDay}},
+		{name: "missing named group", rule: models.DiscoveryRule{EntityID: `temp_(?P<room>\w+)`, SuffixGroup: "chamber", Target: models.InputNumberTempDay}},
+		{name: "group number out of range", rule: models.DiscoveryRule{EntityID: `temp_(\w+)`, SuffixGroup: "2", Target: models.InputNumberTempDay}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileDiscoveryRules([]models.DiscoveryRule{tt.rule}); err == nil {
+				t.Errorf("compileDiscoveryRules accepted %+v", tt.rule)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"testing"
+
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/homeassistant/hatest"
+)
+
+// newChamberServer returns a fake Home Assistant with the controls of chambers sb1 and sb4,
+// an entity of the unconfigured chamber galo, a test entity and a sensor
+func newChamberServer(t *testing.T) *hatest.Server {
+	t.Helper()
+	server := hatest.NewServer("")
+	t.Cleanup(server.Close)
+
+	server.AddInputNumber("input_number.temp_day_sb1", "Temp day", 22, 10, 35, 0.5)
+	server.AddInputNumber("input_number.hum_night_sb1", "Humidity night", 60, 30, 90, 1)
+	server.AddInputNumber("input_number.day_watering_sb1_1", "Watering start", 8, 0, 23, 1)
+	server.AddInputNumber("input_number.test_temp_day_sb1", "Test temp day", 22, 10, 35, 0.5)
+	server.AddNumber("number.co2_day_sb1", "CO2 day", 800, 400, 2000, 10)
+	server.AddLight("light.lamp_1_sb1", "Lamp 1", 60)
+	server.AddSwitch("switch.pump_sb1", "Pump", true)
+	server.AddClimate("climate.heater_sb1", "Heater", 20, 15, 30, 0.5)
+	server.AddInputNumber("input_number.temp_day_sb4", "Temp day", 24, 10, 35, 0.5)
+	server.AddInputNumber("input_number.temp_day_galo", "Temp day", 23, 10, 35, 0.5)
+	server.AddSensor("sensor.temperature_sb1", "Temperature", "temperature", "°C", 21.4)
+	return server
+}
+
+func TestDiscoverChamberEntitiesBySuffix(t *testing.T) {
+	server := newChamberServer(t)
+	discovery := NewDiscoveryService(server.Client())
+	discovery.SetChamberSuffixes([]string{"sb1", "sb4"})
+
+	rooms, err := discovery.DiscoverChamberEntities()
+	if err != nil {
+		t.Fatalf("DiscoverChamberEntities: %v", err)
+	}
+	if len(rooms) != 2 || rooms["sb1"] == nil || rooms["sb4"] == nil {
+		t.Fatalf("discovered rooms %v, want sb1 and sb4", roomSuffixes(rooms))
+	}
+
+	tests := []struct {
+		entityID  string
+		suffix    string
+		find      func(models.ChamberConfig, string) (models.InputNumber, bool)
+		wantValue float64
+		wantMax   float64
+	}{
+		{"input_number.temp_day_sb1", "sb1", inGroup("Temperature", "day"), 22, 35},
+		{"input_number.hum_night_sb1", "sb1", inGroup("Humidity", "night"), 60, 90},
+		{"number.co2_day_sb1", "sb1", inGroup("CO2", "day"), 800, 2000},
+		{"input_number.day_watering_sb1_1", "sb1", inWateringStart("Zone 1"), 8, 23},
+		{"light.lamp_1_sb1", "sb1", inMap("Lamps"), 60, 100},
+		{"switch.pump_sb1", "sb1", inMap("Switches"), 1, 1},
+		{"climate.heater_sb1", "sb1", inMap("Thermostats"), 20, 30},
+		{"input_number.temp_day_sb4", "sb4", inGroup("Temperature", "day"), 24, 35},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entityID, func(t *testing.T) {
+			entity, found := tt.find(rooms[tt.suffix].Config, tt.entityID)
+			if !found {
+				t.Fatalf("%s not discovered in chamber %s", tt.entityID, tt.suffix)
+			}
+			if entity.Value != tt.wantValue || entity.Max != tt.wantMax {
+				t.Errorf("%s discovered with value %v and max %v, want %v and %v",
+					tt.entityID, entity.Value, entity.Max, tt.wantValue, tt.wantMax)
+			}
+		})
+	}
+
+	// Test entities are excluded, entities of other chambers are skipped
+	for _, room := range rooms {
+		for _, entityID := range []string{"input_number.test_temp_day_sb1", "input_number.temp_day_galo"} {
+			if _, found := inGroup("Temperature", "day")(room.Config, entityID); found {
+				t.Errorf("%s discovered in chamber %s", entityID, room.RoomSuffix)
+			}
+		}
+	}
+}
+
+func TestDiscoverChamberEntitiesSuffixChange(t *testing.T) {
+	server := newChamberServer(t)
+	discovery := NewDiscoveryService(server.Client())
+
+	tests := []struct {
+		name     string
+		suffixes []string
+		want     []string
+	}{
+		{name: "no suffixes", suffixes: nil, want: nil},
+		{name: "one chamber", suffixes: []string{"sb4"}, want: []string{"sb4"}},
+		{name: "case insensitive", suffixes: []string{"GALO"}, want: []string{"galo"}},
+		{name: "unknown chamber", suffixes: []string{"oreol"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discovery.SetChamberSuffixes(tt.suffixes)
+			rooms, err := discovery.DiscoverChamberEntities()
+			if err != nil {
+				t.Fatalf("DiscoverChamberEntities: %v", err)
+			}
+			got := roomSuffixes(rooms)
+			if len(got) != len(tt.want) {
+				t.Fatalf("discovered rooms %v, want %v", got, tt.want)
+			}
+			for _, suffix := range tt.want {
+				if rooms[suffix] == nil {
+					t.Errorf("discovered rooms %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// roomSuffixes returns the suffixes of discovered rooms
+func roomSuffixes(rooms map[string]*ChamberEntities) []string {
+	suffixes := make([]string, 0, len(rooms))
+	for suffix := range rooms {
+		suffixes = append(suffixes, suffix)
+	}
+	return suffixes
+}
+
+// inGroup finds an entity in a day/night group of a chamber configuration
+func inGroup(group, period string) func(models.ChamberConfig, string) (models.InputNumber, bool) {
+	return func(config models.ChamberConfig, entityID string) (models.InputNumber, bool) {
+		groups := map[string]map[string]map[string]models.InputNumber{
+			"Temperature": config.Temperature,
+			"Humidity":    config.Humidity,
+			"CO2":         config.CO2,
+		}
+		entity, found := groups[group][period][entityID]
+		return entity, found
+	}
+}
+
+// inMap finds an entity in the lamps, switches or thermostats of a chamber configuration
+func inMap(name string) func(models.ChamberConfig, string) (models.InputNumber, bool) {
+	return func(config models.ChamberConfig, entityID string) (models.InputNumber, bool) {
+		maps := map[string]map[string]models.InputNumber{
+			"Lamps":       config.Lamps,
+			"Switches":    config.Switches,
+			"Thermostats": config.Thermostats,
+		}
+		entity, found := maps[name][entityID]
+		return entity, found
+	}
+}
+
+// inWateringStart finds the start time entity of a watering zone of a chamber configuration
+func inWateringStart(zone string) func(models.ChamberConfig, string) (models.InputNumber, bool) {
+	return func(config models.ChamberConfig, entityID string) (models.InputNumber, bool) {
+		for _, wateringZone := range config.WateringZones {
+			if wateringZone.Name == zone {
+				entity, found := wateringZone.StartTimeEntityID[entityID]
+				return entity, found
+			}
+		}
+		return models.InputNumber{}, false
+	}
+}
+
+func TestDiffChamberConfigs(t *testing.T) {
+	entity := func(entityID, name string, value float64) map[string]models.InputNumber {
+		return map[string]models.InputNumber{entityID: {EntityID: entityID, Name: name, Value: value}}
+	}
+	// base returns a configuration with a lamp, a day temperature and one watering zone
+	base := func() models.ChamberConfig {
+		return models.ChamberConfig{
+			Lamps:       entity("light.lamp_1_sb1", "Lamp 1", 60),
+			Switches:    map[string]models.InputNumber{},
+			Temperature: map[string]map[string]models.InputNumber{"day": entity("input_number.temp_day_sb1", "Temp day", 22)},
+			WateringZones: []models.WateringZone{{
+				Name:              "zone 1",
+				StartTimeEntityID: entity("input_number.day_watering_sb1_1", "Watering start", 8),
+			}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(*models.ChamberConfig)
+		want   []models.DiscoveryChange
+	}{
+		{name: "unchanged", change: func(*models.ChamberConfig) {}},
+		{
+			name: "value change not reported",
+			change: func(c *models.ChamberConfig) {
+				c.Temperature["day"] = entity("input_number.temp_day_sb1", "Temp day", 25)
+			},
+		},
+		{
+			name: "entity added",
+			change: func(c *models.ChamberConfig) {
+				c.Humidity = map[string]map[string]models.InputNumber{"night": entity("input_number.hum_night_sb1", "Humidity night", 60)}
+			},
+			want: []models.DiscoveryChange{
+				{Type: models.DiscoveryChangeAdded, EntityID: "input_number.hum_night_sb1", Name: "Humidity night", NewType: models.InputNumberHumidityNight},
+			},
+		},
+		{
+			name:   "entity removed",
+			change: func(c *models.ChamberConfig) { delete(c.Lamps, "light.lamp_1_sb1") },
+			want: []models.DiscoveryChange{
+				{Type: models.DiscoveryChangeRemoved, EntityID: "light.lamp_1_sb1", Name: "Lamp 1", PreviousType: models.DiscoveryTargetLamp},
+			},
+		},
+		{
+			name: "lamp retyped as switch",
+			change: func(c *models.ChamberConfig) {
+				c.Switches["light.lamp_1_sb1"] = c.Lamps["light.lamp_1_sb1"]
+				delete(c.Lamps, "light.lamp_1_sb1")
+			},
+			want: []models.DiscoveryChange{
+				{Type: models.DiscoveryChangeRetyped, EntityID: "light.lamp_1_sb1", Name: "Lamp 1", PreviousType: models.DiscoveryTargetLamp, NewType: models.DiscoveryTargetSwitch},
+			},
+		},
+		{
+			name: "watering zone entities",
+			change: func(c *models.ChamberConfig) {
+				c.WateringZones[0].StartTimeEntityID = nil
+				c.WateringZones[0].DurationEntityID = entity("input_number.watering_duration_sb1_1", "Watering duration", 5)
+			},
+			want: []models.DiscoveryChange{
+				{Type: models.DiscoveryChangeRemoved, EntityID: "input_number.day_watering_sb1_1", Name: "Watering start", PreviousType: models.InputNumberWateringStart},
+				{Type: models.DiscoveryChangeAdded, EntityID: "input_number.watering_duration_sb1_1", Name: "Watering duration", NewType: models.InputNumberWateringDuration},
+			},
+		},
+		{
+			name: "ordered by entity ID",
+			change: func(c *models.ChamberConfig) {
+				delete(c.Lamps, "light.lamp_1_sb1")
+				c.Switches["switch.pump_sb1"] = models.InputNumber{EntityID: "switch.pump_sb1", Name: "Pump"}
+				c.Temperature["night"] = entity("input_number.temp_night_sb1", "Temp night", 18)
+				c.Lamps["light.lamp_2_sb1"] = models.InputNumber{EntityID: "light.lamp_2_sb1", Name: "Lamp 2"}
+			},
+			want: []models.DiscoveryChange{
+				{Type: models.DiscoveryChangeAdded, EntityID: "input_number.temp_night_sb1", Name: "Temp night", NewType: models.InputNumberTempNight},
+				{Type: models.DiscoveryChangeRemoved, EntityID: "light.lamp_1_sb1", Name: "Lamp 1", PreviousType: models.DiscoveryTargetLamp},
+				{Type: models.DiscoveryChangeAdded, EntityID: "light.lamp_2_sb1", Name: "Lamp 2", NewType: models.DiscoveryTargetLamp},
+				{Type: models.DiscoveryChangeAdded, EntityID: "switch.pump_sb1", Name: "Pump", NewType: models.DiscoveryTargetSwitch},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := base()
+			tt.change(&next)
+
+			got := diffChamberConfigs(base(), next)
+			if len(got) != len(tt.want) {
+				t.Fatalf("changes %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Type != tt.want[i].Type || got[i].EntityID != tt.want[i].EntityID || got[i].Name != tt.want[i].Name ||
+					got[i].PreviousType != tt.want[i].PreviousType || got[i].NewType != tt.want[i].NewType {
+					t.Errorf("change %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
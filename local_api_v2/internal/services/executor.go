@@ -3,13 +3,17 @@ package services
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
+	"local_api_v2/internal/config"
 	"local_api_v2/internal/database"
+	"local_api_v2/internal/metrics"
 	"local_api_v2/internal/models"
-	"local_api_v2/pkg/homeassistant"
+	"local_api_v2/pkg/device"
 	"local_api_v2/pkg/ntp"
 
 	"github.com/robfig/cron/v3"
@@ -17,30 +21,86 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// deviceAPI is the part of the device driver used by the executor
+type deviceAPI interface {
+	SetValue(entityID string, value float64) error
+	GetState(entityID string) (*device.State, error)
+	GetStates() ([]device.State, error)
+	Subscribe() (<-chan device.StateChange, func(), bool)
+}
+
+// executorClock is the time source of the executor
+type executorClock interface {
+	Now() time.Time
+	NowInLocation() time.Time
+	Location() *time.Location
+	IsEnabled() bool
+	IsConnected() bool
+}
+
 // ExecutorService handles the execution of experiment phases
 type ExecutorService struct {
+	config     *config.Config
 	db         *database.MongoDB
-	haClient   *homeassistant.Client
-	ntpService *ntp.TimeService
+	driver     deviceAPI
+	ntpService executorClock
+	logger     *slog.Logger          // carries the chamber_id of this executor
+	audit      *SetpointAuditService // optional, records experiment writes in the setpoint audit log
+	outbox     *OutboxService        // optional, reports executor gaps to the backend
 	cron       *cron.Cron
 	chamberID  primitive.ObjectID // ID of the chamber this executor is responsible for
 	mu         sync.RWMutex
 	isRunning  bool
+	runMu      sync.Mutex         // serializes executions triggered by cron and by state changes
+	stopWatch  context.CancelFunc // stops the state change watcher
+
+	// Setpoint verification state, guarded by resultsMu
+	resultsMu        sync.Mutex
+	setpointResults  map[string]models.SetpointResult // entity_id -> last verified write
+	setpointFailures map[string]int                   // entity_id -> consecutive non-applied writes
+	lastApplied      map[string]float64               // entity_id -> value HA held after the last write, baseline for drift detection
+	writing          map[string]bool                  // entity_id -> write in progress, its state changes are not drift
+	pausedSince      map[primitive.ObjectID]time.Time // experiment ID -> time the executor switched to the pause profile
+	idle             bool                             // no experiment is active or paused, the idle profile applies
+	forced           map[string]bool                  // entity_id -> held at the value of an override
+	unforced         map[string]float64               // entity_id -> value the executor requested before an override forced it
+
+	reportMetrics bool // export ticks and writes as Prometheus metrics, off for simulations
 }
 
+// setpointMismatchThreshold is the number of consecutive non-applied writes
+// after which an entity is reported as mismatched in the executor status
+const setpointMismatchThreshold = 3
+
+// driftDebounce is the delay between a manual change reported over the WebSocket API
+// and the drift check it triggers, so bursts of changes are handled in one run
+const driftDebounce = 2 * time.Second
+
 // NewExecutorService creates a new executor service for a specific chamber
-func NewExecutorService(db *database.MongoDB, haClient *homeassistant.Client, chamberID primitive.ObjectID, ntpService *ntp.TimeService) *ExecutorService {
-	if db == nil || haClient == nil || ntpService == nil {
-		log.Printf("Error: Required dependencies are nil - cannot create executor service")
+func NewExecutorService(cfg *config.Config, db *database.MongoDB, driver device.Driver, chamberID primitive.ObjectID, ntpService *ntp.TimeService) *ExecutorService {
+	if cfg == nil || db == nil || driver == nil || ntpService == nil {
+		slog.Error("Required dependencies are nil, cannot create executor service")
 		return nil
 	}
 
 	return &ExecutorService{
+		config:     cfg,
 		db:         db,
-		haClient:   haClient,
+		driver:     driver,
 		ntpService: ntpService,
+		logger:     slog.Default().With("chamber_id", chamberID.Hex()),
 		cron:       cron.New(cron.WithLocation(time.Local)),
 		chamberID:  chamberID,
+
+		setpointResults:  make(map[string]models.SetpointResult),
+		setpointFailures: make(map[string]int),
+		lastApplied:      make(map[string]float64),
+		writing:          make(map[string]bool),
+		pausedSince:      make(map[primitive.ObjectID]time.Time),
+		forced:           make(map[string]bool),
+		unforced:         make(map[string]float64),
+
+		reportMetrics: true,
 	}
 }
 
@@ -51,6 +111,18 @@ func (s *ExecutorService) SetChamberID(chamberID primitive.ObjectID) {
 	s.chamberID = chamberID
 }
 
+// ChamberID returns the ID of the chamber this executor is responsible for
+func (s *ExecutorService) ChamberID() primitive.ObjectID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.chamberID
+}
+
+// SetAuditService sets the audit log that records the setpoints this executor writes for experiments
+func (s *ExecutorService) SetAuditService(audit *SetpointAuditService) {
+	s.audit = audit
+}
+
 // Start begins the execution loop
 func (s *ExecutorService) Start(ctx context.Context) error {
 	if s == nil {
@@ -69,8 +141,8 @@ func (s *ExecutorService) Start(ctx context.Context) error {
 	if s.db == nil {
 		return fmt.Errorf("database is not initialized")
 	}
-	if s.haClient == nil {
-		return fmt.Errorf("home assistant client is not initialized")
+	if s.driver == nil {
+		return fmt.Errorf("device driver is not initialized")
 	}
 	if s.cron == nil {
 		return fmt.Errorf("cron scheduler is not initialized")
@@ -79,10 +151,15 @@ func (s *ExecutorService) Start(ctx context.Context) error {
 		return fmt.Errorf("NTP service is not initialized")
 	}
 
+	// Report the days missed while the executor was not running, before the first run records a new tick
+	gapCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	s.detectGaps(gapCtx)
+	cancel()
+
 	// Add job to check and execute phases every minute
 	_, err := s.cron.AddFunc("* * * * *", func() {
 		if err := s.executeActivePhasesWrapper(ctx); err != nil {
-			log.Printf("Error executing phases for chamber %s: %v", s.chamberID.Hex(), err)
+			s.logger.Error("Error executing phases", "error", err)
 		}
 	})
 	if err != nil {
@@ -95,10 +172,21 @@ func (s *ExecutorService) Start(ctx context.Context) error {
 	// Start the cron scheduler
 	s.cron.Start()
 
+	// React to manual changes as they happen when the driver pushes state changes
+	if s.config.DriftReconciliation {
+		if changes, unsubscribe, ok := s.driver.Subscribe(); ok {
+			watchCtx, cancel := context.WithCancel(ctx)
+			s.mu.Lock()
+			s.stopWatch = cancel
+			s.mu.Unlock()
+			go s.watchStateChanges(watchCtx, changes, unsubscribe)
+		}
+	}
+
 	// Run immediately on start
 	go func() {
 		if err := s.executeActivePhasesWrapper(ctx); err != nil {
-			log.Printf("Error executing phases on start for chamber %s: %v", s.chamberID.Hex(), err)
+			s.logger.Error("Error executing phases on start", "error", err)
 		}
 	}()
 
@@ -106,14 +194,14 @@ func (s *ExecutorService) Start(ctx context.Context) error {
 	if s.ntpService.IsConnected() {
 		timeSource = "NTP"
 	}
-	log.Printf("Executor service started for chamber %s (using %s time)", s.chamberID.Hex(), timeSource)
+	s.logger.Info("Executor service started", "time_source", timeSource)
 	return nil
 }
 
 // Stop halts the execution loop
 func (s *ExecutorService) Stop() {
 	if s == nil {
-		log.Printf("Warning: Attempt to stop nil executor service")
+		slog.Warn("Attempt to stop nil executor service")
 		return
 	}
 
@@ -124,13 +212,18 @@ func (s *ExecutorService) Stop() {
 		return
 	}
 
+	if s.stopWatch != nil {
+		s.stopWatch()
+		s.stopWatch = nil
+	}
+
 	if s.cron != nil {
 		ctx := s.cron.Stop()
 		<-ctx.Done()
 	}
 
 	s.isRunning = false
-	log.Printf("Executor service stopped for chamber %s", s.chamberID.Hex())
+	s.logger.Info("Executor service stopped")
 }
 
 // executeActivePhasesWrapper wraps executeActivePhases with context checking
@@ -139,28 +232,118 @@ func (s *ExecutorService) executeActivePhasesWrapper(ctx context.Context) error
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		return s.executeActivePhases(ctx)
+		s.runMu.Lock()
+		defer s.runMu.Unlock()
+
+		start := time.Now()
+		err := s.executeActivePhases(ctx)
+		if s.reportMetrics {
+			metrics.ExecutorTickDuration.WithLabelValues(s.chamberID.Hex()).Observe(time.Since(start).Seconds())
+			if err != nil {
+				metrics.ExecutorTickErrors.WithLabelValues(s.chamberID.Hex()).Inc()
+			}
+		}
+		return err
+	}
+}
+
+// watchStateChanges runs an execution shortly after an entity managed by the executor
+// was changed outside of it, so drift is reconciled without waiting for the next tick
+func (s *ExecutorService) watchStateChanges(ctx context.Context, changes <-chan device.StateChange, unsubscribe func()) {
+	defer unsubscribe()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			if debounce == nil && s.isExternalChange(change) {
+				s.logger.Info("Manual change detected, scheduling drift check", "entity_id", change.EntityID)
+				debounce = time.After(driftDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			if err := s.executeActivePhasesWrapper(ctx); err != nil {
+				s.logger.Error("Error executing phases after manual change", "error", err)
+			}
+		}
 	}
 }
 
-// executeActivePhases finds and executes all active experiment phases for this chamber
+// isExternalChange reports whether a state change moved a managed entity away from the value
+// the executor last applied while no write of the executor was in progress
+func (s *ExecutorService) isExternalChange(change device.StateChange) bool {
+	if change.NewState == nil || !change.NewState.Available {
+		return false
+	}
+
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+
+	baseline, known := s.lastApplied[change.EntityID]
+	if !known || s.writing[change.EntityID] {
+		return false
+	}
+	return math.Abs(change.NewState.Value-baseline) > setpointTolerance
+}
+
+// executeActivePhases finds and executes all active experiment phases for this chamber.
+// Paused experiments put the chamber into its pause profile while no other experiment is active.
 func (s *ExecutorService) executeActivePhases(ctx context.Context) error {
-	// Get all active experiments for this chamber
+	// Get all active and paused experiments for this chamber
 	experiments, err := s.getActiveExperimentsForChamber(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get active experiments: %w", err)
 	}
+	defer s.recordTicks(ctx, experiments)
+
+	var active, paused []models.Experiment
+	for _, exp := range experiments {
+		if exp.Status == models.StatusPaused {
+			paused = append(paused, exp)
+		} else {
+			active = append(active, exp)
+		}
+	}
+	s.trackResumedExperiments(paused)
 
-	if len(experiments) == 0 {
+	if len(active) == 0 {
+		overrides, err := s.getActiveOverrides(ctx)
+		if err != nil {
+			s.logger.Error("Failed to load manual overrides", "error", err)
+		}
+
+		if len(paused) > 0 {
+			s.setIdle(false)
+			restored := s.applyOverrideValues(ctx, &paused[0], -1, -1, overrides)
+			profile, err := s.getPauseProfile(ctx)
+			if err != nil {
+				s.logger.Error("Failed to load pause profile, holding last setpoints", "error", err)
+			}
+			s.applyPauseProfile(ctx, paused, profile, overrides, restored)
+		} else {
+			restored := s.applyOverrideValues(ctx, &models.Experiment{}, -1, -1, overrides)
+			idleProfile, err := s.getIdleProfile(ctx)
+			if err != nil {
+				s.logger.Error("Failed to load idle profile", "error", err)
+			} else {
+				s.applyIdleProfile(ctx, idleProfile, overrides, restored)
+			}
+		}
 		return nil // No active experiments
 	}
+	s.setIdle(false)
 
-	log.Printf("Found %d active experiments for chamber %s", len(experiments), s.chamberID.Hex())
+	s.logger.Debug("Found active experiments", "count", len(active))
 
 	// Process each experiment
-	for _, exp := range experiments {
+	for _, exp := range active {
 		if err := s.processExperiment(ctx, &exp); err != nil {
-			log.Printf("Error processing experiment %s: %v", exp.Title, err)
+			s.logger.Error("Error processing experiment", "experiment_id", exp.ID.Hex(), "experiment", exp.Title, "error", err)
 			// Continue with other experiments even if one fails
 		}
 	}
@@ -168,10 +351,10 @@ func (s *ExecutorService) executeActivePhases(ctx context.Context) error {
 	return nil
 }
 
-// getActiveExperimentsForChamber retrieves all active experiments for this chamber
+// getActiveExperimentsForChamber retrieves all active and paused experiments for this chamber
 func (s *ExecutorService) getActiveExperimentsForChamber(ctx context.Context) ([]models.Experiment, error) {
 	filter := bson.M{
-		"status":     "active",
+		"status":     bson.M{"$in": []string{models.StatusActive, models.StatusPaused}},
 		"chamber_id": s.chamberID,
 	}
 
@@ -189,12 +372,246 @@ func (s *ExecutorService) getActiveExperimentsForChamber(ctx context.Context) ([
 	return experiments, nil
 }
 
+// trackResumedExperiments forgets experiments that are no longer paused and logs their resumption
+func (s *ExecutorService) trackResumedExperiments(paused []models.Experiment) {
+	stillPaused := make(map[primitive.ObjectID]bool, len(paused))
+	for _, exp := range paused {
+		stillPaused[exp.ID] = true
+	}
+
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+
+	for experimentID, since := range s.pausedSince {
+		if !stillPaused[experimentID] {
+			s.logger.Info("Experiment left the pause profile", "experiment_id", experimentID.Hex(), "paused_for", s.ntpService.Now().Sub(since).Round(time.Second))
+			delete(s.pausedSince, experimentID)
+		}
+	}
+}
+
+// applyPauseProfile puts the chamber into the pause profile of its configuration.
+// In hold mode the last applied setpoints are left alone, in safe mode the profile values are written.
+// Entities whose override ended get the value back the executor requested before the override.
+func (s *ExecutorService) applyPauseProfile(ctx context.Context, paused []models.Experiment, profile models.PauseProfile, overrides map[string]models.ManualOverride, restored map[string]float64) {
+	exp := &paused[0]
+	now := s.ntpService.Now()
+
+	s.resultsMu.Lock()
+	_, known := s.pausedSince[exp.ID]
+	if !known {
+		s.pausedSince[exp.ID] = now
+	}
+	s.resultsMu.Unlock()
+
+	if !known {
+		s.logger.Info("Experiment is paused, switching to pause profile", "experiment_id", exp.ID.Hex(), "experiment", exp.Title, "mode", profile.Mode)
+	}
+
+	var values map[string]float64
+	if profile.Mode == models.PauseModeSafe {
+		values = withoutOverridden(profile.Values, overrides)
+	}
+	values = withRestored(values, restored)
+	if len(values) == 0 {
+		return
+	}
+
+	phaseIndex := -1
+	if exp.ActivePhaseIndex != nil {
+		phaseIndex = *exp.ActivePhaseIndex
+	}
+	s.applyProfileValues(ctx, exp, phaseIndex, "pause profile", values)
+}
+
+// applyIdleProfile writes the idle profile of this chamber while no experiment is active or paused,
+// so a finished run does not leave the chamber at its last setpoints. Entities whose override ended
+// and that the profile does not cover get the value back the executor requested before the override.
+func (s *ExecutorService) applyIdleProfile(ctx context.Context, idleProfile map[string]float64, overrides map[string]models.ManualOverride, restored map[string]float64) {
+	if s.setIdle(true) {
+		if len(idleProfile) == 0 {
+			s.logger.Info("No active experiment and no idle profile configured, keeping last setpoints")
+		} else {
+			s.logger.Info("No active experiment, applying idle profile")
+		}
+	}
+
+	values := withRestored(withoutOverridden(idleProfile, overrides), restored)
+	if len(values) > 0 {
+		s.applyProfileValues(ctx, &models.Experiment{}, -1, "idle profile", values)
+	}
+}
+
+// setIdle records whether the idle profile applies and reports whether this is a change to idle
+func (s *ExecutorService) setIdle(idle bool) bool {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+
+	entered := idle && !s.idle
+	s.idle = idle
+	return entered
+}
+
+// withoutOverridden returns the profile values of the entities that are not under manual override
+func withoutOverridden(values map[string]float64, overrides map[string]models.ManualOverride) map[string]float64 {
+	if len(overrides) == 0 {
+		return values
+	}
+
+	result := make(map[string]float64, len(values))
+	for entityID, value := range values {
+		if _, overridden := overrides[entityID]; !overridden {
+			result[entityID] = value
+		}
+	}
+	return result
+}
+
+// applyOverrideValues writes the values of overrides that force an entity to a value.
+// Entities whose override ended are reported here and get their scheduled value back
+// from the regular phase writes. Outside of a running phase nothing schedules them, so
+// the values the executor requested before the overrides are returned for the pause
+// and idle profiles to restore.
+func (s *ExecutorService) applyOverrideValues(ctx context.Context, exp *models.Experiment, phaseIndex, day int, overrides map[string]models.ManualOverride) map[string]float64 {
+	values := make(map[string]float64)
+	for entityID, override := range overrides {
+		if override.Value != nil {
+			values[entityID] = *override.Value
+		}
+	}
+
+	restored := make(map[string]float64)
+	s.resultsMu.Lock()
+	for entityID := range s.forced {
+		if _, forced := values[entityID]; !forced {
+			s.logger.Info("Override ended, restoring scheduled value", "entity_id", entityID)
+			delete(s.forced, entityID)
+			if value, known := s.unforced[entityID]; known {
+				restored[entityID] = value
+				delete(s.unforced, entityID)
+			}
+		}
+	}
+	for entityID := range values {
+		if !s.forced[entityID] {
+			s.logger.Info("Override active", "entity_id", entityID, "value", values[entityID], "reason", overrides[entityID].Reason)
+			s.forced[entityID] = true
+			if last, exists := s.setpointResults[entityID]; exists {
+				s.unforced[entityID] = last.RequestedValue
+			}
+		}
+	}
+	s.resultsMu.Unlock()
+
+	if len(values) > 0 {
+		s.writeMissingValues(ctx, exp, phaseIndex, day, "override", values)
+	}
+	return restored
+}
+
+// withRestored adds the values to restore after ended overrides to profile values,
+// entities the profile covers keep their profile value
+func withRestored(values, restored map[string]float64) map[string]float64 {
+	if len(restored) == 0 {
+		return values
+	}
+
+	result := make(map[string]float64, len(values)+len(restored))
+	for entityID, value := range restored {
+		result[entityID] = value
+	}
+	for entityID, value := range values {
+		result[entityID] = value
+	}
+	return result
+}
+
+// applyProfileValues writes the values of a chamber profile to all entities that do not hold them yet
+func (s *ExecutorService) applyProfileValues(ctx context.Context, exp *models.Experiment, phaseIndex int, group string, values map[string]float64) {
+	s.writeMissingValues(ctx, exp, phaseIndex, -1, group, values)
+}
+
+// writeMissingValues writes values to all entities that do not hold them yet.
+// Values Home Assistant adjusted on the last write are not written again.
+func (s *ExecutorService) writeMissingValues(ctx context.Context, exp *models.Experiment, phaseIndex, day int, group string, values map[string]float64) {
+	states, err := s.driver.GetStates()
+	if err != nil {
+		s.logger.Error("Failed to read states", "group", group, "error", err)
+		return
+	}
+
+	current := make(map[string]float64, len(states))
+	for _, state := range states {
+		if state.Available {
+			current[state.EntityID] = state.Value
+		}
+	}
+
+	entityIDs := make([]string, 0, len(values))
+	for entityID := range values {
+		entityIDs = append(entityIDs, entityID)
+	}
+	sort.Strings(entityIDs)
+
+	for _, entityID := range entityIDs {
+		value := values[entityID]
+		if observed, exists := current[entityID]; exists && s.holdsValue(entityID, value, observed) {
+			continue
+		}
+
+		setpoint := plannedSetpoint{EntityID: entityID, Value: value, Group: group}
+		result, err := s.applySetpoint(setpoint.EntityID, setpoint.Value)
+		s.recordApplication(ctx, exp, phaseIndex, day, setpoint, result)
+		if err != nil {
+			s.logger.Error("Failed to set value", "entity_id", entityID, "group", group, "error", err)
+		}
+	}
+}
+
+// holdsValue reports whether an entity holds the requested value, or the value Home Assistant
+// stored for it when the executor last requested it
+func (s *ExecutorService) holdsValue(entityID string, requested, observed float64) bool {
+	if math.Abs(observed-requested) <= setpointTolerance {
+		return true
+	}
+
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+
+	last, exists := s.setpointResults[entityID]
+	return exists && last.Status != models.SetpointFailed && last.ActualValue != nil &&
+		math.Abs(last.RequestedValue-requested) <= setpointTolerance &&
+		math.Abs(*last.ActualValue-observed) <= setpointTolerance
+}
+
+// getPauseProfile loads the pause profile of this chamber, defaulting to hold mode
+func (s *ExecutorService) getPauseProfile(ctx context.Context) (models.PauseProfile, error) {
+	var chamber models.Chamber
+	if err := s.db.ChambersCollection.FindOne(ctx, bson.M{"_id": s.chamberID}).Decode(&chamber); err != nil {
+		return models.PauseProfile{Mode: models.PauseModeHold}, err
+	}
+
+	if chamber.Config.PauseProfile == nil || chamber.Config.PauseProfile.Mode == "" {
+		return models.PauseProfile{Mode: models.PauseModeHold}, nil
+	}
+	return *chamber.Config.PauseProfile, nil
+}
+
+// getIdleProfile loads the idle profile of this chamber, nil when none is configured
+func (s *ExecutorService) getIdleProfile(ctx context.Context) (map[string]float64, error) {
+	var chamber models.Chamber
+	if err := s.db.ChambersCollection.FindOne(ctx, bson.M{"_id": s.chamberID}).Decode(&chamber); err != nil {
+		return nil, err
+	}
+	return chamber.Config.IdleProfile, nil
+}
+
 // processExperiment processes a single experiment using NTP time
 func (s *ExecutorService) processExperiment(ctx context.Context, exp *models.Experiment) error {
 	// Determine current phase based on schedule using NTP time
-	currentPhase, phaseIndex, currentDay := s.getCurrentPhaseWithDay(exp)
+	currentPhase, phaseIndex, at := s.getCurrentPhaseWithDay(exp)
 	if currentPhase == nil {
-		log.Printf("No active phase found for experiment %s", exp.Title)
+		s.logger.Debug("No active phase found", "experiment_id", exp.ID.Hex(), "experiment", exp.Title)
 		return nil
 	}
 
@@ -202,78 +619,322 @@ func (s *ExecutorService) processExperiment(ctx context.Context, exp *models.Exp
 	if s.ntpService.IsConnected() {
 		timeSource = "NTP"
 	}
-	log.Printf("Executing phase %d (%s) for experiment %s, day %d (using %s time)",
-		phaseIndex, currentPhase.Title, exp.Title, currentDay, timeSource)
+	s.logger.Debug("Executing phase",
+		"experiment_id", exp.ID.Hex(),
+		"experiment", exp.Title,
+		"phase_index", phaseIndex,
+		"phase", currentPhase.Title,
+		"day", at.Day,
+		"time_source", timeSource)
 
 	// Update the active phase index if changed
 	if exp.ActivePhaseIndex == nil || *exp.ActivePhaseIndex != phaseIndex {
 		exp.ActivePhaseIndex = &phaseIndex
 		if err := s.updateExperimentActivePhase(ctx, exp); err != nil {
-			log.Printf("Failed to update active phase index: %v", err)
+			s.logger.Error("Failed to update active phase index", "experiment_id", exp.ID.Hex(), "error", err)
 		}
 	}
 
+	overrides, err := s.getActiveOverrides(ctx)
+	if err != nil {
+		s.logger.Error("Failed to load manual overrides", "error", err)
+	}
+
+	// Write forced values first, so they are the drift reference of their entities
+	s.applyOverrideValues(ctx, exp, phaseIndex, at.Day, overrides)
+
+	// Detect values changed by hand since the last tick
+	if s.config.DriftReconciliation {
+		s.reconcileDrift(ctx, exp, phaseIndex, at.Day, phaseSetpoints(currentPhase, at), overrides)
+	}
+
 	// Apply phase settings to Home Assistant
-	return s.applyPhaseSettings(currentPhase, currentDay)
+	return s.applyPhaseSettings(ctx, exp, phaseIndex, currentPhase, at, overrides)
+}
+
+// getActiveOverrides returns the manual overrides currently in effect for this chamber, keyed by entity ID
+func (s *ExecutorService) getActiveOverrides(ctx context.Context) (map[string]models.ManualOverride, error) {
+	now := s.ntpService.Now()
+	filter := bson.M{
+		"chamber_id": s.chamberID,
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": nil},
+			{"expires_at": bson.M{"$gt": now}},
+		},
+	}
+
+	cursor, err := s.db.OverridesCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var overrides []models.ManualOverride
+	if err := cursor.All(ctx, &overrides); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]models.ManualOverride, len(overrides))
+	for _, override := range overrides {
+		result[override.EntityID] = override
+	}
+
+	return result, nil
+}
+
+// reconcileDrift compares the current Home Assistant values with the values the executor last
+// applied and records a drift event for every entity that was changed outside of the executor.
+// Drifted entities are restored by the following applyPhaseSettings call unless they are overridden.
+func (s *ExecutorService) reconcileDrift(ctx context.Context, exp *models.Experiment, phaseIndex, currentDay int, setpoints []plannedSetpoint, overrides map[string]models.ManualOverride) {
+	for _, event := range s.detectDrift(exp, phaseIndex, currentDay, setpoints, overrides) {
+		if _, err := s.db.DriftEventsCollection.InsertOne(ctx, event); err != nil {
+			s.logger.Error("Failed to store drift event", "entity_id", event.EntityID, "error", err)
+		}
+	}
+}
+
+// detectDrift returns a drift event for every setpoint whose entity no longer holds the value the
+// executor last applied. Manual values of overridden entities become their new reference.
+func (s *ExecutorService) detectDrift(exp *models.Experiment, phaseIndex, currentDay int, setpoints []plannedSetpoint, overrides map[string]models.ManualOverride) []models.DriftEvent {
+	states, err := s.driver.GetStates()
+	if err != nil {
+		s.logger.Warn("Drift check skipped", "experiment_id", exp.ID.Hex(), "error", err)
+		return nil
+	}
+
+	statesByEntity := make(map[string]device.State, len(states))
+	for _, state := range states {
+		statesByEntity[state.EntityID] = state
+	}
+
+	now := s.ntpService.Now()
+
+	var events []models.DriftEvent
+	for _, setpoint := range setpoints {
+		baseline, known := s.getLastApplied(setpoint.EntityID)
+		if !known {
+			continue // nothing written since start, no reference value
+		}
+
+		state, exists := statesByEntity[setpoint.EntityID]
+		if !exists {
+			continue
+		}
+
+		observed := state.Value
+		if !state.Available || math.Abs(observed-baseline) <= setpointTolerance {
+			continue
+		}
+
+		event := models.DriftEvent{
+			ID:                  primitive.NewObjectID(),
+			ChamberID:           s.chamberID,
+			ExperimentID:        exp.ID,
+			BackendExperimentID: exp.BackendID,
+			PhaseIndex:          phaseIndex,
+			Day:                 currentDay,
+			EntityID:            setpoint.EntityID,
+			ExpectedValue:       baseline,
+			ObservedValue:       observed,
+			DetectedAt:          now,
+			Action:              models.DriftActionRestored,
+		}
+		if !state.LastChanged.IsZero() {
+			changedAt := state.LastChanged
+			event.ChangedAt = &changedAt
+		}
+
+		if _, overridden := overrides[setpoint.EntityID]; overridden {
+			// Accept the manual value as the new reference so the drift is reported only once
+			event.Action = models.DriftActionOverridden
+			s.setLastApplied(setpoint.EntityID, observed)
+		}
+
+		s.logger.Warn("Drift detected",
+			"experiment_id", exp.ID.Hex(),
+			"entity_id", setpoint.EntityID,
+			"expected", baseline,
+			"observed", observed,
+			"action", event.Action)
+
+		events = append(events, event)
+	}
+	return events
+}
+
+// getLastApplied returns the value an entity held after the executor last wrote it
+func (s *ExecutorService) getLastApplied(entityID string) (float64, bool) {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+
+	value, exists := s.lastApplied[entityID]
+	return value, exists
+}
+
+// setLastApplied updates the drift detection reference value of an entity
+func (s *ExecutorService) setLastApplied(entityID string, value float64) {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+
+	s.lastApplied[entityID] = value
+}
+
+// scheduleTime locates the current moment within an experiment schedule
+type scheduleTime struct {
+	Day         int     // day number within the phase schedule, day 1 is the local date the phase starts on
+	DayProgress float64 // elapsed fraction of the day, for interpolated daily schedules
+	MinuteOfDay int     // minutes since local midnight, for time-of-day programs
 }
 
 // getCurrentPhaseWithDay determines which phase should be active based on the schedule using NTP time
-func (s *ExecutorService) getCurrentPhaseWithDay(exp *models.Experiment) (*models.Phase, int, int) {
-	now := s.ntpService.NowInLocation()
+func (s *ExecutorService) getCurrentPhaseWithDay(exp *models.Experiment) (*models.Phase, int, scheduleTime) {
+	return phaseAt(exp, s.ntpService.NowInLocation())
+}
 
+// phaseAt determines which phase of the schedule is active at the given time.
+// Days begin at midnight in the location of now.
+func phaseAt(exp *models.Experiment, now time.Time) (*models.Phase, int, scheduleTime) {
 	for _, scheduleItem := range exp.Schedule {
 		// Parse schedule timestamps
 		startTime := time.Unix(scheduleItem.StartTimestamp, 0)
 		endTime := time.Unix(scheduleItem.EndTimestamp, 0)
-		currentDay := 0
 
-		log.Printf("Start time: %v, end time: %v", startTime, endTime)
 		if now.After(startTime) && now.Before(endTime) {
 			// Find the corresponding phase
 			if scheduleItem.PhaseIndex < len(exp.Phases) {
-				intervalDays := getDaysAndTimestamps(scheduleItem.StartTimestamp, scheduleItem.EndTimestamp)
-				for i := 0; i < len(intervalDays)-1; i++ {
-					if intervalDays[i].Timestamp < now.Unix() && intervalDays[i+1].Timestamp > now.Unix() {
-						currentDay = intervalDays[i+1].Day
-					}
+				return &exp.Phases[scheduleItem.PhaseIndex], scheduleItem.PhaseIndex, scheduleTime{
+					Day:         scheduleDay(startTime, now),
+					DayProgress: getDayProgress(now),
+					MinuteOfDay: now.Hour()*60 + now.Minute(),
 				}
-				log.Printf("Current day: %d, phase index: %d", currentDay, scheduleItem.PhaseIndex)
-				log.Print(intervalDays)
-				return &exp.Phases[scheduleItem.PhaseIndex], scheduleItem.PhaseIndex, currentDay
 			}
 		}
 	}
 
-	return nil, -1, -1
+	return nil, -1, scheduleTime{Day: -1}
 }
 
-// applyPhaseSettings applies the phase settings to Home Assistant
-func (s *ExecutorService) applyPhaseSettings(phase *models.Phase, currentDay int) error {
-	var errors []error
+// plannedSetpoint is a single value the executor wants an entity to hold
+type plannedSetpoint struct {
+	EntityID string
+	Value    float64
+	Group    string // start day config, climate controls, light controls, watering controls
+	// Interpolated values lie between schedule values and are rounded to the entity step before writing
+	Interpolated bool
+}
 
-	log.Printf("Applying phase settings for phase %s, day %d", phase.Title, currentDay)
+// phaseSetpoints returns all setpoints scheduled by a phase at the given schedule time
+func phaseSetpoints(phase *models.Phase, at scheduleTime) []plannedSetpoint {
+	var setpoints []plannedSetpoint
+	currentDay := at.Day
 
-	// Apply start day configurations
+	// Start day configurations
 	for _, startDayConfig := range phase.StartDay {
-		if err := s.haClient.SetInputNumber(startDayConfig.EntityID, startDayConfig.Value); err != nil {
-			log.Printf("Failed to set %s: %v", startDayConfig.EntityID, err)
-			errors = append(errors, fmt.Errorf("start day config %s: %w", startDayConfig.EntityID, err))
+		setpoints = append(setpoints, plannedSetpoint{startDayConfig.EntityID, startDayConfig.Value, "start day config", false})
+	}
+
+	// Climate controls: work day, temperature, humidity and CO2 day/night schedules
+	climateSchedules := []map[string]models.ScheduleConfig{
+		phase.WorkDaySchedule,
+		phase.TemperatureDaySchedule,
+		phase.TemperatureNightSchedule,
+		phase.HumidityDaySchedule,
+		phase.HumidityNightSchedule,
+		phase.CO2DaySchedule,
+		phase.CO2NightSchedule,
+	}
+	for _, schedules := range climateSchedules {
+		for _, scheduleConfig := range schedules {
+			if value, exists := scheduleValue(scheduleConfig.Schedule, currentDay, at.DayProgress, scheduleConfig.Interpolation); exists {
+				setpoints = append(setpoints, plannedSetpoint{scheduleConfig.EntityID, value, "climate controls", interpolates(scheduleConfig.Interpolation)})
+			}
+		}
+	}
+
+	// Light intensity for each lamp
+	for _, scheduleConfig := range phase.LightIntensitySchedule {
+		if value, exists := scheduleValue(scheduleConfig.Schedule, currentDay, at.DayProgress, scheduleConfig.Interpolation); exists {
+			setpoints = append(setpoints, plannedSetpoint{scheduleConfig.EntityID, value, "light controls", interpolates(scheduleConfig.Interpolation)})
 		}
 	}
 
-	// Apply climate controls based on day/night
-	if err := s.applyClimateControls(phase, currentDay); err != nil {
-		errors = append(errors, fmt.Errorf("climate controls: %w", err))
+	// On/off state of each switch, switches are never interpolated
+	for _, scheduleConfig := range phase.SwitchSchedule {
+		if value, exists := scheduleValue(scheduleConfig.Schedule, currentDay, at.DayProgress, models.InterpolationStep); exists {
+			setpoints = append(setpoints, plannedSetpoint{scheduleConfig.EntityID, value, "switch controls", false})
+		}
+	}
+
+	// Watering zone start time, period, pause between and duration
+	for _, scheduleConfig := range phase.WateringZones {
+		if value, exists := scheduleConfig.StartTimeSchedule[currentDay]; exists {
+			setpoints = append(setpoints, plannedSetpoint{scheduleConfig.StartTimeEntityID, value, "watering controls", false})
+		}
+		if value, exists := scheduleConfig.PeriodSchedule[currentDay]; exists {
+			setpoints = append(setpoints, plannedSetpoint{scheduleConfig.PeriodEntityID, value, "watering controls", false})
+		}
+		if value, exists := scheduleConfig.PauseBetweenSchedule[currentDay]; exists {
+			setpoints = append(setpoints, plannedSetpoint{scheduleConfig.PauseBetweenEntityID, value, "watering controls", false})
+		}
+		if value, exists := scheduleConfig.DurationSchedule[currentDay]; exists {
+			setpoints = append(setpoints, plannedSetpoint{scheduleConfig.DurationEntityID, value, "watering controls", false})
+		}
 	}
 
-	// Apply light controls
-	if err := s.applyLightControls(phase, currentDay); err != nil {
-		errors = append(errors, fmt.Errorf("light controls: %w", err))
+	// Time-of-day programs replace the daily schedules of their entities
+	programValues := make(map[string]float64)
+	programInterpolated := make(map[string]bool)
+	for _, program := range phase.TimeOfDaySchedule {
+		if value, exists := timeOfDayValue(program, at.MinuteOfDay); exists {
+			programValues[program.EntityID] = value
+			programInterpolated[program.EntityID] = interpolates(program.Interpolation)
+		}
+	}
+	if len(programValues) > 0 {
+		filtered := setpoints[:0]
+		for _, setpoint := range setpoints {
+			if _, replaced := programValues[setpoint.EntityID]; !replaced {
+				filtered = append(filtered, setpoint)
+			}
+		}
+		setpoints = filtered
+
+		entityIDs := make([]string, 0, len(programValues))
+		for entityID := range programValues {
+			entityIDs = append(entityIDs, entityID)
+		}
+		sort.Strings(entityIDs)
+		for _, entityID := range entityIDs {
+			setpoints = append(setpoints, plannedSetpoint{entityID, programValues[entityID], "time-of-day program", programInterpolated[entityID]})
+		}
 	}
 
-	// Apply watering controls
-	if err := s.applyWateringControls(phase, currentDay); err != nil {
-		errors = append(errors, fmt.Errorf("watering controls: %w", err))
+	return setpoints
+}
+
+// applyPhaseSettings applies the phase settings to Home Assistant, skipping entities under manual override
+func (s *ExecutorService) applyPhaseSettings(ctx context.Context, exp *models.Experiment, phaseIndex int, phase *models.Phase, at scheduleTime, overrides map[string]models.ManualOverride) error {
+	var errors []error
+
+	s.logger.Debug("Applying phase settings", "experiment_id", exp.ID.Hex(), "phase", phase.Title, "day", at.Day)
+
+	for _, setpoint := range phaseSetpoints(phase, at) {
+		if override, exists := overrides[setpoint.EntityID]; exists {
+			s.logger.Debug("Skipping overridden entity", "entity_id", setpoint.EntityID, "reason", override.Reason)
+			continue
+		}
+
+		if setpoint.Interpolated {
+			setpoint.Value = s.stepValue(setpoint.EntityID, setpoint.Value)
+		}
+
+		result, err := s.applySetpoint(setpoint.EntityID, setpoint.Value)
+		s.recordApplication(ctx, exp, phaseIndex, at.Day, setpoint, result)
+		if err != nil {
+			s.logger.Error("Failed to set value", "experiment_id", exp.ID.Hex(), "entity_id", setpoint.EntityID, "error", err)
+			errors = append(errors, fmt.Errorf("%s %s: %w", setpoint.Group, setpoint.EntityID, err))
+		}
 	}
 
 	// Update last executed time using NTP time
@@ -287,152 +948,164 @@ func (s *ExecutorService) applyPhaseSettings(phase *models.Phase, currentDay int
 	return nil
 }
 
-// applyClimateControls applies temperature, humidity, and CO2 settings
-func (s *ExecutorService) applyClimateControls(phase *models.Phase, currentDay int) error {
-	var errors []error
-
-	// Apply work day schedule
-	for _, scheduleConfig := range phase.WorkDaySchedule {
-		if value, exists := scheduleConfig.Schedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.EntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.EntityID, err)
-				errors = append(errors, err)
-			}
-		}
+// stepValue rounds an interpolated value to the step of its entity, which Home Assistant would
+// otherwise do on write and verification would report as clamped. Values outside the entity range
+// are left alone so they are still reported.
+func (s *ExecutorService) stepValue(entityID string, value float64) float64 {
+	state, err := s.driver.GetState(entityID)
+	if err != nil || state == nil {
+		return value
 	}
 
-	// Apply temperature day schedule
-	for _, scheduleConfig := range phase.TemperatureDaySchedule {
-		if value, exists := scheduleConfig.Schedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.EntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.EntityID, err)
-				errors = append(errors, err)
-			}
-		}
+	if value < state.Min || value > state.Max {
+		return value
 	}
+	return normalizeSetpoint(value, state.Min, state.Max, state.Step)
+}
 
-	// Apply temperature night schedule
-	for _, scheduleConfig := range phase.TemperatureNightSchedule {
-		if value, exists := scheduleConfig.Schedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.EntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.EntityID, err)
-				errors = append(errors, err)
-			}
+// applySetpoint writes a value to Home Assistant and verifies it by reading the entity back
+func (s *ExecutorService) applySetpoint(entityID string, value float64) (models.SetpointResult, error) {
+	var result models.SetpointResult
+
+	s.resultsMu.Lock()
+	s.writing[entityID] = true
+	s.resultsMu.Unlock()
+
+	if err := s.driver.SetValue(entityID, value); err != nil {
+		result = models.SetpointResult{
+			EntityID:       entityID,
+			RequestedValue: value,
+			ExpectedValue:  value,
+			Status:         models.SetpointFailed,
+			Error:          err.Error(),
+			Timestamp:      s.ntpService.Now(),
 		}
-	}
-
-	// Apply humidity day schedule
-	for _, scheduleConfig := range phase.HumidityDaySchedule {
-		if value, exists := scheduleConfig.Schedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.EntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.EntityID, err)
-				errors = append(errors, err)
-			}
+	} else if state, err := s.driver.GetState(entityID); err != nil {
+		result = models.SetpointResult{
+			EntityID:       entityID,
+			RequestedValue: value,
+			ExpectedValue:  value,
+			Status:         models.SetpointFailed,
+			Error:          fmt.Sprintf("read-back failed: %v", err),
+			Timestamp:      s.ntpService.Now(),
 		}
+	} else {
+		result = verifySetpoint(entityID, value, state, s.ntpService.Now())
 	}
 
-	// Apply humidity night schedule
-	for _, scheduleConfig := range phase.HumidityNightSchedule {
-		if value, exists := scheduleConfig.Schedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.EntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.EntityID, err)
-				errors = append(errors, err)
-			}
-		}
+	s.recordSetpointResult(result)
+
+	switch result.Status {
+	case models.SetpointClamped:
+		s.logger.Warn("Home Assistant stored a different value than requested",
+			"entity_id", entityID,
+			"requested", value,
+			"actual", *result.ActualValue,
+			"min", result.Min,
+			"max", result.Max,
+			"step", result.Step)
+	case models.SetpointFailed:
+		return result, fmt.Errorf("%s", result.Error)
 	}
 
-	// Apply CO2 day schedule
-	for _, scheduleConfig := range phase.CO2DaySchedule {
-		if value, exists := scheduleConfig.Schedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.EntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.EntityID, err)
-				errors = append(errors, err)
-			}
-		}
+	return result, nil
+}
+
+// recordApplication appends a verified write to the setpoint audit log. Writes outside an
+// experiment, such as the idle profile, are not recorded: the log is kept per experiment.
+func (s *ExecutorService) recordApplication(ctx context.Context, exp *models.Experiment, phaseIndex, day int, setpoint plannedSetpoint, result models.SetpointResult) {
+	if s.audit == nil || exp.ID.IsZero() {
+		return
 	}
 
-	// Apply CO2 night schedule
-	for _, scheduleConfig := range phase.CO2NightSchedule {
-		if value, exists := scheduleConfig.Schedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.EntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.EntityID, err)
-				errors = append(errors, err)
-			}
-		}
+	timeSource := models.TimeSourceSystem
+	if s.ntpService.IsConnected() {
+		timeSource = models.TimeSourceNTP
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("climate control errors: %v", errors)
+	application := models.SetpointApplication{
+		ChamberID:           s.chamberID,
+		ExperimentID:        exp.ID,
+		BackendExperimentID: exp.BackendID,
+		PhaseIndex:          phaseIndex,
+		Day:                 day,
+		EntityID:            setpoint.EntityID,
+		Group:               setpoint.Group,
+		RequestedValue:      result.RequestedValue,
+		ExpectedValue:       result.ExpectedValue,
+		ActualValue:         result.ActualValue,
+		Status:              result.Status,
+		Error:               result.Error,
+		TimeSource:          timeSource,
+		AppliedAt:           result.Timestamp,
+		SystemTime:          time.Now(),
 	}
 
-	return nil
+	if err := s.audit.Record(ctx, application); err != nil {
+		s.logger.Error("Failed to record setpoint application", "entity_id", setpoint.EntityID, "error", err)
+	}
 }
 
-// applyLightControls applies light intensity settings for each lamp
-func (s *ExecutorService) applyLightControls(phase *models.Phase, currentDay int) error {
-	var errors []error
+// recordSetpointResult stores the last verification result and tracks consecutive mismatches
+func (s *ExecutorService) recordSetpointResult(result models.SetpointResult) {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
 
-	// Apply light intensity schedule
-	for _, scheduleConfig := range phase.LightIntensitySchedule {
-		if value, exists := scheduleConfig.Schedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.EntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.EntityID, err)
-				errors = append(errors, err)
-			}
+	s.setpointResults[result.EntityID] = result
+	delete(s.writing, result.EntityID)
+	if result.ActualValue != nil && result.Status != models.SetpointFailed {
+		s.lastApplied[result.EntityID] = *result.ActualValue
+	}
+
+	if s.reportMetrics {
+		metrics.SetpointWrites.WithLabelValues(s.chamberID.Hex(), result.Status).Inc()
+		if result.ActualValue != nil && result.Status != models.SetpointFailed {
+			metrics.SetpointLastApplied.WithLabelValues(s.chamberID.Hex(), result.EntityID).Set(*result.ActualValue)
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("light control errors: %v", errors)
+	if result.Status == models.SetpointApplied {
+		delete(s.setpointFailures, result.EntityID)
+		return
 	}
 
-	return nil
+	s.setpointFailures[result.EntityID]++
+	if s.setpointFailures[result.EntityID] == setpointMismatchThreshold {
+		s.logger.Warn("Entity repeatedly did not accept its setpoint",
+			"entity_id", result.EntityID,
+			"attempts", setpointMismatchThreshold,
+			"status", result.Status)
+	}
 }
 
-// applyWateringControls handles watering zone controls
-func (s *ExecutorService) applyWateringControls(phase *models.Phase, currentDay int) error {
-	var errors []error
-
-	// Apply watering zone schedules
-	for _, scheduleConfig := range phase.WateringZones {
-		// Start time schedule
-		if value, exists := scheduleConfig.StartTimeSchedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.StartTimeEntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.StartTimeEntityID, err)
-				errors = append(errors, err)
-			}
-		}
+// getSetpointStatus returns the last verification results and the entities with repeated mismatches
+func (s *ExecutorService) getSetpointStatus() ([]models.SetpointResult, []map[string]interface{}) {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
 
-		// Period schedule
-		if value, exists := scheduleConfig.PeriodSchedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.PeriodEntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.PeriodEntityID, err)
-				errors = append(errors, err)
-			}
-		}
+	results := make([]models.SetpointResult, 0, len(s.setpointResults))
+	mismatches := []map[string]interface{}{}
 
-		// Pause between schedule
-		if value, exists := scheduleConfig.PauseBetweenSchedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.PauseBetweenEntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.PauseBetweenEntityID, err)
-				errors = append(errors, err)
-			}
-		}
+	for entityID, result := range s.setpointResults {
+		results = append(results, result)
 
-		// Duration schedule
-		if value, exists := scheduleConfig.DurationSchedule[currentDay]; exists {
-			if err := s.haClient.SetInputNumber(scheduleConfig.DurationEntityID, value); err != nil {
-				log.Printf("Failed to set %s: %v", scheduleConfig.DurationEntityID, err)
-				errors = append(errors, err)
-			}
+		if failures := s.setpointFailures[entityID]; failures >= setpointMismatchThreshold {
+			mismatches = append(mismatches, map[string]interface{}{
+				"entity_id":            entityID,
+				"consecutive_failures": failures,
+				"last_result":          result,
+			})
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("watering control errors: %v", errors)
-	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].EntityID < results[j].EntityID
+	})
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i]["entity_id"].(string) < mismatches[j]["entity_id"].(string)
+	})
 
-	return nil
+	return results, mismatches
 }
 
 // updateExperimentActivePhase updates the active phase index in the database using NTP time
@@ -453,20 +1126,21 @@ func (s *ExecutorService) updateExperimentActivePhase(ctx context.Context, exp *
 	return err
 }
 
-// getDaysAndTimestamps generates an array of days and timestamps between start and end timestamps
-func getDaysAndTimestamps(startTimestamp, endTimestamp int64) []models.DayAndTimestamp {
+// getDaysAndTimestamps returns the midnights in loc between start and end timestamps,
+// each with the number of the schedule day that begins there
+func getDaysAndTimestamps(startTimestamp, endTimestamp int64, loc *time.Location) []models.DayAndTimestamp {
 	var result []models.DayAndTimestamp
 
-	// Calculate number of days
-	startDay := startTimestamp / 86400
-	endDay := endTimestamp / 86400
-
-	// Generate array of days and timestamps
-	for day := startDay; day <= endDay; day++ {
-		timestamp := day * 86400 // Convert day back to timestamp
-		if timestamp >= startTimestamp && timestamp <= endTimestamp {
+	start := time.Unix(startTimestamp, 0).In(loc)
+	for offset := 0; ; offset++ {
+		// time.Date normalises the day, so days stay calendar days across DST changes
+		timestamp := time.Date(start.Year(), start.Month(), start.Day()+offset, 0, 0, 0, 0, loc).Unix()
+		if timestamp > endTimestamp {
+			break
+		}
+		if timestamp >= startTimestamp {
 			result = append(result, models.DayAndTimestamp{
-				Day:       int(day - startDay),
+				Day:       offset + 1,
 				Timestamp: timestamp,
 			})
 		}
@@ -475,17 +1149,49 @@ func getDaysAndTimestamps(startTimestamp, endTimestamp int64) []models.DayAndTim
 	return result
 }
 
+// scheduleDay returns the schedule day of now for a phase starting at start: day 1 is the
+// date the phase starts on in the location of now, every midnight there begins the next day
+func scheduleDay(start, now time.Time) int {
+	start = start.In(now.Location())
+	// Compare dates as UTC days, they are 24 hours long whatever the DST changes of the location
+	startDate := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return int(nowDate.Sub(startDate)/(24*time.Hour)) + 1
+}
+
+// getDayProgress returns the fraction of the current day in the location of now that has
+// elapsed. Days with a DST change are 23 or 25 hours long.
+func getDayProgress(now time.Time) float64 {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	return float64(now.Sub(midnight)) / float64(next.Sub(midnight))
+}
+
 // GetStatus returns executor service status
 func (s *ExecutorService) GetStatus() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	now := s.ntpService.Now()
+	setpoints, mismatches := s.getSetpointStatus()
+
+	s.resultsMu.Lock()
+	paused := make(map[string]time.Time, len(s.pausedSince))
+	for experimentID, since := range s.pausedSince {
+		paused[experimentID.Hex()] = since
+	}
+	idle := s.idle
+	s.resultsMu.Unlock()
+
 	return map[string]interface{}{
-		"running":       s.isRunning,
-		"chamber_id":    s.chamberID.Hex(),
-		"ntp_enabled":   s.ntpService.IsEnabled(),
-		"ntp_connected": s.ntpService.IsConnected(),
-		"current_time":  now.Format("2006-01-02T15:04:05Z07:00"),
+		"running":             s.isRunning,
+		"chamber_id":          s.chamberID.Hex(),
+		"ntp_enabled":         s.ntpService.IsEnabled(),
+		"ntp_connected":       s.ntpService.IsConnected(),
+		"current_time":        now.Format("2006-01-02T15:04:05Z07:00"),
+		"setpoints":           setpoints,
+		"setpoint_mismatches": mismatches,
+		"paused_experiments":  paused,
+		"idle":                idle,
 	}
 }
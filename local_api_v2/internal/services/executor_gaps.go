@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"local_api_v2/internal/models"
+)
+
+// SetOutboxService sets the outbox used to report executor gaps as experiment deviations
+func (s *ExecutorService) SetOutboxService(outbox *OutboxService) {
+	s.outbox = outbox
+}
+
+// recordTicks stores the time of this run for the given experiments. A run counts once the
+// executor evaluated the schedule, failed writes are reported by the setpoint audit instead.
+func (s *ExecutorService) recordTicks(ctx context.Context, experiments []models.Experiment) {
+	now := s.ntpService.Now()
+	for _, exp := range experiments {
+		_, err := s.db.ExecutorTicksCollection.UpdateOne(ctx,
+			bson.M{"_id": exp.ID},
+			bson.M{"$set": bson.M{"chamber_id": s.chamberID, "ticked_at": now}},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			s.logger.Error("Failed to record executor tick", "experiment_id", exp.ID.Hex(), "error", err)
+		}
+	}
+}
+
+// detectGaps reports the active experiments of this chamber whose last tick is older than the
+// gap threshold, i.e. the days and setpoints missed while the executor was not running
+func (s *ExecutorService) detectGaps(ctx context.Context) {
+	experiments, err := s.getActiveExperimentsForChamber(ctx)
+	if err != nil {
+		s.logger.Error("Failed to check executor gaps", "error", err)
+		return
+	}
+
+	now := s.ntpService.Now()
+	for i := range experiments {
+		exp := &experiments[i]
+		if exp.Status != models.StatusActive {
+			continue // paused experiments are shifted by the backend on resume
+		}
+
+		var tick models.ExecutorTick
+		err := s.db.ExecutorTicksCollection.FindOne(ctx, bson.M{"_id": exp.ID}).Decode(&tick)
+		if err == mongo.ErrNoDocuments {
+			continue // never run before, nothing was missed
+		}
+		if err != nil {
+			s.logger.Error("Failed to load executor tick", "experiment_id", exp.ID.Hex(), "error", err)
+			continue
+		}
+		if now.Sub(tick.TickedAt) < s.config.ExecutorGapThreshold {
+			continue
+		}
+
+		gap := s.buildGap(exp, tick.TickedAt, now)
+		s.logger.Warn("Executor was not running, schedule days were missed",
+			"experiment_id", exp.ID.Hex(),
+			"experiment", exp.Title,
+			"from", gap.From,
+			"duration", now.Sub(tick.TickedAt).Round(time.Second),
+			"skipped_days", len(gap.SkippedDays),
+			"missed_setpoints", len(gap.MissedSetpoints))
+
+		if err := s.recordGap(ctx, gap); err != nil {
+			s.logger.Error("Failed to record executor gap", "experiment_id", exp.ID.Hex(), "error", err)
+		}
+	}
+}
+
+// buildGap describes the days and setpoints an experiment missed between two ticks
+func (s *ExecutorService) buildGap(exp *models.Experiment, from, to time.Time) models.ExecutorGap {
+	gap := models.ExecutorGap{
+		ID:                  primitive.NewObjectID(),
+		ChamberID:           s.chamberID,
+		ExperimentID:        exp.ID,
+		BackendExperimentID: exp.BackendID,
+		From:                from,
+		To:                  to,
+		DurationSeconds:     int64(to.Sub(from) / time.Second),
+		SkippedDays:         []models.SkippedDay{},
+		MissedSetpoints:     []models.MissedSetpoint{},
+	}
+
+	// The schedule days begin at local midnight like in getCurrentPhaseWithDay
+	location := s.ntpService.Location()
+	gap.SkippedDays = skippedDays(exp, from.In(location), to.In(location))
+	for _, day := range gap.SkippedDays {
+		for _, setpoint := range phaseSetpoints(&exp.Phases[day.PhaseIndex], scheduleTime{Day: day.Day}) {
+			gap.MissedSetpoints = append(gap.MissedSetpoints, models.MissedSetpoint{
+				PhaseIndex: day.PhaseIndex,
+				Day:        day.Day,
+				EntityID:   setpoint.EntityID,
+				Value:      setpoint.Value,
+				Group:      setpoint.Group,
+			})
+		}
+	}
+	return gap
+}
+
+// skippedDays returns the schedule days that began after from and ended before to, in order.
+// The days of from and to themselves were run by the executor. Days begin at midnight in the
+// location of from.
+func skippedDays(exp *models.Experiment, from, to time.Time) []models.SkippedDay {
+	seen := make(map[models.SkippedDay]bool)
+	for _, t := range []time.Time{from, to} {
+		if _, phaseIndex, at := phaseAt(exp, t); phaseIndex >= 0 {
+			seen[models.SkippedDay{PhaseIndex: phaseIndex, Day: at.Day}] = true
+		}
+	}
+
+	// Every day begins at the start of its schedule item or at a day boundary within it
+	var starts []int64
+	for _, item := range exp.Schedule {
+		starts = append(starts, item.StartTimestamp)
+		for _, boundary := range getDaysAndTimestamps(item.StartTimestamp, item.EndTimestamp, from.Location()) {
+			starts = append(starts, boundary.Timestamp)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	days := []models.SkippedDay{}
+	for _, start := range starts {
+		t := time.Unix(start+1, 0).In(from.Location()) // schedule items exclude their start instant
+		if !t.After(from) || !t.Before(to) {
+			continue
+		}
+		_, phaseIndex, at := phaseAt(exp, t)
+		if phaseIndex < 0 {
+			continue
+		}
+		day := models.SkippedDay{PhaseIndex: phaseIndex, Day: at.Day}
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// recordGap stores a gap and queues it for the backend as an experiment deviation
+func (s *ExecutorService) recordGap(ctx context.Context, gap models.ExecutorGap) error {
+	if _, err := s.db.ExecutorGapsCollection.InsertOne(ctx, gap); err != nil {
+		return fmt.Errorf("failed to store executor gap: %v", err)
+	}
+
+	if gap.BackendExperimentID.IsZero() {
+		return nil
+	}
+	if s.outbox == nil {
+		return fmt.Errorf("outbox not set")
+	}
+
+	return s.outbox.Enqueue(ctx, OutboxRequest{
+		Kind:   models.OutboxKindExecutorGap,
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("/experiments/%s/deviations", gap.BackendExperimentID.Hex()),
+		Payload: map[string]interface{}{
+			"type":             "gap",
+			"local_id":         "gap:" + gap.ID.Hex(),
+			"action":           fmt.Sprintf("executor not running for %s, %d schedule days skipped", gap.To.Sub(gap.From).Round(time.Second), len(gap.SkippedDays)),
+			"occurred_at":      gap.From,
+			"ended_at":         gap.To,
+			"detected_at":      gap.To,
+			"duration_seconds": gap.DurationSeconds,
+			"skipped_days":     gap.SkippedDays,
+			"missed_setpoints": gap.MissedSetpoints,
+		},
+		IdempotencyKey: "executor_gap:" + gap.ID.Hex(),
+	})
+}
@@ -0,0 +1,741 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/homeassistant/hatest"
+)
+
+// newTestExecutor returns an executor writing to a fake Home Assistant at the time of clock
+func newTestExecutor(server *hatest.Server, clock executorClock) *ExecutorService {
+	return &ExecutorService{
+		config:           &config.Config{},
+		driver:           server.Client(),
+		ntpService:       clock,
+		logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		setpointResults:  make(map[string]models.SetpointResult),
+		setpointFailures: make(map[string]int),
+		lastApplied:      make(map[string]float64),
+		writing:          make(map[string]bool),
+		forced:           make(map[string]bool),
+		unforced:         make(map[string]float64),
+		pausedSince:      make(map[primitive.ObjectID]time.Time),
+	}
+}
+
+// mustLoadLocation loads a time zone, skipping the test without the time zone database
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return location
+}
+
+// twoPhaseExperiment returns an experiment with a three day phase from March 1 10:00 to March 3
+// 10:00 in location, followed by a three day phase to March 5 10:00, writing the sb1 controls of
+// newChamberServer
+func twoPhaseExperiment(location *time.Location) *models.Experiment {
+	at := func(day, hour int) int64 {
+		return time.Date(2026, time.March, day, hour, 0, 0, 0, location).Unix()
+	}
+	phase := func(title string, temperature, brightness, pump, heater, co2 map[int]float64) models.Phase {
+		return models.Phase{
+			Title:                    title,
+			DurationDays:             3,
+			TemperatureDaySchedule:   map[string]models.ScheduleConfig{"temp": {EntityID: "input_number.temp_day_sb1", Schedule: temperature}},
+			TemperatureNightSchedule: map[string]models.ScheduleConfig{"heater": {EntityID: "climate.heater_sb1", Schedule: heater}},
+			CO2DaySchedule:           map[string]models.ScheduleConfig{"co2": {EntityID: "number.co2_day_sb1", Schedule: co2}},
+			LightIntensitySchedule:   map[string]models.ScheduleConfig{"lamp": {EntityID: "light.lamp_1_sb1", Schedule: brightness}},
+			SwitchSchedule:           map[string]models.ScheduleConfig{"pump": {EntityID: "switch.pump_sb1", Schedule: pump}},
+		}
+	}
+
+	return &models.Experiment{
+		Title:  "Two phases",
+		Status: models.StatusActive,
+		Phases: []models.Phase{
+			phase("Germination",
+				map[int]float64{1: 20, 2: 22, 3: 24},
+				map[int]float64{1: 40, 2: 60, 3: 80},
+				map[int]float64{1: 1, 2: 0, 3: 1},
+				map[int]float64{1: 18, 2: 19, 3: 20},
+				map[int]float64{1: 800, 2: 900, 3: 1000}),
+			phase("Growth",
+				map[int]float64{1: 26, 2: 27, 3: 28},
+				map[int]float64{1: 100, 2: 0, 3: 50},
+				map[int]float64{1: 0, 2: 1, 3: 0},
+				map[int]float64{1: 21, 2: 22, 3: 23},
+				map[int]float64{1: 1100, 2: 1200, 3: 1300}),
+		},
+		Schedule: []models.ScheduleItem{
+			{PhaseIndex: 0, StartTimestamp: at(1, 10), EndTimestamp: at(3, 10)},
+			{PhaseIndex: 1, StartTimestamp: at(3, 10), EndTimestamp: at(5, 10)},
+		},
+	}
+}
+
+func TestExecutorDaySelection(t *testing.T) {
+	location := mustLoadLocation(t, "Europe/Moscow")
+	at := func(day, hour, minute, second int) time.Time {
+		return time.Date(2026, time.March, day, hour, minute, second, 0, location)
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantPhase int // -1 when no phase is active and nothing is written
+		wantDay   int
+		want      map[string]float64
+	}{
+		{
+			name: "before the start", now: at(1, 9, 59, 59), wantPhase: -1,
+		},
+		{
+			name: "first day", now: at(1, 23, 59, 59), wantPhase: 0, wantDay: 1,
+			want: map[string]float64{"input_number.temp_day_sb1": 20, "light.lamp_1_sb1": 40, "switch.pump_sb1": 1, "climate.heater_sb1": 18, "number.co2_day_sb1": 800},
+		},
+		{
+			// 21:00 UTC, a day counted in UTC would still be day 1
+			name: "local midnight", now: at(2, 0, 0, 0), wantPhase: 0, wantDay: 2,
+			want: map[string]float64{"input_number.temp_day_sb1": 22, "light.lamp_1_sb1": 60, "switch.pump_sb1": 0, "climate.heater_sb1": 19, "number.co2_day_sb1": 900},
+		},
+		{
+			name: "last second of the phase", now: at(3, 9, 59, 59), wantPhase: 0, wantDay: 3,
+			want: map[string]float64{"input_number.temp_day_sb1": 24, "light.lamp_1_sb1": 80, "switch.pump_sb1": 1, "climate.heater_sb1": 20, "number.co2_day_sb1": 1000},
+		},
+		{
+			// Schedule items exclude their start and end instants
+			name: "phase boundary", now: at(3, 10, 0, 0), wantPhase: -1,
+		},
+		{
+			name: "first second of the next phase", now: at(3, 10, 0, 1), wantPhase: 1, wantDay: 1,
+			want: map[string]float64{"input_number.temp_day_sb1": 26, "light.lamp_1_sb1": 100, "switch.pump_sb1": 0, "climate.heater_sb1": 21, "number.co2_day_sb1": 1100},
+		},
+		{
+			name: "light turned off", now: at(4, 12, 0, 0), wantPhase: 1, wantDay: 2,
+			want: map[string]float64{"input_number.temp_day_sb1": 27, "light.lamp_1_sb1": 0, "switch.pump_sb1": 1, "climate.heater_sb1": 22, "number.co2_day_sb1": 1200},
+		},
+		{
+			name: "after the end", now: at(5, 10, 0, 1), wantPhase: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChamberServer(t)
+			server.ResetWrites()
+			executor := newTestExecutor(server, &simulatedClock{now: tt.now, location: location})
+			exp := twoPhaseExperiment(location)
+
+			phase, phaseIndex, scheduled := executor.getCurrentPhaseWithDay(exp)
+			if phaseIndex != tt.wantPhase {
+				t.Fatalf("phase %d, want %d", phaseIndex, tt.wantPhase)
+			}
+			if phase == nil {
+				if writes := server.Writes(); len(writes) != 0 {
+					t.Errorf("%d writes without an active phase", len(writes))
+				}
+				return
+			}
+			if scheduled.Day != tt.wantDay {
+				t.Fatalf("day %d, want %d", scheduled.Day, tt.wantDay)
+			}
+
+			if err := executor.applyPhaseSettings(context.Background(), exp, phaseIndex, phase, scheduled, nil); err != nil {
+				t.Fatalf("applyPhaseSettings: %v", err)
+			}
+
+			for entityID, want := range tt.want {
+				if got, ok := server.Value(entityID); !ok || got != want {
+					t.Errorf("%s = %v (ok %v), want %v", entityID, got, ok, want)
+				}
+				if result := executor.setpointResults[entityID]; result.Status != models.SetpointApplied {
+					t.Errorf("%s write has status %s (%s), want applied", entityID, result.Status, result.Error)
+				}
+			}
+			if writes := server.Writes(); len(writes) != len(tt.want) {
+				t.Errorf("%d writes, want %d: %+v", len(writes), len(tt.want), writes)
+			}
+		})
+	}
+}
+
+func TestExecutorWritesWithDomainServices(t *testing.T) {
+	location := mustLoadLocation(t, "Europe/Moscow")
+	server := newChamberServer(t)
+	server.ResetWrites()
+	exp := twoPhaseExperiment(location)
+
+	// Day 2 of the second phase turns the light off and the pump on
+	clock := &simulatedClock{now: time.Date(2026, time.March, 4, 12, 0, 0, 0, location), location: location}
+	executor := newTestExecutor(server, clock)
+	phase, phaseIndex, scheduled := executor.getCurrentPhaseWithDay(exp)
+	if phase == nil {
+		t.Fatal("no active phase")
+	}
+	if err := executor.applyPhaseSettings(context.Background(), exp, phaseIndex, phase, scheduled, nil); err != nil {
+		t.Fatalf("applyPhaseSettings: %v", err)
+	}
+
+	want := map[string]string{
+		"input_number.temp_day_sb1": "input_number/set_value",
+		"number.co2_day_sb1":        "number/set_value",
+		"light.lamp_1_sb1":          "light/turn_off",
+		"switch.pump_sb1":           "switch/turn_on",
+		"climate.heater_sb1":        "climate/set_temperature",
+	}
+	for _, write := range server.Writes() {
+		if write.Service != want[write.EntityID] {
+			t.Errorf("%s written with %s, want %s", write.EntityID, write.Service, want[write.EntityID])
+		}
+		delete(want, write.EntityID)
+	}
+	for entityID := range want {
+		t.Errorf("%s not written", entityID)
+	}
+
+	// The last morning of the phase dims the light back on
+	server.ResetWrites()
+	clock.now = time.Date(2026, time.March, 5, 8, 0, 0, 0, location)
+	phase, phaseIndex, scheduled = executor.getCurrentPhaseWithDay(exp)
+	if phase == nil || scheduled.Day != 3 {
+		t.Fatalf("phase %d day %d, want phase 1 day 3", phaseIndex, scheduled.Day)
+	}
+	if err := executor.applyPhaseSettings(context.Background(), exp, phaseIndex, phase, scheduled, nil); err != nil {
+		t.Fatalf("applyPhaseSettings: %v", err)
+	}
+	for _, write := range server.Writes() {
+		if write.EntityID == "light.lamp_1_sb1" && (write.Service != "light/turn_on" || write.Requested != 50) {
+			t.Errorf("light written with %s at %v, want light/turn_on at 50", write.Service, write.Requested)
+		}
+	}
+	if state, _ := server.State("light.lamp_1_sb1"); state.State != "on" {
+		t.Errorf("light is %s, want on", state.State)
+	}
+}
+
+func TestExecutorCorrectsDrift(t *testing.T) {
+	location := mustLoadLocation(t, "Europe/Moscow")
+	server := newChamberServer(t)
+	exp := twoPhaseExperiment(location)
+
+	// Day 2 of the second phase sets the temperature to 27 and the CO2 to 1200
+	clock := &simulatedClock{now: time.Date(2026, time.March, 4, 12, 0, 0, 0, location), location: location}
+	executor := newTestExecutor(server, clock)
+	phase, phaseIndex, scheduled := executor.getCurrentPhaseWithDay(exp)
+	if phase == nil {
+		t.Fatal("no active phase")
+	}
+	if err := executor.applyPhaseSettings(context.Background(), exp, phaseIndex, phase, scheduled, nil); err != nil {
+		t.Fatalf("applyPhaseSettings: %v", err)
+	}
+
+	// Both are changed by hand, the CO2 under a manual override
+	server.SetValue("input_number.temp_day_sb1", 30)
+	server.SetValue("number.co2_day_sb1", 1500)
+	overrides := map[string]models.ManualOverride{"number.co2_day_sb1": {EntityID: "number.co2_day_sb1", Reason: "calibration"}}
+
+	events := executor.detectDrift(exp, phaseIndex, scheduled.Day, phaseSetpoints(phase, scheduled), overrides)
+	want := map[string]models.DriftEvent{
+		"input_number.temp_day_sb1": {ExpectedValue: 27, ObservedValue: 30, Action: models.DriftActionRestored},
+		"number.co2_day_sb1":        {ExpectedValue: 1200, ObservedValue: 1500, Action: models.DriftActionOverridden},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("detected %d drift events, want %d", len(events), len(want))
+	}
+	for _, event := range events {
+		w := want[event.EntityID]
+		if event.ExpectedValue != w.ExpectedValue || event.ObservedValue != w.ObservedValue || event.Action != w.Action {
+			t.Errorf("%s drifted from %v to %v with action %s, want %v to %v with %s", event.EntityID,
+				event.ExpectedValue, event.ObservedValue, event.Action, w.ExpectedValue, w.ObservedValue, w.Action)
+		}
+		if event.PhaseIndex != 1 || event.Day != 2 {
+			t.Errorf("%s drift recorded on phase %d day %d, want phase 1 day 2", event.EntityID, event.PhaseIndex, event.Day)
+		}
+	}
+
+	// The next run restores the temperature and leaves the overridden CO2 alone
+	server.ResetWrites()
+	if err := executor.applyPhaseSettings(context.Background(), exp, phaseIndex, phase, scheduled, overrides); err != nil {
+		t.Fatalf("applyPhaseSettings: %v", err)
+	}
+	for _, write := range server.Writes() {
+		if write.EntityID == "number.co2_day_sb1" {
+			t.Errorf("overridden %s written with %v", write.EntityID, write.Requested)
+		}
+	}
+	if value, _ := server.Value("input_number.temp_day_sb1"); value != 27 {
+		t.Errorf("temperature is %v after the drift correction, want 27", value)
+	}
+	if value, _ := server.Value("number.co2_day_sb1"); value != 1500 {
+		t.Errorf("overridden CO2 is %v, want the manual 1500", value)
+	}
+
+	// Both drifts are handled, nothing is reported again
+	if events := executor.detectDrift(exp, phaseIndex, scheduled.Day, phaseSetpoints(phase, scheduled), overrides); len(events) != 0 {
+		t.Errorf("detected %d drift events after the correction, want none", len(events))
+	}
+}
+
+func TestScheduleDay(t *testing.T) {
+	moscow := mustLoadLocation(t, "Europe/Moscow")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name  string
+		start time.Time
+		now   time.Time
+		want  int
+	}{
+		{"start instant", time.Date(2026, 3, 1, 10, 0, 0, 0, moscow), time.Date(2026, 3, 1, 10, 0, 0, 0, moscow), 1},
+		{"last second of the first day", time.Date(2026, 3, 1, 10, 0, 0, 0, moscow), time.Date(2026, 3, 1, 23, 59, 59, 0, moscow), 1},
+		{"local midnight", time.Date(2026, 3, 1, 10, 0, 0, 0, moscow), time.Date(2026, 3, 2, 0, 0, 0, 0, moscow), 2},
+		{"before UTC midnight", time.Date(2026, 3, 1, 10, 0, 0, 0, moscow), time.Date(2026, 3, 2, 2, 59, 59, 0, moscow), 2},
+		{"start at local midnight", time.Date(2026, 3, 1, 0, 0, 0, 0, moscow), time.Date(2026, 3, 1, 23, 59, 59, 0, moscow), 1},
+		{"start given in UTC", time.Date(2026, 3, 1, 21, 30, 0, 0, time.UTC), time.Date(2026, 3, 2, 12, 0, 0, 0, moscow), 1},
+		{"spring forward midnight", time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), time.Date(2026, 3, 29, 0, 0, 0, 0, berlin), 2},
+		{"spring forward last second", time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), time.Date(2026, 3, 29, 23, 59, 59, 0, berlin), 2},
+		{"after spring forward", time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), time.Date(2026, 3, 30, 0, 0, 0, 0, berlin), 3},
+		{"fall back last second", time.Date(2026, 10, 24, 12, 0, 0, 0, berlin), time.Date(2026, 10, 25, 23, 59, 59, 0, berlin), 2},
+		{"after fall back", time.Date(2026, 10, 24, 12, 0, 0, 0, berlin), time.Date(2026, 10, 26, 0, 0, 0, 0, berlin), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scheduleDay(tt.start, tt.now); got != tt.want {
+				t.Errorf("scheduleDay(%v, %v) = %d, want %d", tt.start, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkippedDays(t *testing.T) {
+	location := mustLoadLocation(t, "Europe/Moscow")
+	exp := twoPhaseExperiment(location)
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, time.March, day, hour, 0, 0, 0, location)
+	}
+	day := func(phaseIndex, day int) models.SkippedDay {
+		return models.SkippedDay{PhaseIndex: phaseIndex, Day: day}
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []models.SkippedDay
+	}{
+		{"within one day", at(1, 12), at(1, 20), nil},
+		{"from and to days were run", at(1, 12), at(2, 12), nil},
+		{"days between from and to", at(1, 12), at(3, 12), []models.SkippedDay{day(0, 2), day(0, 3)}},
+		{"into the next phase", at(2, 12), at(4, 12), []models.SkippedDay{day(0, 3), day(1, 1)}},
+		{"to at local midnight", at(1, 12), at(3, 0), []models.SkippedDay{day(0, 2)}},
+		{"from before the schedule", at(0, 12), at(2, 12), []models.SkippedDay{day(0, 1)}},
+		{"from at a phase start instant", at(3, 10), at(4, 12), []models.SkippedDay{day(1, 1)}},
+		{"to after the schedule", at(4, 12), at(6, 12), []models.SkippedDay{day(1, 3)}},
+		{"whole schedule", at(0, 12), at(6, 12), []models.SkippedDay{day(0, 1), day(0, 2), day(0, 3), day(1, 1), day(1, 2), day(1, 3)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := skippedDays(exp, tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("skippedDays = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("skippedDays = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildGap(t *testing.T) {
+	location := mustLoadLocation(t, "Europe/Moscow")
+	exp := twoPhaseExperiment(location)
+	executor := newTestExecutor(newChamberServer(t), &simulatedClock{location: location})
+
+	// March 2 23:00 to March 4 01:00 in Moscow, given in UTC where both are a day earlier
+	from := time.Date(2026, time.March, 2, 20, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.March, 3, 22, 0, 0, 0, time.UTC)
+	gap := executor.buildGap(exp, from, to)
+
+	if gap.DurationSeconds != 26*3600 {
+		t.Errorf("gap lasts %d seconds, want %d", gap.DurationSeconds, 26*3600)
+	}
+	want := []models.SkippedDay{{PhaseIndex: 0, Day: 3}, {PhaseIndex: 1, Day: 1}}
+	if len(gap.SkippedDays) != len(want) || gap.SkippedDays[0] != want[0] || gap.SkippedDays[1] != want[1] {
+		t.Fatalf("skipped days %v, want %v", gap.SkippedDays, want)
+	}
+
+	// Every setpoint of the skipped days is missed with the value of its day
+	wantValues := map[models.SkippedDay]map[string]float64{
+		want[0]: {"input_number.temp_day_sb1": 24, "number.co2_day_sb1": 1000},
+		want[1]: {"input_number.temp_day_sb1": 26, "number.co2_day_sb1": 1100},
+	}
+	missed := make(map[models.SkippedDay]map[string]float64)
+	for _, setpoint := range gap.MissedSetpoints {
+		day := models.SkippedDay{PhaseIndex: setpoint.PhaseIndex, Day: setpoint.Day}
+		if missed[day] == nil {
+			missed[day] = make(map[string]float64)
+		}
+		missed[day][setpoint.EntityID] = setpoint.Value
+	}
+	for day, values := range wantValues {
+		for entityID, value := range values {
+			if got, ok := missed[day][entityID]; !ok || got != value {
+				t.Errorf("phase %d day %d missed %s = %v (%v), want %v", day.PhaseIndex, day.Day, entityID, got, ok, value)
+			}
+		}
+	}
+	if len(missed) != len(want) {
+		t.Errorf("setpoints missed on %d days, want %d", len(missed), len(want))
+	}
+}
+
+func TestGetDayProgress(t *testing.T) {
+	moscow := mustLoadLocation(t, "Europe/Moscow")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want float64
+	}{
+		{"local midnight", time.Date(2026, 3, 2, 0, 0, 0, 0, moscow), 0},
+		{"noon", time.Date(2026, 3, 2, 12, 0, 0, 0, moscow), 0.5},
+		{"last second", time.Date(2026, 3, 2, 23, 59, 59, 0, moscow), 86399.0 / 86400},
+		{"spring forward after the jump", time.Date(2026, 3, 29, 3, 0, 0, 0, berlin), 2.0 / 23},
+		{"spring forward noon", time.Date(2026, 3, 29, 12, 0, 0, 0, berlin), 11.0 / 23},
+		{"spring forward last second", time.Date(2026, 3, 29, 23, 59, 59, 0, berlin), (23*3600 - 1) / (23 * 3600.0)},
+		{"fall back noon", time.Date(2026, 10, 25, 12, 0, 0, 0, berlin), 13.0 / 25},
+		{"fall back last second", time.Date(2026, 10, 25, 23, 59, 59, 0, berlin), (25*3600 - 1) / (25 * 3600.0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getDayProgress(tt.now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("getDayProgress(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhaseAtAcrossDST(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name         string
+		start, end   time.Time
+		now          time.Time
+		wantPhase    int
+		wantDay      int
+		wantProgress float64
+	}{
+		{
+			name:  "spring forward day",
+			start: time.Date(2026, 3, 28, 0, 0, 0, 0, berlin), end: time.Date(2026, 3, 31, 0, 0, 0, 0, berlin),
+			now:       time.Date(2026, 3, 29, 3, 0, 0, 0, berlin),
+			wantPhase: 0, wantDay: 2, wantProgress: 2.0 / 23,
+		},
+		{
+			name:  "last second of a phase",
+			start: time.Date(2026, 3, 28, 0, 0, 0, 0, berlin), end: time.Date(2026, 3, 31, 0, 0, 0, 0, berlin),
+			now:       time.Date(2026, 3, 30, 23, 59, 59, 0, berlin),
+			wantPhase: 0, wantDay: 3, wantProgress: 86399.0 / 86400,
+		},
+		{
+			name:  "end of a phase",
+			start: time.Date(2026, 3, 28, 0, 0, 0, 0, berlin), end: time.Date(2026, 3, 31, 0, 0, 0, 0, berlin),
+			now:       time.Date(2026, 3, 31, 0, 0, 0, 0, berlin),
+			wantPhase: -1, wantDay: -1,
+		},
+		{
+			name:  "repeated hour of fall back",
+			start: time.Date(2026, 10, 24, 0, 0, 0, 0, berlin), end: time.Date(2026, 10, 27, 0, 0, 0, 0, berlin),
+			// 02:30 CET, the second 02:30 of the day
+			now:       time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC).In(berlin),
+			wantPhase: 0, wantDay: 2, wantProgress: 3.5 / 25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := &models.Experiment{
+				Phases:   []models.Phase{{Title: "Phase", DurationDays: 3}},
+				Schedule: []models.ScheduleItem{{PhaseIndex: 0, StartTimestamp: tt.start.Unix(), EndTimestamp: tt.end.Unix()}},
+			}
+
+			_, phaseIndex, at := phaseAt(exp, tt.now)
+			if phaseIndex != tt.wantPhase || at.Day != tt.wantDay {
+				t.Fatalf("phase %d day %d, want phase %d day %d", phaseIndex, at.Day, tt.wantPhase, tt.wantDay)
+			}
+			if phaseIndex >= 0 && math.Abs(at.DayProgress-tt.wantProgress) > 1e-9 {
+				t.Errorf("day progress %v, want %v", at.DayProgress, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestGetDaysAndTimestampsAcrossDST(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	start := time.Date(2026, 3, 28, 12, 0, 0, 0, berlin)
+	end := time.Date(2026, 3, 31, 0, 0, 0, 0, berlin)
+
+	days := getDaysAndTimestamps(start.Unix(), end.Unix(), berlin)
+
+	want := []time.Time{
+		time.Date(2026, 3, 29, 0, 0, 0, 0, berlin),
+		time.Date(2026, 3, 30, 0, 0, 0, 0, berlin),
+		time.Date(2026, 3, 31, 0, 0, 0, 0, berlin),
+	}
+	if len(days) != len(want) {
+		t.Fatalf("got %d midnights, want %d: %+v", len(days), len(want), days)
+	}
+	for i, day := range days {
+		if day.Day != i+2 || day.Timestamp != want[i].Unix() {
+			t.Errorf("midnight %d is day %d at %v, want day %d at %v",
+				i, day.Day, time.Unix(day.Timestamp, 0).In(berlin), i+2, want[i])
+		}
+	}
+}
+
+func TestOverrideRestoreOutsidePhase(t *testing.T) {
+	server := newChamberServer(t)
+	executor := newTestExecutor(server, &simulatedClock{now: time.Now(), location: time.UTC})
+	ctx := context.Background()
+	exp := &models.Experiment{}
+	forced := 30.0
+
+	// The last phase write before the experiment was paused
+	executor.writeMissingValues(ctx, exp, 0, 1, "climate controls", map[string]float64{"input_number.temp_day_sb1": 24})
+
+	tests := []struct {
+		name         string
+		overrides    map[string]models.ManualOverride
+		wantRestored map[string]float64
+		wantValue    float64
+	}{
+		{
+			name:      "override active",
+			overrides: map[string]models.ManualOverride{"input_number.temp_day_sb1": {EntityID: "input_number.temp_day_sb1", Value: &forced}},
+			wantValue: 30,
+		},
+		{
+			name:      "override still active",
+			overrides: map[string]models.ManualOverride{"input_number.temp_day_sb1": {EntityID: "input_number.temp_day_sb1", Value: &forced}},
+			wantValue: 30,
+		},
+		{
+			name:         "override ended",
+			wantRestored: map[string]float64{"input_number.temp_day_sb1": 24},
+			wantValue:    24,
+		},
+		{
+			name:      "restored once",
+			wantValue: 24,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restored := executor.applyOverrideValues(ctx, exp, -1, -1, tt.overrides)
+			if len(restored) != len(tt.wantRestored) {
+				t.Fatalf("restored %v, want %v", restored, tt.wantRestored)
+			}
+			for entityID, value := range tt.wantRestored {
+				if restored[entityID] != value {
+					t.Errorf("restored %s to %v, want %v", entityID, restored[entityID], value)
+				}
+			}
+
+			// Hold mode writes nothing but the restored values
+			executor.applyProfileValues(ctx, exp, -1, "pause profile", withRestored(nil, restored))
+			if value, _ := server.Value("input_number.temp_day_sb1"); value != tt.wantValue {
+				t.Errorf("input_number.temp_day_sb1 holds %v, want %v", value, tt.wantValue)
+			}
+		})
+	}
+
+	// Profile values take precedence over restored values
+	values := withRestored(map[string]float64{"input_number.temp_day_sb1": 18}, map[string]float64{"input_number.temp_day_sb1": 24, "switch.pump_sb1": 1})
+	if values["input_number.temp_day_sb1"] != 18 || values["switch.pump_sb1"] != 1 {
+		t.Errorf("withRestored = %v, want the profile value and the restored switch", values)
+	}
+}
+
+func TestExecutorPauseProfile(t *testing.T) {
+	location := mustLoadLocation(t, "Europe/Moscow")
+	server := newChamberServer(t)
+	ctx := context.Background()
+	exp := twoPhaseExperiment(location)
+	exp.ID = primitive.NewObjectID()
+
+	// Day 1 of the second phase: temperature 26, lamp 100 %, pump off, heater 21, CO2 1100
+	clock := &simulatedClock{now: time.Date(2026, time.March, 3, 12, 0, 0, 0, location), location: location}
+	executor := newTestExecutor(server, clock)
+	phase, phaseIndex, scheduled := executor.getCurrentPhaseWithDay(exp)
+	if phase == nil {
+		t.Fatal("no active phase")
+	}
+	if err := executor.applyPhaseSettings(ctx, exp, phaseIndex, phase, scheduled, nil); err != nil {
+		t.Fatalf("applyPhaseSettings: %v", err)
+	}
+
+	// Hold mode leaves the last setpoints alone
+	exp.Status = models.StatusPaused
+	clock.now = clock.now.Add(time.Minute)
+	server.ResetWrites()
+	executor.applyPauseProfile(ctx, []models.Experiment{*exp}, models.PauseProfile{Mode: models.PauseModeHold}, nil, nil)
+	if writes := server.Writes(); len(writes) != 0 {
+		t.Errorf("hold mode wrote %d values, want none", len(writes))
+	}
+	if since, paused := executor.pausedSince[exp.ID]; !paused || !since.Equal(clock.now) {
+		t.Errorf("pause recorded at %v, want %v", since, clock.now)
+	}
+
+	// Safe mode writes the profile on the next run, except for overridden entities
+	safe := models.PauseProfile{Mode: models.PauseModeSafe, Values: map[string]float64{
+		"input_number.temp_day_sb1": 18,
+		"light.lamp_1_sb1":          0,
+		"switch.pump_sb1":           1,
+	}}
+	forced := 0.0
+	overrides := map[string]models.ManualOverride{"switch.pump_sb1": {EntityID: "switch.pump_sb1", Value: &forced}}
+	clock.now = clock.now.Add(time.Minute)
+	server.ResetWrites()
+	executor.applyPauseProfile(ctx, []models.Experiment{*exp}, safe, overrides, nil)
+
+	want := map[string]float64{"input_number.temp_day_sb1": 18, "light.lamp_1_sb1": 0, "switch.pump_sb1": 0, "number.co2_day_sb1": 1100}
+	for entityID, value := range want {
+		if got, _ := server.Value(entityID); got != value {
+			t.Errorf("%s is %v in the pause profile, want %v", entityID, got, value)
+		}
+	}
+	for _, write := range server.Writes() {
+		if write.EntityID == "switch.pump_sb1" {
+			t.Errorf("overridden %s written with %v", write.EntityID, write.Requested)
+		}
+	}
+
+	// Values the chamber already holds are not written again
+	server.ResetWrites()
+	executor.applyPauseProfile(ctx, []models.Experiment{*exp}, safe, overrides, nil)
+	if writes := server.Writes(); len(writes) != 0 {
+		t.Errorf("pause profile rewritten with %d values, want none", len(writes))
+	}
+
+	// On resume the experiment leaves the pause profile and its setpoints are written again
+	exp.Status = models.StatusActive
+	clock.now = clock.now.Add(time.Minute)
+	executor.trackResumedExperiments(nil)
+	if _, paused := executor.pausedSince[exp.ID]; paused {
+		t.Error("resumed experiment is still tracked as paused")
+	}
+	if err := executor.applyPhaseSettings(ctx, exp, phaseIndex, phase, scheduled, nil); err != nil {
+		t.Fatalf("applyPhaseSettings: %v", err)
+	}
+	want = map[string]float64{"input_number.temp_day_sb1": 26, "light.lamp_1_sb1": 100, "switch.pump_sb1": 0, "number.co2_day_sb1": 1100}
+	for entityID, value := range want {
+		if got, _ := server.Value(entityID); got != value {
+			t.Errorf("%s is %v after the resume, want %v", entityID, got, value)
+		}
+	}
+}
+
+func TestExecutorIdleProfile(t *testing.T) {
+	location := mustLoadLocation(t, "Europe/Moscow")
+	server := newChamberServer(t)
+	ctx := context.Background()
+	exp := twoPhaseExperiment(location)
+	exp.ID = primitive.NewObjectID()
+
+	// The run ends on day 1 of the second phase: temperature 26, lamp 100 %, pump off, heater 21, CO2 1100
+	clock := &simulatedClock{now: time.Date(2026, time.March, 3, 12, 0, 0, 0, location), location: location}
+	executor := newTestExecutor(server, clock)
+	phase, phaseIndex, scheduled := executor.getCurrentPhaseWithDay(exp)
+	if phase == nil {
+		t.Fatal("no active phase")
+	}
+	if err := executor.applyPhaseSettings(ctx, exp, phaseIndex, phase, scheduled, nil); err != nil {
+		t.Fatalf("applyPhaseSettings: %v", err)
+	}
+
+	// Without an idle profile the chamber keeps the last setpoints
+	server.ResetWrites()
+	executor.applyIdleProfile(ctx, nil, nil, nil)
+	if writes := server.Writes(); len(writes) != 0 {
+		t.Errorf("empty idle profile wrote %d values, want none", len(writes))
+	}
+	if !executor.idle {
+		t.Error("executor is not idle without an active experiment")
+	}
+
+	// With no experiment active the idle profile is written, except for overridden entities
+	idleProfile := map[string]float64{
+		"input_number.temp_day_sb1": 18,
+		"light.lamp_1_sb1":          0,
+		"number.co2_day_sb1":        400,
+	}
+	forced := 1500.0
+	overrides := map[string]models.ManualOverride{"number.co2_day_sb1": {EntityID: "number.co2_day_sb1", Value: &forced}}
+	clock.now = clock.now.Add(time.Minute)
+	executor.applyIdleProfile(ctx, idleProfile, overrides, nil)
+
+	want := map[string]float64{"input_number.temp_day_sb1": 18, "light.lamp_1_sb1": 0, "number.co2_day_sb1": 1100, "climate.heater_sb1": 21}
+	for entityID, value := range want {
+		if got, _ := server.Value(entityID); got != value {
+			t.Errorf("%s is %v in the idle profile, want %v", entityID, got, value)
+		}
+	}
+	for _, write := range server.Writes() {
+		if write.EntityID == "number.co2_day_sb1" {
+			t.Errorf("overridden %s written with %v", write.EntityID, write.Requested)
+		}
+	}
+
+	// Values the chamber already holds are not written again
+	server.ResetWrites()
+	executor.applyIdleProfile(ctx, idleProfile, overrides, nil)
+	if writes := server.Writes(); len(writes) != 0 {
+		t.Errorf("idle profile rewritten with %d values, want none", len(writes))
+	}
+
+	// Activating the next experiment leaves the idle profile and writes its setpoints. It starts a
+	// week later, its first day sets temperature 20, lamp 40 %, pump on, heater 18 and CO2 800
+	next := twoPhaseExperiment(location)
+	next.ID = primitive.NewObjectID()
+	for i := range next.Schedule {
+		next.Schedule[i].StartTimestamp += 7 * 24 * 3600
+		next.Schedule[i].EndTimestamp += 7 * 24 * 3600
+	}
+	clock.now = time.Date(2026, time.March, 8, 12, 0, 0, 0, location)
+	executor.setIdle(false)
+	phase, phaseIndex, scheduled = executor.getCurrentPhaseWithDay(next)
+	if phase == nil {
+		t.Fatal("no active phase")
+	}
+	if err := executor.applyPhaseSettings(ctx, next, phaseIndex, phase, scheduled, nil); err != nil {
+		t.Fatalf("applyPhaseSettings: %v", err)
+	}
+	want = map[string]float64{"input_number.temp_day_sb1": 20, "light.lamp_1_sb1": 40, "switch.pump_sb1": 1, "climate.heater_sb1": 18, "number.co2_day_sb1": 800}
+	for entityID, value := range want {
+		if got, _ := server.Value(entityID); got != value {
+			t.Errorf("%s is %v after the activation, want %v", entityID, got, value)
+		}
+	}
+	if executor.idle {
+		t.Error("executor is still idle with an active experiment")
+	}
+}
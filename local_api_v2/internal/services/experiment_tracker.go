@@ -1,15 +1,15 @@
 package services
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"local_api_v2/internal/config"
 	"local_api_v2/internal/database"
@@ -21,8 +21,8 @@ import (
 type ExperimentTracker struct {
 	config     *config.Config
 	db         *database.MongoDB
-	ntpService *ntp.TimeService
-	httpClient *http.Client
+	ntpService executorClock // the time source of the executor, so both agree on schedule days
+	outbox     *OutboxService
 }
 
 // NewExperimentTracker creates a new experiment tracker
@@ -31,19 +31,21 @@ func NewExperimentTracker(cfg *config.Config, db *database.MongoDB, ntpService *
 		config:     cfg,
 		db:         db,
 		ntpService: ntpService,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
 	}
 }
 
+// SetOutboxService sets the outbox used for backend status updates
+func (et *ExperimentTracker) SetOutboxService(outbox *OutboxService) {
+	et.outbox = outbox
+}
+
 // StartTracking starts the experiment tracking service
 func (et *ExperimentTracker) StartTracking(ctx context.Context) {
-	log.Println("🔍 Starting experiment tracking service...")
+	slog.Info("Starting experiment tracking service")
 
 	// Initial check
 	if err := et.checkAndUpdateExperiments(); err != nil {
-		log.Printf("❌ Initial experiment check failed: %v", err)
+		slog.Error("Initial experiment check failed", "error", err)
 	}
 
 	// Periodic check every 2 minutes (more frequent than frontend)
@@ -53,11 +55,11 @@ func (et *ExperimentTracker) StartTracking(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("🛑 Experiment tracking service stopped")
+			slog.Info("Experiment tracking service stopped")
 			return
 		case <-ticker.C:
 			if err := et.checkAndUpdateExperiments(); err != nil {
-				log.Printf("❌ Experiment check failed: %v", err)
+				slog.Error("Experiment check failed", "error", err)
 			}
 		}
 	}
@@ -87,7 +89,7 @@ func (et *ExperimentTracker) checkAndUpdateExperiments() error {
 		return nil
 	}
 
-	log.Printf("🔍 Checking %d active experiments...", len(experiments))
+	slog.Debug("Checking active experiments", "count", len(experiments))
 
 	completedCount := 0
 	now := et.ntpService.Now()
@@ -96,10 +98,10 @@ func (et *ExperimentTracker) checkAndUpdateExperiments() error {
 		isCompleted := et.isExperimentCompleted(experiment, now)
 
 		if isCompleted {
-			log.Printf("✅ Experiment completed: %s", experiment.Title)
+			slog.Info("Experiment completed", "chamber_id", experiment.ChamberID.Hex(), "experiment_id", experiment.ID.Hex(), "experiment", experiment.Title)
 
 			if err := et.completeExperiment(ctx, &experiment); err != nil {
-				log.Printf("❌ Failed to complete experiment %s: %v", experiment.Title, err)
+				slog.Error("Failed to complete experiment", "experiment_id", experiment.ID.Hex(), "error", err)
 				continue
 			}
 
@@ -108,7 +110,7 @@ func (et *ExperimentTracker) checkAndUpdateExperiments() error {
 	}
 
 	if completedCount > 0 {
-		log.Printf("🎯 Completed %d experiments", completedCount)
+		slog.Info("Completed experiments", "count", completedCount)
 	}
 
 	return nil
@@ -141,16 +143,19 @@ func (et *ExperimentTracker) isExperimentCompleted(experiment models.Experiment,
 	return now.After(completionTime)
 }
 
-// completeExperiment marks an experiment as completed and syncs with backend
+// completeExperiment marks an experiment as completed and queues the status update for the backend
 func (et *ExperimentTracker) completeExperiment(ctx context.Context, experiment *models.Experiment) error {
 	// Update local status
 	experiment.Status = models.StatusCompleted
 	experiment.UpdatedAt = et.ntpService.Now()
 
-	// Sync status with backend
-	if err := et.syncExperimentStatusToBackend(experiment); err != nil {
-		log.Printf("⚠️  Failed to sync experiment status to backend: %v", err)
-		// Don't return error - local update succeeded
+	// Queue the status update before the local update, so a failure is retried on the next check
+	if err := et.syncExperimentStatusToBackend(ctx, experiment); err != nil {
+		if experiment.BackendID.IsZero() {
+			slog.Warn("Failed to sync experiment status to backend", "experiment_id", experiment.ID.Hex(), "error", err)
+		} else {
+			return fmt.Errorf("failed to queue status update: %v", err)
+		}
 	}
 
 	// Update in local database
@@ -169,79 +174,60 @@ func (et *ExperimentTracker) completeExperiment(ctx context.Context, experiment
 		return fmt.Errorf("failed to update local experiment: %v", err)
 	}
 
-	log.Printf("✅ Successfully completed experiment: %s", experiment.Title)
+	slog.Info("Marked experiment as completed", "experiment_id", experiment.ID.Hex(), "experiment", experiment.Title)
 	return nil
 }
 
-// syncExperimentStatusToBackend sends status update to the backend
-func (et *ExperimentTracker) syncExperimentStatusToBackend(experiment *models.Experiment) error {
+// syncExperimentStatusToBackend queues a status update for the backend in the outbox
+func (et *ExperimentTracker) syncExperimentStatusToBackend(ctx context.Context, experiment *models.Experiment) error {
 	if experiment.BackendID.IsZero() {
 		return fmt.Errorf("experiment has no backend ID")
 	}
-
-	url := fmt.Sprintf("%s/experiments/%s/status", et.config.BackendURL, experiment.BackendID.Hex())
+	if et.outbox == nil {
+		return fmt.Errorf("outbox service not set")
+	}
 
 	payload := map[string]interface{}{
 		"status": experiment.Status,
 	}
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %v", err)
-	}
-
-	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if et.config.BackendAPIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+et.config.BackendAPIKey)
-	}
-
-	// Add local chamber information
-	req.Header.Set("X-Local-Time", et.ntpService.NowInLocation().Format("2006-01-02T15:04:05Z07:00"))
-	req.Header.Set("X-Chamber-Name", experiment.ChamberName)
-
-	resp, err := et.httpClient.Do(req)
+	err := et.outbox.Enqueue(ctx, OutboxRequest{
+		Kind:    models.OutboxKindExperimentStatus,
+		Method:  http.MethodPatch,
+		Path:    fmt.Sprintf("/experiments/%s/status", experiment.BackendID.Hex()),
+		Payload: payload,
+		Headers: map[string]string{
+			// Add local chamber information
+			"X-Local-Time":   et.ntpService.NowInLocation().Format("2006-01-02T15:04:05Z07:00"),
+			"X-Chamber-Name": experiment.ChamberName,
+		},
+		// Keyed without a timestamp, so a retried completion does not queue a second message
+		IdempotencyKey: fmt.Sprintf("experiment_status:%s:%s", experiment.BackendID.Hex(), experiment.Status),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("backend returned status %d", resp.StatusCode)
+		return err
 	}
 
-	log.Printf("🔄 Synced experiment status to backend: %s -> %s", experiment.Title, experiment.Status)
+	slog.Info("Queued experiment status update for backend", "experiment_id", experiment.ID.Hex(), "status", experiment.Status)
 	return nil
 }
 
 // GetExperimentProgress returns progress information for an experiment
 func (et *ExperimentTracker) GetExperimentProgress(experiment models.Experiment) ExperimentProgress {
-	now := et.ntpService.Now()
+	now := et.ntpService.NowInLocation()
 
 	if len(experiment.Schedule) == 0 {
 		return ExperimentProgress{
 			CurrentPhase:    -1,
+			CurrentDay:      -1,
 			ProgressPercent: 0,
 			TimeRemaining:   0,
 			IsCompleted:     false,
 		}
 	}
 
-	// Find current phase
-	currentPhase := -1
-	for _, scheduleItem := range experiment.Schedule {
-		startTime := time.Unix(scheduleItem.StartTimestamp, 0)
-		endTime := time.Unix(scheduleItem.EndTimestamp, 0)
-
-		if now.After(startTime) && now.Before(endTime) {
-			currentPhase = scheduleItem.PhaseIndex
-			break
-		}
-	}
+	// Find current phase and day, with the same local days as the executor
+	_, currentPhase, at := phaseAt(&experiment, now)
 
 	// Calculate total experiment duration
 	var startTime, endTime time.Time
@@ -284,6 +270,7 @@ func (et *ExperimentTracker) GetExperimentProgress(experiment models.Experiment)
 
 	return ExperimentProgress{
 		CurrentPhase:    currentPhase,
+		CurrentDay:      at.Day,
 		ProgressPercent: progressPercent,
 		TimeRemaining:   timeRemaining,
 		IsCompleted:     isCompleted,
@@ -293,6 +280,7 @@ func (et *ExperimentTracker) GetExperimentProgress(experiment models.Experiment)
 // ExperimentProgress represents the progress of an experiment
 type ExperimentProgress struct {
 	CurrentPhase    int           `json:"current_phase"`
+	CurrentDay      int           `json:"current_day"` // day within the current phase, -1 outside the schedule
 	ProgressPercent float64       `json:"progress_percent"`
 	TimeRemaining   time.Duration `json:"time_remaining"`
 	IsCompleted     bool          `json:"is_completed"`
@@ -329,6 +317,22 @@ func (et *ExperimentTracker) GetActiveExperimentsWithProgress() ([]ExperimentWit
 	return result, nil
 }
 
+// GetGaps returns the periods in which the executor did not run an experiment, most recent first
+func (et *ExperimentTracker) GetGaps(ctx context.Context, experimentID primitive.ObjectID) ([]models.ExecutorGap, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "from", Value: -1}})
+	cursor, err := et.db.ExecutorGapsCollection.Find(ctx, bson.M{"experiment_id": experimentID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query executor gaps: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	gaps := []models.ExecutorGap{}
+	if err := cursor.All(ctx, &gaps); err != nil {
+		return nil, fmt.Errorf("failed to decode executor gaps: %v", err)
+	}
+	return gaps, nil
+}
+
 // ExperimentWithProgress combines experiment data with progress information
 type ExperimentWithProgress struct {
 	Experiment models.Experiment  `json:"experiment"`
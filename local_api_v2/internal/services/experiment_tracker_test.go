@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/models"
+)
+
+func TestExperimentTrackerCompletion(t *testing.T) {
+	location := mustLoadLocation(t, "Europe/Moscow")
+	server := newChamberServer(t)
+	exp := twoPhaseExperiment(location)
+	end := time.Unix(exp.Schedule[len(exp.Schedule)-1].EndTimestamp, 0)
+
+	// The executor and the tracker share the clock like they share the NTP service
+	clock := &simulatedClock{location: location}
+	executor := newTestExecutor(server, clock)
+	tracker := &ExperimentTracker{config: &config.Config{}, ntpService: clock}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		wantPhase     int
+		wantDay       int
+		wantFinished  bool // progress reports the schedule as over
+		wantCompleted bool // the tracker marks the experiment completed
+	}{
+		{name: "last second", now: end.Add(-time.Second), wantPhase: 1, wantDay: 3},
+		{name: "end of schedule", now: end, wantPhase: -1, wantDay: -1, wantFinished: true},
+		{name: "end of grace period", now: end.Add(5 * time.Minute), wantPhase: -1, wantDay: -1, wantFinished: true},
+		{name: "after grace period", now: end.Add(5*time.Minute + time.Second), wantPhase: -1, wantDay: -1, wantFinished: true, wantCompleted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.now = tt.now
+			server.ResetWrites()
+
+			// The executor writes the last day until the schedule ends and nothing after it
+			if phase, phaseIndex, scheduled := executor.getCurrentPhaseWithDay(exp); phase != nil {
+				if err := executor.applyPhaseSettings(context.Background(), exp, phaseIndex, phase, scheduled, nil); err != nil {
+					t.Fatalf("applyPhaseSettings: %v", err)
+				}
+			}
+			if wrote := len(server.Writes()) > 0; wrote != (tt.wantPhase >= 0) {
+				t.Errorf("executor wrote %d values, want writes %v", len(server.Writes()), tt.wantPhase >= 0)
+			}
+
+			progress := tracker.GetExperimentProgress(*exp)
+			if progress.CurrentPhase != tt.wantPhase || progress.CurrentDay != tt.wantDay {
+				t.Errorf("progress at phase %d day %d, want phase %d day %d",
+					progress.CurrentPhase, progress.CurrentDay, tt.wantPhase, tt.wantDay)
+			}
+			if progress.IsCompleted != tt.wantFinished {
+				t.Errorf("progress completed = %v, want %v", progress.IsCompleted, tt.wantFinished)
+			}
+			if tt.wantFinished && (progress.ProgressPercent != 100 || progress.TimeRemaining != 0) {
+				t.Errorf("progress %v%% with %v remaining, want 100%% and none", progress.ProgressPercent, progress.TimeRemaining)
+			}
+
+			if completed := tracker.isExperimentCompleted(*exp, clock.Now()); completed != tt.wantCompleted {
+				t.Errorf("isExperimentCompleted = %v, want %v", completed, tt.wantCompleted)
+			}
+		})
+	}
+}
+
+// TestCheckAndUpdateExperiments needs a MongoDB server, set MONGODB_TEST_URI to run it
+func TestCheckAndUpdateExperiments(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	db, err := database.NewMongoDB(uri, "local_api_test_"+primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Fatalf("NewMongoDB: %v", err)
+	}
+	ctx := context.Background()
+	t.Cleanup(func() {
+		db.Database.Drop(ctx)
+		db.Disconnect(ctx)
+	})
+
+	now := time.Now()
+	experiment := func(title string, end time.Time) models.Experiment {
+		return models.Experiment{
+			ID:     primitive.NewObjectID(),
+			Title:  title,
+			Status: models.StatusActive,
+			Phases: []models.Phase{{Title: "Phase", DurationDays: 1}},
+			Schedule: []models.ScheduleItem{
+				{PhaseIndex: 0, StartTimestamp: end.AddDate(0, 0, -1).Unix(), EndTimestamp: end.Unix()},
+			},
+		}
+	}
+	experiments := map[string]models.Experiment{
+		models.StatusCompleted: experiment("Ended", now.Add(-10*time.Minute)),
+		models.StatusActive:    experiment("In grace period", now.Add(-time.Minute)),
+	}
+	for _, exp := range experiments {
+		if _, err := db.ExperimentsCollection.InsertOne(ctx, exp); err != nil {
+			t.Fatalf("InsertOne: %v", err)
+		}
+	}
+
+	tracker := &ExperimentTracker{config: &config.Config{}, db: db, ntpService: &simulatedClock{now: now, location: time.UTC}}
+	if err := tracker.checkAndUpdateExperiments(); err != nil {
+		t.Fatalf("checkAndUpdateExperiments: %v", err)
+	}
+
+	for wantStatus, exp := range experiments {
+		var stored models.Experiment
+		if err := db.ExperimentsCollection.FindOne(ctx, bson.M{"_id": exp.ID}).Decode(&stored); err != nil {
+			t.Fatalf("FindOne: %v", err)
+		}
+		if stored.Status != wantStatus {
+			t.Errorf("%s has status %s, want %s", exp.Title, stored.Status, wantStatus)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"local_api_v2/internal/models"
+)
+
+// sigmoidSteepness controls how sharp the S-curve of sigmoid interpolation is
+const sigmoidSteepness = 10.0
+
+// scheduleValue returns the value of a daily schedule at the given progress (0..1) through the day.
+// Interpolated schedules ramp from the value of the day towards the value of the next day;
+// the value of the day is held with step interpolation or when the next day has no value.
+func scheduleValue(schedule map[int]float64, day int, dayProgress float64, mode string) (float64, bool) {
+	value, exists := schedule[day]
+	if !exists {
+		return 0, false
+	}
+
+	next, hasNext := schedule[day+1]
+	if !hasNext {
+		return value, true
+	}
+
+	return value + (next-value)*interpolationWeight(mode, dayProgress), true
+}
+
+// interpolates reports whether an interpolation mode produces values between schedule values
+func interpolates(mode string) bool {
+	return mode == models.InterpolationLinear || mode == models.InterpolationSigmoid
+}
+
+// interpolationWeight returns the share of the way to the next day value reached at the given progress
+func interpolationWeight(mode string, progress float64) float64 {
+	progress = math.Max(0, math.Min(1, progress))
+
+	switch mode {
+	case models.InterpolationLinear:
+		return progress
+	case models.InterpolationSigmoid:
+		// Logistic curve rescaled so it starts at exactly 0 and ends at exactly 1
+		low, high := logistic(0), logistic(1)
+		return (logistic(progress) - low) / (high - low)
+	default:
+		return 0
+	}
+}
+
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-sigmoidSteepness*(x-0.5)))
+}
+
+// validateTimeOfDaySchedules checks the breakpoints of the intra-day programs of an experiment,
+// so the executor does not run programs it can only partly evaluate
+func validateTimeOfDaySchedules(exp *models.Experiment) error {
+	for i, phase := range exp.Phases {
+		for key, program := range phase.TimeOfDaySchedule {
+			if len(program.Breakpoints) == 0 {
+				return fmt.Errorf("phase %d: time_of_day_schedule.%s: at least one breakpoint is required", i+1, key)
+			}
+			for _, breakpoint := range program.Breakpoints {
+				if _, err := breakpoint.Minutes(); err != nil {
+					return fmt.Errorf("phase %d: time_of_day_schedule.%s: %v", i+1, key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// timeOfDayValue returns the value of an intra-day program at the given minute of the local day.
+// Breakpoints wrap around midnight, so before the first breakpoint the program continues
+// from the last breakpoint of the previous day. Invalid breakpoints, rejected when the
+// experiment is synced, are ignored.
+func timeOfDayValue(program models.TimeOfDaySchedule, minuteOfDay int) (float64, bool) {
+	type point struct {
+		minute int
+		value  float64
+	}
+
+	points := make([]point, 0, len(program.Breakpoints))
+	for _, breakpoint := range program.Breakpoints {
+		minutes, err := breakpoint.Minutes()
+		if err != nil {
+			continue
+		}
+		points = append(points, point{minutes, breakpoint.Value})
+	}
+	if len(points) == 0 {
+		return 0, false
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].minute < points[j].minute
+	})
+
+	// Find the last breakpoint at or before the current minute
+	current := -1
+	for i, p := range points {
+		if p.minute <= minuteOfDay {
+			current = i
+		}
+	}
+
+	var prev, next point
+	if current == -1 {
+		prev = points[len(points)-1]
+		prev.minute -= 24 * 60
+		next = points[0]
+	} else {
+		prev = points[current]
+		if current+1 < len(points) {
+			next = points[current+1]
+		} else {
+			next = points[0]
+			next.minute += 24 * 60
+		}
+	}
+
+	progress := 0.0
+	if span := next.minute - prev.minute; span > 0 {
+		progress = float64(minuteOfDay-prev.minute) / float64(span)
+	}
+
+	return prev.value + (next.value-prev.value)*interpolationWeight(program.Interpolation, progress), true
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"local_api_v2/internal/models"
+)
+
+func TestTimeOfDayValue(t *testing.T) {
+	program := func(mode string, breakpoints ...models.TimeBreakpoint) models.TimeOfDaySchedule {
+		return models.TimeOfDaySchedule{EntityID: "input_number.temp_day_sb1", Breakpoints: breakpoints, Interpolation: mode}
+	}
+	// 06:00 at 20, 18:00 at 26, listed out of order
+	daily := []models.TimeBreakpoint{{Time: "18:00", Value: 26}, {Time: "06:00", Value: 20}}
+
+	tests := []struct {
+		name       string
+		program    models.TimeOfDaySchedule
+		minute     int
+		want       float64
+		wantExists bool
+	}{
+		{"step at a breakpoint", program("", daily...), 6 * 60, 20, true},
+		{"step between breakpoints", program(models.InterpolationStep, daily...), 12 * 60, 20, true},
+		{"step after the last breakpoint", program("", daily...), 23 * 60, 26, true},
+		{"step before the first breakpoint wraps", program("", daily...), 3 * 60, 26, true},
+		{"linear between breakpoints", program(models.InterpolationLinear, daily...), 9 * 60, 21.5, true},
+		{"linear after the last breakpoint", program(models.InterpolationLinear, daily...), 0, 23, true},
+		{"linear before the first breakpoint wraps", program(models.InterpolationLinear, daily...), 3 * 60, 21.5, true},
+		{"sigmoid halfway", program(models.InterpolationSigmoid, daily...), 12 * 60, 23, true},
+		{"single breakpoint before it", program(models.InterpolationLinear, models.TimeBreakpoint{Time: "12:00", Value: 30}), 60, 30, true},
+		{"single breakpoint after it", program(models.InterpolationLinear, models.TimeBreakpoint{Time: "12:00", Value: 30}), 20 * 60, 30, true},
+		{"invalid breakpoint ignored", program(models.InterpolationLinear, append([]models.TimeBreakpoint{{Time: "25:00", Value: 0}}, daily...)...), 9 * 60, 21.5, true},
+		{"only invalid breakpoints", program("", models.TimeBreakpoint{Time: "noon", Value: 30}), 12 * 60, 0, false},
+		{"no breakpoints", program(""), 12 * 60, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exists := timeOfDayValue(tt.program, tt.minute)
+			if exists != tt.wantExists || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("timeOfDayValue at minute %d = %v, %v, want %v, %v", tt.minute, got, exists, tt.want, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestValidateTimeOfDaySchedules(t *testing.T) {
+	experiment := func(breakpoints ...models.TimeBreakpoint) *models.Experiment {
+		return &models.Experiment{Phases: []models.Phase{
+			{Title: "Plain"},
+			{Title: "Program", TimeOfDaySchedule: map[string]models.TimeOfDaySchedule{
+				"temp": {EntityID: "input_number.temp_day_sb1", Breakpoints: breakpoints},
+			}},
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		exp     *models.Experiment
+		wantErr bool
+	}{
+		{"no programs", &models.Experiment{Phases: []models.Phase{{Title: "Plain"}}}, false},
+		{"valid breakpoints", experiment(models.TimeBreakpoint{Time: "06:00", Value: 20}, models.TimeBreakpoint{Time: "23:59", Value: 18}), false},
+		{"no breakpoints", experiment(), true},
+		{"hour out of range", experiment(models.TimeBreakpoint{Time: "24:00", Value: 20}), true},
+		{"not a time", experiment(models.TimeBreakpoint{Time: "6am", Value: 20}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTimeOfDaySchedules(tt.exp); (err != nil) != tt.wantErr {
+				t.Errorf("validateTimeOfDaySchedules error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
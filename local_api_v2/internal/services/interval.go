@@ -0,0 +1,22 @@
+package services
+
+import "time"
+
+// intervalUpdates delivers a new ticker period to a running loop
+type intervalUpdates chan time.Duration
+
+func newIntervalUpdates() intervalUpdates {
+	return make(intervalUpdates, 1)
+}
+
+// set replaces any update the loop has not picked up yet with d
+func (u intervalUpdates) set(d time.Duration) {
+	select {
+	case <-u:
+	default:
+	}
+	select {
+	case u <- d:
+	default:
+	}
+}
@@ -0,0 +1,486 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/metrics"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/ntp"
+)
+
+// Outbox delivery settings
+const (
+	outboxBatchSize      = 100
+	outboxBaseBackoff    = 5 * time.Second
+	outboxMaxBackoff     = 15 * time.Minute
+	outboxRetention      = 7 * 24 * time.Hour // delivered messages are kept this long for inspection
+	maxOutboxQueryResult = 500
+)
+
+// OutboxService persists outbound backend calls and delivers them with retries,
+// so state transitions survive network outages and restarts
+type OutboxService struct {
+	config     *config.Config
+	db         *database.MongoDB
+	ntpService *ntp.TimeService
+	httpClient *http.Client
+	wake       chan struct{}
+	interval   intervalUpdates
+}
+
+// NewOutboxService creates a new outbox service
+func NewOutboxService(cfg *config.Config, db *database.MongoDB, ntpService *ntp.TimeService) *OutboxService {
+	return &OutboxService{
+		config:     cfg,
+		db:         db,
+		ntpService: ntpService,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		wake:     make(chan struct{}, 1),
+		interval: newIntervalUpdates(),
+	}
+}
+
+// OutboxRequest describes an outbound call to enqueue
+type OutboxRequest struct {
+	Kind           string
+	Method         string
+	Path           string // relative to the backend URL
+	Payload        interface{}
+	Headers        map[string]string
+	IdempotencyKey string // messages with an already known key are ignored
+	CoalesceKey    string // optional, replaces a pending message with the same key
+}
+
+// Enqueue persists an outbound call and triggers a delivery attempt
+func (s *OutboxService) Enqueue(ctx context.Context, req OutboxRequest) error {
+	if req.IdempotencyKey == "" {
+		return fmt.Errorf("idempotency key is required")
+	}
+
+	payload, err := json.Marshal(req.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	now := s.ntpService.Now()
+	message := models.OutboxMessage{
+		IdempotencyKey: req.IdempotencyKey,
+		CoalesceKey:    req.CoalesceKey,
+		Kind:           req.Kind,
+		Method:         req.Method,
+		Path:           req.Path,
+		Payload:        payload,
+		Headers:        req.Headers,
+		Status:         models.OutboxPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if req.CoalesceKey != "" {
+		// Replace the content of a pending message but keep its attempts, backoff and place in
+		// the delivery order
+		filter := bson.M{"coalesce_key": req.CoalesceKey, "status": models.OutboxPending}
+		update := bson.M{
+			"$set": bson.M{
+				"idempotency_key": message.IdempotencyKey,
+				"kind":            message.Kind,
+				"method":          message.Method,
+				"path":            message.Path,
+				"payload":         message.Payload,
+				"headers":         message.Headers,
+				"updated_at":      message.UpdatedAt,
+			},
+			"$setOnInsert": bson.M{
+				"attempts":        message.Attempts,
+				"next_attempt_at": message.NextAttemptAt,
+				"created_at":      message.CreatedAt,
+			},
+		}
+		_, err = s.db.OutboxCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	} else {
+		_, err = s.db.OutboxCollection.InsertOne(ctx, message)
+	}
+
+	if mongo.IsDuplicateKeyError(err) {
+		return nil // already queued or delivered
+	}
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s message: %v", req.Kind, err)
+	}
+
+	s.Wake()
+	return nil
+}
+
+// Wake triggers a delivery pass without waiting for the next tick
+func (s *OutboxService) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartDelivery delivers pending messages until ctx is cancelled
+func (s *OutboxService) StartDelivery(ctx context.Context) {
+	ticker := time.NewTicker(s.config.OutboxInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.deliverDue(ctx); err != nil {
+			slog.Error("Outbox delivery failed", "error", err)
+		}
+		s.updateMetrics(ctx)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Outbox service stopped")
+			return
+		case interval := <-s.interval:
+			ticker.Reset(interval)
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// SetInterval changes the delivery period of a running delivery loop
+func (s *OutboxService) SetInterval(interval time.Duration) {
+	s.interval.set(interval)
+}
+
+// deliverDue sends all pending messages whose next attempt is due, oldest first.
+// Messages to a path with an older message still backing off, or whose older message
+// failed in this pass, are held back to preserve their order.
+func (s *OutboxService) deliverDue(ctx context.Context) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := s.ntpService.Now()
+	filter := bson.M{
+		"status":          models.OutboxPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetLimit(outboxBatchSize)
+
+	cursor, err := s.db.OutboxCollection.Find(queryCtx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find pending messages: %v", err)
+	}
+
+	var messages []models.OutboxMessage
+	if err := cursor.All(queryCtx, &messages); err != nil {
+		return fmt.Errorf("failed to decode pending messages: %v", err)
+	}
+
+	waiting, err := s.oldestWaiting(queryCtx, now)
+	if err != nil {
+		return err
+	}
+
+	blocked := make(map[string]bool)
+	delivered := 0
+
+	for _, message := range messages {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if blocked[message.Path] {
+			continue
+		}
+		if oldest, exists := waiting[message.Path]; exists && oldest.Before(message.CreatedAt) {
+			continue // an older message to the same path is not due yet
+		}
+
+		statusCode, err := s.send(message)
+		if err != nil {
+			blocked[message.Path] = true
+			s.recordFailure(ctx, message, statusCode, err)
+			continue
+		}
+
+		s.recordDelivery(ctx, message, statusCode)
+		delivered++
+	}
+
+	if delivered > 0 {
+		slog.Info("Delivered outbox messages to backend", "count", delivered)
+	}
+
+	// Drop delivered messages past the retention period. Sending may have taken longer than
+	// the query timeout, so the cleanup gets its own.
+	cleanupCtx, cancelCleanup := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelCleanup()
+
+	_, err = s.db.OutboxCollection.DeleteMany(cleanupCtx, bson.M{
+		"status":       models.OutboxDelivered,
+		"delivered_at": bson.M{"$lt": now.Add(-outboxRetention)},
+	})
+	if err != nil {
+		slog.Error("Failed to clean up delivered outbox messages", "error", err)
+	}
+
+	return nil
+}
+
+// oldestWaiting returns, per path, the creation time of the oldest pending message whose
+// next attempt is not due yet
+func (s *OutboxService) oldestWaiting(ctx context.Context, now time.Time) (map[string]time.Time, error) {
+	cursor, err := s.db.OutboxCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":          models.OutboxPending,
+			"next_attempt_at": bson.M{"$gt": now},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$path",
+			"oldest": bson.M{"$min": "$created_at"},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find waiting messages: %v", err)
+	}
+
+	var groups []struct {
+		Path   string    `bson:"_id"`
+		Oldest time.Time `bson:"oldest"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode waiting messages: %v", err)
+	}
+
+	waiting := make(map[string]time.Time, len(groups))
+	for _, group := range groups {
+		waiting[group.Path] = group.Oldest
+	}
+	return waiting, nil
+}
+
+// send performs the HTTP call of a message and returns the response status code
+func (s *OutboxService) send(message models.OutboxMessage) (int, error) {
+	req, err := http.NewRequest(message.Method, s.config.BackendURL+message.Path, bytes.NewReader(message.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", message.IdempotencyKey)
+	if s.config.BackendAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.BackendAPIKey)
+	}
+	for key, value := range message.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("backend returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// sentFilter matches a message only while it still holds the content that was sent. A coalesced
+// message replaced during the call keeps its new content pending.
+func sentFilter(message models.OutboxMessage) bson.M {
+	return bson.M{"_id": message.ID, "idempotency_key": message.IdempotencyKey}
+}
+
+// recordDelivery marks a message as delivered
+func (s *OutboxService) recordDelivery(ctx context.Context, message models.OutboxMessage, statusCode int) {
+	now := s.ntpService.Now()
+	_, err := s.db.OutboxCollection.UpdateOne(ctx, sentFilter(message), bson.M{
+		"$set": bson.M{
+			"status":           models.OutboxDelivered,
+			"attempts":         message.Attempts + 1,
+			"last_status_code": statusCode,
+			"last_error":       "",
+			"delivered_at":     now,
+			"updated_at":       now,
+		},
+	})
+	if err != nil {
+		slog.Error("Failed to mark outbox message as delivered", "message_id", message.ID.Hex(), "error", err)
+	}
+}
+
+// recordFailure schedules the next attempt of a message with exponential backoff.
+// Client errors other than timeouts and rate limits are not retried.
+func (s *OutboxService) recordFailure(ctx context.Context, message models.OutboxMessage, statusCode int, sendErr error) {
+	now := s.ntpService.Now()
+	attempts := message.Attempts + 1
+
+	update := bson.M{
+		"attempts":         attempts,
+		"last_status_code": statusCode,
+		"last_error":       sendErr.Error(),
+		"updated_at":       now,
+	}
+
+	if isPermanentFailure(statusCode) {
+		update["status"] = models.OutboxFailed
+		slog.Error("Outbox message rejected by backend",
+			"idempotency_key", message.IdempotencyKey,
+			"method", message.Method,
+			"path", message.Path,
+			"error", sendErr)
+	} else {
+		backoff := outboxBackoff(attempts)
+		update["next_attempt_at"] = now.Add(backoff)
+		slog.Warn("Outbox message delivery failed, retrying",
+			"idempotency_key", message.IdempotencyKey,
+			"method", message.Method,
+			"path", message.Path,
+			"attempt", attempts,
+			"retry_in", backoff,
+			"error", sendErr)
+	}
+
+	if _, err := s.db.OutboxCollection.UpdateOne(ctx, sentFilter(message), bson.M{"$set": update}); err != nil {
+		slog.Error("Failed to update outbox message", "message_id", message.ID.Hex(), "error", err)
+	}
+}
+
+// isPermanentFailure reports whether a response status code means retrying cannot succeed
+func isPermanentFailure(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 &&
+		statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests
+}
+
+// outboxBackoff returns the delay before the given attempt is retried
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	return backoff
+}
+
+// GetMessages returns the most recent outbox messages, optionally filtered by status
+func (s *OutboxService) GetMessages(ctx context.Context, status string, limit int64) ([]models.OutboxMessage, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if limit <= 0 || limit > maxOutboxQueryResult {
+		limit = maxOutboxQueryResult
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.db.OutboxCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	messages := []models.OutboxMessage{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox: %v", err)
+	}
+
+	return messages, nil
+}
+
+// updateMetrics exports the number of outbox messages per status
+func (s *OutboxService) updateMetrics(ctx context.Context) {
+	countCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	counts, err := s.GetCounts(countCtx)
+	if err != nil {
+		return
+	}
+	for status, count := range counts {
+		metrics.OutboxMessages.WithLabelValues(status).Set(float64(count))
+	}
+}
+
+// GetCounts returns the number of outbox messages per status
+func (s *OutboxService) GetCounts(ctx context.Context) (map[string]int64, error) {
+	counts := map[string]int64{
+		models.OutboxPending:   0,
+		models.OutboxDelivered: 0,
+		models.OutboxFailed:    0,
+	}
+
+	for status := range counts {
+		count, err := s.db.OutboxCollection.CountDocuments(ctx, bson.M{"status": status})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s messages: %v", status, err)
+		}
+		counts[status] = count
+	}
+
+	return counts, nil
+}
+
+// Errors returned by RetryMessage
+var (
+	ErrInvalidOutboxMessageID = errors.New("invalid message ID")
+	ErrOutboxMessageNotFound  = errors.New("message not found")
+	ErrOutboxMessageNotFailed = errors.New("message has not failed")
+)
+
+// RetryMessage puts a failed message back in the queue for immediate delivery
+func (s *OutboxService) RetryMessage(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOutboxMessageID, err)
+	}
+
+	now := s.ntpService.Now()
+	result, err := s.db.OutboxCollection.UpdateOne(ctx,
+		bson.M{"_id": objectID, "status": models.OutboxFailed},
+		bson.M{"$set": bson.M{
+			"status":          models.OutboxPending,
+			"next_attempt_at": now,
+			"updated_at":      now,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retry message: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		count, err := s.db.OutboxCollection.CountDocuments(ctx, bson.M{"_id": objectID})
+		if err != nil {
+			return fmt.Errorf("failed to check message: %v", err)
+		}
+		if count == 0 {
+			return ErrOutboxMessageNotFound
+		}
+		return ErrOutboxMessageNotFailed
+	}
+
+	s.Wake()
+	return nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/ntp"
+)
+
+// TestOutboxCoalesce needs a MongoDB server, set MONGODB_TEST_URI to run it
+func TestOutboxCoalesce(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	db, err := database.NewMongoDB(uri, "local_api_test_"+primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Fatalf("NewMongoDB: %v", err)
+	}
+	ctx := context.Background()
+	t.Cleanup(func() {
+		db.Database.Drop(ctx)
+		db.Disconnect(ctx)
+	})
+
+	outbox := NewOutboxService(&config.Config{}, db, ntp.NewTimeService(ntp.Config{NTPLocation: "UTC"}))
+	heartbeat := func(key string, payload string) OutboxRequest {
+		return OutboxRequest{
+			Kind:           models.OutboxKindHeartbeat,
+			Method:         "POST",
+			Path:           "/heartbeat",
+			Payload:        payload,
+			IdempotencyKey: key,
+			CoalesceKey:    "heartbeat",
+		}
+	}
+	load := func() models.OutboxMessage {
+		var message models.OutboxMessage
+		if err := db.OutboxCollection.FindOne(ctx, bson.M{"coalesce_key": "heartbeat"}).Decode(&message); err != nil {
+			t.Fatalf("FindOne: %v", err)
+		}
+		return message
+	}
+
+	if err := outbox.Enqueue(ctx, heartbeat("heartbeat:1", "first")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	first := load()
+
+	// A failed attempt backs the message off
+	backoff := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	if _, err := db.OutboxCollection.UpdateByID(ctx, first.ID, bson.M{"$set": bson.M{"attempts": 3, "next_attempt_at": backoff}}); err != nil {
+		t.Fatalf("UpdateByID: %v", err)
+	}
+
+	// The first payload is being sent when a newer heartbeat replaces it
+	sent := load()
+	if err := outbox.Enqueue(ctx, heartbeat("heartbeat:2", "second")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	outbox.recordDelivery(ctx, sent, 200)
+
+	replaced := load()
+	if replaced.ID != first.ID {
+		t.Fatalf("coalesced message has ID %s, want %s", replaced.ID.Hex(), first.ID.Hex())
+	}
+	if replaced.Status != models.OutboxPending || string(replaced.Payload) != `"second"` {
+		t.Errorf("message is %s with payload %s, want the second payload pending", replaced.Status, replaced.Payload)
+	}
+	if replaced.Attempts != 3 || !replaced.NextAttemptAt.Equal(backoff) || !replaced.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("coalescing reset attempts %d, next attempt %v or creation %v", replaced.Attempts, replaced.NextAttemptAt, replaced.CreatedAt)
+	}
+
+	// Once the newer payload is sent the message is delivered
+	outbox.recordDelivery(ctx, replaced, 200)
+	if delivered := load(); delivered.Status != models.OutboxDelivered {
+		t.Errorf("message is %s after delivering the second payload, want %s", delivered.Status, models.OutboxDelivered)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/ntp"
+)
+
+// OverrideService manages manual overrides that stop the executor from rewriting an entity
+type OverrideService struct {
+	db         *database.MongoDB
+	ntpService *ntp.TimeService
+	outbox     *OutboxService
+}
+
+// NewOverrideService creates a new override service
+func NewOverrideService(db *database.MongoDB, ntpService *ntp.TimeService) *OverrideService {
+	return &OverrideService{
+		db:         db,
+		ntpService: ntpService,
+	}
+}
+
+// SetOutboxService sets the outbox used to report overrides as experiment deviations
+func (s *OverrideService) SetOutboxService(outbox *OutboxService) {
+	s.outbox = outbox
+}
+
+// CreateOverrideRequest represents the request to register a manual override
+type CreateOverrideRequest struct {
+	ChamberID string     `json:"chamber_id"`
+	EntityID  string     `json:"entity_id"`
+	Value     *float64   `json:"value,omitempty"` // forces the entity to this value while the override is active
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateOverride registers a manual override for an entity of a chamber
+func (s *OverrideService) CreateOverride(ctx context.Context, req *CreateOverrideRequest) (*models.ManualOverride, error) {
+	chamberID, err := primitive.ObjectIDFromHex(req.ChamberID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chamber ID: %v", err)
+	}
+	if req.EntityID == "" {
+		return nil, fmt.Errorf("entity_id is required")
+	}
+
+	now := s.ntpService.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	override := models.ManualOverride{
+		ID:        primitive.NewObjectID(),
+		ChamberID: chamberID,
+		EntityID:  req.EntityID,
+		Value:     req.Value,
+		Reason:    req.Reason,
+		CreatedAt: now,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if _, err := s.db.OverridesCollection.InsertOne(ctx, override); err != nil {
+		return nil, fmt.Errorf("failed to create override: %v", err)
+	}
+
+	slog.Info("Manual override registered", "chamber_id", chamberID.Hex(), "entity_id", override.EntityID, "reason", override.Reason)
+
+	if err := s.queueDeviation(ctx, &override); err != nil {
+		slog.Error("Failed to record override as deviation", "chamber_id", chamberID.Hex(), "override_id", override.ID.Hex(), "error", err)
+	}
+
+	return &override, nil
+}
+
+// queueDeviation reports an override as a deviation of the experiment running in its chamber
+func (s *OverrideService) queueDeviation(ctx context.Context, override *models.ManualOverride) error {
+	if s.outbox == nil {
+		return fmt.Errorf("outbox not set")
+	}
+
+	var experiment models.Experiment
+	err := s.db.ExperimentsCollection.FindOne(ctx, bson.M{
+		"chamber_id": override.ChamberID,
+		"status":     bson.M{"$in": []string{models.StatusActive, models.StatusPaused}},
+	}).Decode(&experiment)
+	if err == mongo.ErrNoDocuments || (err == nil && experiment.BackendID.IsZero()) {
+		return nil // no experiment to record the deviation against
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get running experiment: %v", err)
+	}
+
+	action := "manual control: " + override.Reason
+	if override.ExpiresAt != nil {
+		action = fmt.Sprintf("manual control until %s: %s", override.ExpiresAt.Format(time.RFC3339), override.Reason)
+	}
+
+	return s.outbox.Enqueue(ctx, OutboxRequest{
+		Kind:   models.OutboxKindOverride,
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("/experiments/%s/deviations", experiment.BackendID.Hex()),
+		Payload: map[string]interface{}{
+			"type":           "override",
+			"local_id":       "override:" + override.ID.Hex(),
+			"entity_id":      override.EntityID,
+			"observed_value": override.Value,
+			"action":         action,
+			"occurred_at":    override.CreatedAt,
+		},
+		IdempotencyKey: fmt.Sprintf("override:%s", override.ID.Hex()),
+	})
+}
+
+// GetOverrides returns the overrides in effect, optionally filtered by chamber
+func (s *OverrideService) GetOverrides(ctx context.Context, chamberID string) ([]models.ManualOverride, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": nil},
+			{"expires_at": bson.M{"$gt": s.ntpService.Now()}},
+		},
+	}
+	if chamberID != "" {
+		objectID, err := primitive.ObjectIDFromHex(chamberID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chamber ID: %v", err)
+		}
+		filter["chamber_id"] = objectID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := s.db.OverridesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find overrides: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	overrides := []models.ManualOverride{}
+	if err := cursor.All(ctx, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to decode overrides: %v", err)
+	}
+
+	return overrides, nil
+}
+
+// DeleteOverride removes a manual override so the executor takes control of the entity again
+func (s *OverrideService) DeleteOverride(ctx context.Context, overrideID string) error {
+	objectID, err := primitive.ObjectIDFromHex(overrideID)
+	if err != nil {
+		return fmt.Errorf("invalid override ID: %v", err)
+	}
+
+	result, err := s.db.OverridesCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("failed to delete override: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("override not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/ntp"
+)
+
+// maxDiscoveryReportQuery limits the number of discovery reports returned by a single query
+const maxDiscoveryReportQuery = 500
+
+// RediscoveryService periodically discovers the entities of known chambers again and records
+// what changed since the previous discovery
+type RediscoveryService struct {
+	config         *config.Config
+	db             *database.MongoDB
+	chamberManager *ChamberManager
+	ntpService     *ntp.TimeService
+	outbox         *OutboxService
+	interval       intervalUpdates
+
+	mu sync.Mutex // one re-discovery at a time
+}
+
+// NewRediscoveryService creates a new rediscovery service
+func NewRediscoveryService(cfg *config.Config, db *database.MongoDB, chamberManager *ChamberManager, ntpService *ntp.TimeService) *RediscoveryService {
+	return &RediscoveryService{
+		config:         cfg,
+		db:             db,
+		chamberManager: chamberManager,
+		ntpService:     ntpService,
+		interval:       newIntervalUpdates(),
+	}
+}
+
+// SetOutboxService sets the outbox used to push discovery reports to the backend
+func (s *RediscoveryService) SetOutboxService(outbox *OutboxService) {
+	s.outbox = outbox
+}
+
+// StartRediscovery periodically rediscovers the chambers until ctx is cancelled
+func (s *RediscoveryService) StartRediscovery(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RediscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Rediscovery service stopped")
+			return
+		case interval := <-s.interval:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			if _, err := s.Rediscover(ctx, models.DiscoveryTriggerScheduled); err != nil {
+				slog.Error("Rediscovery failed", "error", err)
+			}
+		}
+	}
+}
+
+// SetInterval changes the period of a running rediscovery service
+func (s *RediscoveryService) SetInterval(interval time.Duration) {
+	s.interval.set(interval)
+}
+
+// Rediscover discovers the entities of all chambers again and returns a report for every chamber
+// whose entities changed. Reports are stored and queued for the backend.
+func (s *RediscoveryService) Rediscover(ctx context.Context, trigger string) ([]models.DiscoveryReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed, err := s.chamberManager.Rediscover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []models.DiscoveryReport{}
+	for _, rediscovered := range changed {
+		chamber := rediscovered.Chamber
+		report := models.DiscoveryReport{
+			ID:               primitive.NewObjectID(),
+			ChamberID:        chamber.ID,
+			BackendChamberID: chamber.BackendID,
+			Suffix:           chamber.Suffix,
+			Instance:         rediscovered.Instance,
+			Trigger:          trigger,
+			Changes:          rediscovered.Changes,
+			DiscoveredAt:     s.ntpService.Now(),
+		}
+
+		if err := s.addReferences(ctx, &report); err != nil {
+			slog.Error("Failed to check experiments for removed entities", "chamber_id", chamber.ID.Hex(), "error", err)
+		}
+
+		slog.Info("Chamber entities changed",
+			"chamber_id", chamber.ID.Hex(),
+			"suffix", chamber.Suffix,
+			"instance", rediscovered.Instance,
+			"changes", len(report.Changes))
+		for _, change := range report.Changes {
+			for _, reference := range change.ReferencedBy {
+				slog.Warn("Removed entity is used by an active experiment",
+					"chamber_id", chamber.ID.Hex(),
+					"entity_id", change.EntityID,
+					"experiment_id", reference.ExperimentID.Hex(),
+					"experiment", reference.Title,
+					"phases", reference.Phases)
+			}
+		}
+
+		if err := s.record(ctx, report); err != nil {
+			slog.Error("Failed to record discovery report", "chamber_id", chamber.ID.Hex(), "error", err)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// addReferences lists the active experiments of the chamber whose phases use a removed entity
+func (s *RediscoveryService) addReferences(ctx context.Context, report *models.DiscoveryReport) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.db.ExperimentsCollection.Find(queryCtx, bson.M{
+		"chamber_id": report.ChamberID,
+		"status":     bson.M{"$in": []string{models.StatusActive, models.StatusPaused}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query experiments: %v", err)
+	}
+
+	var experiments []models.Experiment
+	if err := cursor.All(queryCtx, &experiments); err != nil {
+		return fmt.Errorf("failed to decode experiments: %v", err)
+	}
+
+	for i := range report.Changes {
+		change := &report.Changes[i]
+		if change.Type != models.DiscoveryChangeRemoved {
+			continue
+		}
+		for _, experiment := range experiments {
+			var phases []int
+			for index := range experiment.Phases {
+				if slices.Contains(experiment.Phases[index].EntityIDs(), change.EntityID) {
+					phases = append(phases, index)
+				}
+			}
+			if len(phases) > 0 {
+				change.ReferencedBy = append(change.ReferencedBy, models.DiscoveryReference{
+					ExperimentID: experiment.BackendID,
+					Title:        experiment.Title,
+					Phases:       phases,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// record stores a discovery report and queues it for the backend
+func (s *RediscoveryService) record(ctx context.Context, report models.DiscoveryReport) error {
+	storeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := s.db.DiscoveryReportsCollection.InsertOne(storeCtx, report); err != nil {
+		return fmt.Errorf("failed to store discovery report: %v", err)
+	}
+
+	if report.BackendChamberID.IsZero() {
+		slog.Debug("Chamber not registered, discovery report not sent", "chamber_id", report.ChamberID.Hex())
+		return nil
+	}
+	if s.outbox == nil {
+		return fmt.Errorf("outbox not set")
+	}
+
+	return s.outbox.Enqueue(storeCtx, OutboxRequest{
+		Kind:   models.OutboxKindDiscoveryReport,
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("/chambers/%s/discovery-reports", report.BackendChamberID.Hex()),
+		Payload: map[string]interface{}{
+			"local_id":      report.ID.Hex(),
+			"suffix":        report.Suffix,
+			"instance":      report.Instance,
+			"trigger":       report.Trigger,
+			"changes":       report.Changes,
+			"discovered_at": report.DiscoveredAt,
+		},
+		IdempotencyKey: fmt.Sprintf("discovery_report:%s", report.ID.Hex()),
+	})
+}
+
+// GetReports returns the discovery reports of a chamber, or of all chambers when chamberID is zero,
+// most recent first
+func (s *RediscoveryService) GetReports(ctx context.Context, chamberID primitive.ObjectID, limit int64) ([]models.DiscoveryReport, error) {
+	query := bson.M{}
+	if !chamberID.IsZero() {
+		query["chamber_id"] = chamberID
+	}
+
+	if limit <= 0 || limit > maxDiscoveryReportQuery {
+		limit = maxDiscoveryReportQuery
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "discovered_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.db.DiscoveryReportsCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query discovery reports: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	reports := []models.DiscoveryReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery reports: %v", err)
+	}
+
+	return reports, nil
+}
+
+// configEntities flattens a chamber configuration into its entities keyed by entity ID,
+// with Type set to the input number type, lamp, switch, thermostat or watering type
+func configEntities(config models.ChamberConfig) map[string]models.InputNumber {
+	entities := make(map[string]models.InputNumber)
+	add := func(entityType string, inputNumbers map[string]models.InputNumber) {
+		for entityID, inputNumber := range inputNumbers {
+			inputNumber.Type = entityType
+			entities[entityID] = inputNumber
+		}
+	}
+
+	add(models.DiscoveryTargetLamp, config.Lamps)
+	add(models.DiscoveryTargetSwitch, config.Switches)
+	add(models.DiscoveryTargetThermostat, config.Thermostats)
+	add(models.InputNumberUnrecognised, config.UnrecognisedEntities)
+	add(models.InputNumberDayStart, config.DayStart)
+	add(models.InputNumberDayDuration, config.DayDuration)
+	add(models.InputNumberTempDay, config.Temperature["day"])
+	add(models.InputNumberTempNight, config.Temperature["night"])
+	add(models.InputNumberHumidityDay, config.Humidity["day"])
+	add(models.InputNumberHumidityNight, config.Humidity["night"])
+	add(models.InputNumberCO2Day, config.CO2["day"])
+	add(models.InputNumberCO2Night, config.CO2["night"])
+	for _, zone := range config.WateringZones {
+		add(models.InputNumberWateringStart, zone.StartTimeEntityID)
+		add(models.InputNumberWateringPeriod, zone.PeriodEntityID)
+		add(models.InputNumberWateringPause, zone.PauseBetweenEntityID)
+		add(models.InputNumberWateringDuration, zone.DurationEntityID)
+	}
+	return entities
+}
+
+// diffChamberConfigs returns the entities added, removed or retyped between two configurations,
+// ordered by entity ID. Value changes are not reported.
+func diffChamberConfigs(previous, next models.ChamberConfig) []models.DiscoveryChange {
+	before := configEntities(previous)
+	after := configEntities(next)
+
+	var changes []models.DiscoveryChange
+	for entityID, entity := range after {
+		old, existed := before[entityID]
+		switch {
+		case !existed:
+			changes = append(changes, models.DiscoveryChange{
+				Type:     models.DiscoveryChangeAdded,
+				EntityID: entityID,
+				Name:     entity.Name,
+				NewType:  entity.Type,
+			})
+		case old.Type != entity.Type:
+			changes = append(changes, models.DiscoveryChange{
+				Type:         models.DiscoveryChangeRetyped,
+				EntityID:     entityID,
+				Name:         entity.Name,
+				PreviousType: old.Type,
+				NewType:      entity.Type,
+			})
+		}
+	}
+	for entityID, entity := range before {
+		if _, exists := after[entityID]; !exists {
+			changes = append(changes, models.DiscoveryChange{
+				Type:         models.DiscoveryChangeRemoved,
+				EntityID:     entityID,
+				Name:         entity.Name,
+				PreviousType: entity.Type,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].EntityID < changes[j].EntityID
+	})
+	return changes
+}
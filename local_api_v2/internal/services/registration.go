@@ -6,7 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -26,6 +26,8 @@ type RegistrationService struct {
 	ntpService   *ntp.TimeService
 	httpClient   *http.Client
 	chamberIDMap map[primitive.ObjectID]primitive.ObjectID // local ID -> backend ID
+	outbox       *OutboxService
+	interval     intervalUpdates
 }
 
 // NewRegistrationService creates a new registration service
@@ -38,9 +40,15 @@ func NewRegistrationService(cfg *config.Config, db *database.MongoDB, ntpService
 			Timeout: 30 * time.Second,
 		},
 		chamberIDMap: make(map[primitive.ObjectID]primitive.ObjectID),
+		interval:     newIntervalUpdates(),
 	}
 }
 
+// SetOutboxService sets the outbox used for heartbeats
+func (s *RegistrationService) SetOutboxService(outbox *OutboxService) {
+	s.outbox = outbox
+}
+
 // Updated RegistrationRequest in registration.go
 type RegistrationRequest struct {
 	Name                 string                                   `json:"name"`
@@ -50,7 +58,11 @@ type RegistrationRequest struct {
 	AccessToken          string                                   `json:"access_token"`
 	LocalAPIversion      int                                      `json:"local_api_version"`
 	LocalIP              string                                   `json:"local_ip"`
+	TimeZone             string                                   `json:"time_zone"`   // NTP_LOCATION, schedule days start at its midnight
+	TimeOffset           int                                      `json:"time_offset"` // current UTC offset of TimeZone in minutes
 	Lamps                map[string]models.InputNumber            `json:"lamps"`
+	Switches             map[string]models.InputNumber            `json:"switches"`
+	Thermostats          map[string]models.InputNumber            `json:"thermostats"`
 	WateringZones        []models.WateringZone                    `json:"watering_zones"`
 	UnrecognisedEntities map[string]models.InputNumber            `json:"unrecognised_entities"`
 	DayDuration          map[string]models.InputNumber            `json:"day_duration"`
@@ -65,20 +77,32 @@ func (s *RegistrationService) RegisterChamberWithBackend(chamber *models.Chamber
 	// Skip if already registered
 	if !chamber.BackendID.IsZero() {
 		s.chamberIDMap[chamber.ID] = chamber.BackendID
-		log.Printf("Chamber %s already registered with backend ID: %s", chamber.Name, chamber.BackendID.Hex())
+		slog.Info("Chamber already registered", "chamber", chamber.Name, "backend_id", chamber.BackendID.Hex())
 		return nil
 	}
 
+	// The token of the Home Assistant instance that owns the chamber, chambers on MQTT or Modbus have none
+	accessToken := s.config.HomeAssistantToken
+	if instance := s.config.HomeAssistantInstance(chamber.HomeAssistantName); instance != nil {
+		accessToken = instance.Token
+	} else if s.config.MQTTInstance(chamber.HomeAssistantName) != nil || s.config.ModbusInstance(chamber.HomeAssistantName) != nil {
+		accessToken = ""
+	}
+
 	// Prepare registration request
 	req := RegistrationRequest{
 		Name:                 chamber.Name,
 		Suffix:               chamber.Suffix,
 		Location:             fmt.Sprintf("Local API v2 - %s", chamber.Suffix),
 		HAUrl:                chamber.HomeAssistantURL,
-		AccessToken:          s.config.HomeAssistantToken,
+		AccessToken:          accessToken,
 		LocalAPIversion:      s.config.LocalAPIversion,
 		LocalIP:              chamber.LocalIP,
+		TimeZone:             s.ntpService.Location().String(),
+		TimeOffset:           s.ntpService.TimeOffset(),
 		Lamps:                chamber.Config.Lamps,
+		Switches:             chamber.Config.Switches,
+		Thermostats:          chamber.Config.Thermostats,
 		WateringZones:        chamber.Config.WateringZones,
 		UnrecognisedEntities: chamber.Config.UnrecognisedEntities,
 		DayDuration:          chamber.Config.DayDuration,
@@ -164,14 +188,18 @@ func (s *RegistrationService) RegisterChamberWithBackend(chamber *models.Chamber
 		return fmt.Errorf("failed to update chamber with backend ID: %v", err)
 	}
 
-	log.Printf("✅ Successfully registered chamber %s with backend. Backend ID: %s", chamber.Name, backendID.Hex())
-	log.Printf("  - Sent %d lamps", len(req.Lamps))
-	log.Printf("  - Sent %d watering zones", len(req.WateringZones))
-	log.Printf("  - Sent %d unrecognised entities", len(req.UnrecognisedEntities))
-	log.Printf("  - Climate mappings: %d day_start, %d day_duration",
-		len(req.DayStart), len(req.DayDuration))
-	log.Printf("  - Temperature: %d day, %d night",
-		len(req.Temperature["day"]), len(req.Temperature["night"]))
+	slog.Info("Registered chamber with backend",
+		"chamber", chamber.Name,
+		"backend_id", backendID.Hex(),
+		"lamps", len(req.Lamps),
+		"switches", len(req.Switches),
+		"thermostats", len(req.Thermostats),
+		"watering_zones", len(req.WateringZones),
+		"unrecognised_entities", len(req.UnrecognisedEntities),
+		"day_start", len(req.DayStart),
+		"day_duration", len(req.DayDuration),
+		"temperature_day", len(req.Temperature["day"]),
+		"temperature_night", len(req.Temperature["night"]))
 
 	return nil
 }
@@ -187,14 +215,21 @@ func (s *RegistrationService) StartHeartbeat(ctx context.Context, chamberManager
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Heartbeat service stopped")
+			slog.Info("Heartbeat service stopped")
 			return
+		case interval := <-s.interval:
+			ticker.Reset(interval)
 		case <-ticker.C:
 			s.sendHeartbeats(chamberManager)
 		}
 	}
 }
 
+// SetHeartbeatInterval changes the heartbeat period of a running heartbeat service
+func (s *RegistrationService) SetHeartbeatInterval(interval time.Duration) {
+	s.interval.set(interval)
+}
+
 // sendHeartbeats sends heartbeats for all registered chambers
 func (s *RegistrationService) sendHeartbeats(chamberManager *ChamberManager) {
 	// Update local heartbeats first
@@ -202,7 +237,7 @@ func (s *RegistrationService) sendHeartbeats(chamberManager *ChamberManager) {
 	defer cancel()
 
 	if err := chamberManager.UpdateHeartbeat(ctx); err != nil {
-		log.Printf("Failed to update local heartbeats: %v", err)
+		slog.Error("Failed to update local heartbeats", "error", err)
 	}
 
 	// Send heartbeats to backend for registered chambers
@@ -213,50 +248,46 @@ func (s *RegistrationService) sendHeartbeats(chamberManager *ChamberManager) {
 		}
 
 		if err := s.sendHeartbeat(backendID); err != nil {
-			log.Printf("Failed to send heartbeat for chamber %s: %v", chamber.Name, err)
+			slog.Error("Failed to send heartbeat", "chamber_id", chamber.ID.Hex(), "error", err)
 		}
 	}
 }
 
-// sendHeartbeat sends a heartbeat for a specific chamber
+// sendHeartbeat queues a heartbeat for a specific chamber. Only the latest undelivered
+// heartbeat of a chamber is kept in the outbox.
 func (s *RegistrationService) sendHeartbeat(backendID primitive.ObjectID) error {
+	if s.outbox == nil {
+		return fmt.Errorf("outbox service not set")
+	}
+
+	now := s.ntpService.Now()
+
 	// Prepare heartbeat payload with NTP status
 	heartbeatData := map[string]interface{}{
-		"timestamp":     s.ntpService.Now().Format("2006-01-02T15:04:05Z07:00"),
+		"timestamp":     now.Format("2006-01-02T15:04:05Z07:00"),
 		"ntp_enabled":   s.ntpService.IsEnabled(),
 		"ntp_connected": s.ntpService.IsConnected(),
 		"ntp_offset":    s.ntpService.GetOffset().String(),
+		"time_zone":     s.ntpService.Location().String(),
+		"time_offset":   s.ntpService.TimeOffset(),
 	}
 
-	jsonData, err := json.Marshal(heartbeatData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal heartbeat data: %v", err)
-	}
-
-	// Send heartbeat to backend
-	url := fmt.Sprintf("%s/chambers/%s/heartbeat", s.config.BackendURL, backendID.Hex())
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create heartbeat request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if s.config.BackendAPIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+s.config.BackendAPIKey)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	resp, err := s.httpClient.Do(req)
+	err := s.outbox.Enqueue(ctx, OutboxRequest{
+		Kind:           models.OutboxKindHeartbeat,
+		Method:         http.MethodPost,
+		Path:           fmt.Sprintf("/chambers/%s/heartbeat", backendID.Hex()),
+		Payload:        heartbeatData,
+		IdempotencyKey: fmt.Sprintf("heartbeat:%s:%d", backendID.Hex(), now.UnixNano()),
+		CoalesceKey:    "heartbeat:" + backendID.Hex(),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to send heartbeat: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("heartbeat failed with status %d: %s", resp.StatusCode, string(body))
+		return err
 	}
 
-	log.Printf("💓 Heartbeat sent for chamber %s", backendID.Hex())
+	slog.Debug("Heartbeat queued", "backend_id", backendID.Hex())
 	return nil
 }
 
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/device"
+)
+
+// setpointTolerance is the absolute difference under which two setpoint values are considered equal
+const setpointTolerance = 1e-6
+
+// normalizeSetpoint returns the value Home Assistant is expected to store for the requested value,
+// i.e. the value clamped to [min, max] and rounded to the nearest step counted from min
+func normalizeSetpoint(value, min, max, step float64) float64 {
+	if max >= min {
+		value = math.Max(min, math.Min(max, value))
+	}
+
+	if step > 0 {
+		steps := math.Round((value - min) / step)
+		value = min + steps*step
+		if max >= min && value > max {
+			value -= step
+		}
+	}
+
+	return value
+}
+
+// verifySetpoint compares the read-back state of an entity with the requested value
+func verifySetpoint(entityID string, requested float64, state *device.State, now time.Time) models.SetpointResult {
+	result := models.SetpointResult{
+		EntityID:       entityID,
+		RequestedValue: requested,
+		ExpectedValue:  normalizeSetpoint(requested, state.Min, state.Max, state.Step),
+		Min:            state.Min,
+		Max:            state.Max,
+		Step:           state.Step,
+		Timestamp:      now,
+	}
+
+	if !state.Available {
+		result.Status = models.SetpointFailed
+		result.Error = fmt.Sprintf("entity reports non-numeric state %q", state.Raw)
+		return result
+	}
+	actual := state.Value
+	result.ActualValue = &actual
+
+	switch {
+	case math.Abs(actual-requested) <= setpointTolerance:
+		result.Status = models.SetpointApplied
+	case math.Abs(result.ExpectedValue-requested) > setpointTolerance &&
+		math.Abs(actual-result.ExpectedValue) <= math.Max(setpointTolerance, state.Step/2):
+		result.Status = models.SetpointClamped
+	default:
+		result.Status = models.SetpointFailed
+		result.Error = fmt.Sprintf("read-back value %v does not match requested value %v", actual, requested)
+	}
+
+	return result
+}
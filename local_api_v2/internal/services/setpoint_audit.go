@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/ntp"
+)
+
+// Setpoint audit settings
+const (
+	setpointUploadBatchSize     = 500
+	maxSetpointApplicationQuery = 5000
+)
+
+// SetpointAuditService keeps the append-only log of setpoints written by the executors
+// and uploads it to the backend
+type SetpointAuditService struct {
+	config     *config.Config
+	db         *database.MongoDB
+	ntpService *ntp.TimeService
+	outbox     *OutboxService
+	interval   intervalUpdates
+
+	lastMu sync.Mutex
+	last   map[setpointAuditKey]models.SetpointApplication // last recorded application per entity
+}
+
+// setpointAuditKey identifies the entity of an experiment a setpoint is written to
+type setpointAuditKey struct {
+	experimentID primitive.ObjectID
+	entityID     string
+}
+
+// NewSetpointAuditService creates a new setpoint audit service
+func NewSetpointAuditService(cfg *config.Config, db *database.MongoDB, ntpService *ntp.TimeService) *SetpointAuditService {
+	return &SetpointAuditService{
+		config:     cfg,
+		db:         db,
+		ntpService: ntpService,
+		interval:   newIntervalUpdates(),
+		last:       make(map[setpointAuditKey]models.SetpointApplication),
+	}
+}
+
+// SetOutboxService sets the outbox used for uploads to the backend
+func (s *SetpointAuditService) SetOutboxService(outbox *OutboxService) {
+	s.outbox = outbox
+}
+
+// Record appends a setpoint application to the audit log. The executors rewrite every setpoint
+// each minute, so applications with the same requested and actual value and the same verification
+// status as the last one recorded for the entity are skipped.
+func (s *SetpointAuditService) Record(ctx context.Context, application models.SetpointApplication) error {
+	key := setpointAuditKey{experimentID: application.ExperimentID, entityID: application.EntityID}
+
+	s.lastMu.Lock()
+	defer s.lastMu.Unlock()
+
+	if last, ok := s.last[key]; ok && sameSetpointApplication(last, application) {
+		return nil
+	}
+
+	application.ID = primitive.NewObjectID()
+	application.Synced = false
+
+	if _, err := s.db.SetpointApplicationsCollection.InsertOne(ctx, application); err != nil {
+		return fmt.Errorf("failed to store setpoint application for %s: %v", application.EntityID, err)
+	}
+	s.last[key] = application
+	return nil
+}
+
+// sameSetpointApplication reports whether two applications wrote and read back the same values
+// with the same verification status
+func sameSetpointApplication(a, b models.SetpointApplication) bool {
+	if a.RequestedValue != b.RequestedValue || a.Status != b.Status {
+		return false
+	}
+	if a.ActualValue == nil || b.ActualValue == nil {
+		return a.ActualValue == nil && b.ActualValue == nil
+	}
+	return *a.ActualValue == *b.ActualValue
+}
+
+// GetApplications returns the setpoint applications matching the filter, most recent first
+func (s *SetpointAuditService) GetApplications(ctx context.Context, filter models.SetpointApplicationFilter) ([]models.SetpointApplication, error) {
+	query := bson.M{}
+	if !filter.ChamberID.IsZero() {
+		query["chamber_id"] = filter.ChamberID
+	}
+	if !filter.ExperimentID.IsZero() {
+		query["$or"] = bson.A{
+			bson.M{"experiment_id": filter.ExperimentID},
+			bson.M{"backend_experiment_id": filter.ExperimentID},
+		}
+	}
+	if filter.EntityID != "" {
+		query["entity_id"] = filter.EntityID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	appliedAt := bson.M{}
+	if !filter.From.IsZero() {
+		appliedAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		appliedAt["$lte"] = filter.To
+	}
+	if len(appliedAt) > 0 {
+		query["applied_at"] = appliedAt
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > maxSetpointApplicationQuery {
+		limit = maxSetpointApplicationQuery
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "applied_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.db.SetpointApplicationsCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query setpoint applications: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	applications := []models.SetpointApplication{}
+	if err := cursor.All(ctx, &applications); err != nil {
+		return nil, fmt.Errorf("failed to decode setpoint applications: %v", err)
+	}
+
+	return applications, nil
+}
+
+// StartUpload periodically queues setpoint applications for the backend until ctx is cancelled
+func (s *SetpointAuditService) StartUpload(ctx context.Context) {
+	ticker := time.NewTicker(s.config.AuditUploadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Setpoint audit upload stopped")
+			return
+		case interval := <-s.interval:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			if err := s.uploadPending(ctx); err != nil {
+				slog.Error("Setpoint audit upload failed", "error", err)
+			}
+		}
+	}
+}
+
+// SetInterval changes the upload period of a running upload loop
+func (s *SetpointAuditService) SetInterval(interval time.Duration) {
+	s.interval.set(interval)
+}
+
+// uploadPending queues the applications not handed to the outbox yet, one message per experiment and batch
+func (s *SetpointAuditService) uploadPending(ctx context.Context) error {
+	if s.outbox == nil {
+		return fmt.Errorf("outbox not set")
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	for {
+		opts := options.Find().
+			SetSort(bson.D{{Key: "applied_at", Value: 1}}).
+			SetLimit(setpointUploadBatchSize)
+
+		cursor, err := s.db.SetpointApplicationsCollection.Find(queryCtx, bson.M{
+			"synced":                false,
+			"backend_experiment_id": bson.M{"$ne": primitive.NilObjectID},
+		}, opts)
+		if err != nil {
+			return fmt.Errorf("failed to find pending setpoint applications: %v", err)
+		}
+
+		var applications []models.SetpointApplication
+		if err := cursor.All(queryCtx, &applications); err != nil {
+			return fmt.Errorf("failed to decode pending setpoint applications: %v", err)
+		}
+		if len(applications) == 0 {
+			return nil
+		}
+
+		byExperiment := make(map[primitive.ObjectID][]models.SetpointApplication)
+		for _, application := range applications {
+			byExperiment[application.BackendExperimentID] = append(byExperiment[application.BackendExperimentID], application)
+		}
+
+		for experimentID, batch := range byExperiment {
+			if err := s.queueBatch(queryCtx, experimentID, batch); err != nil {
+				return err
+			}
+
+			ids := make([]primitive.ObjectID, len(batch))
+			for i, application := range batch {
+				ids[i] = application.ID
+			}
+			_, err := s.db.SetpointApplicationsCollection.UpdateMany(queryCtx,
+				bson.M{"_id": bson.M{"$in": ids}},
+				bson.M{"$set": bson.M{"synced": true}},
+			)
+			if err != nil {
+				return fmt.Errorf("failed to mark setpoint applications as synced: %v", err)
+			}
+		}
+
+		slog.Info("Queued setpoint applications for backend", "count", len(applications))
+
+		if len(applications) < setpointUploadBatchSize {
+			return nil
+		}
+	}
+}
+
+// queueBatch queues a batch of applications of one experiment for the backend
+func (s *SetpointAuditService) queueBatch(ctx context.Context, backendExperimentID primitive.ObjectID, batch []models.SetpointApplication) error {
+	records := make([]map[string]interface{}, len(batch))
+	for i, application := range batch {
+		records[i] = map[string]interface{}{
+			"local_id":        application.ID.Hex(),
+			"phase_index":     application.PhaseIndex,
+			"day":             application.Day,
+			"entity_id":       application.EntityID,
+			"group":           application.Group,
+			"requested_value": application.RequestedValue,
+			"expected_value":  application.ExpectedValue,
+			"actual_value":    application.ActualValue,
+			"status":          application.Status,
+			"error":           application.Error,
+			"time_source":     application.TimeSource,
+			"applied_at":      application.AppliedAt,
+			"system_time":     application.SystemTime,
+		}
+	}
+
+	first, last := batch[0].ID.Hex(), batch[len(batch)-1].ID.Hex()
+	return s.outbox.Enqueue(ctx, OutboxRequest{
+		Kind:           models.OutboxKindSetpointApplications,
+		Method:         http.MethodPost,
+		Path:           fmt.Sprintf("/experiments/%s/setpoint-applications", backendExperimentID.Hex()),
+		Payload:        map[string]interface{}{"applications": records},
+		IdempotencyKey: fmt.Sprintf("setpoint_applications:%s:%s", first, last),
+	})
+}
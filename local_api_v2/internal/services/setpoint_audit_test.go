@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/ntp"
+)
+
+// newAuditTestService connects a setpoint audit service to a throwaway database, skipping the
+// test when MONGODB_TEST_URI is not set
+func newAuditTestService(t *testing.T) (*SetpointAuditService, *database.MongoDB) {
+	t.Helper()
+
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	db, err := database.NewMongoDB(uri, "local_api_test_"+primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Fatalf("NewMongoDB: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Database.Drop(context.Background())
+		db.Disconnect(context.Background())
+	})
+
+	ntpService := ntp.NewTimeService(ntp.Config{NTPLocation: "UTC"})
+	audit := NewSetpointAuditService(&config.Config{}, db, ntpService)
+	audit.SetOutboxService(NewOutboxService(&config.Config{}, db, ntpService))
+	return audit, db
+}
+
+// TestSetpointAuditRecord needs a MongoDB server, set MONGODB_TEST_URI to run it
+func TestSetpointAuditRecord(t *testing.T) {
+	audit, db := newAuditTestService(t)
+	ctx := context.Background()
+
+	experimentID := primitive.NewObjectID()
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	application := func(minute int, entityID string, requested, actual float64, status string) models.SetpointApplication {
+		return models.SetpointApplication{
+			ExperimentID:   experimentID,
+			EntityID:       entityID,
+			RequestedValue: requested,
+			ExpectedValue:  requested,
+			ActualValue:    &actual,
+			Status:         status,
+			AppliedAt:      start.Add(time.Duration(minute) * time.Minute),
+		}
+	}
+
+	writes := []models.SetpointApplication{
+		application(0, "input_number.temp", 21, 21, models.SetpointApplied),
+		application(0, "input_number.humidity", 60, 60, models.SetpointApplied),
+		application(1, "input_number.temp", 21, 21, models.SetpointApplied),     // unchanged, skipped
+		application(1, "input_number.humidity", 60, 60, models.SetpointApplied), // unchanged, skipped
+		application(2, "input_number.temp", 22, 22, models.SetpointApplied),     // requested value changed
+		application(3, "input_number.temp", 22, 21.5, models.SetpointFailed),    // read-back and status changed
+		application(4, "input_number.temp", 22, 21.5, models.SetpointFailed),    // unchanged, skipped
+		application(5, "input_number.temp", 22, 22, models.SetpointApplied),     // recovered
+	}
+	writes[6].Error = "read back 21.5" // a new error message alone is not a change
+
+	for _, write := range writes {
+		if err := audit.Record(ctx, write); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "applied_at", Value: 1}, {Key: "entity_id", Value: 1}})
+	cursor, err := db.SetpointApplicationsCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	var stored []models.SetpointApplication
+	if err := cursor.All(ctx, &stored); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := []struct {
+		minute   int
+		entityID string
+		status   string
+	}{
+		{0, "input_number.humidity", models.SetpointApplied},
+		{0, "input_number.temp", models.SetpointApplied},
+		{2, "input_number.temp", models.SetpointApplied},
+		{3, "input_number.temp", models.SetpointFailed},
+		{5, "input_number.temp", models.SetpointApplied},
+	}
+	if len(stored) != len(want) {
+		t.Fatalf("stored %d applications, want %d", len(stored), len(want))
+	}
+	for i, w := range want {
+		got := stored[i]
+		if !got.AppliedAt.Equal(start.Add(time.Duration(w.minute)*time.Minute)) || got.EntityID != w.entityID || got.Status != w.status {
+			t.Errorf("application %d is %s %s at %v, want %s %s at minute %d", i, got.EntityID, got.Status, got.AppliedAt, w.entityID, w.status, w.minute)
+		}
+		if got.Synced {
+			t.Errorf("application %d is already synced", i)
+		}
+	}
+
+	// A new experiment records the first write of each entity again
+	next := application(6, "input_number.temp", 22, 22, models.SetpointApplied)
+	next.ExperimentID = primitive.NewObjectID()
+	if err := audit.Record(ctx, next); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	count, err := db.SetpointApplicationsCollection.CountDocuments(ctx, bson.M{"experiment_id": next.ExperimentID})
+	if err != nil {
+		t.Fatalf("CountDocuments: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("new experiment has %d applications, want 1", count)
+	}
+}
+
+// TestSetpointAuditUploadPending needs a MongoDB server, set MONGODB_TEST_URI to run it
+func TestSetpointAuditUploadPending(t *testing.T) {
+	audit, db := newAuditTestService(t)
+	ctx := context.Background()
+
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	var applications []interface{}
+	add := func(backendExperimentID primitive.ObjectID, minute int) {
+		applications = append(applications, models.SetpointApplication{
+			ID:                  primitive.NewObjectID(),
+			ExperimentID:        primitive.NewObjectID(),
+			BackendExperimentID: backendExperimentID,
+			EntityID:            "input_number.temp",
+			Status:              models.SetpointApplied,
+			AppliedAt:           start.Add(time.Duration(minute) * time.Minute),
+		})
+	}
+
+	// One batch more than fits the first query, then two of another experiment and one of an
+	// experiment unknown to the backend
+	for i := 0; i <= setpointUploadBatchSize; i++ {
+		add(first, i)
+	}
+	add(second, setpointUploadBatchSize+1)
+	add(second, setpointUploadBatchSize+2)
+	add(primitive.NilObjectID, setpointUploadBatchSize+3)
+
+	if _, err := db.SetpointApplicationsCollection.InsertMany(ctx, applications); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	if err := audit.uploadPending(ctx); err != nil {
+		t.Fatalf("uploadPending: %v", err)
+	}
+
+	cursor, err := db.OutboxCollection.Find(ctx, bson.M{"kind": models.OutboxKindSetpointApplications})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	var messages []models.OutboxMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	sizes := make(map[string][]int)
+	for _, message := range messages {
+		var payload struct {
+			Applications []map[string]interface{} `json:"applications"`
+		}
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			t.Fatalf("payload of %s: %v", message.Path, err)
+		}
+		sizes[message.Path] = append(sizes[message.Path], len(payload.Applications))
+	}
+
+	firstPath := "/experiments/" + first.Hex() + "/setpoint-applications"
+	secondPath := "/experiments/" + second.Hex() + "/setpoint-applications"
+	if len(messages) != 3 || len(sizes) != 2 {
+		t.Fatalf("queued %d messages for %v, want 3 for two experiments", len(messages), sizes)
+	}
+	if got := sizes[firstPath]; len(got) != 2 || got[0]+got[1] != setpointUploadBatchSize+1 {
+		t.Errorf("first experiment batches %v, want %d applications in two batches", got, setpointUploadBatchSize+1)
+	}
+	if got := sizes[secondPath]; len(got) != 1 || got[0] != 2 {
+		t.Errorf("second experiment batches %v, want one batch of 2", got)
+	}
+
+	pending, err := db.SetpointApplicationsCollection.Find(ctx, bson.M{"synced": false})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	var unsynced []models.SetpointApplication
+	if err := pending.All(ctx, &unsynced); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(unsynced) != 1 || !unsynced[0].BackendExperimentID.IsZero() {
+		t.Errorf("%d applications left unsynced, want only the one without backend experiment", len(unsynced))
+	}
+
+	// Nothing is queued twice
+	if err := audit.uploadPending(ctx); err != nil {
+		t.Fatalf("uploadPending: %v", err)
+	}
+	count, err := db.OutboxCollection.CountDocuments(ctx, bson.M{"kind": models.OutboxKindSetpointApplications})
+	if err != nil {
+		t.Fatalf("CountDocuments: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("outbox holds %d messages after a second upload, want 3", count)
+	}
+}
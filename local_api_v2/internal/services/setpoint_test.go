@@ -0,0 +1,95 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/device"
+)
+
+func TestNormalizeSetpoint(t *testing.T) {
+	tests := []struct {
+		name                  string
+		value, min, max, step float64
+		want                  float64
+	}{
+		{"in range on step", 22, 10, 35, 0.5, 22},
+		{"rounded to step", 22.3, 10, 35, 0.5, 22.5},
+		{"rounded down to step", 22.2, 10, 35, 0.5, 22},
+		{"steps counted from min", 12, 11, 20, 2, 13},
+		{"clamped to max", 40, 10, 35, 0.5, 35},
+		{"clamped to min", 5, 10, 35, 0.5, 10},
+		{"max off the step grid", 20, 11, 20, 2, 19},
+		{"no step", 22.37, 10, 35, 0, 22.37},
+		{"no range", 1234.5, 0, -1, 1, 1235},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeSetpoint(tt.value, tt.min, tt.max, tt.step); math.Abs(got-tt.want) > setpointTolerance {
+				t.Errorf("normalizeSetpoint(%v, %v, %v, %v) = %v, want %v", tt.value, tt.min, tt.max, tt.step, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySetpoint(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	inputNumber := func(value float64) *device.State {
+		return &device.State{
+			EntityID:  "input_number.temp_day_sb1",
+			Raw:       strconv.FormatFloat(value, 'f', -1, 64),
+			Value:     value,
+			Available: true,
+			Min:       10,
+			Max:       35,
+			Step:      0.5,
+		}
+	}
+	unavailable := &device.State{EntityID: "input_number.temp_day_sb1", Raw: "unavailable", Min: 10, Max: 35, Step: 0.5}
+	light := func(percent float64) *device.State {
+		return &device.State{EntityID: "light.lamp_1_sb1", Raw: "on", Value: percent, Available: true, Max: 100, Step: 1}
+	}
+
+	tests := []struct {
+		name         string
+		requested    float64
+		state        *device.State
+		wantStatus   string
+		wantExpected float64
+	}{
+		{"applied", 22, inputNumber(22), models.SetpointApplied, 22},
+		{"applied within tolerance", 22, inputNumber(22.0000001), models.SetpointApplied, 22},
+		{"clamped to max", 40, inputNumber(35), models.SetpointClamped, 35},
+		{"clamped to min", 5, inputNumber(10), models.SetpointClamped, 10},
+		{"rounded to step", 22.3, inputNumber(22.5), models.SetpointClamped, 22.5},
+		{"rounded within half a step", 22.3, inputNumber(22.4), models.SetpointClamped, 22.5},
+		{"out of range value not clamped", 40, inputNumber(30), models.SetpointFailed, 35},
+		{"valid value not stored", 22, inputNumber(20), models.SetpointFailed, 22},
+		{"unavailable", 22, unavailable, models.SetpointFailed, 22},
+		{"light brightness", 60, light(60), models.SetpointApplied, 60},
+		{"light off", 0, light(0), models.SetpointApplied, 0},
+		{"light clamped to 100 %", 120, light(100), models.SetpointClamped, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := verifySetpoint(tt.state.EntityID, tt.requested, tt.state, now)
+			if result.Status != tt.wantStatus {
+				t.Errorf("status %s (%s), want %s", result.Status, result.Error, tt.wantStatus)
+			}
+			if math.Abs(result.ExpectedValue-tt.wantExpected) > setpointTolerance {
+				t.Errorf("expected value %v, want %v", result.ExpectedValue, tt.wantExpected)
+			}
+			if (result.ActualValue == nil) == tt.state.Available {
+				t.Errorf("actual value %v for state %q", result.ActualValue, tt.state.Raw)
+			}
+			if result.Status == models.SetpointFailed && result.Error == "" {
+				t.Error("failed result without error")
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/device"
+)
+
+// maxSimulationTicks bounds the work of a single simulation
+const maxSimulationTicks = 200000
+
+// SimulationOptions configures an experiment simulation
+type SimulationOptions struct {
+	Step     time.Duration  // interval between executor ticks, one minute like the cron job when zero
+	Location *time.Location // location of the chamber's local time, UTC when nil
+}
+
+// SimulateExperiment runs an experiment through the executor from the start to the end of its
+// schedule, using a simulated clock and a recording Home Assistant that holds the given entities
+func SimulateExperiment(exp *models.Experiment, entities []models.InputNumber, opts SimulationOptions) (*models.SimulationResult, error) {
+	if len(exp.Schedule) == 0 {
+		return nil, fmt.Errorf("experiment has no schedule")
+	}
+
+	step := opts.Step
+	if step <= 0 {
+		step = time.Minute
+	}
+	location := opts.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	from, to := exp.Schedule[0].StartTimestamp, exp.Schedule[0].EndTimestamp
+	for _, item := range exp.Schedule {
+		from = min(from, item.StartTimestamp)
+		to = max(to, item.EndTimestamp)
+	}
+	if to <= from {
+		return nil, fmt.Errorf("schedule ends before it starts")
+	}
+	if ticks := time.Duration(to-from) * time.Second / step; ticks > maxSimulationTicks {
+		return nil, fmt.Errorf("schedule needs %d ticks with step %v, maximum is %d; use a larger step", ticks, step, maxSimulationTicks)
+	}
+
+	clock := &simulatedClock{location: location}
+	ha := newRecordingHA(entities)
+	executor := &ExecutorService{
+		config:           &config.Config{},
+		driver:           ha,
+		ntpService:       clock,
+		logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		chamberID:        exp.ChamberID,
+		setpointResults:  make(map[string]models.SetpointResult),
+		setpointFailures: make(map[string]int),
+		lastApplied:      make(map[string]float64),
+		writing:          make(map[string]bool),
+	}
+
+	// applyPhaseSettings records the execution time on the phase, keep the caller's copy untouched
+	simulated := *exp
+	simulated.Phases = append([]models.Phase(nil), exp.Phases...)
+
+	result := &models.SimulationResult{
+		From:     time.Unix(from, 0),
+		To:       time.Unix(to, 0),
+		Step:     step.String(),
+		Timeline: []models.SimulatedWrite{},
+		Issues:   []models.SimulationIssue{},
+	}
+
+	lastValues := make(map[string]float64)
+	reported := make(map[string]bool) // issue type + entity, reported once
+	daysSeen := make(map[int]map[int]bool)
+	var gapStart *time.Time
+	gapTicks := 0
+
+	end := time.Unix(to, 0)
+	for now := time.Unix(from, 0); !now.After(end); now = now.Add(step) {
+		clock.now = now
+		result.Ticks++
+
+		phase, phaseIndex, at := executor.getCurrentPhaseWithDay(&simulated)
+		if phase == nil {
+			if gapStart == nil {
+				start := now
+				gapStart = &start
+			}
+			gapTicks++
+			continue
+		}
+		if gapStart != nil {
+			addGapIssue(result, *gapStart, now, gapTicks)
+			gapStart, gapTicks = nil, 0
+		}
+
+		if daysSeen[phaseIndex] == nil {
+			daysSeen[phaseIndex] = make(map[int]bool)
+		}
+		daysSeen[phaseIndex][at.Day] = true
+
+		ha.writes = ha.writes[:0]
+		executor.applyPhaseSettings(context.Background(), &simulated, phaseIndex, phase, at, nil) // failures are reported per write below
+
+		for _, write := range ha.writes {
+			result.TotalWrites++
+			verified := executor.setpointResults[write.EntityID]
+
+			entry := models.SimulatedWrite{
+				Timestamp:   now,
+				PhaseIndex:  phaseIndex,
+				Day:         at.Day,
+				EntityID:    write.EntityID,
+				Value:       write.Value,
+				StoredValue: verified.ActualValue,
+				Status:      verified.Status,
+				Error:       verified.Error,
+			}
+
+			switch verified.Status {
+			case models.SetpointFailed:
+				result.FailedWrites++
+				if !ha.exists(write.EntityID) && !reported[models.SimulationMissingEntity+write.EntityID] {
+					reported[models.SimulationMissingEntity+write.EntityID] = true
+					result.Issues = append(result.Issues, newSimulationIssue(models.SimulationMissingEntity, entry,
+						fmt.Sprintf("entity %s does not exist in the chamber", write.EntityID)))
+				}
+			case models.SetpointClamped:
+				if !reported[models.SimulationValueClamped+write.EntityID] {
+					reported[models.SimulationValueClamped+write.EntityID] = true
+					result.Issues = append(result.Issues, newSimulationIssue(models.SimulationValueClamped, entry,
+						fmt.Sprintf("value %v of %s is stored as %v (min %v, max %v, step %v)",
+							write.Value, write.EntityID, *verified.ActualValue, verified.Min, verified.Max, verified.Step)))
+				}
+			}
+
+			// Only list writes that change the value of an entity
+			if last, exists := lastValues[write.EntityID]; exists && math.Abs(last-write.Value) <= setpointTolerance {
+				continue
+			}
+			lastValues[write.EntityID] = write.Value
+			result.Timeline = append(result.Timeline, entry)
+		}
+	}
+	if gapStart != nil {
+		addGapIssue(result, *gapStart, end, gapTicks)
+	}
+
+	checkScheduleDays(result, exp, daysSeen)
+
+	return result, nil
+}
+
+// newSimulationIssue creates an issue located at a simulated write
+func newSimulationIssue(issueType string, write models.SimulatedWrite, message string) models.SimulationIssue {
+	phaseIndex, day, timestamp := write.PhaseIndex, write.Day, write.Timestamp
+	return models.SimulationIssue{
+		Type:       issueType,
+		PhaseIndex: &phaseIndex,
+		Day:        &day,
+		EntityID:   write.EntityID,
+		Timestamp:  &timestamp,
+		Message:    message,
+	}
+}
+
+// addGapIssue reports a period without an active phase. A single tick at a phase boundary is ignored,
+// since the executor treats the exact start time as outside of the phase.
+func addGapIssue(result *models.SimulationResult, start, end time.Time, ticks int) {
+	if ticks <= 1 {
+		return
+	}
+	result.Issues = append(result.Issues, models.SimulationIssue{
+		Type:      models.SimulationNoActivePhase,
+		Timestamp: &start,
+		Message:   fmt.Sprintf("no active phase from %s to %s", start.Format(time.RFC3339), end.Format(time.RFC3339)),
+	})
+}
+
+// checkScheduleDays compares the days the executor reached in each phase with the days
+// defined in the phase schedules
+func checkScheduleDays(result *models.SimulationResult, exp *models.Experiment, daysSeen map[int]map[int]bool) {
+	phaseIndices := make([]int, 0, len(daysSeen))
+	for phaseIndex := range daysSeen {
+		phaseIndices = append(phaseIndices, phaseIndex)
+	}
+	sort.Ints(phaseIndices)
+
+	for _, phaseIndex := range phaseIndices {
+		phase := exp.Phases[phaseIndex]
+		seen := daysSeen[phaseIndex]
+
+		check := func(name, entityID string, schedule map[int]float64) {
+			var missing, unused []int
+			for day := range seen {
+				if _, exists := schedule[day]; !exists {
+					missing = append(missing, day)
+				}
+			}
+			for day := range schedule {
+				if !seen[day] {
+					unused = append(unused, day)
+				}
+			}
+
+			index := phaseIndex
+			if len(missing) > 0 {
+				result.Issues = append(result.Issues, models.SimulationIssue{
+					Type:       models.SimulationMissingDayValue,
+					PhaseIndex: &index,
+					EntityID:   entityID,
+					Message:    fmt.Sprintf("%s has no value for days %s", name, formatDays(missing)),
+				})
+			}
+			if len(unused) > 0 {
+				result.Issues = append(result.Issues, models.SimulationIssue{
+					Type:       models.SimulationUnusedDayValue,
+					PhaseIndex: &index,
+					EntityID:   entityID,
+					Message:    fmt.Sprintf("%s defines values for days %s that are never reached", name, formatDays(unused)),
+				})
+			}
+		}
+
+		dailySchedules := []struct {
+			field     string
+			schedules map[string]models.ScheduleConfig
+		}{
+			{"work_day_schedule", phase.WorkDaySchedule},
+			{"temperature_day_schedule", phase.TemperatureDaySchedule},
+			{"temperature_night_schedule", phase.TemperatureNightSchedule},
+			{"humidity_day_schedule", phase.HumidityDaySchedule},
+			{"humidity_night_schedule", phase.HumidityNightSchedule},
+			{"co2_day_schedule", phase.CO2DaySchedule},
+			{"co2_night_schedule", phase.CO2NightSchedule},
+			{"light_intensity_schedule", phase.LightIntensitySchedule},
+			{"switch_schedule", phase.SwitchSchedule},
+		}
+		for _, daily := range dailySchedules {
+			for _, key := range sortedKeys(daily.schedules) {
+				config := daily.schedules[key]
+				check(daily.field+"."+key, config.EntityID, config.Schedule)
+			}
+		}
+
+		for _, key := range sortedKeys(phase.WateringZones) {
+			zone := phase.WateringZones[key]
+			check("watering_zones."+key+".start_time_schedule", zone.StartTimeEntityID, zone.StartTimeSchedule)
+			check("watering_zones."+key+".period_schedule", zone.PeriodEntityID, zone.PeriodSchedule)
+			check("watering_zones."+key+".pause_between_schedule", zone.PauseBetweenEntityID, zone.PauseBetweenSchedule)
+			check("watering_zones."+key+".duration_schedule", zone.DurationEntityID, zone.DurationSchedule)
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map in ascending order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatDays formats a list of day numbers in ascending order
+func formatDays(days []int) string {
+	sort.Ints(days)
+	parts := make([]string, len(days))
+	for i, day := range days {
+		parts[i] = strconv.Itoa(day)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// simulatedClock is an executor clock that returns a fixed, externally advanced time
+type simulatedClock struct {
+	now      time.Time
+	location *time.Location
+}
+
+func (c *simulatedClock) Now() time.Time {
+	return c.now
+}
+
+// NowInLocation mirrors ntp.TimeService.NowInLocation for the simulated time
+func (c *simulatedClock) NowInLocation() time.Time {
+	return c.now.In(c.location)
+}
+
+// Location mirrors ntp.TimeService.Location, schedule days begin at midnight there
+func (c *simulatedClock) Location() *time.Location {
+	return c.location
+}
+
+func (c *simulatedClock) IsEnabled() bool {
+	return false
+}
+
+func (c *simulatedClock) IsConnected() bool {
+	return false
+}
+
+// recordedWrite is a set_value call received by the recording Home Assistant
+type recordedWrite struct {
+	EntityID string
+	Value    float64
+}
+
+// recordingHA is an in-memory device that records writes and stores values clamped to the
+// entity min/max and step like Home Assistant input_number entities do
+type recordingHA struct {
+	entities map[string]device.State
+	writes   []recordedWrite
+}
+
+func newRecordingHA(entities []models.InputNumber) *recordingHA {
+	ha := &recordingHA{entities: make(map[string]device.State, len(entities))}
+	for _, entity := range entities {
+		state := device.State{
+			EntityID: entity.EntityID,
+			Min:      entity.Min,
+			Max:      entity.Max,
+			Step:     entity.Step,
+		}
+		setControlState(&state, entity.Value)
+		ha.entities[entity.EntityID] = state
+	}
+	return ha
+}
+
+// setControlState stores a control value in a state, switches and lights also report on or off
+func setControlState(state *device.State, value float64) {
+	state.Value, state.Available = value, true
+	state.Raw = strconv.FormatFloat(value, 'f', -1, 64)
+	switch device.Domain(state.EntityID) {
+	case device.DomainSwitch, device.DomainLight:
+		state.Raw = "off"
+		if value > 0 {
+			state.Raw = "on"
+		}
+	}
+}
+
+func (ha *recordingHA) exists(entityID string) bool {
+	_, exists := ha.entities[entityID]
+	return exists
+}
+
+func (ha *recordingHA) SetValue(entityID string, value float64) error {
+	ha.writes = append(ha.writes, recordedWrite{entityID, value})
+
+	state, exists := ha.entities[entityID]
+	if !exists {
+		return fmt.Errorf("entity %s not found", entityID)
+	}
+
+	stored := normalizeSetpoint(value, state.Min, state.Max, state.Step)
+	setControlState(&state, stored)
+	ha.entities[entityID] = state
+	return nil
+}
+
+func (ha *recordingHA) GetState(entityID string) (*device.State, error) {
+	state, exists := ha.entities[entityID]
+	if !exists {
+		return nil, fmt.Errorf("entity %s not found", entityID)
+	}
+	return &state, nil
+}
+
+func (ha *recordingHA) GetStates() ([]device.State, error) {
+	states := make([]device.State, 0, len(ha.entities))
+	for _, state := range ha.entities {
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (ha *recordingHA) Subscribe() (<-chan device.StateChange, func(), bool) {
+	return nil, nil, false
+}
+
+// ChamberInputNumbers returns all controls known from a chamber configuration
+func ChamberInputNumbers(chamberConfig models.ChamberConfig) []models.InputNumber {
+	var entities []models.InputNumber
+
+	addAll := func(group map[string]models.InputNumber) {
+		for _, entity := range group {
+			entities = append(entities, entity)
+		}
+	}
+
+	addAll(chamberConfig.Lamps)
+	addAll(chamberConfig.Switches)
+	addAll(chamberConfig.Thermostats)
+	addAll(chamberConfig.UnrecognisedEntities)
+	addAll(chamberConfig.DayDuration)
+	addAll(chamberConfig.DayStart)
+	for _, groups := range []map[string]map[string]models.InputNumber{chamberConfig.Temperature, chamberConfig.Humidity, chamberConfig.CO2} {
+		for _, group := range groups {
+			addAll(group)
+		}
+	}
+	for _, zone := range chamberConfig.WateringZones {
+		addAll(zone.StartTimeEntityID)
+		addAll(zone.PeriodEntityID)
+		addAll(zone.PauseBetweenEntityID)
+		addAll(zone.DurationEntityID)
+	}
+
+	return entities
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"local_api_v2/internal/models"
+)
+
+func TestSimulateExperimentInterpolatedValues(t *testing.T) {
+	const entityID = "input_number.temp_day_sb1"
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entities := []models.InputNumber{
+		{EntityID: entityID, Name: "Temp day", Min: 10, Max: 35, Step: 0.5, Value: 20},
+	}
+
+	tests := []struct {
+		name          string
+		interpolation string
+		schedule      map[int]float64
+		wantClamped   bool
+	}{
+		{name: "linear ramp", interpolation: models.InterpolationLinear, schedule: map[int]float64{1: 20, 2: 23.3, 3: 23.3}},
+		{name: "sigmoid ramp", interpolation: models.InterpolationSigmoid, schedule: map[int]float64{1: 20, 2: 30, 3: 30}},
+		{name: "ramp above max", interpolation: models.InterpolationLinear, schedule: map[int]float64{1: 30, 2: 40, 3: 40}, wantClamped: true},
+		{name: "step value off step", interpolation: models.InterpolationStep, schedule: map[int]float64{1: 20.2, 2: 20.2, 3: 20.2}, wantClamped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := &models.Experiment{
+				Phases: []models.Phase{{
+					DurationDays: 3,
+					TemperatureDaySchedule: map[string]models.ScheduleConfig{
+						"temp": {EntityID: entityID, Schedule: tt.schedule, Interpolation: tt.interpolation},
+					},
+				}},
+				Schedule: []models.ScheduleItem{
+					{PhaseIndex: 0, StartTimestamp: start.Unix(), EndTimestamp: start.AddDate(0, 0, 3).Unix()},
+				},
+			}
+
+			result, err := SimulateExperiment(exp, entities, SimulationOptions{Step: 10 * time.Minute})
+			if err != nil {
+				t.Fatalf("SimulateExperiment: %v", err)
+			}
+
+			clamped := false
+			for _, issue := range result.Issues {
+				if issue.Type == models.SimulationValueClamped {
+					clamped = true
+				}
+			}
+			if clamped != tt.wantClamped {
+				t.Errorf("value_clamped reported = %v, want %v (issues %+v)", clamped, tt.wantClamped, result.Issues)
+			}
+
+			if !tt.wantClamped {
+				for _, write := range result.Timeline {
+					if write.Status != models.SetpointApplied {
+						t.Fatalf("write of %v at %s has status %s, want applied", write.Value, write.Timestamp, write.Status)
+					}
+				}
+			}
+		})
+	}
+}
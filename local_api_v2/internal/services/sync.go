@@ -5,15 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"local_api_v2/internal/config"
 	"local_api_v2/internal/database"
+	"local_api_v2/internal/metrics"
 	"local_api_v2/internal/models"
 	"local_api_v2/pkg/ntp"
 )
@@ -26,6 +29,8 @@ type SyncService struct {
 	httpClient          *http.Client
 	chamberManager      *ChamberManager
 	registrationService *RegistrationService
+	outbox              *OutboxService
+	discoveryRules      *DiscoveryRuleSet
 }
 
 // NewSyncService creates a new sync service
@@ -50,11 +55,21 @@ func (s *SyncService) SetRegistrationService(rs *RegistrationService) {
 	s.registrationService = rs
 }
 
+// SetOutboxService sets the outbox used for events pushed to the backend
+func (s *SyncService) SetOutboxService(outbox *OutboxService) {
+	s.outbox = outbox
+}
+
+// SetDiscoveryRules sets the rule set that receives the discovery rules defined in the backend
+func (s *SyncService) SetDiscoveryRules(rules *DiscoveryRuleSet) {
+	s.discoveryRules = rules
+}
+
 // StartSync starts the periodic synchronization
 func (s *SyncService) StartSync(ctx context.Context) {
 	// Initial sync
 	if err := s.syncAll(); err != nil {
-		log.Printf("❌ Initial sync failed: %v", err)
+		slog.Error("Initial sync failed", "error", err)
 	}
 
 	// Periodic sync every 60 seconds
@@ -64,11 +79,11 @@ func (s *SyncService) StartSync(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Sync service stopped")
+			slog.Info("Sync service stopped")
 			return
 		case <-ticker.C:
 			if err := s.syncAll(); err != nil {
-				log.Printf("❌ Sync failed: %v", err)
+				slog.Error("Sync failed", "error", err)
 			}
 		}
 	}
@@ -76,28 +91,133 @@ func (s *SyncService) StartSync(ctx context.Context) {
 
 // syncAll performs all synchronization tasks
 func (s *SyncService) syncAll() error {
+	// Discovery rules are global and needed before chambers can be found
+	if s.discoveryRules != nil {
+		err := s.syncDiscoveryRules()
+		metrics.ObserveSync("", "discovery_rules", err, s.ntpService.Now())
+		if err != nil {
+			slog.Error("Failed to sync discovery rules", "error", err)
+		}
+	}
+
 	// Get registered chambers
 	registeredChambers := s.chamberManager.GetRegisteredChambers()
 	if len(registeredChambers) == 0 {
-		log.Printf("⚠️  No chambers registered with backend yet")
+		slog.Warn("No chambers registered with backend yet")
 		return nil
 	}
 
 	// Sync experiments for each chamber
 	for _, chamber := range registeredChambers {
-		if err := s.syncExperimentsForChamber(chamber); err != nil {
-			log.Printf("Failed to sync experiments for chamber %s: %v", chamber.Name, err)
+		err := s.syncExperimentsForChamber(chamber)
+		metrics.ObserveSync(chamber.ID.Hex(), "experiments", err, s.ntpService.Now())
+		if err != nil {
+			slog.Error("Failed to sync experiments", "chamber_id", chamber.ID.Hex(), "error", err)
 		}
 	}
 
 	// Sync chamber configurations
 	if err := s.syncChamberConfigs(); err != nil {
-		log.Printf("Failed to sync chamber configs: %v", err)
+		slog.Error("Failed to sync chamber configs", "error", err)
+	}
+
+	// Sync alarm rules
+	for _, chamber := range registeredChambers {
+		err := s.syncAlarmRulesForChamber(chamber)
+		metrics.ObserveSync(chamber.ID.Hex(), "alarm_rules", err, s.ntpService.Now())
+		if err != nil {
+			slog.Error("Failed to sync alarm rules", "chamber_id", chamber.ID.Hex(), "error", err)
+		}
+	}
+
+	// Sync overrides
+	for _, chamber := range registeredChambers {
+		err := s.syncOverridesForChamber(chamber)
+		metrics.ObserveSync(chamber.ID.Hex(), "overrides", err, s.ntpService.Now())
+		if err != nil {
+			slog.Error("Failed to sync overrides", "chamber_id", chamber.ID.Hex(), "error", err)
+		}
+	}
+
+	// Push drift events to backend
+	if err := s.syncDriftEvents(); err != nil {
+		slog.Error("Failed to sync drift events", "error", err)
 	}
 
 	return nil
 }
 
+// syncDriftEvents queues drift events that have not been handed to the outbox yet
+// as experiment deviations for the backend
+func (s *SyncService) syncDriftEvents() error {
+	if s.outbox == nil {
+		return fmt.Errorf("outbox service not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := s.db.DriftEventsCollection.Find(ctx, bson.M{"synced": false})
+	if err != nil {
+		return fmt.Errorf("failed to find drift events: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.DriftEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return fmt.Errorf("failed to decode drift events: %v", err)
+	}
+
+	queuedCount := 0
+	for _, event := range events {
+		if event.BackendExperimentID.IsZero() {
+			continue
+		}
+
+		if err := s.queueDriftEvent(ctx, event); err != nil {
+			slog.Error("Failed to queue drift event", "entity_id", event.EntityID, "error", err)
+			continue
+		}
+
+		_, err := s.db.DriftEventsCollection.UpdateByID(ctx, event.ID, bson.M{"$set": bson.M{"synced": true}})
+		if err != nil {
+			slog.Error("Failed to mark drift event as synced", "event_id", event.ID.Hex(), "error", err)
+			continue
+		}
+		queuedCount++
+	}
+
+	if queuedCount > 0 {
+		slog.Info("Queued drift events for backend", "count", queuedCount)
+	}
+
+	return nil
+}
+
+// queueDriftEvent queues a single drift event for the backend deviations endpoint
+func (s *SyncService) queueDriftEvent(ctx context.Context, event models.DriftEvent) error {
+	payload := map[string]interface{}{
+		"type":           "drift",
+		"local_id":       event.ID.Hex(),
+		"phase_index":    event.PhaseIndex,
+		"day":            event.Day,
+		"entity_id":      event.EntityID,
+		"expected_value": event.ExpectedValue,
+		"observed_value": event.ObservedValue,
+		"action":         event.Action,
+		"occurred_at":    event.ChangedAt,
+		"detected_at":    event.DetectedAt,
+	}
+
+	return s.outbox.Enqueue(ctx, OutboxRequest{
+		Kind:           models.OutboxKindDriftEvent,
+		Method:         http.MethodPost,
+		Path:           fmt.Sprintf("/experiments/%s/deviations", event.BackendExperimentID.Hex()),
+		Payload:        payload,
+		IdempotencyKey: "drift_event:" + event.ID.Hex(),
+	})
+}
+
 // syncChamberConfigs fetches and updates chamber configurations from backend
 func (s *SyncService) syncChamberConfigs() error {
 	if s.chamberManager == nil {
@@ -115,18 +235,21 @@ func (s *SyncService) syncChamberConfigs() error {
 		// Check if config needs update
 		needsUpdate, err := s.checkConfigNeedsUpdate(chamber)
 		if err != nil {
-			log.Printf("Failed to check config update for chamber %s: %v", chamber.Name, err)
+			metrics.ObserveSync(chamber.ID.Hex(), "config", err, s.ntpService.Now())
+			slog.Error("Failed to check config update", "chamber_id", chamber.ID.Hex(), "error", err)
 			continue
 		}
 
 		if !needsUpdate {
+			metrics.ObserveSync(chamber.ID.Hex(), "config", nil, s.ntpService.Now())
 			continue
 		}
 
 		// Fetch updated config from backend
 		config, err := s.fetchChamberConfig(chamber.BackendID)
 		if err != nil {
-			log.Printf("Failed to fetch config for chamber %s: %v", chamber.Name, err)
+			metrics.ObserveSync(chamber.ID.Hex(), "config", err, s.ntpService.Now())
+			slog.Error("Failed to fetch config", "chamber_id", chamber.ID.Hex(), "error", err)
 			continue
 		}
 
@@ -134,17 +257,19 @@ func (s *SyncService) syncChamberConfigs() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		if err := s.chamberManager.UpdateChamberConfig(ctx, chamber.ID, config); err != nil {
 			cancel()
-			log.Printf("Failed to update config for chamber %s: %v", chamber.Name, err)
+			metrics.ObserveSync(chamber.ID.Hex(), "config", err, s.ntpService.Now())
+			slog.Error("Failed to update config", "chamber_id", chamber.ID.Hex(), "error", err)
 			continue
 		}
 		cancel()
 
+		metrics.ObserveSync(chamber.ID.Hex(), "config", nil, s.ntpService.Now())
 		successCount++
-		log.Printf("📊 Synced configuration for chamber: %s", chamber.Name)
+		slog.Info("Synced chamber configuration", "chamber_id", chamber.ID.Hex(), "chamber", chamber.Name)
 	}
 
 	if successCount > 0 {
-		log.Printf("✅ Successfully synced configs for %d chambers", successCount)
+		slog.Info("Synced chamber configs", "count", successCount)
 	}
 
 	return nil
@@ -219,6 +344,8 @@ func (s *SyncService) fetchChamberConfig(backendID primitive.ObjectID) (*models.
 			ID                   primitive.ObjectID                       `json:"id"`
 			ChamberID            primitive.ObjectID                       `json:"chamber_id"`
 			Lamps                map[string]models.InputNumber            `json:"lamps"`
+			Switches             map[string]models.InputNumber            `json:"switches"`
+			Thermostats          map[string]models.InputNumber            `json:"thermostats"`
 			WateringZones        []models.WateringZone                    `json:"watering_zones"`
 			UnrecognisedEntities map[string]models.InputNumber            `json:"unrecognised_entities"`
 			DayDuration          map[string]models.InputNumber            `json:"day_duration"`
@@ -226,6 +353,8 @@ func (s *SyncService) fetchChamberConfig(backendID primitive.ObjectID) (*models.
 			Temperature          map[string]map[string]models.InputNumber `json:"temperature"`
 			Humidity             map[string]map[string]models.InputNumber `json:"humidity"`
 			CO2                  map[string]map[string]models.InputNumber `json:"co2"`
+			PauseProfile         *models.PauseProfile                     `json:"pause_profile,omitempty"`
+			IdleProfile          map[string]float64                       `json:"idle_profile,omitempty"`
 			UpdatedAt            time.Time                                `json:"updated_at"`
 			SyncedAt             *time.Time                               `json:"synced_at,omitempty"`
 		} `json:"data"`
@@ -242,6 +371,8 @@ func (s *SyncService) fetchChamberConfig(backendID primitive.ObjectID) (*models.
 
 	return &models.ChamberConfig{
 		Lamps:                response.Data.Lamps,
+		Switches:             response.Data.Switches,
+		Thermostats:          response.Data.Thermostats,
 		WateringZones:        response.Data.WateringZones,
 		UnrecognisedEntities: response.Data.UnrecognisedEntities,
 		DayDuration:          response.Data.DayDuration,
@@ -249,6 +380,8 @@ func (s *SyncService) fetchChamberConfig(backendID primitive.ObjectID) (*models.
 		Temperature:          response.Data.Temperature,
 		Humidity:             response.Data.Humidity,
 		CO2:                  response.Data.CO2,
+		PauseProfile:         response.Data.PauseProfile,
+		IdleProfile:          response.Data.IdleProfile,
 		UpdatedAt:            response.Data.UpdatedAt,
 		SyncedAt:             response.Data.SyncedAt,
 	}, nil
@@ -306,6 +439,12 @@ func (s *SyncService) syncExperimentsForChamber(chamber *models.Chamber) error {
 	now := s.ntpService.Now()
 
 	for _, experiment := range response.Data {
+		// Keep the stored version of experiments the executor could not run
+		if err := validateTimeOfDaySchedules(&experiment); err != nil {
+			slog.Error("Skipping experiment with an invalid intra-day program", "chamber_id", chamber.ID.Hex(), "experiment_id", experiment.ID.Hex(), "error", err)
+			continue
+		}
+
 		// Store backend ID and chamber info
 		backendID := experiment.ID
 		experiment.BackendID = backendID
@@ -334,7 +473,7 @@ func (s *SyncService) syncExperimentsForChamber(chamber *models.Chamber) error {
 				experiment,
 			)
 			if err != nil {
-				log.Printf("Failed to update experiment %s: %v", experiment.Title, err)
+				slog.Error("Failed to update experiment", "chamber_id", chamber.ID.Hex(), "experiment_id", experiment.ID.Hex(), "error", err)
 				continue
 			}
 		} else {
@@ -344,7 +483,7 @@ func (s *SyncService) syncExperimentsForChamber(chamber *models.Chamber) error {
 			experiment.UpdatedAt = now
 			_, err = s.db.ExperimentsCollection.InsertOne(ctx, experiment)
 			if err != nil {
-				log.Printf("Failed to insert experiment %s: %v", experiment.Title, err)
+				slog.Error("Failed to insert experiment", "chamber_id", chamber.ID.Hex(), "experiment", experiment.Title, "error", err)
 				continue
 			}
 		}
@@ -353,11 +492,251 @@ func (s *SyncService) syncExperimentsForChamber(chamber *models.Chamber) error {
 
 		// Log active experiments
 		if experiment.Status == models.StatusActive {
-			log.Printf("🔄 Active experiment for chamber %s: %s", chamber.Name, experiment.Title)
+			slog.Debug("Active experiment", "chamber_id", chamber.ID.Hex(), "experiment_id", experiment.ID.Hex(), "experiment", experiment.Title)
+		}
+	}
+
+	slog.Debug("Synced experiments", "chamber_id", chamber.ID.Hex(), "count", syncedCount)
+	return nil
+}
+
+// syncAlarmRulesForChamber mirrors the alarm rules the backend defines for a chamber
+func (s *SyncService) syncAlarmRulesForChamber(chamber *models.Chamber) error {
+	url := fmt.Sprintf("%s/alarm-rules?chamber_id=%s", s.config.BackendURL, chamber.BackendID.Hex())
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	if s.config.BackendAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.BackendAPIKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch alarm rules: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("backend returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Success bool               `json:"success"`
+		Data    []models.AlarmRule `json:"data"`
+		Error   string             `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("alarm rule sync failed: %s", response.Error)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Rules are upserted by backend ID so their local IDs, which raised alarms refer to, stay stable
+	now := s.ntpService.Now()
+	backendIDs := make([]primitive.ObjectID, 0, len(response.Data))
+	for _, rule := range response.Data {
+		rule.BackendID = rule.ID
+		rule.ID = primitive.NilObjectID
+		rule.ChamberID = chamber.ID
+		rule.BackendChamberID = chamber.BackendID
+		rule.SyncedAt = now
+
+		_, err := s.db.AlarmRulesCollection.UpdateOne(ctx,
+			bson.M{"chamber_id": chamber.ID, "backend_id": rule.BackendID},
+			bson.M{
+				"$set":         rule,
+				"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store alarm rule %s: %v", rule.BackendID.Hex(), err)
+		}
+		backendIDs = append(backendIDs, rule.BackendID)
+	}
+
+	// Rules deleted in the backend are removed, their alarms are cleared by the alarm service
+	_, err = s.db.AlarmRulesCollection.DeleteMany(ctx, bson.M{
+		"chamber_id": chamber.ID,
+		"backend_id": bson.M{"$nin": backendIDs},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove deleted alarm rules: %v", err)
+	}
+
+	return nil
+}
+
+// storedDiscoveryRules is the local copy of the discovery rules defined in the backend
+type storedDiscoveryRules struct {
+	ID       string                 `bson:"_id"`
+	Rules    []models.DiscoveryRule `bson:"rules"`
+	SyncedAt time.Time              `bson:"synced_at"`
+}
+
+// storedDiscoveryRulesID is the ID of the single stored discovery rules document
+const storedDiscoveryRulesID = "backend"
+
+// syncDiscoveryRules fetches the discovery rules defined in the backend, stores them for restarts
+// without backend and installs them. Invalid rules are rejected and the previous ones kept.
+func (s *SyncService) syncDiscoveryRules() error {
+	req, err := http.NewRequest("GET", s.config.BackendURL+"/discovery-rules", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	if s.config.BackendAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.BackendAPIKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch discovery rules: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("backend returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Success bool                   `json:"success"`
+		Data    []models.DiscoveryRule `json:"data"`
+		Error   string                 `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("discovery rule sync failed: %s", response.Error)
+	}
+
+	if err := s.discoveryRules.SetBackend(response.Data); err != nil {
+		return fmt.Errorf("invalid discovery rules from backend: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stored := storedDiscoveryRules{ID: storedDiscoveryRulesID, Rules: response.Data, SyncedAt: s.ntpService.Now()}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.db.DiscoveryRulesCollection.ReplaceOne(ctx, bson.M{"_id": storedDiscoveryRulesID}, stored, opts); err != nil {
+		return fmt.Errorf("failed to store discovery rules: %v", err)
+	}
+
+	return nil
+}
+
+// LoadDiscoveryRules installs the discovery rules stored by the last sync, so discovery at startup
+// uses them before the backend is reachable
+func (s *SyncService) LoadDiscoveryRules(ctx context.Context) error {
+	var stored storedDiscoveryRules
+	err := s.db.DiscoveryRulesCollection.FindOne(ctx, bson.M{"_id": storedDiscoveryRulesID}).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load discovery rules: %v", err)
+	}
+	return s.discoveryRules.SetBackend(stored.Rules)
+}
+
+// syncOverridesForChamber mirrors the active backend overrides of a chamber into the local
+// manual overrides, removing the ones that were cancelled in the backend
+func (s *SyncService) syncOverridesForChamber(chamber *models.Chamber) error {
+	url := fmt.Sprintf("%s/chambers/%s/overrides", s.config.BackendURL, chamber.BackendID.Hex())
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	if s.config.BackendAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.BackendAPIKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch overrides: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("backend returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Success bool `json:"success"`
+		Data    []struct {
+			ID        primitive.ObjectID `json:"id"`
+			EntityID  string             `json:"entity_id"`
+			Value     float64            `json:"value"`
+			Reason    string             `json:"reason"`
+			ExpiresAt time.Time          `json:"expires_at"`
+			CreatedAt time.Time          `json:"created_at"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("override sync failed: %s", response.Error)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	backendIDs := make([]primitive.ObjectID, 0, len(response.Data))
+	for _, item := range response.Data {
+		value, expiresAt := item.Value, item.ExpiresAt
+		override := models.ManualOverride{
+			BackendID: item.ID,
+			ChamberID: chamber.ID,
+			EntityID:  item.EntityID,
+			Value:     &value,
+			Reason:    item.Reason,
+			CreatedAt: item.CreatedAt,
+			ExpiresAt: &expiresAt,
+		}
+
+		_, err := s.db.OverridesCollection.UpdateOne(ctx,
+			bson.M{"backend_id": item.ID},
+			bson.M{
+				"$set":         override,
+				"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store override %s: %v", item.ID.Hex(), err)
 		}
+		backendIDs = append(backendIDs, item.ID)
+	}
+
+	// Overrides cancelled in the backend end immediately
+	_, err = s.db.OverridesCollection.DeleteMany(ctx, bson.M{
+		"chamber_id": chamber.ID,
+		"backend_id": bson.M{"$exists": true, "$nin": backendIDs},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove cancelled overrides: %v", err)
 	}
 
-	log.Printf("📊 Synced %d experiments for chamber %s", syncedCount, chamber.Name)
 	return nil
 }
 
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"local_api_v2/internal/config"
+	"local_api_v2/internal/database"
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/device"
+	"local_api_v2/pkg/ntp"
+)
+
+// maxTelemetryQueryResults limits the number of samples returned by a single query
+const maxTelemetryQueryResults = 10000
+
+// TelemetryService samples measured sensor values and stores them per chamber
+type TelemetryService struct {
+	config         *config.Config
+	db             *database.MongoDB
+	chamberManager *ChamberManager
+	ntpService     *ntp.TimeService
+	interval       intervalUpdates
+}
+
+// NewTelemetryService creates a new telemetry service
+func NewTelemetryService(cfg *config.Config, db *database.MongoDB, chamberManager *ChamberManager, ntpService *ntp.TimeService) *TelemetryService {
+	return &TelemetryService{
+		config:         cfg,
+		db:             db,
+		chamberManager: chamberManager,
+		ntpService:     ntpService,
+		interval:       newIntervalUpdates(),
+	}
+}
+
+// StartSampling starts the periodic sensor sampling
+func (s *TelemetryService) StartSampling(ctx context.Context) {
+	if !s.config.TelemetryEnabled {
+		slog.Info("Telemetry sampling disabled")
+		return
+	}
+
+	// Initial sample
+	if err := s.sample(ctx); err != nil {
+		slog.Error("Initial telemetry sample failed", "error", err)
+	}
+
+	ticker := time.NewTicker(s.config.TelemetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Telemetry service stopped")
+			return
+		case interval := <-s.interval:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			if err := s.sample(ctx); err != nil {
+				slog.Error("Telemetry sample failed", "error", err)
+			}
+		}
+	}
+}
+
+// SetInterval changes the sampling period of a running telemetry service
+func (s *TelemetryService) SetInterval(interval time.Duration) {
+	s.interval.set(interval)
+}
+
+// sample reads all sensors from every Home Assistant instance and stores the ones that belong to a known chamber
+func (s *TelemetryService) sample(ctx context.Context) error {
+	sensors, err := s.chamberManager.GetSensors()
+	if err != nil {
+		return fmt.Errorf("failed to get sensors: %v", err)
+	}
+
+	now := s.ntpService.Now()
+	var samples []interface{}
+
+	for _, sensor := range sensors {
+		metric := classifySensor(sensor.Sensor)
+		if metric == "" {
+			continue
+		}
+
+		chamber := sensor.Chamber
+
+		samples = append(samples, models.TelemetrySample{
+			Timestamp: now,
+			Meta: models.TelemetryMeta{
+				ChamberID: chamber.ID,
+				Metric:    metric,
+				EntityID:  sensor.EntityID,
+				Unit:      sensor.Unit,
+			},
+			Value: sensor.Value,
+		})
+	}
+
+	if len(samples) == 0 {
+		return nil
+	}
+
+	insertCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := s.db.TelemetryCollection.InsertMany(insertCtx, samples); err != nil {
+		return fmt.Errorf("failed to store telemetry samples: %v", err)
+	}
+
+	return nil
+}
+
+// classifySensor determines the telemetry metric of a sensor based on its device class,
+// falling back to substring matching on the entity ID and friendly name
+func classifySensor(sensor device.Sensor) string {
+	if metric, ok := models.TelemetryDeviceClasses[sensor.DeviceClass]; ok {
+		return metric
+	}
+
+	lowerID := strings.ToLower(sensor.EntityID)
+	lowerName := strings.ToLower(sensor.FriendlyName)
+
+	for _, metric := range []string{models.MetricCO2, models.MetricHumidity, models.MetricTemperature, models.MetricLight} {
+		for _, substr := range models.TelemetrySensorSubstrings[metric] {
+			if strings.Contains(lowerID, substr) || strings.Contains(lowerName, substr) {
+				return metric
+			}
+		}
+	}
+
+	return ""
+}
+
+// GetTelemetry returns the stored samples of a chamber within the given time range,
+// optionally filtered by metric
+func (s *TelemetryService) GetTelemetry(ctx context.Context, chamberID primitive.ObjectID, from, to time.Time, metric string) ([]models.TelemetrySample, error) {
+	filter := bson.M{
+		"meta.chamber_id": chamberID,
+		"timestamp": bson.M{
+			"$gte": from,
+			"$lte": to,
+		},
+	}
+	if metric != "" {
+		filter["meta.metric"] = metric
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: 1}}).
+		SetLimit(maxTelemetryQueryResults)
+
+	cursor, err := s.db.TelemetryCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	samples := []models.TelemetrySample{}
+	if err := cursor.All(ctx, &samples); err != nil {
+		return nil, fmt.Errorf("failed to decode telemetry: %v", err)
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"local_api_v2/internal/models"
+	"local_api_v2/pkg/device"
+)
+
+func TestClassifySensor(t *testing.T) {
+	tests := []struct {
+		entityID     string
+		friendlyName string
+		deviceClass  string
+		unit         string
+		want         string
+	}{
+		{"sensor.air_sb1", "Air", "temperature", "°C", models.MetricTemperature},
+		{"sensor.air_2_sb1", "Air", "humidity", "%", models.MetricHumidity},
+		{"sensor.gas_sb1", "Gas", "carbon_dioxide", "ppm", models.MetricCO2},
+		{"sensor.par_sb1", "PAR", "illuminance", "lx", models.MetricLight},
+		{"sensor.hum_temp_sb1", "Probe", "temperature", "°C", models.MetricTemperature}, // device class wins over substrings
+		{"sensor.temp_sb1", "Probe", "", "°C", models.MetricTemperature},
+		{"sensor.probe_sb1", "Temperature 1", "", "°C", models.MetricTemperature},
+		{"sensor.hum_sb1", "Probe", "", "%", models.MetricHumidity},
+		{"sensor.CO2_SB1", "Probe", "", "ppm", models.MetricCO2},
+		{"sensor.ppfd_sb1", "Probe", "", "µmol/m²/s", models.MetricLight},
+		{"sensor.lux_sb1", "Probe", "", "lx", models.MetricLight},
+		{"sensor.co2_temp_sb1", "Probe", "", "ppm", models.MetricCO2},         // CO2 before temperature
+		{"sensor.humidity_temp_sb1", "Probe", "", "%", models.MetricHumidity}, // humidity before temperature
+		{"sensor.voltage_sb1", "Supply", "voltage", "V", ""},                  // unknown device class, no substring
+		{"sensor.power_sb1", "Power", "power", "W", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entityID+"/"+tt.deviceClass, func(t *testing.T) {
+			sensor := device.Sensor{EntityID: tt.entityID, FriendlyName: tt.friendlyName, DeviceClass: tt.deviceClass, Unit: tt.unit}
+			if got := classifySensor(sensor); got != tt.want {
+				t.Errorf("classifySensor(%s, %q, %q, %q) = %q, want %q", tt.entityID, tt.friendlyName, tt.deviceClass, tt.unit, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,23 +2,35 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"local_api_v2/internal/config"
 	"local_api_v2/internal/database"
+	"local_api_v2/internal/logging"
+	"local_api_v2/internal/metrics"
 	"local_api_v2/internal/models"
 	"local_api_v2/internal/services"
 	"local_api_v2/pkg/homeassistant"
+	"local_api_v2/pkg/modbus"
+	"local_api_v2/pkg/mqtt"
 	"local_api_v2/pkg/ntp"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -27,10 +39,21 @@ func main() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
 	}
 
-	log.Printf("Starting Local API v2 with chamber suffixes: %v", cfg.ChamberSuffixes)
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("Starting Local API v2",
+		"chamber_suffixes", cfg.ChamberSuffixes,
+		"ntp_enabled", cfg.NTPEnabled,
+		"ntp_servers", cfg.NTPServers,
+		"ntp_sync_interval", cfg.NTPSyncInterval,
+		"log_level", logging.Level())
 
 	// Set up context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -47,33 +70,120 @@ func main() {
 
 	// Start NTP service
 	if err := ntpService.Start(ctx, cfg.NTPSyncInterval); err != nil {
-		log.Printf("Warning: Failed to start NTP service: %v", err)
+		slog.Warn("Failed to start NTP service", "error", err)
 	}
+	metrics.RegisterTimeService(ntpService)
 
 	// Initialize database
 	db, err := database.NewMongoDB(cfg.MongoDBURI, cfg.MongoDBDatabase)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
 	}
 	defer func() {
 		if err := db.Disconnect(context.Background()); err != nil {
-			log.Printf("Error disconnecting from database: %v", err)
+			slog.Error("Error disconnecting from database", "error", err)
 		}
 	}()
 
-	// Initialize Home Assistant client
-	haClient := homeassistant.NewClient(cfg.HomeAssistantURL, cfg.HomeAssistantToken)
+	// Initialize a driver for every Home Assistant instance, MQTT broker and Modbus controller
+	var instances []*services.DeviceInstance
+	for _, instance := range cfg.HomeAssistant {
+		haClient := homeassistant.NewClient(instance.URL, instance.Token)
+		haClient.SetObserver(metrics.ObserveHARequest)
+		if cfg.HAWebSocketEnabled {
+			haStream := homeassistant.NewWebSocketClient(instance.URL, instance.Token)
+			haClient.UseWebSocket(haStream)
+			go haStream.Run(ctx)
+		}
+		instances = append(instances, services.NewDeviceInstance(instance.Name, haClient, instance.ChamberSuffixes))
+		slog.Info("Home Assistant instance configured", "instance", instance.Name, "url", instance.URL, "suffixes", instance.ChamberSuffixes)
+	}
+	for _, instance := range cfg.MQTT {
+		mqttClient := mqtt.NewClient(mqtt.Config{
+			Broker:         instance.Broker,
+			ClientID:       instance.ClientID,
+			Username:       instance.Username,
+			Password:       instance.Password,
+			TopicPrefix:    instance.TopicPrefix,
+			ConfirmTimeout: instance.ConfirmTimeout,
+			ConfirmBudget:  instance.ConfirmBudget,
+		})
+		go mqttClient.Run(ctx)
+		instances = append(instances, services.NewDeviceInstance(instance.Name, mqttClient, instance.ChamberSuffixes))
+		slog.Info("MQTT instance configured", "instance", instance.Name, "broker", instance.Broker, "suffixes", instance.ChamberSuffixes)
+	}
+	for _, instance := range cfg.Modbus {
+		registerMap, err := modbus.LoadRegisterMap(instance.RegisterMap)
+		if err != nil {
+			slog.Error("Failed to load Modbus register map", "instance", instance.Name, "error", err)
+			os.Exit(1)
+		}
+		modbusClient := modbus.NewClient(modbus.Config{
+			Address: instance.Address,
+			UnitID:  instance.UnitID,
+			Timeout: instance.Timeout,
+			Suffix:  instance.ChamberSuffix,
+		}, registerMap)
+		instances = append(instances, services.NewDeviceInstance(instance.Name, modbusClient, []string{instance.ChamberSuffix}))
+		slog.Info("Modbus controller configured", "instance", instance.Name, "address", instance.Address, "model", registerMap.Model, "suffix", instance.ChamberSuffix)
+	}
 
 	// Initialize services
-	discoveryService := services.NewDiscoveryService(haClient)
-	chamberManager := services.NewChamberManager(cfg, db, discoveryService, ntpService)
+	chamberManager := services.NewChamberManager(cfg, db, instances, ntpService)
 	registrationService := services.NewRegistrationService(cfg, db, ntpService)
 	syncService := services.NewSyncService(cfg, db, ntpService)
 	experimentTracker := services.NewExperimentTracker(cfg, db, ntpService)
+	telemetryService := services.NewTelemetryService(cfg, db, chamberManager, ntpService)
+	overrideService := services.NewOverrideService(db, ntpService)
+	outboxService := services.NewOutboxService(cfg, db, ntpService)
+	auditService := services.NewSetpointAuditService(cfg, db, ntpService)
+	alarmService := services.NewAlarmService(cfg, db, chamberManager, ntpService)
+	rediscoveryService := services.NewRediscoveryService(cfg, db, chamberManager, ntpService)
+
+	// Discovery rules: the rules file or the defaults, replaced by rules from the backend once synced
+	discoveryRules := services.NewDiscoveryRuleSet()
+	if cfg.DiscoveryRulesFile != "" {
+		rules, err := services.LoadDiscoveryRulesFile(cfg.DiscoveryRulesFile)
+		if err != nil {
+			slog.Error("Failed to load discovery rules", "error", err)
+			os.Exit(1)
+		}
+		if err := discoveryRules.SetBase(rules, models.DiscoveryRulesFile); err != nil {
+			slog.Error("Invalid discovery rules", "error", err)
+			os.Exit(1)
+		}
+	}
+	chamberManager.SetDiscoveryRules(discoveryRules)
+	syncService.SetDiscoveryRules(discoveryRules)
+	if err := syncService.LoadDiscoveryRules(ctx); err != nil {
+		slog.Warn("Failed to load stored discovery rules", "error", err)
+	}
+	_, rulesSource := discoveryRules.Rules()
+	slog.Info("Discovery rules loaded", "source", rulesSource)
 
 	// Set cross-references
 	syncService.SetChamberManager(chamberManager)
 	syncService.SetRegistrationService(registrationService)
+	syncService.SetOutboxService(outboxService)
+	registrationService.SetOutboxService(outboxService)
+	experimentTracker.SetOutboxService(outboxService)
+	auditService.SetOutboxService(outboxService)
+	alarmService.SetOutboxService(outboxService)
+	overrideService.SetOutboxService(outboxService)
+	rediscoveryService.SetOutboxService(outboxService)
+
+	// Deliver messages queued before a restart without waiting for Home Assistant
+	go func() {
+		slog.Info("Starting outbox delivery")
+		outboxService.StartDelivery(ctx)
+	}()
+
+	// Upload the setpoint audit log
+	go func() {
+		slog.Info("Starting setpoint audit upload")
+		auditService.StartUpload(ctx)
+	}()
 
 	// Use WaitGroups and channels for proper synchronization
 	var (
@@ -81,9 +191,23 @@ func main() {
 		chamberInitialized    = make(chan struct{})
 		registrationCompleted = make(chan struct{})
 		executorServices      []*services.ExecutorService
+		executorsStarted      bool
 		mu                    sync.Mutex
 	)
 
+	// newExecutor creates the executor service of a chamber and tracks it for status and shutdown
+	newExecutor := func(chamber *models.Chamber) *services.ExecutorService {
+		executor := services.NewExecutorService(cfg, db, chamberManager.DriverFor(chamber.ID), chamber.ID, ntpService)
+		if executor != nil {
+			executor.SetAuditService(auditService)
+			executor.SetOutboxService(outboxService)
+		}
+		mu.Lock()
+		executorServices = append(executorServices, executor)
+		mu.Unlock()
+		return executor
+	}
+
 	// Step 1: Wait for Home Assistant connection and initialize chambers
 	wg.Add(1)
 	go func() {
@@ -91,36 +215,34 @@ func main() {
 		defer close(chamberInitialized)
 
 		for {
-			if haClient.IsConnected() {
-				log.Printf("Home Assistant connected. Discovering chambers...")
+			if chamberManager.IsConnected() {
+				slog.Info("Devices connected, discovering chambers")
 
 				// Initialize chambers
 				if err := chamberManager.InitializeChambers(ctx); err != nil {
-					log.Printf("Warning: Chamber initialization failed: %v", err)
+					slog.Warn("Chamber initialization failed", "error", err)
 					time.Sleep(10 * time.Second)
 					continue
 				}
 
 				// Log discovered chambers
 				chambers := chamberManager.GetChambers()
-				log.Printf("Successfully discovered %d chambers:", len(chambers))
+				slog.Info("Discovered chambers", "count", len(chambers))
 				for suffix, chamber := range chambers {
-					log.Printf("  Chamber '%s': %s (%d lamps, %d zones)",
-						suffix, chamber.Name,
-						len(chamber.Config.Lamps),
-						len(chamber.Config.WateringZones))
+					slog.Info("Chamber discovered",
+						"chamber_id", chamber.ID.Hex(),
+						"suffix", suffix,
+						"name", chamber.Name,
+						"instance", chamber.HomeAssistantName,
+						"lamps", len(chamber.Config.Lamps),
+						"watering_zones", len(chamber.Config.WateringZones))
 
 					// Create executor service for each chamber
-					executor := services.NewExecutorService(db, haClient, chamber.ID, ntpService)
-					mu.Lock()
-					executorServices = append(executorServices, executor)
-					mu.Unlock()
+					newExecutor(chamber)
 				}
-
-				haClient.Status = true
 				break
 			}
-			log.Println("Waiting for Home Assistant connection...")
+			slog.Info("Waiting for Home Assistant or MQTT connection")
 			time.Sleep(10 * time.Second)
 		}
 	}()
@@ -136,13 +258,13 @@ func main() {
 
 		chambers := chamberManager.GetChambers()
 		if len(chambers) == 0 {
-			log.Printf("No chambers discovered")
+			slog.Warn("No chambers discovered")
 			return
 		}
 
-		log.Println("Registering chambers with backend...")
+		slog.Info("Registering chambers with backend")
 		if err := chamberManager.RegisterChambersWithBackend(registrationService); err != nil {
-			log.Printf("Warning: Chamber registration failed: %v", err)
+			slog.Warn("Chamber registration failed", "error", err)
 			// Don't return - we can still function without backend registration
 		}
 	}()
@@ -157,52 +279,125 @@ func main() {
 
 		// Start heartbeat service
 		go func() {
-			log.Println("Starting heartbeat service...")
+			slog.Info("Starting heartbeat service")
 			registrationService.StartHeartbeat(ctx, chamberManager)
 		}()
 
 		// Start sync service
 		go func() {
-			log.Println("Starting sync service...")
+			slog.Info("Starting sync service")
 			syncService.StartSync(ctx)
 		}()
 
 		// Start experiment tracking service
 		go func() {
-			log.Println("Starting experiment tracking service...")
+			slog.Info("Starting experiment tracking service")
 			experimentTracker.StartTracking(ctx)
 		}()
 
+		// Start telemetry sampling
+		go func() {
+			slog.Info("Starting telemetry service")
+			telemetryService.StartSampling(ctx)
+		}()
+
+		// Start alarm evaluation
+		go func() {
+			slog.Info("Starting alarm service")
+			alarmService.StartEvaluation(ctx)
+		}()
+
+		// Start periodic re-discovery of the chamber entities
+		go func() {
+			slog.Info("Starting rediscovery service")
+			rediscoveryService.StartRediscovery(ctx)
+		}()
+
 		// Start executor services for each chamber
 		time.Sleep(2 * time.Second) // Give sync service time to fetch experiments
 
 		mu.Lock()
 		defer mu.Unlock()
 
+		executorsStarted = true
 		for i, executor := range executorServices {
 			if executor != nil {
-				log.Printf("Starting executor service %d...", i+1)
+				slog.Info("Starting executor service", "index", i+1)
 				if err := executor.Start(ctx); err != nil {
-					log.Printf("Warning: Failed to start executor service %d: %v", i+1, err)
+					slog.Warn("Failed to start executor service", "index", i+1, "error", err)
 				}
 			}
 		}
 	}()
 
+	// Reload the configuration on SIGHUP or file change without restarting running executors
+	go func() {
+		current := cfg
+		config.Watch(ctx, cfg, func(next *config.Config) {
+			applyConfigReload(current, next, ntpService, registrationService, telemetryService, outboxService, auditService, alarmService, rediscoveryService, discoveryRules)
+
+			// Compare per instance, a suffix moved between instances keeps the flattened list
+			suffixes := next.InstanceSuffixes()
+			if !maps.EqualFunc(current.InstanceSuffixes(), suffixes, slices.Equal) {
+				slog.Info("Chamber suffixes changed", "suffixes", suffixes)
+
+				// Stop the executors of chambers whose suffix was removed or moved
+				removed := chamberManager.SetChamberSuffixes(suffixes)
+				mu.Lock()
+				for _, chamber := range removed {
+					for i, executor := range executorServices {
+						if executor != nil && executor.ChamberID() == chamber.ID {
+							slog.Info("Stopping executor of removed chamber", "chamber_id", chamber.ID.Hex(), "suffix", chamber.Suffix)
+							executor.Stop()
+							executorServices = append(executorServices[:i], executorServices[i+1:]...)
+							break
+						}
+					}
+				}
+				mu.Unlock()
+
+				// Before the initial discovery finished the startup sequence picks the new suffixes up
+				select {
+				case <-chamberInitialized:
+					addChambers(ctx, chamberManager, registrationService, func(chamber *models.Chamber) {
+						executor := newExecutor(chamber)
+						mu.Lock()
+						start := executorsStarted
+						mu.Unlock()
+						if start {
+							if err := executor.Start(ctx); err != nil {
+								slog.Warn("Failed to start executor service", "chamber_id", chamber.ID.Hex(), "error", err)
+							}
+						}
+					})
+				default:
+				}
+			}
+
+			current = next
+		})
+	}()
+
 	// Start simple HTTP server for health checks
 	mux := http.NewServeMux()
-	setupRoutes(mux, db, chamberManager, ntpService, syncService, experimentTracker)
+	getExecutors := func() []*services.ExecutorService {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*services.ExecutorService(nil), executorServices...)
+	}
+	setupRoutes(mux, cfg, db, chamberManager, discoveryRules, rediscoveryService, ntpService, syncService, experimentTracker, telemetryService, overrideService, outboxService, auditService, alarmService, getExecutors)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.Port),
-		Handler: mux,
+		Handler: logging.Middleware(mux),
 	}
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("Starting HTTP server on port %s", cfg.Port)
+		slog.Info("Starting HTTP server", "port", cfg.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			slog.Error("Failed to start server", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -210,11 +405,11 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	log.Println("🚀 Local API v2 initialization complete - waiting for shutdown signal...")
+	slog.Info("Local API v2 initialization complete, waiting for shutdown signal")
 
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Stop NTP service
 	ntpService.Stop()
@@ -223,7 +418,7 @@ func main() {
 	mu.Lock()
 	for i, executor := range executorServices {
 		if executor != nil {
-			log.Printf("Stopping executor service %d...", i+1)
+			slog.Info("Stopping executor service", "index", i+1)
 			executor.Stop()
 		}
 	}
@@ -237,14 +432,192 @@ func main() {
 	defer shutdownCancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
 	}
 
-	log.Println("Server exited")
+	slog.Info("Server exited")
+}
+
+// applyConfigReload applies the settings that can change at runtime and warns about the ones
+// that only take effect after a restart
+func applyConfigReload(current, next *config.Config, ntpService *ntp.TimeService, registrationService *services.RegistrationService, telemetryService *services.TelemetryService, outboxService *services.OutboxService, auditService *services.SetpointAuditService, alarmService *services.AlarmService, rediscoveryService *services.RediscoveryService, discoveryRules *services.DiscoveryRuleSet) {
+	if next.LogLevel != current.LogLevel {
+		if err := logging.SetLevel(next.LogLevel); err != nil {
+			slog.Error("Failed to change log level", "error", err)
+		}
+	}
+	if !slices.Equal(next.NTPServers, current.NTPServers) || next.NTPTimeout != current.NTPTimeout || next.NTPSyncInterval != current.NTPSyncInterval {
+		ntpService.Reconfigure(next.NTPServers, next.NTPTimeout, next.NTPSyncInterval)
+		slog.Info("NTP configuration changed", "servers", next.NTPServers, "sync_interval", next.NTPSyncInterval)
+	}
+	if next.HeartbeatInterval != current.HeartbeatInterval {
+		registrationService.SetHeartbeatInterval(time.Duration(next.HeartbeatInterval) * time.Second)
+	}
+	if next.TelemetryInterval != current.TelemetryInterval {
+		telemetryService.SetInterval(next.TelemetryInterval)
+	}
+	if next.OutboxInterval != current.OutboxInterval {
+		outboxService.SetInterval(next.OutboxInterval)
+	}
+	if next.AuditUploadInterval != current.AuditUploadInterval {
+		auditService.SetInterval(next.AuditUploadInterval)
+	}
+	if next.AlarmInterval != current.AlarmInterval {
+		alarmService.SetInterval(next.AlarmInterval)
+	}
+	if next.RediscoveryInterval != current.RediscoveryInterval {
+		rediscoveryService.SetInterval(next.RediscoveryInterval)
+	}
+
+	// The rules file is re-read on every reload, it is not watched itself
+	if next.DiscoveryRulesFile != "" {
+		rules, err := services.LoadDiscoveryRulesFile(next.DiscoveryRulesFile)
+		if err == nil {
+			err = discoveryRules.SetBase(rules, models.DiscoveryRulesFile)
+		}
+		if err != nil {
+			slog.Error("Failed to reload discovery rules, keeping previous rules", "error", err)
+		}
+	} else if current.DiscoveryRulesFile != "" {
+		discoveryRules.SetBase(services.DefaultDiscoveryRules(), models.DiscoveryRulesDefault)
+	}
+
+	restartOnly := map[string]bool{
+		"port":                   next.Port != current.Port,
+		"home_assistant":         homeAssistantConnectionsChanged(current, next),
+		"mqtt":                   mqttConnectionsChanged(current, next),
+		"modbus":                 modbusConnectionsChanged(current, next),
+		"ha_websocket_enabled":   next.HAWebSocketEnabled != current.HAWebSocketEnabled,
+		"mongodb_uri":            next.MongoDBURI != current.MongoDBURI,
+		"mongodb_database":       next.MongoDBDatabase != current.MongoDBDatabase,
+		"backend_url":            next.BackendURL != current.BackendURL,
+		"backend_api_key":        next.BackendAPIKey != current.BackendAPIKey,
+		"chamber_name":           next.ChamberName != current.ChamberName,
+		"local_ip":               next.LocalIP != current.LocalIP,
+		"ntp_enabled":            next.NTPEnabled != current.NTPEnabled,
+		"ntp_location":           next.NTPLocation != current.NTPLocation,
+		"telemetry_enabled":      next.TelemetryEnabled != current.TelemetryEnabled,
+		"drift_reconciliation":   next.DriftReconciliation != current.DriftReconciliation,
+		"executor_gap_threshold": next.ExecutorGapThreshold != current.ExecutorGapThreshold,
+		"log_format":             next.LogFormat != current.LogFormat,
+		"config_watch_interval":  next.ConfigWatchInterval != current.ConfigWatchInterval,
+	}
+	for key, changed := range restartOnly {
+		if changed {
+			slog.Warn("Configuration change requires a restart to take effect", "key", key)
+		}
+	}
+}
+
+// homeAssistantConnectionsChanged reports whether Home Assistant instances were added, removed,
+// renamed or pointed to another URL or token. Suffix changes are applied at runtime.
+func homeAssistantConnectionsChanged(current, next *config.Config) bool {
+	if len(current.HomeAssistant) != len(next.HomeAssistant) {
+		return true
+	}
+	for i := range current.HomeAssistant {
+		a, b := current.HomeAssistant[i], next.HomeAssistant[i]
+		if a.Name != b.Name || a.URL != b.URL || a.Token != b.Token {
+			return true
+		}
+	}
+	return false
+}
+
+// mqttConnectionsChanged reports whether MQTT brokers were added, removed, renamed or
+// reconfigured. Suffix changes are applied at runtime.
+func mqttConnectionsChanged(current, next *config.Config) bool {
+	if len(current.MQTT) != len(next.MQTT) {
+		return true
+	}
+	for i := range current.MQTT {
+		a, b := current.MQTT[i], next.MQTT[i]
+		if a.Name != b.Name || a.Broker != b.Broker || a.ClientID != b.ClientID || a.Username != b.Username ||
+			a.Password != b.Password || a.TopicPrefix != b.TopicPrefix || a.ConfirmTimeout != b.ConfirmTimeout ||
+			a.ConfirmBudget != b.ConfirmBudget {
+			return true
+		}
+	}
+	return false
+}
+
+// modbusConnectionsChanged reports whether Modbus controllers were added, removed or changed.
+// A controller names its entities after its suffix, so unlike other instances a suffix change
+// also needs a restart. Register maps are only read at startup.
+func modbusConnectionsChanged(current, next *config.Config) bool {
+	if len(current.Modbus) != len(next.Modbus) {
+		return true
+	}
+	for i := range current.Modbus {
+		if current.Modbus[i] != next.Modbus[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// addChambers discovers the chambers of newly configured suffixes, registers them with the
+// backend and calls onNew for every chamber that was not known before
+func addChambers(ctx context.Context, chamberManager *services.ChamberManager, registrationService *services.RegistrationService, onNew func(*models.Chamber)) {
+	known := chamberManager.GetChambers()
+
+	if err := chamberManager.InitializeChambers(ctx); err != nil {
+		slog.Warn("Chamber initialization failed", "error", err)
+		return
+	}
+
+	for suffix, chamber := range chamberManager.GetChambers() {
+		if _, ok := known[suffix]; ok {
+			continue
+		}
+		if err := registrationService.RegisterChamberWithBackend(chamber); err != nil {
+			slog.Warn("Failed to register chamber", "suffix", suffix, "error", err)
+		}
+		onNew(chamber)
+	}
 }
 
 // setupRoutes configures HTTP routes
-func setupRoutes(mux *http.ServeMux, db *database.MongoDB, chamberManager *services.ChamberManager, ntpService *ntp.TimeService, syncService *services.SyncService, experimentTracker *services.ExperimentTracker) {
+func setupRoutes(mux *http.ServeMux, cfg *config.Config, db *database.MongoDB, chamberManager *services.ChamberManager, discoveryRules *services.DiscoveryRuleSet, rediscoveryService *services.RediscoveryService, ntpService *ntp.TimeService, syncService *services.SyncService, experimentTracker *services.ExperimentTracker, telemetryService *services.TelemetryService, overrideService *services.OverrideService, outboxService *services.OutboxService, auditService *services.SetpointAuditService, alarmService *services.AlarmService, getExecutors func() []*services.ExecutorService) {
+	// Prometheus metrics endpoint
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	// Log level endpoints
+	mux.HandleFunc("GET /api/v1/admin/loglevel", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"level": logging.Level()},
+		})
+		w.Write(response)
+	})
+
+	mux.HandleFunc("PUT /api/v1/admin/loglevel", requireBackendAPIKey(cfg.BackendAPIKey, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req struct {
+			Level string `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+		if err := logging.SetLevel(req.Level); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		logging.FromContext(r.Context()).Info("Log level changed", "level", logging.Level())
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"level": logging.Level()},
+		})
+		w.Write(response)
+	}))
+
 	// Health check endpoint
 	mux.HandleFunc("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -277,6 +650,257 @@ func setupRoutes(mux *http.ServeMux, db *database.MongoDB, chamberManager *servi
 		w.Write(statusJSON)
 	})
 
+	// Outbox inspection endpoint
+	mux.HandleFunc("GET /api/v1/sync/outbox", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		var limit int64
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed <= 0 {
+				writeError(w, http.StatusBadRequest, "Invalid 'limit' parameter")
+				return
+			}
+			limit = parsed
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		counts, err := outboxService.GetCounts(ctx)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		messages, err := outboxService.GetMessages(ctx, query.Get("status"), limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data": map[string]interface{}{
+				"counts":   counts,
+				"messages": messages,
+			},
+		})
+		w.Write(response)
+	})
+
+	// Retrying a failed message re-sends it to the backend and requires the backend API key
+	mux.HandleFunc("POST /api/v1/sync/outbox/{id}/retry", requireBackendAPIKey(cfg.BackendAPIKey, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := outboxService.RetryMessage(r.Context(), r.PathValue("id")); err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidOutboxMessageID):
+				writeError(w, http.StatusBadRequest, err.Error())
+			case errors.Is(err, services.ErrOutboxMessageNotFound):
+				writeError(w, http.StatusNotFound, err.Error())
+			case errors.Is(err, services.ErrOutboxMessageNotFailed):
+				writeError(w, http.StatusConflict, err.Error())
+			default:
+				writeError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"message": "Message queued for delivery",
+		})
+		w.Write(response)
+	}))
+
+	// Experiment simulation endpoint, runs an experiment against a simulated clock without touching Home Assistant
+	mux.HandleFunc("POST /api/v1/simulate", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req struct {
+			Experiment  models.Experiment `json:"experiment"`
+			StepMinutes int               `json:"step_minutes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+		if req.StepMinutes < 0 {
+			writeError(w, http.StatusBadRequest, "'step_minutes' must not be negative")
+			return
+		}
+
+		// The experiment may reference the chamber by its local or its backend ID
+		chamber := chamberManager.GetChamberByID(req.Experiment.ChamberID)
+		if chamber == nil {
+			chamber = chamberManager.GetChamberByBackendID(req.Experiment.ChamberID)
+		}
+		if chamber == nil {
+			writeError(w, http.StatusNotFound, "Chamber not found")
+			return
+		}
+
+		result, err := services.SimulateExperiment(&req.Experiment, services.ChamberInputNumbers(chamber.Config), services.SimulationOptions{
+			Step:     time.Duration(req.StepMinutes) * time.Minute,
+			Location: ntpService.Location(),
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    result,
+		})
+		w.Write(response)
+	})
+
+	// Discovery rules in use, in evaluation order
+	mux.HandleFunc("GET /api/v1/discovery/rules", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		rules, source := discoveryRules.Rules()
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data": map[string]interface{}{
+				"source": source,
+				"rules":  rules,
+			},
+		})
+		w.Write(response)
+	})
+
+	// Discovery preview, classifies the current entities with the rules in use or the rules in the body.
+	// Preview and run query Home Assistant and require the backend API key.
+	mux.HandleFunc("POST /api/v1/discovery/preview", requireBackendAPIKey(cfg.BackendAPIKey, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req struct {
+			Rules []models.DiscoveryRule `json:"rules"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+
+		var rules *services.DiscoveryRuleSet
+		if len(req.Rules) > 0 {
+			preview, err := services.NewPreviewRuleSet(req.Rules)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			rules = preview
+		}
+
+		entities, err := chamberManager.PreviewDiscovery(rules)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    entities,
+		})
+		w.Write(response)
+	}))
+
+	// On-demand re-discovery, returns the reports of the chambers whose entities changed
+	mux.HandleFunc("POST /api/v1/discovery/run", requireBackendAPIKey(cfg.BackendAPIKey, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		reports, err := rediscoveryService.Rediscover(ctx, models.DiscoveryTriggerManual)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    reports,
+		})
+		w.Write(response)
+	}))
+
+	// Discovery reports endpoint
+	mux.HandleFunc("GET /api/v1/discovery/reports", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+
+		var chamberID primitive.ObjectID
+		if value := query.Get("chamber_id"); value != "" {
+			var err error
+			chamberID, err = primitive.ObjectIDFromHex(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid chamber ID format")
+				return
+			}
+		}
+
+		var limit int64
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed <= 0 {
+				writeError(w, http.StatusBadRequest, "Invalid 'limit' parameter")
+				return
+			}
+			limit = parsed
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		reports, err := rediscoveryService.GetReports(ctx, chamberID, limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    reports,
+		})
+		w.Write(response)
+	})
+
+	// Executor status endpoint
+	mux.HandleFunc("/api/v1/executor/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		statuses := []map[string]interface{}{}
+		for _, executor := range getExecutors() {
+			if executor != nil {
+				statuses = append(statuses, executor.GetStatus())
+			}
+		}
+
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    statuses,
+		})
+		w.Write(response)
+	})
+
 	// Chambers endpoint
 	mux.HandleFunc("/api/v1/chambers", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -299,6 +923,8 @@ func setupRoutes(mux *http.ServeMux, db *database.MongoDB, chamberManager *servi
 				"status":             chamber.Status,
 				"registered":         !chamber.BackendID.IsZero(),
 				"lamp_count":         len(chamber.Config.Lamps),
+				"switch_count":       len(chamber.Config.Switches),
+				"thermostat_count":   len(chamber.Config.Thermostats),
 				"zone_count":         len(chamber.Config.WateringZones),
 				"unrecognised_count": len(chamber.Config.UnrecognisedEntities),
 				"climate_mappings": map[string]interface{}{
@@ -321,6 +947,227 @@ func setupRoutes(mux *http.ServeMux, db *database.MongoDB, chamberManager *servi
 		w.Write(response)
 	})
 
+	// Chamber telemetry endpoint
+	mux.HandleFunc("GET /api/v1/chambers/{id}/telemetry", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		chamberID, err := primitive.ObjectIDFromHex(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid chamber ID format")
+			return
+		}
+		if chamberManager.GetChamberByID(chamberID) == nil {
+			writeError(w, http.StatusNotFound, "Chamber not found")
+			return
+		}
+
+		query := r.URL.Query()
+		now := ntpService.Now()
+
+		to, err := parseTimeParam(query.Get("to"), now)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid 'to' parameter: "+err.Error())
+			return
+		}
+		from, err := parseTimeParam(query.Get("from"), to.Add(-24*time.Hour))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid 'from' parameter: "+err.Error())
+			return
+		}
+		if from.After(to) {
+			writeError(w, http.StatusBadRequest, "'from' must be before 'to'")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		samples, err := telemetryService.GetTelemetry(ctx, chamberID, from, to, query.Get("metric"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    samples,
+		})
+		w.Write(response)
+	})
+
+	// Setpoint audit log endpoint
+	mux.HandleFunc("GET /api/v1/setpoint-applications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		filter := models.SetpointApplicationFilter{
+			EntityID: query.Get("entity_id"),
+			Status:   query.Get("status"),
+		}
+
+		if value := query.Get("chamber_id"); value != "" {
+			chamberID, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid chamber ID format")
+				return
+			}
+			filter.ChamberID = chamberID
+		}
+		if value := query.Get("experiment_id"); value != "" {
+			experimentID, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid experiment ID format")
+				return
+			}
+			filter.ExperimentID = experimentID
+		}
+
+		var err error
+		if filter.From, err = parseTimeParam(query.Get("from"), time.Time{}); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid 'from' parameter: "+err.Error())
+			return
+		}
+		if filter.To, err = parseTimeParam(query.Get("to"), time.Time{}); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid 'to' parameter: "+err.Error())
+			return
+		}
+		if limit := query.Get("limit"); limit != "" {
+			parsed, err := strconv.ParseInt(limit, 10, 64)
+			if err != nil || parsed <= 0 {
+				writeError(w, http.StatusBadRequest, "Invalid 'limit' parameter")
+				return
+			}
+			filter.Limit = parsed
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		applications, err := auditService.GetApplications(ctx, filter)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    applications,
+		})
+		w.Write(response)
+	})
+
+	// Alarm events endpoint
+	mux.HandleFunc("GET /api/v1/alarms", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+
+		var chamberID primitive.ObjectID
+		if value := query.Get("chamber_id"); value != "" {
+			var err error
+			chamberID, err = primitive.ObjectIDFromHex(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid chamber ID format")
+				return
+			}
+		}
+
+		activeOnly := false
+		if value := query.Get("active"); value != "" {
+			var err error
+			activeOnly, err = strconv.ParseBool(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid 'active' parameter")
+				return
+			}
+		}
+
+		var limit int64
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed <= 0 {
+				writeError(w, http.StatusBadRequest, "Invalid 'limit' parameter")
+				return
+			}
+			limit = parsed
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		events, err := alarmService.GetEvents(ctx, chamberID, activeOnly, limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    events,
+		})
+		w.Write(response)
+	})
+
+	// Manual override endpoints, they force setpoints and require the backend API key
+	mux.HandleFunc("GET /api/v1/overrides", requireBackendAPIKey(cfg.BackendAPIKey, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		overrides, err := overrideService.GetOverrides(r.Context(), r.URL.Query().Get("chamber_id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    overrides,
+		})
+		w.Write(response)
+	}))
+
+	mux.HandleFunc("POST /api/v1/overrides", requireBackendAPIKey(cfg.BackendAPIKey, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req services.CreateOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+
+		override, err := overrideService.CreateOverride(r.Context(), &req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"data":    override,
+		})
+		w.Write(response)
+	}))
+
+	mux.HandleFunc("DELETE /api/v1/overrides/{id}", requireBackendAPIKey(cfg.BackendAPIKey, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := overrideService.DeleteOverride(r.Context(), r.PathValue("id")); err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		response, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"message": "Override removed",
+		})
+		w.Write(response)
+	}))
+
 	// Time endpoint (returns current time from NTP or system)
 	mux.HandleFunc("/api/v1/time", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -444,14 +1291,60 @@ func setupRoutes(mux *http.ServeMux, db *database.MongoDB, chamberManager *servi
 
 		progress := experimentTracker.GetExperimentProgress(experiment)
 
+		gaps, err := experimentTracker.GetGaps(ctx, objectID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
 		response, _ := json.Marshal(map[string]interface{}{
 			"success": true,
 			"data": map[string]interface{}{
 				"experiment": experiment,
 				"progress":   progress,
+				"gaps":       gaps,
 			},
 		})
 		w.Write(response)
 	})
 }
+
+// requireBackendAPIKey only passes requests carrying the backend API key as a bearer token.
+// Without a configured key the endpoint is disabled.
+func requireBackendAPIKey(apiKey string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if apiKey == "" {
+			writeError(w, http.StatusForbidden, "Endpoint requires backend_api_key to be configured")
+			return
+		}
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+			writeError(w, http.StatusUnauthorized, "Invalid or missing API key")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeError writes a JSON error response with the given status code
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	response, _ := json.Marshal(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+	w.Write(response)
+}
+
+// parseTimeParam parses a query time given as RFC3339 or Unix seconds, returning fallback when empty
+func parseTimeParam(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,103 @@
+// Package device defines the interface between the chamber services and the systems
+// that expose chamber entities, such as Home Assistant, an MQTT broker or a Modbus controller.
+package device
+
+import (
+	"strings"
+	"time"
+)
+
+// Entity domains that can be controlled by the chamber services. Entity IDs follow the Home
+// Assistant naming <domain>.<object_id> whatever the driver.
+const (
+	DomainInputNumber = "input_number"
+	DomainNumber      = "number"
+	DomainSwitch      = "switch"
+	DomainLight       = "light"
+	DomainClimate     = "climate"
+	DomainSensor      = "sensor"
+)
+
+// Domain returns the domain of an entity ID, e.g. switch for switch.pump_1
+func Domain(entityID string) string {
+	domain, _, _ := strings.Cut(entityID, ".")
+	return domain
+}
+
+// IsControl reports whether an entity belongs to a domain the chamber services can write
+func IsControl(entityID string) bool {
+	switch Domain(entityID) {
+	case DomainInputNumber, DomainNumber, DomainSwitch, DomainLight, DomainClimate:
+		return true
+	}
+	return false
+}
+
+// State is the current state of an entity as read by a driver. The value of a control is 1 or 0
+// for switches, the brightness in percent for lights (0 when off), the target temperature for
+// thermostats and the number held by input_number and number entities.
+type State struct {
+	EntityID string
+	// Raw is the state as reported by the device, e.g. 21.5, on or unavailable
+	Raw string
+	// Value is the numeric value of the state, valid when Available is true
+	Value     float64
+	Available bool
+	// Min, Max and Step are the limits of controls, zero for sensors
+	Min  float64
+	Max  float64
+	Step float64
+	// LastChanged is when the state last changed, zero when unknown
+	LastChanged time.Time
+}
+
+// StateChange reports a changed entity. OldState is nil for new entities,
+// NewState is nil for removed entities.
+type StateChange struct {
+	EntityID string
+	OldState *State
+	NewState *State
+}
+
+// Control is a writable entity with its value and limits. Switches range from 0 to 1
+// and lights from 0 to 100 %.
+type Control struct {
+	EntityID     string  `json:"entity_id"`
+	FriendlyName string  `json:"friendly_name"`
+	Value        float64 `json:"value"`
+	Min          float64 `json:"min"`
+	Max          float64 `json:"max"`
+	Step         float64 `json:"step"`
+	Unit         string  `json:"unit_of_measurement,omitempty"`
+}
+
+// Sensor is a sensor entity that currently reports a numeric value
+type Sensor struct {
+	EntityID     string  `json:"entity_id"`
+	FriendlyName string  `json:"friendly_name"`
+	DeviceClass  string  `json:"device_class,omitempty"`
+	Value        float64 `json:"value"`
+	Unit         string  `json:"unit_of_measurement,omitempty"`
+	LastUpdated  string  `json:"last_updated"`
+}
+
+// Driver reads and writes chamber entities. Setpoints are input_number.*, number.*, switch.*,
+// light.* or climate.* entities and sensors are sensor.* entities; each driver converts the
+// states of its system into the values above.
+type Driver interface {
+	// URL identifies the endpoint the driver talks to, stored with the chambers it owns
+	URL() string
+	IsConnected() bool
+
+	GetStates() ([]State, error)
+	GetState(entityID string) (*State, error)
+	// GetControls returns the writable entities with their value and limits
+	GetControls() ([]Control, error)
+	GetSensors() ([]Sensor, error)
+	// SetValue writes the value of a control, see State for the value of each domain
+	SetValue(entityID string, value float64) error
+
+	// Subscribe streams state changes as they happen. ok is false when the driver
+	// cannot push changes, callers then rely on polling.
+	Subscribe() (changes <-chan StateChange, unsubscribe func(), ok bool)
+}
@@ -5,19 +5,32 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+
+	"local_api_v2/pkg/device"
 )
 
+var _ device.Driver = (*Client)(nil)
+
 // Client represents Home Assistant API client
 type Client struct {
 	BaseURL    string
 	Token      string
 	HTTPClient *http.Client
 	Status     bool
+
+	stream   *WebSocketClient // optional state mirror, see UseWebSocket
+	observer RequestObserver  // optional, see SetObserver
 }
 
+// RequestObserver is notified about every REST call with its duration and error
+type RequestObserver func(operation string, duration time.Duration, err error)
+
 // NewClient creates a new Home Assistant client
 func NewClient(baseURL, token string) *Client {
 	return &Client{
@@ -30,7 +43,60 @@ func NewClient(baseURL, token string) *Client {
 	}
 }
 
+// UseWebSocket makes the client serve state listings from the mirror of a WebSocket client
+// while it is synced, instead of polling /api/states
+func (c *Client) UseWebSocket(stream *WebSocketClient) {
+	c.stream = stream
+}
+
+// SetObserver registers a function that is called after every REST call, e.g. to record metrics
+func (c *Client) SetObserver(observer RequestObserver) {
+	c.observer = observer
+}
+
+// observe reports a finished REST call to the observer
+func (c *Client) observe(operation string, start time.Time, err error) {
+	if c.observer != nil {
+		c.observer(operation, time.Since(start), err)
+	}
+}
+
+// WebSocket returns the attached WebSocket client, or nil
+func (c *Client) WebSocket() *WebSocketClient {
+	return c.stream
+}
+
+// URL returns the base URL of the Home Assistant instance
+func (c *Client) URL() string {
+	return c.BaseURL
+}
+
+// Subscribe streams state changes from the attached WebSocket client, see DeviceStateChange.
+// ok is false when no WebSocket client is attached.
+func (c *Client) Subscribe() (changes <-chan device.StateChange, unsubscribe func(), ok bool) {
+	if c.stream == nil {
+		return nil, nil, false
+	}
+
+	stateChanges, unsubscribe := c.stream.Subscribe()
+	converted := make(chan device.StateChange, c.stream.SubscriberBuffer)
+	go func() {
+		defer close(converted)
+		for change := range stateChanges {
+			select {
+			case converted <- DeviceStateChange(change):
+			default:
+				slog.Warn("Dropping state change for slow subscriber", "entity_id", change.EntityID)
+			}
+		}
+	}()
+	return converted, unsubscribe, true
+}
+
 func (c *Client) IsConnected() bool {
+	if c.stream != nil && c.stream.IsConnected() {
+		return true
+	}
 	resp, err := c.HTTPClient.Get(c.BaseURL)
 	if err != nil {
 		return false
@@ -51,19 +117,29 @@ type State struct {
 	LastUpdated string                 `json:"last_updated"`
 }
 
-// InputNumberEntity represents an input_number entity in Home Assistant
-type InputNumberEntity struct {
-	EntityID     string  `json:"entity_id"`
-	FriendlyName string  `json:"friendly_name"`
-	Value        float64 `json:"value"`
-	Min          float64 `json:"min"`
-	Max          float64 `json:"max"`
-	Step         float64 `json:"step"`
-	Unit         string  `json:"unit_of_measurement,omitempty"`
+// GetStates retrieves all states from Home Assistant, see DeviceState
+func (c *Client) GetStates() ([]device.State, error) {
+	states, err := c.states()
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]device.State, 0, len(states))
+	for _, state := range states {
+		converted = append(converted, DeviceState(state))
+	}
+	return converted, nil
 }
 
-// GetStates retrieves all states from Home Assistant
-func (c *Client) GetStates() ([]State, error) {
+// states retrieves all Home Assistant states, from the WebSocket mirror when it is synced
+func (c *Client) states() (states []State, err error) {
+	if c.stream != nil && c.stream.IsSynced() {
+		return c.stream.GetStates(), nil
+	}
+
+	start := time.Now()
+	defer func() { c.observe("get_states", start, err) }()
+
 	req, err := http.NewRequest("GET", c.BaseURL+"/api/states", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
@@ -83,7 +159,6 @@ func (c *Client) GetStates() ([]State, error) {
 		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
 
-	var states []State
 	if err := json.NewDecoder(resp.Body).Decode(&states); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %v", err)
 	}
@@ -92,47 +167,173 @@ func (c *Client) GetStates() ([]State, error) {
 }
 
 // GetInputNumbers retrieves all input_number entities
-func (c *Client) GetInputNumbers() ([]InputNumberEntity, error) {
-	states, err := c.GetStates()
+func (c *Client) GetInputNumbers() ([]device.Control, error) {
+	states, err := c.states()
 	if err != nil {
 		return nil, err
 	}
 
-	var inputNumbers []InputNumberEntity
+	var inputNumbers []device.Control
 	for _, state := range states {
-		if strings.HasPrefix(state.EntityID, "input_number.") {
-			value := parseFloat(state.State)
-
-			entity := InputNumberEntity{
-				EntityID:     state.EntityID,
-				FriendlyName: getStringAttribute(state.Attributes, "friendly_name", state.EntityID),
-				Value:        value,
-				Min:          getFloatAttribute(state.Attributes, "min", 0),
-				Max:          getFloatAttribute(state.Attributes, "max", 100),
-				Step:         getFloatAttribute(state.Attributes, "step", 1),
-				Unit:         getStringAttribute(state.Attributes, "unit_of_measurement", ""),
-			}
-
-			inputNumbers = append(inputNumbers, entity)
+		if device.Domain(state.EntityID) == device.DomainInputNumber {
+			inputNumbers = append(inputNumbers, InputNumberFromState(state))
 		}
 	}
 
 	return inputNumbers, nil
 }
 
+// GetControls retrieves all entities that can be written: input_number, number, switch, light and climate
+func (c *Client) GetControls() ([]device.Control, error) {
+	states, err := c.states()
+	if err != nil {
+		return nil, err
+	}
+
+	return ControlsFromStates(states), nil
+}
+
+// InputNumberFromState converts an input_number state into a device.Control
+func InputNumberFromState(state State) device.Control {
+	return device.Control{
+		EntityID:     state.EntityID,
+		FriendlyName: getStringAttribute(state.Attributes, "friendly_name", state.EntityID),
+		Value:        parseFloat(state.State),
+		Min:          getFloatAttribute(state.Attributes, "min", 0),
+		Max:          getFloatAttribute(state.Attributes, "max", 100),
+		Step:         getFloatAttribute(state.Attributes, "step", 1),
+		Unit:         getStringAttribute(state.Attributes, "unit_of_measurement", ""),
+	}
+}
+
+// GetSensors retrieves all sensor entities that currently report a numeric value
+func (c *Client) GetSensors() ([]device.Sensor, error) {
+	states, err := c.states()
+	if err != nil {
+		return nil, err
+	}
+
+	return SensorsFromStates(states), nil
+}
+
+// SensorsFromStates extracts numeric sensor entities from a list of states.
+// Sensors that are unavailable or report a non-numeric state are skipped.
+func SensorsFromStates(states []State) []device.Sensor {
+	var sensors []device.Sensor
+	for _, state := range states {
+		if device.Domain(state.EntityID) != device.DomainSensor {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(state.State), 64)
+		if err != nil {
+			continue
+		}
+
+		sensors = append(sensors, device.Sensor{
+			EntityID:     state.EntityID,
+			FriendlyName: getStringAttribute(state.Attributes, "friendly_name", state.EntityID),
+			DeviceClass:  getStringAttribute(state.Attributes, "device_class", ""),
+			Value:        value,
+			Unit:         getStringAttribute(state.Attributes, "unit_of_measurement", ""),
+			LastUpdated:  state.LastUpdated,
+		})
+	}
+
+	return sensors
+}
+
 // SetInputNumber sets the value of an input_number entity
 func (c *Client) SetInputNumber(entityID string, value float64) error {
-	payload := map[string]interface{}{
+	return c.callService("set_value", device.DomainInputNumber, "set_value", map[string]interface{}{
 		"entity_id": entityID,
 		"value":     value,
+	})
+}
+
+// SetNumber sets the value of a number entity of a device integration
+func (c *Client) SetNumber(entityID string, value float64) error {
+	return c.callService("set_value", device.DomainNumber, "set_value", map[string]interface{}{
+		"entity_id": entityID,
+		"value":     value,
+	})
+}
+
+// TurnOn turns on a switch or light entity
+func (c *Client) TurnOn(entityID string) error {
+	return c.callService("turn_on", device.Domain(entityID), "turn_on", map[string]interface{}{
+		"entity_id": entityID,
+	})
+}
+
+// TurnOff turns off a switch or light entity
+func (c *Client) TurnOff(entityID string) error {
+	return c.callService("turn_off", device.Domain(entityID), "turn_off", map[string]interface{}{
+		"entity_id": entityID,
+	})
+}
+
+// SetBrightness turns on a light at a brightness in percent, a brightness of 0 or less turns it off
+func (c *Client) SetBrightness(entityID string, percent float64) error {
+	if percent <= 0 {
+		return c.TurnOff(entityID)
+	}
+	return c.callService("turn_on", device.DomainLight, "turn_on", map[string]interface{}{
+		"entity_id":      entityID,
+		"brightness_pct": math.Round(min(percent, 100)),
+	})
+}
+
+// SetTemperature sets the target temperature of a climate entity
+func (c *Client) SetTemperature(entityID string, temperature float64) error {
+	return c.callService("set_temperature", device.DomainClimate, "set_temperature", map[string]interface{}{
+		"entity_id":   entityID,
+		"temperature": temperature,
+	})
+}
+
+// SetHVACMode sets the HVAC mode of a climate entity, e.g. heat, cool or off
+func (c *Client) SetHVACMode(entityID, mode string) error {
+	return c.callService("set_hvac_mode", device.DomainClimate, "set_hvac_mode", map[string]interface{}{
+		"entity_id": entityID,
+		"hvac_mode": mode,
+	})
+}
+
+// SetValue writes a control with the service of its domain: the value of input_number and number
+// entities, on (value > 0) or off for switches, the brightness in percent for lights and the
+// target temperature for thermostats
+func (c *Client) SetValue(entityID string, value float64) error {
+	switch device.Domain(entityID) {
+	case device.DomainInputNumber:
+		return c.SetInputNumber(entityID, value)
+	case device.DomainNumber:
+		return c.SetNumber(entityID, value)
+	case device.DomainSwitch:
+		if value > 0 {
+			return c.TurnOn(entityID)
+		}
+		return c.TurnOff(entityID)
+	case device.DomainLight:
+		return c.SetBrightness(entityID, value)
+	case device.DomainClimate:
+		return c.SetTemperature(entityID, value)
+	default:
+		return fmt.Errorf("entity %s is not a control", entityID)
 	}
+}
+
+// callService calls a Home Assistant service, operation names the call for the observer
+func (c *Client) callService(operation, domain, service string, data map[string]interface{}) (err error) {
+	start := time.Now()
+	defer func() { c.observe(operation, start, err) }()
 
-	jsonData, err := json.Marshal(payload)
+	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", c.BaseURL+"/api/services/input_number/set_value", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", c.BaseURL+"/api/services/"+domain+"/"+service, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
@@ -154,8 +355,21 @@ func (c *Client) SetInputNumber(entityID string, value float64) error {
 	return nil
 }
 
-// GetState gets the current state of a specific entity
-func (c *Client) GetState(entityID string) (*State, error) {
+// GetState gets the current state of a specific entity, see DeviceState
+func (c *Client) GetState(entityID string) (*device.State, error) {
+	state, err := c.state(entityID)
+	if err != nil {
+		return nil, err
+	}
+	converted := DeviceState(*state)
+	return &converted, nil
+}
+
+// state fetches the Home Assistant state of an entity
+func (c *Client) state(entityID string) (_ *State, err error) {
+	start := time.Now()
+	defer func() { c.observe("get_state", start, err) }()
+
 	req, err := http.NewRequest("GET", c.BaseURL+"/api/states/"+entityID, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
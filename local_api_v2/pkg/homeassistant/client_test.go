@@ -0,0 +1,136 @@
+package homeassistant_test
+
+import (
+	"testing"
+	"time"
+
+	"local_api_v2/pkg/device"
+	"local_api_v2/pkg/homeassistant/hatest"
+)
+
+func TestClientSetValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		clampMode   hatest.ClampMode
+		entityID    string
+		value       float64
+		wantErr     bool
+		wantService string
+		wantValue   float64
+		wantState   string
+	}{
+		{name: "input_number", entityID: "input_number.temp_day_sb1", value: 24.5, wantService: "input_number/set_value", wantValue: 24.5, wantState: "24.5"},
+		{name: "input_number out of range", entityID: "input_number.temp_day_sb1", value: 40, wantErr: true, wantService: "input_number/set_value", wantValue: 22, wantState: "22.0"},
+		{name: "input_number clamped", clampMode: hatest.ClampStore, entityID: "input_number.temp_day_sb1", value: 40, wantService: "input_number/set_value", wantValue: 35, wantState: "35.0"},
+		{name: "number", entityID: "number.co2_day_sb1", value: 1200, wantService: "number/set_value", wantValue: 1200, wantState: "1200.0"},
+		{name: "switch on", entityID: "switch.pump_sb1", value: 1, wantService: "switch/turn_on", wantValue: 1, wantState: "on"},
+		{name: "switch off", entityID: "switch.pump_sb1", value: 0, wantService: "switch/turn_off", wantValue: 0, wantState: "off"},
+		{name: "light dimmed", entityID: "light.lamp_1_sb1", value: 37, wantService: "light/turn_on", wantValue: 37, wantState: "on"},
+		{name: "light above 100", entityID: "light.lamp_1_sb1", value: 150, wantService: "light/turn_on", wantValue: 100, wantState: "on"},
+		{name: "light off", entityID: "light.lamp_1_sb1", value: 0, wantService: "light/turn_off", wantValue: 0, wantState: "off"},
+		{name: "climate", entityID: "climate.heater_sb1", value: 23.5, wantService: "climate/set_temperature", wantValue: 23.5, wantState: "heat"},
+		{name: "climate out of range", entityID: "climate.heater_sb1", value: 40, wantErr: true, wantService: "climate/set_temperature", wantValue: 20, wantState: "heat"},
+		{name: "climate off step", clampMode: hatest.ClampStore, entityID: "climate.heater_sb1", value: 23.3, wantService: "climate/set_temperature", wantValue: 23.5, wantState: "heat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := hatest.NewServer("")
+			defer server.Close()
+			server.SetClampMode(tt.clampMode)
+			server.AddInputNumber("input_number.temp_day_sb1", "Temp day", 22, 10, 35, 0.5)
+			server.AddNumber("number.co2_day_sb1", "CO2 day", 800, 400, 2000, 10)
+			server.AddSwitch("switch.pump_sb1", "Pump", false)
+			server.AddLight("light.lamp_1_sb1", "Lamp 1", 60)
+			server.AddClimate("climate.heater_sb1", "Heater", 20, 15, 30, 0.5)
+
+			err := server.Client().SetValue(tt.entityID, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetValue error = %v, want error %v", err, tt.wantErr)
+			}
+
+			writes := server.Writes()
+			if len(writes) != 1 || writes[0].Service != tt.wantService {
+				t.Fatalf("writes %+v, want one %s call", writes, tt.wantService)
+			}
+			if value, _ := server.Value(tt.entityID); value != tt.wantValue {
+				t.Errorf("value %v, want %v", value, tt.wantValue)
+			}
+			if state, _ := server.State(tt.entityID); state.State != tt.wantState {
+				t.Errorf("state %s, want %s", state.State, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestClientServiceErrors(t *testing.T) {
+	server := hatest.NewServer("")
+	defer server.Close()
+	server.AddClimate("climate.heater_sb1", "Heater", 20, 15, 30, 0.5)
+	server.AddSwitch("switch.pump_sb1", "Pump", false)
+	client := server.Client()
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr bool
+	}{
+		{name: "supported hvac mode", call: func() error { return client.SetHVACMode("climate.heater_sb1", "cool") }},
+		{name: "unsupported hvac mode", call: func() error { return client.SetHVACMode("climate.heater_sb1", "dry") }, wantErr: true},
+		{name: "unknown entity", call: func() error { return client.TurnOn("switch.fan_sb1") }, wantErr: true},
+		{name: "wrong domain", call: func() error { return client.SetInputNumber("switch.pump_sb1", 1) }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if state, _ := server.State("climate.heater_sb1"); state.State != "cool" {
+		t.Errorf("climate.heater_sb1 is %s, want cool", state.State)
+	}
+}
+
+func TestClientGetState(t *testing.T) {
+	server := hatest.NewServer("")
+	defer server.Close()
+	server.SetClock(func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) })
+	server.AddInputNumber("input_number.temp_day_sb1", "Temp day", 22, 10, 35, 0.5)
+	server.AddSwitch("switch.pump_sb1", "Pump", true)
+	server.AddLight("light.lamp_1_sb1", "Lamp 1", 60)
+	server.AddLight("light.lamp_2_sb1", "Lamp 2", 0)
+	server.AddClimate("climate.heater_sb1", "Heater", 20, 15, 30, 0.5)
+	server.AddSensor("sensor.temperature_sb1", "Temperature", "temperature", "°C", 21.4)
+	server.SetState("number.co2_day_sb1", "unavailable", map[string]interface{}{"min": 400.0, "max": 2000.0, "step": 10.0})
+
+	tests := []struct {
+		entityID string
+		want     device.State // EntityID and LastChanged are not compared
+	}{
+		{entityID: "input_number.temp_day_sb1", want: device.State{Raw: "22.0", Value: 22, Available: true, Min: 10, Max: 35, Step: 0.5}},
+		{entityID: "switch.pump_sb1", want: device.State{Raw: "on", Value: 1, Available: true, Max: 1, Step: 1}},
+		{entityID: "light.lamp_1_sb1", want: device.State{Raw: "on", Value: 60, Available: true, Max: 100, Step: 1}},
+		{entityID: "light.lamp_2_sb1", want: device.State{Raw: "off", Value: 0, Available: true, Max: 100, Step: 1}},
+		{entityID: "climate.heater_sb1", want: device.State{Raw: "heat", Value: 20, Available: true, Min: 15, Max: 30, Step: 0.5}},
+		{entityID: "sensor.temperature_sb1", want: device.State{Raw: "21.4", Value: 21.4, Available: true}},
+		{entityID: "number.co2_day_sb1", want: device.State{Raw: "unavailable", Min: 400, Max: 2000, Step: 10}},
+	}
+
+	client := server.Client()
+	for _, tt := range tests {
+		t.Run(tt.entityID, func(t *testing.T) {
+			state, err := client.GetState(tt.entityID)
+			if err != nil {
+				t.Fatalf("GetState: %v", err)
+			}
+			tt.want.EntityID = tt.entityID
+			tt.want.LastChanged = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+			if *state != tt.want {
+				t.Errorf("state %+v, want %+v", *state, tt.want)
+			}
+		})
+	}
+}
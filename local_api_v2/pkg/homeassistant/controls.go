@@ -0,0 +1,110 @@
+package homeassistant
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"local_api_v2/pkg/device"
+)
+
+// ControlValue returns the numeric value of a control state: 1 or 0 for switches, the brightness
+// in percent for lights (0 when off), the target temperature for thermostats and the state of
+// input_number and number entities. ok is false when the state holds no value, e.g. unavailable.
+func ControlValue(state State) (float64, bool) {
+	switch device.Domain(state.EntityID) {
+	case device.DomainSwitch:
+		switch state.State {
+		case "on":
+			return 1, true
+		case "off":
+			return 0, true
+		}
+		return 0, false
+	case device.DomainLight:
+		switch state.State {
+		case "off":
+			return 0, true
+		case "on":
+			brightness, exists := state.Attributes["brightness"]
+			if !exists || brightness == nil {
+				return 100, true // on/off light or brightness not reported
+			}
+			return math.Round(parseFloat(brightness) / 255 * 100), true
+		}
+		return 0, false
+	case device.DomainClimate:
+		temperature, exists := state.Attributes["temperature"]
+		if !exists || temperature == nil {
+			return 0, false
+		}
+		return parseFloat(temperature), true
+	default:
+		value, err := strconv.ParseFloat(strings.TrimSpace(state.State), 64)
+		if err != nil {
+			return 0, false
+		}
+		return value, true
+	}
+}
+
+// ControlFromState converts the state of a control into a device.Control holding its value
+// and limits. Switches range from 0 to 1, lights from 0 to 100 % and thermostats between their
+// min_temp and max_temp.
+func ControlFromState(state State) device.Control {
+	entity := InputNumberFromState(state)
+	entity.Value, _ = ControlValue(state)
+
+	switch device.Domain(state.EntityID) {
+	case device.DomainSwitch:
+		entity.Min, entity.Max, entity.Step, entity.Unit = 0, 1, 1, ""
+	case device.DomainLight:
+		entity.Min, entity.Max, entity.Step, entity.Unit = 0, 100, 1, "%"
+	case device.DomainClimate:
+		entity.Min = getFloatAttribute(state.Attributes, "min_temp", 7)
+		entity.Max = getFloatAttribute(state.Attributes, "max_temp", 35)
+		entity.Step = getFloatAttribute(state.Attributes, "target_temp_step", 0.5)
+	}
+	return entity
+}
+
+// ControlsFromStates extracts the controls from a list of states
+func ControlsFromStates(states []State) []device.Control {
+	var controls []device.Control
+	for _, state := range states {
+		if device.IsControl(state.EntityID) {
+			controls = append(controls, ControlFromState(state))
+		}
+	}
+	return controls
+}
+
+// DeviceState converts a Home Assistant state into a device.State, see ControlValue for the value
+// of each domain. Entities that are not controls get no limits.
+func DeviceState(state State) device.State {
+	converted := device.State{EntityID: state.EntityID, Raw: state.State}
+	converted.Value, converted.Available = ControlValue(state)
+	if device.IsControl(state.EntityID) {
+		control := ControlFromState(state)
+		converted.Min, converted.Max, converted.Step = control.Min, control.Max, control.Step
+	}
+	if changedAt, err := time.Parse(time.RFC3339Nano, state.LastChanged); err == nil {
+		converted.LastChanged = changedAt
+	}
+	return converted
+}
+
+// DeviceStateChange converts a state_changed event into a device.StateChange
+func DeviceStateChange(change StateChange) device.StateChange {
+	converted := device.StateChange{EntityID: change.EntityID}
+	if change.OldState != nil {
+		oldState := DeviceState(*change.OldState)
+		converted.OldState = &oldState
+	}
+	if change.NewState != nil {
+		newState := DeviceState(*change.NewState)
+		converted.NewState = &newState
+	}
+	return converted
+}
@@ -0,0 +1,629 @@
+// Package hatest provides an in-process fake Home Assistant server for integration tests.
+//
+// The server serves the REST endpoints used by homeassistant.Client (/, /api/, /api/states,
+// /api/states/{entity_id} and /api/services/{domain}/{service}) and the WebSocket API
+// (auth, subscribe_events for state_changed and get_states) from a scripted entity set.
+// The services written by the chamber controls are implemented: set_value of input_number
+// and number entities, turn_on and turn_off of switches and lights (with brightness_pct),
+// and set_temperature and set_hvac_mode of climate entities. Faults can be injected per
+// endpoint: added latency, error responses and clamping of written values.
+package hatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"local_api_v2/pkg/device"
+	"local_api_v2/pkg/homeassistant"
+)
+
+// DefaultToken is the access token accepted by servers created without an explicit token
+const DefaultToken = "hatest-token"
+
+// Endpoints that faults can be injected into
+const (
+	EndpointRoot    = "root"      // GET / and GET /api/
+	EndpointStates  = "states"    // GET /api/states
+	EndpointState   = "state"     // GET /api/states/{entity_id}
+	EndpointService = "service"   // POST /api/services/{domain}/{service}
+	EndpointWS      = "websocket" // GET /api/websocket
+)
+
+// ClampMode controls how service calls handle values outside the range or off the step of an
+// entity: the value of input_number and number entities, the brightness_pct of lights and the
+// target temperature of thermostats
+type ClampMode int
+
+const (
+	// ClampReject rejects out-of-range values with 400 Bad Request, like Home Assistant does
+	ClampReject ClampMode = iota
+	// ClampStore clamps to [min, max] and rounds to the step before storing, like a device with limits
+	ClampStore
+	// ClampNone stores every value as requested
+	ClampNone
+)
+
+// Write records a service call received by the server
+type Write struct {
+	EntityID  string
+	Service   string  // domain/service, e.g. light/turn_on
+	Requested float64 // written value, 1 and 0 for turn_on and turn_off without brightness
+	Stored    float64 // value of the entity after the call, see homeassistant.ControlValue
+	HVACMode  string  // mode requested by set_hvac_mode
+	Status    int     // response status code
+	Time      time.Time
+}
+
+// fault describes errors injected into an endpoint
+type fault struct {
+	latency time.Duration
+	status  int
+	remain  int // number of requests that still fail, negative means unlimited
+}
+
+// Server is a fake Home Assistant instance
+type Server struct {
+	URL   string
+	Token string
+
+	httpServer *httptest.Server
+	upgrader   websocket.Upgrader
+
+	mu        sync.Mutex
+	states    map[string]homeassistant.State
+	writes    []Write
+	faults    map[string]*fault
+	clampMode ClampMode
+	now       func() time.Time
+
+	connMu sync.Mutex
+	conns  map[*wsConn]struct{}
+}
+
+// NewServer starts a fake Home Assistant server with the given initial states.
+// An empty token accepts DefaultToken.
+func NewServer(token string, states ...homeassistant.State) *Server {
+	if token == "" {
+		token = DefaultToken
+	}
+
+	s := &Server{
+		Token:  token,
+		states: make(map[string]homeassistant.State),
+		faults: make(map[string]*fault),
+		now:    time.Now,
+		conns:  make(map[*wsConn]struct{}),
+	}
+	for _, state := range states {
+		s.states[state.EntityID] = s.stamp(state)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleRoot)
+	mux.HandleFunc("GET /api/{$}", s.handleRoot)
+	mux.HandleFunc("GET /api/states", s.handleStates)
+	mux.HandleFunc("GET /api/states/{entity_id}", s.handleState)
+	mux.HandleFunc("POST /api/services/{domain}/{service}", s.handleService)
+	mux.HandleFunc("GET /api/websocket", s.handleWebSocket)
+
+	s.httpServer = httptest.NewServer(mux)
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// Close disconnects all WebSocket clients and shuts the server down
+func (s *Server) Close() {
+	s.DropWebSockets()
+	s.httpServer.Close()
+}
+
+// Client returns a REST client for the server
+func (s *Server) Client() *homeassistant.Client {
+	return homeassistant.NewClient(s.URL, s.Token)
+}
+
+// WebSocketClient returns a WebSocket client for the server with short reconnect backoff
+func (s *Server) WebSocketClient() *homeassistant.WebSocketClient {
+	client := homeassistant.NewWebSocketClient(s.URL, s.Token)
+	client.MinBackoff = 10 * time.Millisecond
+	client.MaxBackoff = 100 * time.Millisecond
+	return client
+}
+
+// SetClock replaces the clock used for last_changed/last_updated timestamps and write records
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// SetClampMode sets how service calls handle values outside the range of an entity
+func (s *Server) SetClampMode(mode ClampMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clampMode = mode
+}
+
+// SetLatency delays every request to an endpoint
+func (s *Server) SetLatency(endpoint string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fault(endpoint).latency = latency
+}
+
+// FailNext makes the next count requests to an endpoint fail with the given status code.
+// A negative count fails all requests until ClearFaults is called.
+func (s *Server) FailNext(endpoint string, status, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.fault(endpoint)
+	f.status = status
+	f.remain = count
+}
+
+// ClearFaults removes all injected latency and errors
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string]*fault)
+}
+
+func (s *Server) fault(endpoint string) *fault {
+	f, exists := s.faults[endpoint]
+	if !exists {
+		f = &fault{}
+		s.faults[endpoint] = f
+	}
+	return f
+}
+
+// injectFault applies the faults of an endpoint and reports whether the request was answered
+func (s *Server) injectFault(w http.ResponseWriter, endpoint string) bool {
+	s.mu.Lock()
+	var latency time.Duration
+	status := 0
+	if f, exists := s.faults[endpoint]; exists {
+		latency = f.latency
+		if f.status != 0 && f.remain != 0 {
+			status = f.status
+			if f.remain > 0 {
+				f.remain--
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if status != 0 {
+		http.Error(w, fmt.Sprintf("injected failure on %s", endpoint), status)
+		return true
+	}
+	return false
+}
+
+// authorized checks the bearer token of a REST request
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		http.Error(w, "401: Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// SetState creates or replaces an entity state and notifies WebSocket subscribers
+func (s *Server) SetState(entityID, state string, attributes map[string]interface{}) {
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+
+	s.mu.Lock()
+	old, existed := s.states[entityID]
+	newState := s.stamp(homeassistant.State{EntityID: entityID, State: state, Attributes: attributes})
+	if existed && old.State == newState.State {
+		newState.LastChanged = old.LastChanged
+	}
+	s.states[entityID] = newState
+	s.mu.Unlock()
+
+	var oldState *homeassistant.State
+	if existed {
+		oldState = &old
+	}
+	s.broadcast(homeassistant.StateChange{EntityID: entityID, OldState: oldState, NewState: &newState})
+}
+
+// SetValue changes the state of an existing entity, keeping its attributes.
+// It simulates a change made outside the service, e.g. from the Home Assistant UI.
+func (s *Server) SetValue(entityID string, value float64) error {
+	s.mu.Lock()
+	state, exists := s.states[entityID]
+	s.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("entity %s not found", entityID)
+	}
+
+	s.SetState(entityID, formatValue(value), state.Attributes)
+	return nil
+}
+
+// AddInputNumber creates an input_number entity
+func (s *Server) AddInputNumber(entityID, friendlyName string, value, min, max, step float64) {
+	s.SetState(entityID, formatValue(value), map[string]interface{}{
+		"friendly_name": friendlyName,
+		"min":           min,
+		"max":           max,
+		"step":          step,
+		"mode":          "slider",
+	})
+}
+
+// AddNumber creates a number entity of a device integration
+func (s *Server) AddNumber(entityID, friendlyName string, value, min, max, step float64) {
+	s.SetState(entityID, formatValue(value), map[string]interface{}{
+		"friendly_name": friendlyName,
+		"min":           min,
+		"max":           max,
+		"step":          step,
+		"mode":          "auto",
+	})
+}
+
+// AddSwitch creates a switch entity
+func (s *Server) AddSwitch(entityID, friendlyName string, on bool) {
+	s.SetState(entityID, onOff(on), map[string]interface{}{
+		"friendly_name": friendlyName,
+	})
+}
+
+// AddLight creates a dimmable light entity, a brightness of 0 turns it off
+func (s *Server) AddLight(entityID, friendlyName string, brightnessPct float64) {
+	attributes := map[string]interface{}{
+		"friendly_name":         friendlyName,
+		"supported_color_modes": []string{"brightness"},
+		"brightness":            nil,
+	}
+	if brightnessPct > 0 {
+		attributes["brightness"] = brightness(brightnessPct)
+	}
+	s.SetState(entityID, onOff(brightnessPct > 0), attributes)
+}
+
+// AddClimate creates a thermostat in heat mode with a target temperature
+func (s *Server) AddClimate(entityID, friendlyName string, temperature, minTemp, maxTemp, step float64) {
+	s.SetState(entityID, "heat", map[string]interface{}{
+		"friendly_name":    friendlyName,
+		"hvac_modes":       []string{"off", "heat", "cool"},
+		"temperature":      temperature,
+		"min_temp":         minTemp,
+		"max_temp":         maxTemp,
+		"target_temp_step": step,
+	})
+}
+
+// AddSensor creates a numeric sensor entity
+func (s *Server) AddSensor(entityID, friendlyName, deviceClass, unit string, value float64) {
+	s.SetState(entityID, formatValue(value), map[string]interface{}{
+		"friendly_name":       friendlyName,
+		"device_class":        deviceClass,
+		"unit_of_measurement": unit,
+		"state_class":         "measurement",
+	})
+}
+
+// RemoveEntity deletes an entity and notifies WebSocket subscribers
+func (s *Server) RemoveEntity(entityID string) {
+	s.mu.Lock()
+	old, existed := s.states[entityID]
+	delete(s.states, entityID)
+	s.mu.Unlock()
+
+	if existed {
+		s.broadcast(homeassistant.StateChange{EntityID: entityID, OldState: &old})
+	}
+}
+
+// State returns the current state of an entity
+func (s *Server) State(entityID string) (homeassistant.State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.states[entityID]
+	return state, exists
+}
+
+// Value returns the numeric value of an entity as read by homeassistant.ControlValue: 1 or 0 for
+// switches, the brightness in percent for lights, the target temperature for thermostats and
+// the state of other entities
+func (s *Server) Value(entityID string) (float64, bool) {
+	state, exists := s.State(entityID)
+	if !exists {
+		return 0, false
+	}
+	return homeassistant.ControlValue(state)
+}
+
+// Writes returns all service calls received so far
+func (s *Server) Writes() []Write {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Write(nil), s.writes...)
+}
+
+// ResetWrites clears the recorded service calls
+func (s *Server) ResetWrites() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes = nil
+}
+
+// stamp sets the timestamps of a state, callers hold s.mu or own the server exclusively
+func (s *Server) stamp(state homeassistant.State) homeassistant.State {
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	if state.LastChanged == "" {
+		state.LastChanged = now
+	}
+	if state.LastUpdated == "" {
+		state.LastUpdated = now
+	}
+	if state.Attributes == nil {
+		state.Attributes = map[string]interface{}{}
+	}
+	return state
+}
+
+// sortedStates returns all states ordered by entity ID
+func (s *Server) sortedStates() []homeassistant.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]homeassistant.State, 0, len(s.states))
+	for _, state := range s.states {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].EntityID < states[j].EntityID
+	})
+	return states
+}
+
+func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	if s.injectFault(w, EndpointRoot) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "API running."})
+}
+
+func (s *Server) handleStates(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) || s.injectFault(w, EndpointStates) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.sortedStates())
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) || s.injectFault(w, EndpointState) {
+		return
+	}
+
+	state, exists := s.State(r.PathValue("entity_id"))
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Entity not found."})
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) handleService(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) || s.injectFault(w, EndpointService) {
+		return
+	}
+
+	var data map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid service data."})
+		return
+	}
+
+	status, state, message := s.applyService(r.PathValue("domain"), r.PathValue("service"), data)
+	if status != http.StatusOK {
+		writeJSON(w, status, map[string]string{"message": message})
+		return
+	}
+
+	s.SetState(state.EntityID, state.State, state.Attributes)
+	newState, _ := s.State(state.EntityID)
+	writeJSON(w, http.StatusOK, []homeassistant.State{newState})
+}
+
+// applyService validates a service call against the entity, records it and returns the new
+// state of the entity. The state is not stored, the caller does so with SetState.
+func (s *Server) applyService(domain, service string, data map[string]interface{}) (int, homeassistant.State, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entityID, _ := data["entity_id"].(string)
+	write := Write{EntityID: entityID, Service: domain + "/" + service, Time: s.now()}
+	defer func() { s.writes = append(s.writes, write) }()
+
+	fail := func(format string, args ...interface{}) (int, homeassistant.State, string) {
+		write.Status = http.StatusBadRequest
+		return write.Status, homeassistant.State{}, fmt.Sprintf(format, args...)
+	}
+
+	state, exists := s.states[entityID]
+	if !exists || device.Domain(entityID) != domain {
+		return fail("Entity %q is not a %s entity", entityID, domain)
+	}
+
+	// The attributes of the stored state are shared with clients, change a copy
+	attributes := make(map[string]interface{}, len(state.Attributes))
+	for key, value := range state.Attributes {
+		attributes[key] = value
+	}
+	state.Attributes = attributes
+
+	number := func(key string) (float64, bool) {
+		value, ok := data[key].(float64)
+		if ok {
+			write.Requested = value
+		}
+		return value, ok
+	}
+
+	switch write.Service {
+	case "input_number/set_value", "number/set_value":
+		value, ok := number("value")
+		if !ok {
+			return fail("Invalid service data for %s", write.Service)
+		}
+		stored, err := s.clamp(value,
+			numberAttribute(attributes, "min", math.Inf(-1)),
+			numberAttribute(attributes, "max", math.Inf(1)),
+			numberAttribute(attributes, "step", 0))
+		if err != nil {
+			return fail("Invalid value for %s: %v", entityID, err)
+		}
+		state.State = formatValue(stored)
+
+	case "switch/turn_on", "switch/turn_off", "light/turn_off":
+		on := service == "turn_on"
+		write.Requested = 0
+		if on {
+			write.Requested = 1
+		}
+		state.State = onOff(on)
+		if domain == device.DomainLight {
+			attributes["brightness"] = nil
+		}
+
+	case "light/turn_on":
+		percent, ok := number("brightness_pct")
+		if !ok {
+			percent = 100
+			write.Requested = 1
+		}
+		stored, err := s.clamp(percent, 0, 100, 1)
+		if err != nil {
+			return fail("Invalid brightness_pct for %s: %v", entityID, err)
+		}
+		state.State = onOff(stored > 0)
+		attributes["brightness"] = nil
+		if stored > 0 {
+			attributes["brightness"] = brightness(stored)
+		}
+
+	case "climate/set_temperature":
+		temperature, ok := number("temperature")
+		if !ok {
+			return fail("Invalid service data for %s", write.Service)
+		}
+		stored, err := s.clamp(temperature,
+			numberAttribute(attributes, "min_temp", 7),
+			numberAttribute(attributes, "max_temp", 35),
+			numberAttribute(attributes, "target_temp_step", 0.5))
+		if err != nil {
+			return fail("Invalid temperature for %s: %v", entityID, err)
+		}
+		attributes["temperature"] = stored
+
+	case "climate/set_hvac_mode":
+		mode, _ := data["hvac_mode"].(string)
+		write.HVACMode = mode
+		if mode == "" || !supportsMode(attributes["hvac_modes"], mode) {
+			return fail("Invalid hvac_mode for %s: %q", entityID, mode)
+		}
+		state.State = mode
+
+	default:
+		return fail("Service %s not found", write.Service)
+	}
+
+	write.Status = http.StatusOK
+	write.Stored, _ = homeassistant.ControlValue(state)
+	return write.Status, state, ""
+}
+
+// clamp applies the clamp mode to a value written to an entity with the given limits,
+// callers hold s.mu
+func (s *Server) clamp(value, min, max, step float64) (float64, error) {
+	switch s.clampMode {
+	case ClampReject:
+		if value < min || value > max {
+			return 0, fmt.Errorf("%v outside range %v - %v", value, min, max)
+		}
+	case ClampStore:
+		value = math.Max(min, math.Min(max, value))
+		if step > 0 && !math.IsInf(min, 0) {
+			value = min + math.Round((value-min)/step)*step
+			if value > max {
+				value -= step
+			}
+		}
+	}
+	return value, nil
+}
+
+// supportsMode reports whether a mode is in the hvac_modes attribute of a thermostat,
+// thermostats without the attribute accept every mode
+func supportsMode(modes interface{}, mode string) bool {
+	switch modes := modes.(type) {
+	case []string:
+		return slices.Contains(modes, mode)
+	case []interface{}:
+		return slices.Contains(modes, interface{}(mode))
+	}
+	return true
+}
+
+// onOff returns the state of a switch or light
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// brightness converts a brightness in percent to the 0-255 scale of the brightness attribute
+func brightness(percent float64) float64 {
+	return math.Round(percent * 255 / 100)
+}
+
+// numberAttribute reads a numeric attribute that may be encoded as a number or a string
+func numberAttribute(attributes map[string]interface{}, key string, defaultValue float64) float64 {
+	switch v := attributes[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// formatValue formats a number the way Home Assistant reports input_number states
+func formatValue(value float64) string {
+	if value == math.Trunc(value) {
+		return strconv.FormatFloat(value, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
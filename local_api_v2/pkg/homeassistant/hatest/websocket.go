@@ -0,0 +1,175 @@
+package hatest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"local_api_v2/pkg/homeassistant"
+)
+
+// wsConn is an authenticated WebSocket connection of a client
+type wsConn struct {
+	conn *websocket.Conn
+
+	writeMu       sync.Mutex
+	subscriptions map[int]bool // command IDs of state_changed subscriptions
+}
+
+// wsCommand is a command sent by a client
+type wsCommand struct {
+	ID           int    `json:"id"`
+	Type         string `json:"type"`
+	AccessToken  string `json:"access_token"`
+	EventType    string `json:"event_type"`
+	Subscription int    `json:"subscription"`
+}
+
+func (c *wsConn) write(message interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(message)
+}
+
+// DropWebSockets closes all WebSocket connections, clients are expected to reconnect
+func (s *Server) DropWebSockets() {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	for conn := range s.conns {
+		conn.conn.Close()
+		delete(s.conns, conn)
+	}
+}
+
+// WebSocketConnections returns the number of authenticated WebSocket connections
+func (s *Server) WebSocketConnections() int {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	return len(s.conns)
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.injectFault(w, EndpointWS) {
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	client := &wsConn{conn: conn, subscriptions: make(map[int]bool)}
+	if !s.authenticate(client) {
+		return
+	}
+
+	s.connMu.Lock()
+	s.conns[client] = struct{}{}
+	s.connMu.Unlock()
+
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, client)
+		s.connMu.Unlock()
+	}()
+
+	for {
+		var cmd wsCommand
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+		if err := s.handleCommand(client, cmd); err != nil {
+			return
+		}
+	}
+}
+
+// authenticate performs the auth handshake of the WebSocket API
+func (s *Server) authenticate(client *wsConn) bool {
+	if err := client.write(map[string]string{"type": "auth_required", "ha_version": "hatest"}); err != nil {
+		return false
+	}
+
+	var cmd wsCommand
+	if err := client.conn.ReadJSON(&cmd); err != nil {
+		return false
+	}
+	if cmd.Type != "auth" || cmd.AccessToken != s.Token {
+		client.write(map[string]string{"type": "auth_invalid", "message": "Invalid access token or password"})
+		return false
+	}
+
+	return client.write(map[string]string{"type": "auth_ok", "ha_version": "hatest"}) == nil
+}
+
+// handleCommand answers a single command of an authenticated client
+func (s *Server) handleCommand(client *wsConn, cmd wsCommand) error {
+	switch cmd.Type {
+	case "subscribe_events":
+		if cmd.EventType != "" && cmd.EventType != "state_changed" {
+			return client.write(resultError(cmd.ID, "not_supported", "Only state_changed events are supported"))
+		}
+		client.writeMu.Lock()
+		client.subscriptions[cmd.ID] = true
+		client.writeMu.Unlock()
+		return client.write(resultOK(cmd.ID, nil))
+	case "unsubscribe_events":
+		client.writeMu.Lock()
+		delete(client.subscriptions, cmd.Subscription)
+		client.writeMu.Unlock()
+		return client.write(resultOK(cmd.ID, nil))
+	case "get_states":
+		return client.write(resultOK(cmd.ID, s.sortedStates()))
+	case "ping":
+		return client.write(map[string]interface{}{"id": cmd.ID, "type": "pong"})
+	default:
+		return client.write(resultError(cmd.ID, "unknown_command", "Unknown command."))
+	}
+}
+
+// broadcast sends a state_changed event to all subscribed connections
+func (s *Server) broadcast(change homeassistant.StateChange) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	for client := range s.conns {
+		client.writeMu.Lock()
+		for id := range client.subscriptions {
+			client.conn.WriteJSON(map[string]interface{}{
+				"id":   id,
+				"type": "event",
+				"event": map[string]interface{}{
+					"event_type": "state_changed",
+					"data":       change,
+				},
+			})
+		}
+		client.writeMu.Unlock()
+	}
+}
+
+func resultOK(id int, result interface{}) map[string]interface{} {
+	raw, _ := json.Marshal(result)
+	return map[string]interface{}{
+		"id":      id,
+		"type":    "result",
+		"success": true,
+		"result":  json.RawMessage(raw),
+	}
+}
+
+func resultError(id int, code, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"type":    "result",
+		"success": false,
+		"error": map[string]string{
+			"code":    code,
+			"message": message,
+		},
+	}
+}
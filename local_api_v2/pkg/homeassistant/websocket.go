@@ -0,0 +1,391 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Reconnect backoff bounds of the WebSocket client
+const (
+	wsMinBackoff = 1 * time.Second
+	wsMaxBackoff = 60 * time.Second
+)
+
+// StateChange represents a state_changed event. OldState is nil for new entities,
+// NewState is nil for removed entities.
+type StateChange struct {
+	EntityID string `json:"entity_id"`
+	OldState *State `json:"old_state"`
+	NewState *State `json:"new_state"`
+}
+
+// WebSocketClient keeps an in-memory mirror of all Home Assistant entity states
+// using the WebSocket API and publishes state changes to subscribers
+type WebSocketClient struct {
+	URL              string
+	Token            string
+	Dialer           *websocket.Dialer
+	MinBackoff       time.Duration
+	MaxBackoff       time.Duration
+	SubscriberBuffer int
+
+	mu        sync.RWMutex
+	states    map[string]State
+	connected bool
+	synced    bool // states hold a full snapshot of the current connection
+
+	subMu       sync.Mutex
+	subscribers map[int]chan StateChange
+	nextSubID   int
+
+	writeMu sync.Mutex
+	nextID  int
+}
+
+// wsMessage is the envelope of all WebSocket API messages
+type wsMessage struct {
+	ID      int             `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Success *bool           `json:"success,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+	Event *struct {
+		EventType string      `json:"event_type"`
+		Data      StateChange `json:"data"`
+	} `json:"event,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// NewWebSocketClient creates a new WebSocket client for the Home Assistant instance at baseURL
+func NewWebSocketClient(baseURL, token string) *WebSocketClient {
+	return &WebSocketClient{
+		URL:              WebSocketURL(baseURL),
+		Token:            token,
+		Dialer:           &websocket.Dialer{HandshakeTimeout: 10 * time.Second},
+		MinBackoff:       wsMinBackoff,
+		MaxBackoff:       wsMaxBackoff,
+		SubscriberBuffer: 256,
+		states:           make(map[string]State),
+		subscribers:      make(map[int]chan StateChange),
+	}
+}
+
+// WebSocketURL converts a Home Assistant base URL into its WebSocket API URL
+func WebSocketURL(baseURL string) string {
+	url := strings.TrimRight(baseURL, "/")
+	switch {
+	case strings.HasPrefix(url, "https://"):
+		url = "wss://" + strings.TrimPrefix(url, "https://")
+	case strings.HasPrefix(url, "http://"):
+		url = "ws://" + strings.TrimPrefix(url, "http://")
+	}
+	return url + "/api/websocket"
+}
+
+// Run connects to Home Assistant and keeps the state mirror up to date until ctx is cancelled.
+// Lost connections are re-established with exponential backoff.
+func (c *WebSocketClient) Run(ctx context.Context) {
+	backoff := c.MinBackoff
+
+	for {
+		start := time.Now()
+		err := c.runConnection(ctx)
+		c.setConnected(false)
+
+		if ctx.Err() != nil {
+			slog.Info("Home Assistant WebSocket client stopped")
+			c.closeSubscribers()
+			return
+		}
+
+		// A connection that stayed up for a while resets the backoff
+		if time.Since(start) > c.MaxBackoff {
+			backoff = c.MinBackoff
+		}
+
+		slog.Error("Home Assistant WebSocket disconnected", "error", err, "reconnect_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Home Assistant WebSocket client stopped")
+			c.closeSubscribers()
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > c.MaxBackoff {
+			backoff = c.MaxBackoff
+		}
+	}
+}
+
+// runConnection performs the auth handshake, subscribes to state changes,
+// loads the initial snapshot and processes events until the connection fails
+func (c *WebSocketClient) runConnection(ctx context.Context) error {
+	conn, _, err := c.Dialer.DialContext(ctx, c.URL, http.Header{})
+	if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Unblock ReadJSON when the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := c.authenticate(conn); err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	c.nextID = 0
+	c.writeMu.Unlock()
+
+	// Subscribe before fetching the snapshot so no change falls in between
+	subscribeID, err := c.send(conn, map[string]interface{}{
+		"type":       "subscribe_events",
+		"event_type": "state_changed",
+	})
+	if err != nil {
+		return err
+	}
+	statesID, err := c.send(conn, map[string]interface{}{
+		"type": "get_states",
+	})
+	if err != nil {
+		return err
+	}
+
+	c.setConnected(true)
+	slog.Info("Connected to Home Assistant WebSocket API", "url", c.URL)
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return fmt.Errorf("failed to read message: %v", err)
+		}
+
+		switch msg.Type {
+		case "result":
+			if msg.Success != nil && !*msg.Success {
+				reason := "unknown error"
+				if msg.Error != nil {
+					reason = msg.Error.Message
+				}
+				return fmt.Errorf("command %d failed: %s", msg.ID, reason)
+			}
+			if msg.ID == statesID {
+				var states []State
+				if err := json.Unmarshal(msg.Result, &states); err != nil {
+					return fmt.Errorf("failed to decode states: %v", err)
+				}
+				c.replaceStates(states)
+			}
+		case "event":
+			if msg.ID == subscribeID && msg.Event != nil && msg.Event.EventType == "state_changed" {
+				c.applyChange(msg.Event.Data)
+			}
+		}
+	}
+}
+
+// authenticate performs the Home Assistant WebSocket auth handshake
+func (c *WebSocketClient) authenticate(conn *websocket.Conn) error {
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		return fmt.Errorf("failed to read auth request: %v", err)
+	}
+	if msg.Type != "auth_required" {
+		return fmt.Errorf("unexpected message %q, expected auth_required", msg.Type)
+	}
+
+	if err := conn.WriteJSON(map[string]string{
+		"type":         "auth",
+		"access_token": c.Token,
+	}); err != nil {
+		return fmt.Errorf("failed to send auth: %v", err)
+	}
+
+	msg = wsMessage{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		return fmt.Errorf("failed to read auth response: %v", err)
+	}
+
+	switch msg.Type {
+	case "auth_ok":
+		return nil
+	case "auth_invalid":
+		return fmt.Errorf("authentication failed: %s", msg.Message)
+	default:
+		return fmt.Errorf("unexpected auth response %q", msg.Type)
+	}
+}
+
+// send writes a command with the next message ID and returns that ID
+func (c *WebSocketClient) send(conn *websocket.Conn, command map[string]interface{}) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.nextID++
+	command["id"] = c.nextID
+	if err := conn.WriteJSON(command); err != nil {
+		return 0, fmt.Errorf("failed to send %v: %v", command["type"], err)
+	}
+	return c.nextID, nil
+}
+
+// replaceStates installs a full snapshot and publishes every difference to the previous mirror,
+// so subscribers also learn about changes that happened while disconnected
+func (c *WebSocketClient) replaceStates(states []State) {
+	var changes []StateChange
+
+	c.mu.Lock()
+	previous := c.states
+	c.states = make(map[string]State, len(states))
+	for _, state := range states {
+		newState := state
+		c.states[state.EntityID] = newState
+
+		oldState, existed := previous[state.EntityID]
+		if !existed {
+			changes = append(changes, StateChange{EntityID: state.EntityID, NewState: &newState})
+		} else if oldState.State != newState.State || oldState.LastUpdated != newState.LastUpdated {
+			old := oldState
+			changes = append(changes, StateChange{EntityID: state.EntityID, OldState: &old, NewState: &newState})
+		}
+	}
+	for entityID, oldState := range previous {
+		if _, exists := c.states[entityID]; !exists {
+			old := oldState
+			changes = append(changes, StateChange{EntityID: entityID, OldState: &old})
+		}
+	}
+	c.synced = true
+	c.mu.Unlock()
+
+	for _, change := range changes {
+		c.publish(change)
+	}
+}
+
+// applyChange updates the mirror with a single state_changed event
+func (c *WebSocketClient) applyChange(change StateChange) {
+	c.mu.Lock()
+	if change.NewState == nil {
+		delete(c.states, change.EntityID)
+	} else {
+		c.states[change.EntityID] = *change.NewState
+	}
+	c.mu.Unlock()
+
+	c.publish(change)
+}
+
+// Subscribe returns a channel receiving all state changes and a function to cancel the subscription.
+// Changes are dropped for subscribers that do not keep up with the buffer.
+func (c *WebSocketClient) Subscribe() (<-chan StateChange, func()) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	id := c.nextSubID
+	c.nextSubID++
+	ch := make(chan StateChange, c.SubscriberBuffer)
+	c.subscribers[id] = ch
+
+	return ch, func() {
+		c.subMu.Lock()
+		defer c.subMu.Unlock()
+		if ch, exists := c.subscribers[id]; exists {
+			delete(c.subscribers, id)
+			close(ch)
+		}
+	}
+}
+
+// publish delivers a change to all subscribers without blocking
+func (c *WebSocketClient) publish(change StateChange) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for _, ch := range c.subscribers {
+		select {
+		case ch <- change:
+		default:
+			slog.Warn("Dropping state change for slow subscriber", "entity_id", change.EntityID)
+		}
+	}
+}
+
+// closeSubscribers closes all subscriber channels
+func (c *WebSocketClient) closeSubscribers() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for id, ch := range c.subscribers {
+		delete(c.subscribers, id)
+		close(ch)
+	}
+}
+
+func (c *WebSocketClient) setConnected(connected bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connected = connected
+	if !connected {
+		c.synced = false
+	}
+}
+
+// IsConnected reports whether the WebSocket connection is established and authenticated
+func (c *WebSocketClient) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connected
+}
+
+// IsSynced reports whether the mirror holds an up-to-date snapshot of all states
+func (c *WebSocketClient) IsSynced() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connected && c.synced
+}
+
+// GetState returns the mirrored state of an entity
+func (c *WebSocketClient) GetState(entityID string) (State, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state, exists := c.states[entityID]
+	return state, exists
+}
+
+// GetStates returns all mirrored states
+func (c *WebSocketClient) GetStates() []State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	states := make([]State, 0, len(c.states))
+	for _, state := range c.states {
+		states = append(states, state)
+	}
+	return states
+}
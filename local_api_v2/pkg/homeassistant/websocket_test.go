@@ -0,0 +1,264 @@
+package homeassistant_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"local_api_v2/pkg/homeassistant"
+	"local_api_v2/pkg/homeassistant/hatest"
+)
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// receive returns the next change of a subscription
+func receive(t *testing.T, changes <-chan homeassistant.StateChange) homeassistant.StateChange {
+	t.Helper()
+	select {
+	case change, ok := <-changes:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a state change")
+	}
+	return homeassistant.StateChange{}
+}
+
+// runClient starts a WebSocket client against the server and stops it when the test ends
+func runClient(t *testing.T, client *homeassistant.WebSocketClient) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestWebSocketClientAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		connected bool
+	}{
+		{name: "valid token", token: hatest.DefaultToken, connected: true},
+		{name: "invalid token", token: "wrong-token", connected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := hatest.NewServer("")
+			defer server.Close()
+
+			client := server.WebSocketClient()
+			client.Token = tt.token
+			runClient(t, client)
+
+			if tt.connected {
+				waitFor(t, "connection", client.IsConnected)
+				waitFor(t, "authenticated connection on the server", func() bool {
+					return server.WebSocketConnections() == 1
+				})
+				return
+			}
+
+			// A rejected client keeps retrying but never counts as connected
+			time.Sleep(200 * time.Millisecond)
+			if client.IsConnected() {
+				t.Error("client connected with an invalid token")
+			}
+			if n := server.WebSocketConnections(); n != 0 {
+				t.Errorf("server has %d authenticated connections, want 0", n)
+			}
+		})
+	}
+}
+
+func TestWebSocketClientMirror(t *testing.T) {
+	server := hatest.NewServer("")
+	defer server.Close()
+	server.AddInputNumber("input_number.temp_day_sb1", "Temp day", 22, 10, 35, 0.5)
+	server.AddSensor("sensor.temperature_sb1", "Temperature", "temperature", "°C", 21.4)
+
+	client := server.WebSocketClient()
+	runClient(t, client)
+	waitFor(t, "initial snapshot", client.IsSynced)
+
+	// The mirror starts from get_states
+	if got := len(client.GetStates()); got != 2 {
+		t.Fatalf("mirror holds %d states, want 2", got)
+	}
+	state, ok := client.GetState("input_number.temp_day_sb1")
+	if !ok || state.State != "22.0" {
+		t.Fatalf("input_number.temp_day_sb1 = %+v (found %v), want state 22.0", state, ok)
+	}
+
+	changes, cancel := client.Subscribe()
+	defer cancel()
+
+	tests := []struct {
+		name      string
+		change    func()
+		entityID  string
+		wantState string // empty when the entity is removed
+		wantOld   bool
+	}{
+		{
+			name:      "changed entity",
+			change:    func() { server.SetValue("input_number.temp_day_sb1", 24.5) },
+			entityID:  "input_number.temp_day_sb1",
+			wantState: "24.5",
+			wantOld:   true,
+		},
+		{
+			name:      "new entity",
+			change:    func() { server.AddSensor("sensor.humidity_sb1", "Humidity", "humidity", "%", 55) },
+			entityID:  "sensor.humidity_sb1",
+			wantState: "55.0",
+		},
+		{
+			name:     "removed entity",
+			change:   func() { server.RemoveEntity("sensor.temperature_sb1") },
+			entityID: "sensor.temperature_sb1",
+			wantOld:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			change := receive(t, changes)
+
+			if change.EntityID != tt.entityID {
+				t.Fatalf("change for %s, want %s", change.EntityID, tt.entityID)
+			}
+			if (change.OldState != nil) != tt.wantOld {
+				t.Errorf("old state present = %v, want %v", change.OldState != nil, tt.wantOld)
+			}
+
+			state, exists := client.GetState(tt.entityID)
+			if tt.wantState == "" {
+				if change.NewState != nil {
+					t.Errorf("new state = %+v, want nil", change.NewState)
+				}
+				if exists {
+					t.Errorf("removed entity still mirrored: %+v", state)
+				}
+				return
+			}
+			if change.NewState == nil || change.NewState.State != tt.wantState {
+				t.Errorf("new state = %+v, want %s", change.NewState, tt.wantState)
+			}
+			if !exists || state.State != tt.wantState {
+				t.Errorf("mirrored state = %+v (found %v), want %s", state, exists, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestWebSocketClientReconnect(t *testing.T) {
+	server := hatest.NewServer("")
+	defer server.Close()
+	server.AddInputNumber("input_number.temp_day_sb1", "Temp day", 22, 10, 35, 0.5)
+
+	client := server.WebSocketClient() // backoff 10ms doubling up to 100ms
+	runClient(t, client)
+	waitFor(t, "initial snapshot", client.IsSynced)
+
+	// Let the connection stay up longer than MaxBackoff, which resets the backoff
+	time.Sleep(2 * client.MaxBackoff)
+
+	changes, cancel := client.Subscribe()
+	defer cancel()
+
+	// Two failed attempts after the drop wait 10ms, 20ms and 40ms before the third succeeds
+	server.FailNext(hatest.EndpointWS, http.StatusServiceUnavailable, 2)
+	dropped := time.Now()
+	server.DropWebSockets()
+	waitFor(t, "disconnect", func() bool { return !client.IsSynced() })
+
+	// A change while disconnected is published from the snapshot after the reconnect
+	server.SetValue("input_number.temp_day_sb1", 25)
+
+	waitFor(t, "reconnect", client.IsSynced)
+	if elapsed := time.Since(dropped); elapsed < 70*time.Millisecond {
+		t.Errorf("reconnected after %v, want at least 70ms of backoff", elapsed)
+	}
+
+	change := receive(t, changes)
+	if change.EntityID != "input_number.temp_day_sb1" || change.NewState == nil || change.NewState.State != "25.0" {
+		t.Errorf("change after reconnect = %+v, want input_number.temp_day_sb1 at 25.0", change)
+	}
+	if change.OldState == nil || change.OldState.State != "22.0" {
+		t.Errorf("old state after reconnect = %+v, want 22.0", change.OldState)
+	}
+}
+
+func TestWebSocketClientSubscribers(t *testing.T) {
+	server := hatest.NewServer("")
+	defer server.Close()
+	server.AddInputNumber("input_number.temp_day_sb1", "Temp day", 22, 10, 35, 0.5)
+
+	client := server.WebSocketClient()
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		stop()
+		<-done
+	}()
+	waitFor(t, "initial snapshot", client.IsSynced)
+
+	first, cancelFirst := client.Subscribe()
+	second, _ := client.Subscribe()
+
+	// Every subscriber receives every change
+	server.SetValue("input_number.temp_day_sb1", 23)
+	for i, changes := range []<-chan homeassistant.StateChange{first, second} {
+		if change := receive(t, changes); change.NewState == nil || change.NewState.State != "23.0" {
+			t.Errorf("subscriber %d received %+v, want 23.0", i, change)
+		}
+	}
+
+	// Cancelling closes only that subscription
+	cancelFirst()
+	if _, ok := <-first; ok {
+		t.Error("cancelled subscription still open")
+	}
+	server.SetValue("input_number.temp_day_sb1", 24)
+	if change := receive(t, second); change.NewState == nil || change.NewState.State != "24.0" {
+		t.Errorf("remaining subscriber received %+v, want 24.0", change)
+	}
+
+	// Stopping the client closes the remaining subscriptions
+	stop()
+	<-done
+	select {
+	case _, ok := <-second:
+		if ok {
+			t.Error("subscription open after the client stopped")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("subscription not closed after the client stopped")
+	}
+}
@@ -0,0 +1,228 @@
+// Package modbus drives climate controllers directly over Modbus TCP.
+//
+// A controller serves one chamber. Its registers are described by a register map file of the
+// controller model and exposed as entities following the Home Assistant naming, so setpoint registers
+// become input_number.<name>_<suffix> and sensor registers sensor.<name>_<suffix> entities.
+package modbus
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"local_api_v2/pkg/device"
+)
+
+var _ device.Driver = (*Client)(nil)
+
+const defaultTimeout = 5 * time.Second
+
+// Config configures the connection to a controller
+type Config struct {
+	Address string // host:port of the controller
+	UnitID  byte
+	Timeout time.Duration
+	Suffix  string // chamber suffix appended to the entity names
+}
+
+// Client reads and writes the registers of one controller
+type Client struct {
+	config    Config
+	registers *RegisterMap
+	transport *transport
+}
+
+// NewClient creates a client for the controller, the connection is opened on the first request
+func NewClient(cfg Config, registers *RegisterMap) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Client{
+		config:    cfg,
+		registers: registers,
+		transport: &transport{
+			address: cfg.Address,
+			unitID:  cfg.UnitID,
+			timeout: cfg.Timeout,
+		},
+	}
+}
+
+// URL identifies the controller
+func (c *Client) URL() string {
+	return fmt.Sprintf("modbus://%s/%d", c.config.Address, c.config.UnitID)
+}
+
+// IsConnected reports whether the connection to the controller is open. It does not
+// send a request, a connection that turns out broken is closed by the next read or write.
+func (c *Client) IsConnected() bool {
+	return c.transport.connected()
+}
+
+// entityID returns the entity ID a register is exposed as
+func (c *Client) entityID(register *Register) string {
+	domain := device.DomainInputNumber
+	if register.Kind == KindSensor {
+		domain = device.DomainSensor
+	}
+	return domain + "." + register.Name + "_" + c.config.Suffix
+}
+
+// register returns the register exposed as entityID, or nil
+func (c *Client) register(entityID string) *Register {
+	for i := range c.registers.Registers {
+		if c.entityID(&c.registers.Registers[i]) == entityID {
+			return &c.registers.Registers[i]
+		}
+	}
+	return nil
+}
+
+// read reads the read-back register of a register and returns its value as text, rounded to the
+// resolution of the register, and as a number
+func (c *Client) read(register *Register) (string, float64, error) {
+	values, err := c.transport.readRegisters(register.Table, *register.ReadBack, 1)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read register %s: %v", register.Name, err)
+	}
+
+	text := register.format(register.decode(values[0]))
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read register %s: %v", register.Name, err)
+	}
+	return text, value, nil
+}
+
+// readState reads a register into a state, setpoints carry the limits of the register map
+func (c *Client) readState(register *Register) (device.State, error) {
+	text, value, err := c.read(register)
+	if err != nil {
+		return device.State{}, err
+	}
+
+	state := device.State{
+		EntityID:  c.entityID(register),
+		Raw:       text,
+		Value:     value,
+		Available: true, // controllers report no change times
+	}
+	if register.Kind == KindSetpoint {
+		state.Min, state.Max, state.Step = register.Min, register.Max, register.Step
+	}
+	return state, nil
+}
+
+// GetStates reads all registers of the map
+func (c *Client) GetStates() ([]device.State, error) {
+	states := make([]device.State, 0, len(c.registers.Registers))
+	for i := range c.registers.Registers {
+		state, err := c.readState(&c.registers.Registers[i])
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// GetState reads the register exposed as entityID
+func (c *Client) GetState(entityID string) (*device.State, error) {
+	register := c.register(entityID)
+	if register == nil {
+		return nil, fmt.Errorf("entity %s not found", entityID)
+	}
+	state, err := c.readState(register)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// GetInputNumbers returns the setpoint registers as input_number entities
+func (c *Client) GetInputNumbers() ([]device.Control, error) {
+	var inputNumbers []device.Control
+	for i := range c.registers.Registers {
+		register := &c.registers.Registers[i]
+		if register.Kind != KindSetpoint {
+			continue
+		}
+
+		_, value, err := c.read(register)
+		if err != nil {
+			return nil, err
+		}
+		inputNumbers = append(inputNumbers, device.Control{
+			EntityID:     c.entityID(register),
+			FriendlyName: register.FriendlyName,
+			Value:        value,
+			Min:          register.Min,
+			Max:          register.Max,
+			Step:         register.Step,
+			Unit:         register.Unit,
+		})
+	}
+	return inputNumbers, nil
+}
+
+// GetSensors returns the sensor registers as sensor entities
+func (c *Client) GetSensors() ([]device.Sensor, error) {
+	var sensors []device.Sensor
+	for i := range c.registers.Registers {
+		register := &c.registers.Registers[i]
+		if register.Kind != KindSensor {
+			continue
+		}
+
+		_, value, err := c.read(register)
+		if err != nil {
+			return nil, err
+		}
+		sensors = append(sensors, device.Sensor{
+			EntityID:     c.entityID(register),
+			FriendlyName: register.FriendlyName,
+			DeviceClass:  register.DeviceClass,
+			Value:        value,
+			Unit:         register.Unit,
+			LastUpdated:  time.Now().UTC().Format(time.RFC3339Nano),
+		})
+	}
+	return sensors, nil
+}
+
+// SetInputNumber writes a setpoint register. Values outside the min/max of the register map
+// are clamped like Home Assistant does, controllers do not always check their limits.
+func (c *Client) SetInputNumber(entityID string, value float64) error {
+	register := c.register(entityID)
+	if register == nil {
+		return fmt.Errorf("entity %s not found", entityID)
+	}
+	if register.Kind != KindSetpoint {
+		return fmt.Errorf("entity %s is read-only", entityID)
+	}
+
+	value = min(max(value, register.Min), register.Max)
+	raw, err := register.encode(value)
+	if err != nil {
+		return err
+	}
+	if err := c.transport.writeRegister(register.Address, raw); err != nil {
+		return fmt.Errorf("failed to write register %s: %v", register.Name, err)
+	}
+	return nil
+}
+
+// GetControls returns the setpoint registers, a controller exposes no other controls
+func (c *Client) GetControls() ([]device.Control, error) {
+	return c.GetInputNumbers()
+}
+
+// SetValue writes a setpoint register, see SetInputNumber
+func (c *Client) SetValue(entityID string, value float64) error {
+	return c.SetInputNumber(entityID, value)
+}
+
+// Subscribe is not supported, controllers are polled
+func (c *Client) Subscribe() (<-chan device.StateChange, func(), bool) {
+	return nil, nil, false
+}
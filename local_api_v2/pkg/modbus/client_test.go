@@ -0,0 +1,310 @@
+package modbus
+
+import (
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	simulator "github.com/simonvetter/modbus"
+)
+
+const testUnitID = 3
+
+// controller simulates a climate controller. Setpoints are written to holding registers
+// 100-102, the controller applies a setpoint of 101 to the read-back register 201.
+// Input register 0 holds the measured temperature. Other addresses are not served.
+type controller struct {
+	mu      sync.Mutex
+	holding map[uint16]uint16
+	input   map[uint16]uint16
+	writes  int
+}
+
+func (c *controller) HandleCoils(*simulator.CoilsRequest) ([]bool, error) {
+	return nil, simulator.ErrIllegalFunction
+}
+
+func (c *controller) HandleDiscreteInputs(*simulator.DiscreteInputsRequest) ([]bool, error) {
+	return nil, simulator.ErrIllegalFunction
+}
+
+func (c *controller) HandleHoldingRegisters(req *simulator.HoldingRegistersRequest) ([]uint16, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.UnitId != testUnitID {
+		return nil, simulator.ErrIllegalFunction
+	}
+	if req.IsWrite {
+		for i, value := range req.Args {
+			address := req.Addr + uint16(i)
+			if address < 100 || address > 102 {
+				return nil, simulator.ErrIllegalDataAddress
+			}
+			c.holding[address] = value
+			if address == 101 {
+				c.holding[201] = value
+			}
+		}
+		c.writes++
+		return nil, nil
+	}
+	return read(c.holding, req.Addr, req.Quantity)
+}
+
+func (c *controller) HandleInputRegisters(req *simulator.InputRegistersRequest) ([]uint16, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.UnitId != testUnitID {
+		return nil, simulator.ErrIllegalFunction
+	}
+	return read(c.input, req.Addr, req.Quantity)
+}
+
+func read(table map[uint16]uint16, address, quantity uint16) ([]uint16, error) {
+	values := make([]uint16, quantity)
+	for i := range values {
+		value, found := table[address+uint16(i)]
+		if !found {
+			return nil, simulator.ErrIllegalDataAddress
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (c *controller) holdingValue(address uint16) uint16 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.holding[address]
+}
+
+func (c *controller) writeCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+// startController runs a simulated controller and returns it with its address
+func startController(t *testing.T) (*controller, string) {
+	t.Helper()
+
+	// The simulator does not report the port it listens on, reserve a free one first
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	c := &controller{
+		holding: map[uint16]uint16{100: 220, 101: 60, 102: 0xFFF6, 201: 60}, // 22.0 °C, 60 %, -1.0 °C
+		input:   map[uint16]uint16{0: 0xFFE2},                               // -3.0 °C
+	}
+	server, err := simulator.NewServer(&simulator.ServerConfiguration{
+		URL:        "tcp://" + address,
+		Timeout:    10 * time.Second,
+		MaxClients: 4,
+		Logger:     log.New(io.Discard, "", 0),
+	}, c)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+	return c, address
+}
+
+// newTestClient connects a client with the register map of the simulated controller
+func newTestClient(t *testing.T, address string, registers ...Register) *Client {
+	t.Helper()
+	if len(registers) == 0 {
+		readBack := uint16(201)
+		registers = []Register{
+			{Name: "temp_day", Address: 100, Scale: 0.1, Min: 10, Max: 35, Step: 0.5, Unit: "°C"},
+			{Name: "hum_day", Address: 101, ReadBack: &readBack, Min: 30, Max: 90, Unit: "%"},
+			{Name: "temp_offset", Address: 102, Scale: 0.1, Signed: true, Min: -5, Max: 5},
+			{Name: "temperature", Kind: KindSensor, Table: TableInput, Address: 0, Scale: 0.1, Signed: true, Unit: "°C", DeviceClass: "temperature"},
+		}
+	}
+	registerMap := &RegisterMap{Model: "test", Registers: registers}
+	if err := registerMap.normalize(); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	return NewClient(Config{Address: address, UnitID: testUnitID, Timeout: 2 * time.Second, Suffix: "sb1"}, registerMap)
+}
+
+func TestClientReadRegisters(t *testing.T) {
+	_, address := startController(t)
+	client := newTestClient(t, address)
+
+	tests := []struct {
+		entityID  string
+		wantState string
+		wantMax   float64 // zero for sensors
+	}{
+		{entityID: "input_number.temp_day_sb1", wantState: "22.0", wantMax: 35.0},
+		{entityID: "input_number.hum_day_sb1", wantState: "60", wantMax: 90.0},
+		{entityID: "input_number.temp_offset_sb1", wantState: "-1.0", wantMax: 5.0},
+		{entityID: "sensor.temperature_sb1", wantState: "-3.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entityID, func(t *testing.T) {
+			state, err := client.GetState(tt.entityID)
+			if err != nil {
+				t.Fatalf("GetState: %v", err)
+			}
+			if state.Raw != tt.wantState || !state.Available {
+				t.Errorf("state %s, want %s", state.Raw, tt.wantState)
+			}
+			if state.Max != tt.wantMax {
+				t.Errorf("max %v, want %v", state.Max, tt.wantMax)
+			}
+		})
+	}
+
+	controls, err := client.GetControls()
+	if err != nil {
+		t.Fatalf("GetControls: %v", err)
+	}
+	if len(controls) != 3 {
+		t.Errorf("%d controls, want the 3 setpoints", len(controls))
+	}
+	sensors, err := client.GetSensors()
+	if err != nil {
+		t.Fatalf("GetSensors: %v", err)
+	}
+	if len(sensors) != 1 || sensors[0].Value != -3 {
+		t.Errorf("sensors %+v, want temperature at -3", sensors)
+	}
+}
+
+func TestClientSetValue(t *testing.T) {
+	controller, address := startController(t)
+	client := newTestClient(t, address)
+
+	tests := []struct {
+		name      string
+		entityID  string
+		value     float64
+		wantRaw   uint16 // holding register after the write
+		address   uint16
+		wantState string // read-back
+	}{
+		{name: "scaled", entityID: "input_number.temp_day_sb1", value: 24.5, address: 100, wantRaw: 245, wantState: "24.5"},
+		{name: "scaled rounding", entityID: "input_number.temp_day_sb1", value: 23.26, address: 100, wantRaw: 233, wantState: "23.3"},
+		{name: "clamped to max", entityID: "input_number.temp_day_sb1", value: 40, address: 100, wantRaw: 350, wantState: "35.0"},
+		{name: "clamped to min", entityID: "input_number.temp_day_sb1", value: 2, address: 100, wantRaw: 100, wantState: "10.0"},
+		{name: "separate read-back", entityID: "input_number.hum_day_sb1", value: 75, address: 101, wantRaw: 75, wantState: "75"},
+		{name: "signed negative", entityID: "input_number.temp_offset_sb1", value: -2.5, address: 102, wantRaw: 0xFFE7, wantState: "-2.5"},
+		{name: "signed positive", entityID: "input_number.temp_offset_sb1", value: 1.5, address: 102, wantRaw: 15, wantState: "1.5"},
+		{name: "signed clamped", entityID: "input_number.temp_offset_sb1", value: -9, address: 102, wantRaw: 0xFFCE, wantState: "-5.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := client.SetValue(tt.entityID, tt.value); err != nil {
+				t.Fatalf("SetValue: %v", err)
+			}
+			if raw := controller.holdingValue(tt.address); raw != tt.wantRaw {
+				t.Errorf("register %d holds %#04x, want %#04x", tt.address, raw, tt.wantRaw)
+			}
+			state, err := client.GetState(tt.entityID)
+			if err != nil {
+				t.Fatalf("GetState: %v", err)
+			}
+			if state.Raw != tt.wantState {
+				t.Errorf("read-back %s, want %s", state.Raw, tt.wantState)
+			}
+		})
+	}
+
+	writes := controller.writeCount()
+	for _, entityID := range []string{"sensor.temperature_sb1", "input_number.co2_day_sb1"} {
+		if err := client.SetValue(entityID, 1); err == nil {
+			t.Errorf("SetValue %s succeeded", entityID)
+		}
+	}
+	if controller.writeCount() != writes {
+		t.Error("rejected writes reached the controller")
+	}
+}
+
+func TestClientExceptions(t *testing.T) {
+	_, address := startController(t)
+	client := newTestClient(t, address,
+		Register{Name: "temp_day", Address: 100, Scale: 0.1, Min: 10, Max: 35},
+		Register{Name: "co2_day", Address: 110, Min: 400, Max: 2000},
+		Register{Name: "humidity", Kind: KindSensor, Table: TableInput, Address: 5},
+	)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "read unserved holding register", call: func() error {
+			_, err := client.GetState("input_number.co2_day_sb1")
+			return err
+		}},
+		{name: "read unserved input register", call: func() error {
+			_, err := client.GetState("sensor.humidity_sb1")
+			return err
+		}},
+		{name: "write unserved register", call: func() error { return client.SetValue("input_number.co2_day_sb1", 800) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !client.IsConnected() {
+				t.Fatal("client not connected")
+			}
+			conn := client.transport.conn
+
+			err := tt.call()
+			if err == nil || !strings.Contains(err.Error(), "illegal data address") {
+				t.Fatalf("error = %v, want an illegal data address exception", err)
+			}
+
+			// The controller answered, the connection stays open and served registers can be read
+			if client.transport.conn != conn {
+				t.Error("connection closed after an exception response")
+			}
+			if _, err := client.GetState("input_number.temp_day_sb1"); err != nil {
+				t.Errorf("GetState after exception: %v", err)
+			}
+		})
+	}
+
+	if _, err := client.GetStates(); err == nil {
+		t.Error("GetStates succeeded with unserved registers")
+	}
+}
+
+func TestClientConnectionState(t *testing.T) {
+	_, address := startController(t)
+	client := newTestClient(t, address)
+
+	if !client.IsConnected() {
+		t.Fatal("client not connected to a running controller")
+	}
+	if _, err := client.GetStates(); err != nil {
+		t.Fatalf("GetStates: %v", err)
+	}
+
+	// A controller that is not listening is reported as disconnected without a request
+	offline := newTestClient(t, "127.0.0.1:1")
+	if offline.IsConnected() {
+		t.Error("client connected to a closed port")
+	}
+	if _, err := offline.GetStates(); err == nil {
+		t.Error("GetStates succeeded without a controller")
+	}
+}
@@ -0,0 +1,198 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Modbus function codes used by the driver
+const (
+	funcReadHoldingRegisters = 0x03
+	funcReadInputRegisters   = 0x04
+	funcWriteSingleRegister  = 0x06
+)
+
+// mbapHeaderSize is the size of the Modbus TCP application header
+const mbapHeaderSize = 7
+
+// exceptionError is a Modbus exception response, the controller answered and the
+// connection stays usable
+type exceptionError struct {
+	code byte
+}
+
+func (e *exceptionError) Error() string {
+	return fmt.Sprintf("modbus exception %d (%s)", e.code, exceptionText(e.code))
+}
+
+// transport runs Modbus TCP transactions over a single connection that is
+// re-established on the next request after a failure
+type transport struct {
+	address string
+	unitID  byte
+	timeout time.Duration
+
+	mu            sync.Mutex
+	conn          net.Conn
+	transactionID uint16
+}
+
+// readRegisters reads quantity registers starting at address from the holding or input table
+func (t *transport) readRegisters(table Table, address, quantity uint16) ([]uint16, error) {
+	function := byte(funcReadHoldingRegisters)
+	if table == TableInput {
+		function = funcReadInputRegisters
+	}
+
+	request := make([]byte, 4)
+	binary.BigEndian.PutUint16(request[0:], address)
+	binary.BigEndian.PutUint16(request[2:], quantity)
+
+	response, err := t.transact(function, request)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < 1 || int(response[0]) != 2*int(quantity) || len(response) != 1+int(response[0]) {
+		return nil, fmt.Errorf("invalid response length %d for %d registers", len(response), quantity)
+	}
+
+	values := make([]uint16, quantity)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(response[1+2*i:])
+	}
+	return values, nil
+}
+
+// writeRegister writes a single holding register
+func (t *transport) writeRegister(address, value uint16) error {
+	request := make([]byte, 4)
+	binary.BigEndian.PutUint16(request[0:], address)
+	binary.BigEndian.PutUint16(request[2:], value)
+
+	response, err := t.transact(funcWriteSingleRegister, request)
+	if err != nil {
+		return err
+	}
+	if len(response) != 4 || binary.BigEndian.Uint16(response[0:]) != address || binary.BigEndian.Uint16(response[2:]) != value {
+		return fmt.Errorf("write of register %d was not echoed", address)
+	}
+	return nil
+}
+
+// transact sends a request PDU and returns the data of the response PDU
+func (t *transport) transact(function byte, data []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	response, err := t.roundTrip(function, data)
+	var exception *exceptionError
+	if err != nil && !errors.As(err, &exception) {
+		// The stream may hold a partial frame, start over with a new connection
+		t.close()
+	}
+	return response, err
+}
+
+// connected reports whether the connection is open. The connection is closed when a
+// transaction fails, while it is down every call tries to re-establish it.
+func (t *transport) connected() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.connect() == nil
+}
+
+// connect opens the connection unless it is open
+func (t *transport) connect() error {
+	if t.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", t.address, t.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", t.address, err)
+	}
+	t.conn = conn
+	return nil
+}
+
+func (t *transport) roundTrip(function byte, data []byte) ([]byte, error) {
+	if err := t.connect(); err != nil {
+		return nil, err
+	}
+	if err := t.conn.SetDeadline(time.Now().Add(t.timeout)); err != nil {
+		return nil, err
+	}
+
+	t.transactionID++
+	frame := make([]byte, mbapHeaderSize+1+len(data))
+	binary.BigEndian.PutUint16(frame[0:], t.transactionID)
+	binary.BigEndian.PutUint16(frame[2:], 0) // protocol identifier
+	binary.BigEndian.PutUint16(frame[4:], uint16(2+len(data)))
+	frame[6] = t.unitID
+	frame[7] = function
+	copy(frame[8:], data)
+
+	if _, err := t.conn.Write(frame); err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+
+	header := make([]byte, mbapHeaderSize)
+	if _, err := io.ReadFull(t.conn, header); err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	if length < 2 || length > 254 {
+		return nil, fmt.Errorf("invalid response length %d", length)
+	}
+	pdu := make([]byte, length-1)
+	if _, err := io.ReadFull(t.conn, pdu); err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if id := binary.BigEndian.Uint16(header[0:]); id != t.transactionID {
+		return nil, fmt.Errorf("unexpected transaction %d, expected %d", id, t.transactionID)
+	}
+	if header[6] != t.unitID {
+		return nil, fmt.Errorf("response from unit %d, expected %d", header[6], t.unitID)
+	}
+	if pdu[0] == function|0x80 {
+		if len(pdu) < 2 {
+			return nil, fmt.Errorf("truncated exception response")
+		}
+		return nil, &exceptionError{code: pdu[1]}
+	}
+	if pdu[0] != function {
+		return nil, fmt.Errorf("unexpected function code %d, expected %d", pdu[0], function)
+	}
+	return pdu[1:], nil
+}
+
+func (t *transport) close() {
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// exceptionText describes the standard Modbus exception codes
+func exceptionText(code byte) string {
+	switch code {
+	case 1:
+		return "illegal function"
+	case 2:
+		return "illegal data address"
+	case 3:
+		return "illegal data value"
+	case 4:
+		return "server device failure"
+	case 6:
+		return "server device busy"
+	default:
+		return "unknown"
+	}
+}
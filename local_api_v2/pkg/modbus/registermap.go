@@ -0,0 +1,159 @@
+package modbus
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kind of value a register holds
+type Kind string
+
+const (
+	KindSetpoint Kind = "setpoint" // writable, exposed as an input_number entity
+	KindSensor   Kind = "sensor"   // read-only, exposed as a sensor entity
+)
+
+// Table is the Modbus register table a value is read from
+type Table string
+
+const (
+	TableHolding Table = "holding"
+	TableInput   Table = "input"
+)
+
+// RegisterMap describes the registers of one controller model
+type RegisterMap struct {
+	Model     string     `yaml:"model"`
+	Registers []Register `yaml:"registers"`
+}
+
+// Register maps one controller value to an entity. The entity ID is <domain>.<name>_<suffix>
+// so names should follow the Home Assistant naming discovery expects, e.g. temp_day.
+type Register struct {
+	Name         string  `yaml:"name"`
+	FriendlyName string  `yaml:"friendly_name"`
+	Kind         Kind    `yaml:"kind"`
+	Address      uint16  `yaml:"address"`   // setpoints are written to this holding register
+	ReadBack     *uint16 `yaml:"read_back"` // register holding the applied value, defaults to Address
+	Table        Table   `yaml:"table"`     // table the value is read from, default holding
+	Scale        float64 `yaml:"scale"`     // value = raw * scale, default 1
+	Signed       bool    `yaml:"signed"`    // raw value is a two's complement int16
+	Min          float64 `yaml:"min"`
+	Max          float64 `yaml:"max"`
+	Step         float64 `yaml:"step"`
+	Unit         string  `yaml:"unit"`
+	DeviceClass  string  `yaml:"device_class"`
+}
+
+var registerName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// LoadRegisterMap reads and validates a register map file
+func LoadRegisterMap(path string) (*RegisterMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read register map: %v", err)
+	}
+
+	var registerMap RegisterMap
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&registerMap); err != nil {
+		return nil, fmt.Errorf("failed to parse register map %s: %v", path, err)
+	}
+	if err := registerMap.normalize(); err != nil {
+		return nil, fmt.Errorf("invalid register map %s: %v", path, err)
+	}
+	return &registerMap, nil
+}
+
+// normalize applies defaults and checks every register
+func (m *RegisterMap) normalize() error {
+	if len(m.Registers) == 0 {
+		return fmt.Errorf("no registers defined")
+	}
+
+	names := make(map[string]bool)
+	for i := range m.Registers {
+		register := &m.Registers[i]
+		if !registerName.MatchString(register.Name) {
+			return fmt.Errorf("registers[%d]: name %q must be lowercase letters, digits and underscores", i, register.Name)
+		}
+		if names[register.Name] {
+			return fmt.Errorf("registers[%d]: duplicate name %q", i, register.Name)
+		}
+		names[register.Name] = true
+
+		switch register.Kind {
+		case KindSetpoint, KindSensor:
+		case "":
+			register.Kind = KindSetpoint
+		default:
+			return fmt.Errorf("registers[%d]: invalid kind %q", i, register.Kind)
+		}
+		switch register.Table {
+		case TableHolding, TableInput:
+		case "":
+			register.Table = TableHolding
+		default:
+			return fmt.Errorf("registers[%d]: invalid table %q", i, register.Table)
+		}
+		if register.ReadBack == nil {
+			address := register.Address
+			register.ReadBack = &address
+		}
+		if register.Scale == 0 {
+			register.Scale = 1
+		}
+		if register.FriendlyName == "" {
+			register.FriendlyName = register.Name
+		}
+		if register.Kind == KindSetpoint {
+			if register.Min >= register.Max {
+				return fmt.Errorf("registers[%d]: min must be below max", i)
+			}
+			if register.Step <= 0 {
+				register.Step = register.Scale
+			}
+		}
+	}
+	return nil
+}
+
+// decode converts a raw register value into the entity value
+func (r *Register) decode(raw uint16) float64 {
+	if r.Signed {
+		return float64(int16(raw)) * r.Scale
+	}
+	return float64(raw) * r.Scale
+}
+
+// format renders a decoded value with the precision of the scale, hiding float noise like 0.30000000000000004
+func (r *Register) format(value float64) string {
+	decimals := 0
+	if _, fraction, found := strings.Cut(strconv.FormatFloat(r.Scale, 'f', -1, 64), "."); found {
+		decimals = len(fraction)
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
+
+// encode converts an entity value into a raw register value
+func (r *Register) encode(value float64) (uint16, error) {
+	raw := math.Round(value / r.Scale)
+	if r.Signed {
+		if raw < math.MinInt16 || raw > math.MaxInt16 {
+			return 0, fmt.Errorf("value %v does not fit register %s", value, r.Name)
+		}
+		return uint16(int16(raw)), nil
+	}
+	if raw < 0 || raw > math.MaxUint16 {
+		return 0, fmt.Errorf("value %v does not fit register %s", value, r.Name)
+	}
+	return uint16(raw), nil
+}
@@ -0,0 +1,599 @@
+// Package mqtt drives chamber devices that publish their entities on an MQTT broker.
+//
+// Entities follow the Home Assistant naming so chambers are discovered the same way:
+//
+//	<prefix>/<domain>/<object_id>/state  retained state published by the device
+//	<prefix>/<domain>/<object_id>/set    setpoint written by the local API
+//
+// The entity ID of a topic is <domain>.<object_id>, e.g. growchamber/input_number/day_temp_room1/state
+// is input_number.day_temp_room1. A state payload is either the bare value ("21.5") or a JSON object
+// {"state": 21.5, "attributes": {"min": 10, "max": 35, "step": 0.5, "unit_of_measurement": "°C"}}.
+// Switches report on or off and lights on, off or their brightness in percent, all other entities
+// a number. An empty retained payload removes the entity. Set payloads are the bare value, except for
+// switches (on or off) and lights (the brightness in percent, 0 turns the light off).
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"local_api_v2/pkg/device"
+)
+
+var _ device.Driver = (*Client)(nil)
+
+const (
+	// DefaultTopicPrefix is used when no topic prefix is configured
+	DefaultTopicPrefix = "growchamber"
+
+	// retainedSettle is the time given to the broker to deliver the retained states after subscribing,
+	// there is no end marker so the mirror counts as complete once it has passed
+	retainedSettle = 2 * time.Second
+
+	publishTimeout        = 10 * time.Second
+	defaultConfirmTimeout = 5 * time.Second
+	defaultConfirmBudget  = 20 * time.Second
+
+	// confirmWindow is the period confirmation waits share ConfirmBudget in, the executor interval
+	confirmWindow = time.Minute
+)
+
+// Config configures the connection to a broker
+type Config struct {
+	Broker      string // e.g. tcp://localhost:1883
+	ClientID    string
+	Username    string
+	Password    string
+	TopicPrefix string
+
+	// ConfirmTimeout is how long SetValue waits for the device to publish the new state
+	ConfirmTimeout time.Duration
+	// ConfirmBudget caps the time all SetValue calls of a minute wait for confirmations together,
+	// so devices that stop confirming cannot hold up an executor tick
+	ConfirmBudget time.Duration
+}
+
+// Client keeps an in-memory mirror of the retained entity states on a broker,
+// writes setpoints and publishes state changes to subscribers
+type Client struct {
+	config Config
+	client paho.Client
+
+	mu        sync.RWMutex
+	states    map[string]entityState
+	connected bool
+	synced    bool // retained states of the current connection have been received
+
+	subMu       sync.Mutex
+	subscribers map[int]chan device.StateChange
+	nextSubID   int
+
+	budgetMu    sync.Mutex
+	budgetReset time.Time     // end of the current confirmation window
+	budgetLeft  time.Duration // confirmation wait left in the current window
+}
+
+// NewClient creates a client for the broker, call Run to connect
+func NewClient(cfg Config) *Client {
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = DefaultTopicPrefix
+	}
+	cfg.TopicPrefix = strings.TrimRight(cfg.TopicPrefix, "/")
+	if cfg.ConfirmTimeout <= 0 {
+		cfg.ConfirmTimeout = defaultConfirmTimeout
+	}
+	if cfg.ConfirmBudget <= 0 {
+		cfg.ConfirmBudget = defaultConfirmBudget
+	}
+
+	c := &Client{
+		config:      cfg,
+		states:      make(map[string]entityState),
+		subscribers: make(map[int]chan device.StateChange),
+	}
+
+	options := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetOnConnectHandler(c.onConnect).
+		SetConnectionLostHandler(c.onConnectionLost)
+	c.client = paho.NewClient(options)
+
+	return c
+}
+
+// Run connects to the broker and keeps the state mirror up to date until ctx is cancelled.
+// Lost connections are re-established by the MQTT client.
+func (c *Client) Run(ctx context.Context) {
+	c.client.Connect()
+	<-ctx.Done()
+
+	c.client.Disconnect(250)
+	c.setConnected(false)
+	c.closeSubscribers()
+	slog.Info("MQTT client stopped", "broker", c.config.Broker)
+}
+
+// onConnect subscribes to the state topics, the broker then delivers all retained states
+func (c *Client) onConnect(client paho.Client) {
+	topic := c.config.TopicPrefix + "/+/+/state"
+	token := client.Subscribe(topic, 1, c.onMessage)
+	if !token.WaitTimeout(publishTimeout) || token.Error() != nil {
+		slog.Error("MQTT subscription failed", "broker", c.config.Broker, "topic", topic, "error", token.Error())
+		return
+	}
+
+	c.setConnected(true)
+	slog.Info("MQTT connected", "broker", c.config.Broker, "topic", topic)
+
+	time.AfterFunc(retainedSettle, func() {
+		c.mu.Lock()
+		c.synced = c.connected
+		c.mu.Unlock()
+	})
+}
+
+func (c *Client) onConnectionLost(_ paho.Client, err error) {
+	c.setConnected(false)
+	slog.Error("MQTT connection lost", "broker", c.config.Broker, "error", err)
+}
+
+// onMessage applies a state message to the mirror
+func (c *Client) onMessage(_ paho.Client, message paho.Message) {
+	entityID, ok := c.entityID(message.Topic())
+	if !ok {
+		return
+	}
+
+	change := device.StateChange{EntityID: entityID}
+
+	c.mu.Lock()
+	old, existed := c.states[entityID]
+	if existed {
+		oldState := old.deviceState()
+		change.OldState = &oldState
+	}
+	if len(message.Payload()) == 0 {
+		delete(c.states, entityID)
+	} else {
+		state, err := parseState(entityID, message.Payload())
+		if err != nil {
+			c.mu.Unlock()
+			slog.Warn("Ignoring invalid MQTT state", "topic", message.Topic(), "error", err)
+			return
+		}
+		state.lastChanged = state.lastUpdated
+		if existed && old.state == state.state {
+			state.lastChanged = old.lastChanged
+		}
+		c.states[entityID] = state
+		newState := state.deviceState()
+		change.NewState = &newState
+	}
+	c.mu.Unlock()
+
+	c.publish(change)
+}
+
+// entityID converts <prefix>/<domain>/<object_id>/state into <domain>.<object_id>
+func (c *Client) entityID(topic string) (string, bool) {
+	rest, found := strings.CutPrefix(topic, c.config.TopicPrefix+"/")
+	if !found {
+		return "", false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[2] != "state" {
+		return "", false
+	}
+	return parts[0] + "." + parts[1], true
+}
+
+// commandTopic returns the topic setpoints of an entity are written to
+func (c *Client) commandTopic(entityID string) (string, error) {
+	domain, objectID, found := strings.Cut(entityID, ".")
+	if !found || domain == "" || objectID == "" {
+		return "", fmt.Errorf("invalid entity ID %q", entityID)
+	}
+	return c.config.TopicPrefix + "/" + domain + "/" + objectID + "/set", nil
+}
+
+// entityState is the last state payload received for an entity
+type entityState struct {
+	entityID    string
+	state       string
+	attributes  map[string]interface{}
+	lastChanged time.Time
+	lastUpdated time.Time
+}
+
+// parseState decodes a bare value or a JSON state payload
+func parseState(entityID string, payload []byte) (entityState, error) {
+	state := entityState{
+		entityID:    entityID,
+		attributes:  map[string]interface{}{},
+		lastUpdated: time.Now().UTC(),
+	}
+
+	text := strings.TrimSpace(string(payload))
+	if !strings.HasPrefix(text, "{") {
+		state.state = text
+		return state, nil
+	}
+
+	var message struct {
+		State      interface{}            `json:"state"`
+		Attributes map[string]interface{} `json:"attributes"`
+	}
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return state, fmt.Errorf("failed to decode state: %v", err)
+	}
+	switch value := message.State.(type) {
+	case nil:
+		return state, fmt.Errorf("state is missing")
+	case string:
+		state.state = value
+	case float64:
+		state.state = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		state.state = fmt.Sprint(value)
+	}
+	if message.Attributes != nil {
+		state.attributes = message.Attributes
+	}
+	return state, nil
+}
+
+// value returns the numeric value of the state: 1 or 0 for switches, the brightness in percent for
+// lights (100 when on without brightness) and the number of all other entities
+func (s entityState) value() (float64, bool) {
+	domain := device.Domain(s.entityID)
+	switch {
+	case s.state == "on" && domain == device.DomainSwitch:
+		return 1, true
+	case s.state == "on" && domain == device.DomainLight:
+		return 100, true
+	case s.state == "off" && (domain == device.DomainSwitch || domain == device.DomainLight):
+		return 0, true
+	}
+
+	value, err := strconv.ParseFloat(s.state, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// control returns the entity with its value and limits. Switches range from 0 to 1 and lights
+// from 0 to 100 %, other controls publish their min, max and step attributes.
+func (s entityState) control() device.Control {
+	control := device.Control{
+		EntityID:     s.entityID,
+		FriendlyName: s.stringAttribute("friendly_name", s.entityID),
+		Min:          s.floatAttribute("min", 0),
+		Max:          s.floatAttribute("max", 100),
+		Step:         s.floatAttribute("step", 1),
+		Unit:         s.stringAttribute("unit_of_measurement", ""),
+	}
+	control.Value, _ = s.value()
+
+	switch device.Domain(s.entityID) {
+	case device.DomainSwitch:
+		control.Min, control.Max, control.Step, control.Unit = 0, 1, 1, ""
+	case device.DomainLight:
+		control.Min, control.Max, control.Step, control.Unit = 0, 100, 1, "%"
+	}
+	return control
+}
+
+// deviceState converts the state for the chamber services, sensors get no limits
+func (s entityState) deviceState() device.State {
+	state := device.State{EntityID: s.entityID, Raw: s.state, LastChanged: s.lastChanged}
+	state.Value, state.Available = s.value()
+	if device.IsControl(s.entityID) {
+		control := s.control()
+		state.Min, state.Max, state.Step = control.Min, control.Max, control.Step
+	}
+	return state
+}
+
+func (s entityState) floatAttribute(key string, defaultValue float64) float64 {
+	if value, ok := s.attributes[key].(float64); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func (s entityState) stringAttribute(key, defaultValue string) string {
+	if value, ok := s.attributes[key].(string); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func (c *Client) setConnected(connected bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connected = connected
+	if !connected {
+		c.synced = false
+	}
+}
+
+// URL returns the broker address
+func (c *Client) URL() string {
+	return c.config.Broker
+}
+
+// IsConnected reports whether the broker is connected and the retained states have been received
+func (c *Client) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connected && c.synced
+}
+
+// GetStates returns all mirrored states
+func (c *Client) GetStates() ([]device.State, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.connected {
+		return nil, fmt.Errorf("not connected to MQTT broker %s", c.config.Broker)
+	}
+
+	states := make([]device.State, 0, len(c.states))
+	for _, state := range c.states {
+		states = append(states, state.deviceState())
+	}
+	return states, nil
+}
+
+// GetState returns the mirrored state of an entity
+func (c *Client) GetState(entityID string) (*device.State, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.connected {
+		return nil, fmt.Errorf("not connected to MQTT broker %s", c.config.Broker)
+	}
+
+	state, exists := c.states[entityID]
+	if !exists {
+		return nil, fmt.Errorf("entity %s not found", entityID)
+	}
+	converted := state.deviceState()
+	return &converted, nil
+}
+
+// entities returns the mirrored states of the entities that pass keep
+func (c *Client) entities(keep func(entityState) bool) ([]entityState, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.connected {
+		return nil, fmt.Errorf("not connected to MQTT broker %s", c.config.Broker)
+	}
+
+	var states []entityState
+	for _, state := range c.states {
+		if keep(state) {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+// GetInputNumbers returns all input_number entities
+func (c *Client) GetInputNumbers() ([]device.Control, error) {
+	states, err := c.entities(func(state entityState) bool {
+		return device.Domain(state.entityID) == device.DomainInputNumber
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inputNumbers := make([]device.Control, 0, len(states))
+	for _, state := range states {
+		inputNumbers = append(inputNumbers, state.control())
+	}
+	return inputNumbers, nil
+}
+
+// GetControls returns all entities that can be written: input_number, number, switch, light and climate
+func (c *Client) GetControls() ([]device.Control, error) {
+	states, err := c.entities(func(state entityState) bool {
+		return device.IsControl(state.entityID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	controls := make([]device.Control, 0, len(states))
+	for _, state := range states {
+		controls = append(controls, state.control())
+	}
+	return controls, nil
+}
+
+// GetSensors returns all sensor entities that currently report a numeric value
+func (c *Client) GetSensors() ([]device.Sensor, error) {
+	states, err := c.entities(func(state entityState) bool {
+		return device.Domain(state.entityID) == device.DomainSensor
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var sensors []device.Sensor
+	for _, state := range states {
+		value, ok := state.value()
+		if !ok {
+			continue
+		}
+		sensors = append(sensors, device.Sensor{
+			EntityID:     state.entityID,
+			FriendlyName: state.stringAttribute("friendly_name", state.entityID),
+			DeviceClass:  state.stringAttribute("device_class", ""),
+			Value:        value,
+			Unit:         state.stringAttribute("unit_of_measurement", ""),
+			LastUpdated:  state.lastUpdated.Format(time.RFC3339Nano),
+		})
+	}
+	return sensors, nil
+}
+
+// SetInputNumber publishes the setpoint of an input_number entity, see SetValue
+func (c *Client) SetInputNumber(entityID string, value float64) error {
+	if device.Domain(entityID) != device.DomainInputNumber {
+		return fmt.Errorf("entity %s is not an input_number", entityID)
+	}
+	return c.SetValue(entityID, value)
+}
+
+// SetValue publishes a setpoint and waits up to ConfirmTimeout for the device to publish
+// the resulting state. A device that does not confirm in time is not an error here,
+// the read-back of the caller reports the value that is actually held. Once the waits of
+// the current minute have used up ConfirmBudget, SetValue returns right after publishing.
+func (c *Client) SetValue(entityID string, value float64) error {
+	if !device.IsControl(entityID) {
+		return fmt.Errorf("entity %s is not a control", entityID)
+	}
+	topic, err := c.commandTopic(entityID)
+	if err != nil {
+		return err
+	}
+	if !c.client.IsConnectionOpen() {
+		return fmt.Errorf("not connected to MQTT broker %s", c.config.Broker)
+	}
+
+	changes, unsubscribe, _ := c.Subscribe()
+	defer unsubscribe()
+
+	payload := strconv.FormatFloat(value, 'f', -1, 64)
+	switch device.Domain(entityID) {
+	case device.DomainSwitch:
+		payload = "off"
+		if value > 0 {
+			payload = "on"
+		}
+	case device.DomainLight:
+		payload = strconv.FormatFloat(math.Round(min(max(value, 0), 100)), 'f', -1, 64)
+	}
+
+	token := c.client.Publish(topic, 1, false, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out publishing to %s", topic)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %v", topic, err)
+	}
+
+	wait := c.reserveConfirmWait()
+	if wait <= 0 {
+		slog.Debug("MQTT confirmation budget used up, not waiting for the device", "entity_id", entityID, "value", value)
+		return nil
+	}
+	start := time.Now()
+	defer func() { c.refundConfirmWait(wait - time.Since(start)) }()
+
+	timeout := time.After(wait)
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return nil
+			}
+			if change.EntityID == entityID && change.NewState != nil {
+				return nil
+			}
+		case <-timeout:
+			slog.Debug("MQTT device did not confirm setpoint", "entity_id", entityID, "value", value)
+			return nil
+		}
+	}
+}
+
+// reserveConfirmWait takes the time a write may wait for its confirmation from the budget
+// of the current window
+func (c *Client) reserveConfirmWait() time.Duration {
+	c.budgetMu.Lock()
+	defer c.budgetMu.Unlock()
+
+	if now := time.Now(); now.After(c.budgetReset) {
+		c.budgetReset = now.Add(confirmWindow)
+		c.budgetLeft = c.config.ConfirmBudget
+	}
+	wait := min(c.config.ConfirmTimeout, c.budgetLeft)
+	c.budgetLeft -= wait
+	return wait
+}
+
+// refundConfirmWait returns the unused part of a reserved wait to the budget
+func (c *Client) refundConfirmWait(unused time.Duration) {
+	if unused <= 0 {
+		return
+	}
+	c.budgetMu.Lock()
+	defer c.budgetMu.Unlock()
+	c.budgetLeft += unused
+}
+
+// Subscribe returns a channel receiving all state changes and a function to cancel the subscription.
+// Changes are dropped for subscribers that do not keep up with the buffer.
+func (c *Client) Subscribe() (<-chan device.StateChange, func(), bool) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	id := c.nextSubID
+	c.nextSubID++
+	ch := make(chan device.StateChange, 256)
+	c.subscribers[id] = ch
+
+	return ch, func() {
+		c.subMu.Lock()
+		defer c.subMu.Unlock()
+		if ch, exists := c.subscribers[id]; exists {
+			delete(c.subscribers, id)
+			close(ch)
+		}
+	}, true
+}
+
+// publish delivers a change to all subscribers without blocking
+func (c *Client) publish(change device.StateChange) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for _, ch := range c.subscribers {
+		select {
+		case ch <- change:
+		default:
+			slog.Warn("Dropping state change for slow subscriber", "entity_id", change.EntityID)
+		}
+	}
+}
+
+// closeSubscribers closes all subscriber channels
+func (c *Client) closeSubscribers() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for id, ch := range c.subscribers {
+		delete(c.subscribers, id)
+		close(ch)
+	}
+}
@@ -0,0 +1,347 @@
+package mqtt_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+
+	"local_api_v2/pkg/device"
+	"local_api_v2/pkg/mqtt"
+)
+
+// scriptedDevice is an embedded broker with a scripted chamber device behind it. The device answers
+// setpoints on <prefix>/+/+/set by publishing the new retained state, unless it is silent.
+type scriptedDevice struct {
+	broker *mochi.Server
+	url    string
+
+	mu     sync.Mutex
+	silent bool
+	sets   map[string][]string // set topic -> received payloads
+}
+
+// newDevice starts an embedded broker on a free local port
+func newDevice(t *testing.T) *scriptedDevice {
+	t.Helper()
+
+	broker := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatalf("AddHook: %v", err)
+	}
+	listener := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	if err := broker.AddListener(listener); err != nil {
+		t.Fatalf("AddListener: %v", err)
+	}
+	if err := broker.Serve(); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	d := &scriptedDevice{broker: broker, url: "tcp://" + listener.Address(), sets: make(map[string][]string)}
+	err := broker.Subscribe(mqtt.DefaultTopicPrefix+"/+/+/set", 1, func(_ *mochi.Client, _ packets.Subscription, pk packets.Packet) {
+		d.mu.Lock()
+		d.sets[pk.TopicName] = append(d.sets[pk.TopicName], string(pk.Payload))
+		silent := d.silent
+		d.mu.Unlock()
+
+		if !silent {
+			broker.Publish(strings.TrimSuffix(pk.TopicName, "/set")+"/state", pk.Payload, true, 1)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	return d
+}
+
+// publishState publishes the retained state of an entity like the device does
+func (d *scriptedDevice) publishState(t *testing.T, entityID, payload string) {
+	t.Helper()
+	domain, objectID, _ := strings.Cut(entityID, ".")
+	topic := mqtt.DefaultTopicPrefix + "/" + domain + "/" + objectID + "/state"
+	if err := d.broker.Publish(topic, []byte(payload), true, 1); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+}
+
+func (d *scriptedDevice) setSilent(silent bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.silent = silent
+}
+
+// received returns the payloads written to the set topic of an entity
+func (d *scriptedDevice) received(entityID string) []string {
+	domain, objectID, _ := strings.Cut(entityID, ".")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.sets[mqtt.DefaultTopicPrefix+"/"+domain+"/"+objectID+"/set"]...)
+}
+
+// runClient connects a client to the broker and waits until the retained states arrived
+func runClient(t *testing.T, cfg mqtt.Config) *mqtt.Client {
+	t.Helper()
+	client := mqtt.NewClient(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	waitFor(t, "connection", client.IsConnected)
+	return client
+}
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientMirror(t *testing.T) {
+	d := newDevice(t)
+	d.publishState(t, "input_number.temp_day_sb1", `{"state": 22, "attributes": {"min": 10, "max": 35, "step": 0.5}}`)
+	d.publishState(t, "switch.pump_sb1", "on")
+	d.publishState(t, "light.lamp_1_sb1", "60")
+	d.publishState(t, "sensor.temperature_sb1", "21.4")
+
+	client := runClient(t, mqtt.Config{Broker: d.url, ClientID: t.Name()})
+
+	// Retained states are mirrored on connect
+	controls, err := client.GetControls()
+	if err != nil {
+		t.Fatalf("GetControls: %v", err)
+	}
+	if len(controls) != 3 {
+		t.Fatalf("%d controls mirrored, want 3: %+v", len(controls), controls)
+	}
+	state, err := client.GetState("input_number.temp_day_sb1")
+	if err != nil || state.Raw != "22" || state.Value != 22 || !state.Available {
+		t.Fatalf("input_number.temp_day_sb1 = %+v (%v), want 22", state, err)
+	}
+	if state.Min != 10 || state.Max != 35 || state.Step != 0.5 {
+		t.Errorf("limits %v-%v step %v, want 10-35 step 0.5", state.Min, state.Max, state.Step)
+	}
+
+	// Switches report on or off, lights their brightness in percent
+	for entityID, want := range map[string]float64{"switch.pump_sb1": 1, "light.lamp_1_sb1": 60} {
+		if state, err := client.GetState(entityID); err != nil || state.Value != want || !state.Available {
+			t.Errorf("%s = %+v (%v), want %v", entityID, state, err, want)
+		}
+	}
+
+	changes, unsubscribe, ok := client.Subscribe()
+	if !ok {
+		t.Fatal("client cannot push changes")
+	}
+	defer unsubscribe()
+
+	tests := []struct {
+		name      string
+		entityID  string
+		payload   string
+		wantState string // empty when the entity is removed
+	}{
+		{name: "bare value", entityID: "sensor.temperature_sb1", payload: "21.9", wantState: "21.9"},
+		{name: "new entity", entityID: "sensor.humidity_sb1", payload: `{"state": 55.5}`, wantState: "55.5"},
+		{name: "removed entity", entityID: "switch.pump_sb1", payload: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d.publishState(t, tt.entityID, tt.payload)
+
+			var change device.StateChange
+			select {
+			case change = <-changes:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for a state change")
+			}
+			if change.EntityID != tt.entityID {
+				t.Fatalf("change for %s, want %s", change.EntityID, tt.entityID)
+			}
+
+			state, err := client.GetState(tt.entityID)
+			if tt.wantState == "" {
+				if change.NewState != nil || err == nil {
+					t.Errorf("removed entity still present: change %+v, state %+v", change.NewState, state)
+				}
+				return
+			}
+			if change.NewState == nil || change.NewState.Raw != tt.wantState || err != nil || state.Raw != tt.wantState {
+				t.Errorf("change %+v, state %+v (%v), want %s", change.NewState, state, err, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestClientSetValue(t *testing.T) {
+	d := newDevice(t)
+	d.publishState(t, "input_number.temp_day_sb1", "22")
+	d.publishState(t, "switch.pump_sb1", "off")
+	d.publishState(t, "light.lamp_1_sb1", "60")
+
+	client := runClient(t, mqtt.Config{Broker: d.url, ClientID: t.Name()})
+
+	tests := []struct {
+		name        string
+		entityID    string
+		value       float64
+		wantPayload string
+	}{
+		{name: "input_number", entityID: "input_number.temp_day_sb1", value: 24.5, wantPayload: "24.5"},
+		{name: "switch on", entityID: "switch.pump_sb1", value: 1, wantPayload: "on"},
+		{name: "switch off", entityID: "switch.pump_sb1", value: 0, wantPayload: "off"},
+		{name: "light above 100", entityID: "light.lamp_1_sb1", value: 150, wantPayload: "100"},
+		{name: "light rounded", entityID: "light.lamp_1_sb1", value: 37.4, wantPayload: "37"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := client.SetValue(tt.entityID, tt.value); err != nil {
+				t.Fatalf("SetValue: %v", err)
+			}
+
+			received := d.received(tt.entityID)
+			if len(received) == 0 || received[len(received)-1] != tt.wantPayload {
+				t.Fatalf("device received %q, want %q last", received, tt.wantPayload)
+			}
+
+			// SetValue returns once the device confirmed, the mirror already holds the new state
+			state, err := client.GetState(tt.entityID)
+			if err != nil || state.Raw != tt.wantPayload {
+				t.Errorf("state after SetValue = %+v (%v), want %s", state, err, tt.wantPayload)
+			}
+		})
+	}
+
+	if err := client.SetValue("sensor.temperature_sb1", 1); err == nil {
+		t.Error("SetValue on a sensor succeeded")
+	}
+}
+
+func TestClientConfirmTimeout(t *testing.T) {
+	d := newDevice(t)
+	d.publishState(t, "input_number.temp_day_sb1", "22")
+	d.setSilent(true)
+
+	client := runClient(t, mqtt.Config{Broker: d.url, ClientID: t.Name(), ConfirmTimeout: 200 * time.Millisecond})
+
+	start := time.Now()
+	if err := client.SetValue("input_number.temp_day_sb1", 25); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("SetValue returned after %v, want the confirm timeout of 200ms", elapsed)
+	}
+
+	// The device did not confirm, the read-back reports the value it still holds
+	if received := d.received("input_number.temp_day_sb1"); len(received) != 1 || received[0] != "25" {
+		t.Errorf("device received %q, want [25]", received)
+	}
+	if state, err := client.GetState("input_number.temp_day_sb1"); err != nil || state.Raw != "22" {
+		t.Errorf("state = %+v (%v), want 22", state, err)
+	}
+}
+
+func TestClientConfirmBudget(t *testing.T) {
+	d := newDevice(t)
+	entityIDs := []string{"input_number.temp_day_sb1", "input_number.temp_night_sb1", "input_number.hum_day_sb1", "input_number.hum_night_sb1"}
+	for _, entityID := range entityIDs {
+		d.publishState(t, entityID, "22")
+	}
+	d.setSilent(true)
+
+	client := runClient(t, mqtt.Config{
+		Broker:         d.url,
+		ClientID:       t.Name(),
+		ConfirmTimeout: 200 * time.Millisecond,
+		ConfirmBudget:  300 * time.Millisecond,
+	})
+
+	// Waits of 200ms and 100ms use up the budget, the remaining writes do not wait
+	start := time.Now()
+	for _, entityID := range entityIDs {
+		if err := client.SetValue(entityID, 25); err != nil {
+			t.Fatalf("SetValue %s: %v", entityID, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 700*time.Millisecond {
+		t.Errorf("%d unconfirmed writes took %v, want about the budget of 300ms", len(entityIDs), elapsed)
+	}
+	for _, entityID := range entityIDs {
+		if received := d.received(entityID); len(received) != 1 {
+			t.Errorf("device received %q on %s, want one setpoint", received, entityID)
+		}
+	}
+
+	// With the budget used up the setpoint is still published, the device confirms it later
+	d.setSilent(false)
+	if err := client.SetValue("input_number.temp_day_sb1", 26); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+	waitFor(t, "confirmation", func() bool {
+		state, _ := client.GetState("input_number.temp_day_sb1")
+		return state != nil && state.Raw == "26"
+	})
+}
+
+func TestClientConfirmBudgetRefund(t *testing.T) {
+	d := newDevice(t)
+	d.publishState(t, "input_number.temp_day_sb1", "22")
+
+	client := runClient(t, mqtt.Config{
+		Broker:         d.url,
+		ClientID:       t.Name(),
+		ConfirmTimeout: 200 * time.Millisecond,
+		ConfirmBudget:  300 * time.Millisecond,
+	})
+
+	// Confirmed writes return the unused part of their wait, so the budget still covers a full wait
+	for value := 23; value <= 30; value++ {
+		if err := client.SetValue("input_number.temp_day_sb1", float64(value)); err != nil {
+			t.Fatalf("SetValue: %v", err)
+		}
+	}
+
+	d.setSilent(true)
+	start := time.Now()
+	if err := client.SetValue("input_number.temp_day_sb1", 31); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("unconfirmed write after confirmed ones waited %v, want the confirm timeout of 200ms", elapsed)
+	}
+}
+
+func TestClientNotConnected(t *testing.T) {
+	client := mqtt.NewClient(mqtt.Config{Broker: "tcp://127.0.0.1:1"})
+	if err := client.SetValue("input_number.temp_day_sb1", 22); err == nil {
+		t.Error("SetValue without a connection succeeded")
+	}
+	if _, err := client.GetStates(); err == nil {
+		t.Error("GetStates without a connection succeeded")
+	}
+}
@@ -3,7 +3,7 @@ package ntp
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -19,12 +19,13 @@ type TimeService struct {
 	syncedTime  time.Time
 	localTime   time.Time
 	offset      time.Duration
-	location    string
+	location    *time.Location
 	lastSync    time.Time
 	mu          sync.RWMutex
 	isConnected bool
 	enabled     bool
 	stopChan    chan struct{}
+	intervals   chan time.Duration // sync interval changes for the running Start loop
 }
 
 // Config holds NTP service configuration
@@ -54,27 +55,35 @@ func NewTimeService(config Config) *TimeService {
 		timeout = 5 * time.Second
 	}
 
+	// The configuration validates NTP_LOCATION, the fallback only covers services created without it
+	location, err := time.LoadLocation(config.NTPLocation)
+	if err != nil {
+		slog.Warn("Could not load timezone, using UTC", "location", config.NTPLocation, "error", err)
+		location = time.UTC
+	}
+
 	return &TimeService{
-		servers:  servers,
-		timeout:  timeout,
-		enabled:  config.Enabled,
-		stopChan: make(chan struct{}),
-		location: config.NTPLocation,
+		servers:   servers,
+		timeout:   timeout,
+		enabled:   config.Enabled,
+		stopChan:  make(chan struct{}),
+		intervals: make(chan time.Duration, 1),
+		location:  location,
 	}
 }
 
 // Start begins the NTP service with periodic synchronization
 func (ts *TimeService) Start(ctx context.Context, syncInterval time.Duration) error {
 	if !ts.enabled {
-		log.Println("NTP service disabled - using system time")
+		slog.Info("NTP service disabled, using system time")
 		return nil
 	}
 
-	log.Printf("Starting NTP service with servers: %v", ts.servers)
+	slog.Info("Starting NTP service", "servers", ts.servers)
 
 	// Initial sync
 	if err := ts.Sync(); err != nil {
-		log.Printf("Initial NTP sync failed: %v", err)
+		slog.Warn("Initial NTP sync failed", "error", err)
 		// Don't return error - continue with periodic attempts
 	}
 
@@ -86,14 +95,16 @@ func (ts *TimeService) Start(ctx context.Context, syncInterval time.Duration) er
 		for {
 			select {
 			case <-ctx.Done():
-				log.Println("NTP service stopped by context")
+				slog.Info("NTP service stopped by context")
 				return
 			case <-ts.stopChan:
-				log.Println("NTP service stopped")
+				slog.Info("NTP service stopped")
 				return
+			case interval := <-ts.intervals:
+				ticker.Reset(interval)
 			case <-ticker.C:
 				if err := ts.Sync(); err != nil {
-					log.Printf("Periodic NTP sync failed: %v", err)
+					slog.Warn("Periodic NTP sync failed", "error", err)
 				}
 			}
 		}
@@ -109,18 +120,46 @@ func (ts *TimeService) Stop() {
 	}
 }
 
+// Reconfigure replaces the NTP servers, query timeout and sync interval of a running service
+func (ts *TimeService) Reconfigure(servers []string, timeout, syncInterval time.Duration) {
+	ts.mu.Lock()
+	if len(servers) > 0 {
+		ts.servers = append([]string(nil), servers...)
+	}
+	if timeout > 0 {
+		ts.timeout = timeout
+	}
+	ts.mu.Unlock()
+
+	if syncInterval > 0 {
+		select {
+		case <-ts.intervals:
+		default:
+		}
+		select {
+		case ts.intervals <- syncInterval:
+		default:
+		}
+	}
+}
+
 // Sync synchronizes with NTP servers
 func (ts *TimeService) Sync() error {
 	var lastErr error
 
-	for _, server := range ts.servers {
+	ts.mu.RLock()
+	servers := ts.servers
+	timeout := ts.timeout
+	ts.mu.RUnlock()
+
+	for _, server := range servers {
 		response, err := ntp.QueryWithOptions(server, ntp.QueryOptions{
-			Timeout: ts.timeout,
+			Timeout: timeout,
 		})
 
 		if err != nil {
 			lastErr = err
-			log.Printf("NTP sync failed for %s: %v", server, err)
+			slog.Debug("NTP sync failed", "server", server, "error", err)
 			continue
 		}
 
@@ -132,7 +171,7 @@ func (ts *TimeService) Sync() error {
 		ts.isConnected = true
 		ts.mu.Unlock()
 
-		log.Printf("✅ NTP synced with %s (offset: %v) Local time: %v", server, response.ClockOffset, ts.localTime)
+		slog.Debug("NTP synced", "server", server, "offset", response.ClockOffset, "local_time", ts.localTime)
 		return nil
 	}
 
@@ -158,26 +197,21 @@ func (ts *TimeService) Now() time.Time {
 	return ts.syncedTime.Add(elapsed)
 }
 
-// TimeOffset returns the time offset in hours
+// Location returns the configured location, UTC when it could not be loaded
+func (ts *TimeService) Location() *time.Location {
+	return ts.location
+}
+
+// TimeOffset returns the current UTC offset of the configured location in minutes, e.g. 330
+// for Asia/Kolkata. The offset changes with DST, schedule days are anchored to Location instead.
 func (ts *TimeService) TimeOffset() int {
-	currentTime := ts.Now()
-	location, err := time.LoadLocation(ts.location)
-	if err != nil {
-		log.Printf("Warning: Could not load %s timezone: %v", ts.location, err)
-		return 0
-	}
-	locationTime := currentTime.In(location)
-	_, locationOffset := locationTime.Zone()
-	_, currentOffset := currentTime.Zone()
-	diffSeconds := locationOffset - currentOffset
-	return diffSeconds / 3600
+	_, offset := ts.NowInLocation().Zone()
+	return offset / 60
 }
 
-// NowInMoscow returns current time in Moscow timezone
+// NowInLocation returns the current time in the configured location
 func (ts *TimeService) NowInLocation() time.Time {
-	currentTime := ts.Now()
-	offset := ts.TimeOffset()
-	return currentTime.Add(time.Duration(offset) * time.Hour)
+	return ts.Now().In(ts.Location())
 }
 
 // Unix returns Unix timestamp using NTP time
@@ -212,7 +246,7 @@ func (ts *TimeService) StartPeriodicSync(interval time.Duration) chan struct{} {
 	go func() {
 		// Initial sync
 		if err := ts.Sync(); err != nil {
-			log.Printf("Initial NTP sync failed: %v", err)
+			slog.Warn("Initial NTP sync failed", "error", err)
 		}
 
 		ticker := time.NewTicker(interval)
@@ -222,10 +256,10 @@ func (ts *TimeService) StartPeriodicSync(interval time.Duration) chan struct{} {
 			select {
 			case <-ticker.C:
 				if err := ts.Sync(); err != nil {
-					log.Printf("Periodic NTP sync failed: %v", err)
+					slog.Warn("Periodic NTP sync failed", "error", err)
 				}
 			case <-stop:
-				log.Println("NTP periodic sync stopped")
+				slog.Info("NTP periodic sync stopped")
 				return
 			}
 		}
@@ -0,0 +1,26 @@
+package ntp
+
+import "testing"
+
+func TestTimeOffset(t *testing.T) {
+	tests := []struct {
+		location string
+		want     int
+	}{
+		{"UTC", 0},
+		{"Europe/Moscow", 180},
+		{"Asia/Kolkata", 330},
+		{"Asia/Kathmandu", 345},
+		{"Pacific/Marquesas", -570},
+		{"Invalid/Zone", 0}, // falls back to UTC
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			ts := NewTimeService(Config{NTPLocation: tt.location})
+			if got := ts.TimeOffset(); got != tt.want {
+				t.Errorf("TimeOffset() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}